@@ -60,6 +60,20 @@ func ConnectDatabase() {
 		log.Fatal("❌ Failed to enable PostGIS:", err)
 	}
 
+	// ============================
+	// ✅ ลบ station pair ที่ซ้ำก่อนสร้าง unique index
+	// ============================
+	if err := dedupeStationPairs(db); err != nil {
+		log.Fatal("❌ Failed to dedupe station pairs:", err)
+	}
+
+	// ============================
+	// ✅ travel cache แบบเก่า (key ไม่มี server) ลบทิ้งให้สร้างใหม่
+	// ============================
+	if err := resetLegacyTravelCache(db); err != nil {
+		log.Fatal("❌ Failed to reset travel cache:", err)
+	}
+
 	// ============================
 	// ✅ AutoMigrate (ปลอดภัยอยู่แล้ว)
 	// ============================
@@ -76,15 +90,21 @@ func ConnectDatabase() {
 		&model_database.RoutePath{},
 		&model_database.StationPair{},
 		&model_database.ScheduleData{},
+		&model_database.ScheduleHeadwayBand{},
 		&model_database.BusInformation{},
+		&model_database.BusInformationBand{},
 		&model_database.ScenarioDetail{},
 		&model_database.Order{},
 		&model_database.AlightingData{},
 		&model_database.InterArrivalData{},
+		&model_database.ObservationDataset{},
 		&model_database.UserConfiguration{},
 		&model_database.PublicConfiguration{},
 		&model_database.UserScenario{},
 		&model_database.PublicScenario{},
+		&model_database.SimulationRun{},
+		&model_database.CalibrationReport{},
+		&model_database.TravelCache{},
 	); err != nil {
 		log.Fatal("❌ AutoMigrate failed:", err)
 	}
@@ -93,6 +113,64 @@ func ConnectDatabase() {
 	fmt.Println("✅ Migration complete")
 }
 
+// dedupeStationPairs รวม station pair ที่ซ้ำกันใน network model เดียวกัน (ก่อนมี unique index)
+// เก็บแถวที่ id น้อยสุด ย้าย order ที่อ้างแถวซ้ำไปหาแถวที่เก็บ แล้วลบแถวซ้ำพร้อม route between ที่ไม่มีใครใช้แล้ว
+func dedupeStationPairs(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&model_database.StationPair{}) {
+		return nil
+	}
+	const duplicates = `
+		SELECT id, keep_id FROM (
+			SELECT id, first_value(id) OVER (
+				PARTITION BY network_model_id, fst_station_id, snd_station_id ORDER BY id
+			) AS keep_id
+			FROM station_pairs
+		) ranked WHERE id <> keep_id`
+
+	var count int64
+	if err := db.Raw(`SELECT count(*) FROM (` + duplicates + `) dup`).Scan(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return nil
+	}
+	log.Printf("⚠️  Removing %d duplicate station pair(s)", count)
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if tx.Migrator().HasTable(&model_database.Order{}) {
+			if err := tx.Exec(`
+				UPDATE orders o SET station_pair_id = dup.keep_id
+				FROM (` + duplicates + `) dup
+				WHERE o.station_pair_id = dup.id`).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Exec(`
+			WITH removed AS (
+				DELETE FROM station_pairs sp USING (` + duplicates + `) dup
+				WHERE sp.id = dup.id
+				RETURNING sp.id, sp.route_between_id
+			)
+			DELETE FROM route_betweens rb USING removed
+			WHERE rb.id = removed.route_between_id
+			  AND NOT EXISTS (
+				SELECT 1 FROM station_pairs sp
+				WHERE sp.route_between_id = rb.id AND sp.id NOT IN (SELECT id FROM removed)
+			  )`).Error
+	})
+}
+
+// resetLegacyTravelCache ลบตาราง travel cache ที่ยังไม่มีคอลัมน์ server ใน primary key
+// เป็นแค่ cache จึงทิ้งได้ ค่าที่ไม่รู้ว่ามาจาก server ไหนไม่ควรใช้ต่อ
+func resetLegacyTravelCache(db *gorm.DB) error {
+	m := db.Migrator()
+	if !m.HasTable(&model_database.TravelCache{}) || m.HasColumn(&model_database.TravelCache{}, "server") {
+		return nil
+	}
+	log.Println("⚠️  Dropping travel cache without server key")
+	return m.DropTable(&model_database.TravelCache{})
+}
+
 func DropDatabase(db *gorm.DB, schema string) error {
     log.Println("⚠️  Dropping schema...")
 
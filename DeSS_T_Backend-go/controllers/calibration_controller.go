@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"DeSS_T_Backend-go/models"
+	"DeSS_T_Backend-go/services"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// CalibrateSimulationHandler เทียบข้อมูลที่วัดจริงกับผล simulation แล้วบันทึกรายงาน
+func CalibrateSimulationHandler(c *fiber.Ctx) error {
+	var req models.CalibrationRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if len(req.Observed.Stations) == 0 && len(req.Observed.Routes) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "observed stations or routes are required",
+		})
+	}
+
+	if req.SimulationRunID == "" && req.TimePeriods == "" && len(req.TimeWindows) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "time_periods or time_windows is required when simulation_run_id is not given",
+		})
+	}
+
+	report, err := services.CreateCalibrationReport(req)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "scenario detail or simulation run not found",
+			})
+		}
+		if errors.Is(err, services.ErrCalibrationScenarioMismatch) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		var fleetErr *services.FleetFeasibilityError
+		if errors.As(err, &fleetErr) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":      "จำนวนรถไม่พอสำหรับตารางเวลา",
+				"detail":     err.Error(),
+				"violations": fleetErr.Violations,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to calibrate simulation: " + err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(report)
+}
+
+// GetCalibrationReports ดึงรายงาน calibration ทั้งหมดของ scenario detail
+func GetCalibrationReports(c *fiber.Ctx) error {
+	scenarioDetailID := c.Params("scenario_detail_id")
+	if scenarioDetailID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "scenario_detail_id is required",
+		})
+	}
+
+	reports, err := services.GetCalibrationReportsByScenarioDetailID(scenarioDetailID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"calibration_reports": reports,
+	})
+}
@@ -1,16 +1,127 @@
 package controllers
 
 import (
+	"DeSS_T_Backend-go/fitting"
 	"DeSS_T_Backend-go/models"
 	"DeSS_T_Backend-go/services"
 	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"path/filepath"
+	"strings"
 
-	// "path/filepath"
 	// "time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+// parseFitOptions อ่าน engine (auto | python | go) และ criterion (aic | bic | ks | chi2)
+// criterion ใช้กับ Go เท่านั้น ฝั่ง Python เลือกด้วย AIC เสมอ
+func parseFitOptions(c *fiber.Ctx) (string, fitting.Criterion, error) {
+    engine, err := services.ParseFitEngine(c.FormValue("engine", c.Query("engine")))
+    if err != nil {
+        return "", "", err
+    }
+    criterion, err := fitting.ParseCriterion(c.FormValue("criterion", c.Query("criterion")))
+    if err != nil {
+        return "", "", err
+    }
+    return engine, criterion, nil
+}
+
+// readDistributionUpload เลือก parser ตามรูปแบบไฟล์
+// CSV/TSV แบบหนึ่งไฟล์ต่อ station ใช้ form field "station" หรือชื่อไฟล์เป็นชื่อ station
+// strict=true ตรวจทุก cell แล้วคืนรายงาน (nil ถ้าไม่ได้ใช้ strict) ถ้ามี error จะได้ models.ErrValidationFailed
+func readDistributionUpload(
+    c *fiber.Ctx,
+    f *multipart.FileHeader,
+    reader io.Reader,
+    stationMap map[string]string,
+    kind fitting.Kind,
+) (models.Data, *models.ValidationReport, error) {
+
+    format, err := models.DetectUploadFormat(f.Filename, f.Header.Get("Content-Type"))
+    if err != nil {
+        return models.Data{}, nil, err
+    }
+
+    station := c.FormValue("station")
+    if station == "" && format != models.FormatXLSX {
+        station = strings.TrimSuffix(filepath.Base(f.Filename), filepath.Ext(f.Filename))
+    }
+
+    if !isStrictUpload(c) {
+        var data models.Data
+        if format == models.FormatXLSX {
+            data, err = models.DistributionExcelToJSONReader(reader, stationMap)
+        } else {
+            data, err = models.DistributionDelimitedToJSONReader(reader, format, station, stationMap)
+        }
+        return data, nil, err
+    }
+
+    // จำนวนคนลงเป็นจำนวนเต็ม ค่าทศนิยมจะถูกเตือน
+    integerValues := kind == fitting.KindAlighting
+    var data models.Data
+    var report models.ValidationReport
+    if format == models.FormatXLSX {
+        data, report, err = models.DistributionExcelToJSONStrict(reader, stationMap, integerValues)
+    } else {
+        data, report, err = models.DistributionDelimitedToJSONStrict(reader, format, station, stationMap, integerValues)
+    }
+    return data, &report, err
+}
+
+func isStrictUpload(c *fiber.Ctx) bool {
+    return strings.EqualFold(c.FormValue("strict", c.Query("strict")), "true")
+}
+
+// uploadError ตอบ 422 พร้อมรายงานเมื่อ strict mode ไม่ผ่าน ไม่งั้นเป็น error ของการอ่านไฟล์ตามเดิม
+func uploadError(c *fiber.Ctx, err error, report *models.ValidationReport, status int) error {
+    if errors.Is(err, models.ErrValidationFailed) && report != nil {
+        return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+            "error":  err.Error(),
+            "report": report,
+        })
+    }
+    return c.Status(status).JSON(fiber.Map{"error": err.Error()})
+}
+
+// readArrivalTimestampUpload อ่านไฟล์เวลามาถึงแล้วแบ่งตาม time_ranges ของ configuration
+// unit=seconds ถ้าต้องการช่วงห่างเป็นวินาที (ค่าเริ่มต้นเป็นนาที)
+func readArrivalTimestampUpload(
+    c *fiber.Ctx,
+    f *multipart.FileHeader,
+    reader io.Reader,
+    stationMap map[string]string,
+) (models.Data, error) {
+
+    timeRanges, err := models.ParseTimeRanges(c.FormValue("time_ranges", c.Query("time_ranges")))
+    if err != nil {
+        return models.Data{}, err
+    }
+
+    format, err := models.DetectUploadFormat(f.Filename, f.Header.Get("Content-Type"))
+    if err != nil {
+        return models.Data{}, err
+    }
+
+    rows, err := models.ReadUploadRows(reader, format)
+    if err != nil {
+        return models.Data{}, err
+    }
+
+    unitSeconds := strings.EqualFold(c.FormValue("unit", c.Query("unit")), "seconds")
+    return models.ArrivalTimestampsToData(rows, timeRanges, stationMap, unitSeconds)
+}
+
+// GetDistributionRegistry คืน distribution ที่รู้จักพร้อม parameter และช่วงค่าที่รับได้
+// ใช้สร้างฟอร์มแก้ distribution เอง (simulated=false คือดูผล fit ได้แต่ simulate ไม่ได้)
+func GetDistributionRegistry(c *fiber.Ctx) error {
+    return c.JSON(fiber.Map{"distributions": fitting.Specs()})
+}
+
 func UploadGuestAlightingFit(c *fiber.Ctx) error {
     // รับไฟล์
     f, err := c.FormFile("file")
@@ -28,6 +139,11 @@ func UploadGuestAlightingFit(c *fiber.Ctx) error {
     if err := json.Unmarshal([]byte(stationMapStr), &stationMap); err != nil {
         return c.Status(400).JSON(fiber.Map{"error": "invalid station_map"})
     }
+
+    engine, criterion, err := parseFitOptions(c)
+    if err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+    }
     // เปิดไฟล์เป็น reader
     reader, err := f.Open()
     if err != nil {
@@ -36,21 +152,19 @@ func UploadGuestAlightingFit(c *fiber.Ctx) error {
     defer reader.Close()
 
 
-    // อ่าน Excel → JSON (แก้ฟังก์ชันให้รับ io.Reader)
-    jsonData, err := models.DistributionExcelToJSONReader(
-        reader,
-        stationMap,
-    )
+    // อ่าน Excel / CSV / TSV → JSON (strict=true ตรวจแบบละเอียด)
+    jsonData, report, err := readDistributionUpload(c, f, reader, stationMap, fitting.KindAlighting)
 
     if err != nil {
-        return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+        return uploadError(c, err, report, 500)
     }
 
-    // ส่ง JSON ไป Python
-    result, err := services.CallPythonAlightingDistributionFit(jsonData)
+    // fit ด้วย Python หรือ Go ตาม engine ที่เลือก
+    result, err := services.FitDistributions(fitting.KindAlighting, jsonData, engine, criterion)
     if err != nil {
         return c.Status(500).JSON(fiber.Map{"error": err.Error()})
     }
+    result.Validation = report
 
     return c.JSON(result)
 }
@@ -70,27 +184,45 @@ func UploadGuestInterarrivalFit(c *fiber.Ctx) error {
     if err := json.Unmarshal([]byte(stationMapStr), &stationMap); err != nil {
         return c.Status(400).JSON(fiber.Map{"error": "invalid station_map"})
     }
+
+    engine, criterion, err := parseFitOptions(c)
+    if err != nil {
+        return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+    }
     // เปิดไฟล์เป็น reader
     reader, err := f.Open()
     if err != nil {
         return c.Status(500).JSON(fiber.Map{"error": "cannot open file", "detail": err.Error()})
     }
     defer reader.Close()
-    // อ่าน Excel → JSON (แก้ฟังก์ชันให้รับ io.Reader)
-    jsonData, err := models.DistributionExcelToJSONReader(
-        reader,
-        stationMap,
-    )
+
+    // mode=timestamps: ไฟล์เป็นเวลามาถึงของผู้โดยสาร (station, timestamp) → คำนวณช่วงห่างเอง
+    if c.FormValue("mode", c.Query("mode")) == "timestamps" {
+        jsonData, err := readArrivalTimestampUpload(c, f, reader, stationMap)
+        if err != nil {
+            return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+        }
+
+        result, err := services.FitDistributions(fitting.KindInterarrival, jsonData, engine, criterion)
+        if err != nil {
+            return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+        }
+        return c.JSON(result)
+    }
+
+    // อ่าน Excel / CSV / TSV → JSON (strict=true ตรวจแบบละเอียด)
+    jsonData, report, err := readDistributionUpload(c, f, reader, stationMap, fitting.KindInterarrival)
 
     if err != nil {
-        return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+        return uploadError(c, err, report, 500)
     }
 
-    // ส่ง JSON ไป Python
-    result, err := services.CallPythonInterarrivalDistributionFit(jsonData)
+    // fit ด้วย Python หรือ Go ตาม engine ที่เลือก
+    result, err := services.FitDistributions(fitting.KindInterarrival, jsonData, engine, criterion)
 
     if err != nil {
         return c.Status(500).JSON(fiber.Map{"error": err.Error()})
     }
+    result.Validation = report
     return c.JSON(result)
 }
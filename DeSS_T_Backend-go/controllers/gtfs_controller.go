@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"DeSS_T_Backend-go/models"
+	"DeSS_T_Backend-go/services"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// parseGTFSRouteIDs รับ route_ids เป็น JSON array หรือคั่นด้วย comma
+func parseGTFSRouteIDs(raw string) ([]string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	if strings.HasPrefix(raw, "[") {
+		var ids []string
+		if err := json.Unmarshal([]byte(raw), &ids); err != nil {
+			return nil, err
+		}
+		return ids, nil
+	}
+	return strings.Split(raw, ","), nil
+}
+
+// ImportGTFSFeed นำเข้า GTFS zip เป็น configuration + scenario ใหม่ของผู้ใช้
+// form: file, name, create_by, route_ids (ไม่ระบุ = ทุกเส้นทาง), capacity, max_distance_km,
+// reference_date (YYYYMMDD, ไม่ระบุ = วันที่มีเที่ยววิ่งมากที่สุด; ใช้เฉพาะ service ที่วิ่งในสัปดาห์นั้น)
+// dry_run=true คืนผลการแปลง (ป้าย, เส้นทาง, ตารางเวลา, คำเตือน) โดยไม่บันทึก
+func ImportGTFSFeed(c *fiber.Ctx) error {
+	f, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "file missing"})
+	}
+
+	routeIDs, err := parseGTFSRouteIDs(c.FormValue("route_ids"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid route_ids", "detail": err.Error()})
+	}
+	opts := models.GTFSOptions{RouteIDs: routeIDs}
+	if raw := c.FormValue("capacity"); raw != "" {
+		if opts.Capacity, err = strconv.Atoi(raw); err != nil || opts.Capacity <= 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "capacity must be a positive integer"})
+		}
+	}
+	if raw := c.FormValue("max_distance_km"); raw != "" {
+		if opts.MaxDistanceKm, err = strconv.ParseFloat(raw, 64); err != nil || opts.MaxDistanceKm <= 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "max_distance_km must be a positive number"})
+		}
+	}
+
+	if raw := strings.TrimSpace(c.FormValue("reference_date")); raw != "" {
+		if _, err := time.Parse("20060102", raw); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "reference_date must be YYYYMMDD"})
+		}
+		opts.ReferenceDate = raw
+	}
+
+	dryRun := strings.EqualFold(c.FormValue("dry_run", c.Query("dry_run")), "true")
+	createBy := c.FormValue("create_by")
+	if !dryRun && createBy == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ต้องระบุ create_by"})
+	}
+	name := strings.TrimSpace(c.FormValue("name"))
+	if name == "" {
+		name = strings.TrimSuffix(f.Filename, filepath.Ext(f.Filename))
+	}
+
+	reader, err := f.Open()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "cannot open file", "detail": err.Error()})
+	}
+	defer reader.Close()
+
+	plan, err := models.GTFSToImportPlan(reader, f.Size, opts)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "GTFS feed ไม่ถูกต้อง", "detail": err.Error()})
+	}
+
+	if dryRun {
+		return c.JSON(fiber.Map{"data": plan})
+	}
+
+	result, err := services.ImportGTFS(plan, name, createBy)
+	if err != nil {
+		log.Printf("❌ GTFS import error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "ไม่สามารถบันทึกข้อมูลได้", "detail": err.Error()})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "นำเข้า GTFS สำเร็จ",
+		"data":    result,
+	})
+}
+
+// ExportUserScenarioGTFS ดาวน์โหลด user scenario เป็น GTFS zip
+// query: agency_name, agency_url (ต้องระบุถ้าไม่ได้ตั้ง GTFS_AGENCY_URL), timezone (ค่าเริ่มต้น Asia/Bangkok),
+// start_date / end_date (YYYYMMDD)
+// จำนวนข้อมูลที่ export ไม่ได้อยู่ใน header X-GTFS-Warnings (รายละเอียดอยู่ใน log)
+func ExportUserScenarioGTFS(c *fiber.Ctx) error {
+	userScenarioID := c.Params("id")
+	if userScenarioID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ต้องระบุ user_scenario_id"})
+	}
+
+	feed := models.GTFSExportFeed{
+		AgencyName: strings.TrimSpace(c.Query("agency_name")),
+		AgencyURL:  strings.TrimSpace(c.Query("agency_url")),
+		Timezone:   strings.TrimSpace(c.Query("timezone")),
+		StartDate:  strings.TrimSpace(c.Query("start_date")),
+		EndDate:    strings.TrimSpace(c.Query("end_date")),
+	}
+	if feed.Timezone != "" {
+		if _, err := time.LoadLocation(feed.Timezone); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid timezone", "detail": err.Error()})
+		}
+	}
+	for _, d := range []string{feed.StartDate, feed.EndDate} {
+		if _, err := time.Parse("20060102", d); d != "" && err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": fmt.Sprintf("invalid date %q, expected YYYYMMDD", d)})
+		}
+	}
+	if feed.StartDate != "" && feed.EndDate != "" && feed.EndDate < feed.StartDate {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "end_date is before start_date"})
+	}
+
+	buf, warnings, err := services.ExportUserScenarioGTFS(userScenarioID, feed)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "ไม่พบข้อมูล Scenario นี้ในระบบ"})
+		}
+		if errors.Is(err, services.ErrInvalidGTFSExport) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ไม่สามารถแปลง Scenario เป็น GTFS ได้", "detail": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "เกิดข้อผิดพลาดในการดึงข้อมูล", "detail": err.Error()})
+	}
+
+	for _, w := range warnings {
+		log.Printf("⚠️ GTFS export %s: %s", userScenarioID, w)
+	}
+	c.Set("X-GTFS-Warnings", strconv.Itoa(len(warnings)))
+	c.Attachment("gtfs.zip")
+	return c.Send(buf.Bytes())
+}
@@ -4,6 +4,7 @@ import (
 	"DeSS_T_Backend-go/models"
 	"DeSS_T_Backend-go/services"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -83,26 +84,47 @@ func GetAreaCache(c *fiber.Ctx) error {
 }
 
 type BuildNetworkRequest struct {
-	Stations    []map[string]interface{} `json:"stations"`
-	NetworkName string                   `json:"network_name"`
+	Stations        []map[string]interface{} `json:"stations"`
+	NetworkName     string                   `json:"network_name"`
+	RoutingProvider string                   `json:"routing_provider,omitempty"` // ors | osrm | valhalla | osm | haversine (ว่าง = ตามค่าตั้งของระบบ)
+
+	// คู่ station ที่จะคำนวณ: full (ค่าเริ่มต้น) | knn | radius | routes
+	// คู่ที่ไม่ได้สร้างตอนนี้ขอเพิ่มภายหลังได้ที่ POST /api/configuration-details/:id/station-pairs
+	PairMode      string     `json:"pair_mode,omitempty"`
+	K             int        `json:"k,omitempty"`              // knn: จำนวนเพื่อนบ้าน (ค่าเริ่มต้น 8)
+	RadiusM       float64    `json:"radius_m,omitempty"`       // radius: ระยะเส้นตรงสูงสุด (เมตร, ค่าเริ่มต้น 1500)
+	RouteStations [][]string `json:"route_stations,omitempty"` // routes: ลำดับ station_detail_id ของแต่ละเส้นทางที่ร่างไว้
 }
 
-func BuildNetworkModel(c *fiber.Ctx) error {
-	// ORS key is optional — we will fallback if absent
-	orsKey := os.Getenv("ORS_API_KEY")
+// buildNetworkResponse คือ network model พร้อม provider ที่ใช้คำนวณจริง
+// routing_fallback = true เมื่อ provider ของระบบใช้ไม่ได้และเวลาเดินทางเป็นค่าประมาณจากระยะเส้นตรง
+type buildNetworkResponse struct {
+	models.NetworkModel
+	RoutingProvider string `json:"routing_provider"`
+	RoutingFallback bool   `json:"routing_fallback"`
+}
 
-	// DEBUG: Log if ORS key is available
-	if orsKey == "" {
-		fmt.Println("⚠️ WARNING: ORS_API_KEY not found in environment - will use straight lines only")
-	} else {
-		fmt.Println("✅ ORS_API_KEY found - will use OpenRouteService for route geometry")
+// resolveRoutingProvider เลือก provider ของ request แปลง error เป็น 400
+func resolveRoutingProvider(requested string) (services.RoutingProvider, bool, error) {
+	provider, fallback, err := services.ResolveRoutingProvider(requested)
+	if err != nil {
+		return nil, false, fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
+	return provider, fallback, nil
+}
 
+func BuildNetworkModel(c *fiber.Ctx) error {
 	var req BuildNetworkRequest
 	if err := c.BodyParser(&req); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid JSON: "+err.Error())
 	}
 
+	provider, fallback, err := resolveRoutingProvider(req.RoutingProvider)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✅ Building network matrix with %s\n", provider.Name())
+
 	// Convert generic station maps to StationDetail objects
 	stations := []models.StationDetail{}
 	for _, sMap := range req.Stations {
@@ -165,108 +187,75 @@ func BuildNetworkModel(c *fiber.Ctx) error {
 		return fiber.NewError(fiber.StatusBadRequest, "No valid stations found in request")
 	}
 
-	// 1) Matrix mode selection
-	matrixMode := strings.ToLower(os.Getenv("MATRIX_MODE")) // expect "ors"
-	if matrixMode == "" {
-		matrixMode = "ors"
-	}
-	maxStations := 0 // 0 = no limit; set env MATRIX_MAX_STATIONS to cap ORS usage
+	sel := models.PairSelection{Mode: req.PairMode, K: req.K, RadiusM: req.RadiusM, Routes: req.RouteStations}
+	full := sel.Mode == "" || strings.EqualFold(sel.Mode, models.PairModeFull)
+
+	// 1) Matrix size limit (เฉพาะ full ที่ต้องขอ n×n)
+	maxStations := 0 // 0 = no limit; set env MATRIX_MAX_STATIONS to cap routing server usage
 	if v := os.Getenv("MATRIX_MAX_STATIONS"); v != "" {
 		if p, perr := strconv.Atoi(v); perr == nil {
 			maxStations = p
 		}
 	}
 
-	if matrixMode != "ors" {
-		return fiber.NewError(fiber.StatusBadRequest, "Local matrix calculation disabled; set MATRIX_MODE=ors")
-	}
-	if orsKey == "" {
-		return fiber.NewError(fiber.StatusBadRequest, "ORS_API_KEY is missing; set it in the environment")
-	}
-	if maxStations > 0 && len(stations) > maxStations {
-		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Too many stations for ORS matrix: %d > %d", len(stations), maxStations))
+	if full && maxStations > 0 && len(stations) > maxStations {
+		return fiber.NewError(fiber.StatusBadRequest, fmt.Sprintf("Too many stations for %s matrix: %d > %d (use pair_mode knn, radius or routes)", provider.Name(), len(stations), maxStations))
 	}
 
-	var matrix *services.ORSMatrixResponse
-	var err error
-	// Soft timeout guard: ensure the handler returns fast even if ORS is slow
+	// 2. Create pair list with RouteBetween data
+	var pairs []models.StationPair
+	// Soft timeout guard: ensure the handler returns fast even if the routing server is slow
 	done := make(chan struct{})
-	var mErr error
+	var pErr error
 	go func() {
-		matrix, mErr = services.OrsMatrix(stations, orsKey)
+		pairs, pErr = services.BuildStationPairs(provider, stations, sel)
 		close(done)
 	}()
 	select {
 	case <-done:
-		err = mErr
+		err = pErr
 	case <-time.After(20 * time.Second):
-		err = fmt.Errorf("ORS matrix timed out")
+		err = fmt.Errorf("%s matrix timed out", provider.Name())
 	}
-	if err != nil || matrix == nil {
-		return fiber.NewError(fiber.StatusBadGateway, fmt.Sprintf("ORS matrix failed: %v", err))
+	if errors.Is(err, services.ErrInvalidPairRequest) {
+		return fiber.NewError(fiber.StatusBadRequest, err.Error())
 	}
-
-	// 2. Create pair list with RouteBetween data
-	pairs := []models.StationPair{}
-	routeBetweens := []models.RouteBetween{}
-
-	n := len(stations)
-
-	for i := 0; i < n; i++ {
-		for j := 0; j < n; j++ {
-			if i == j {
-				continue
-			}
-
-			// Create RouteBetween record with distance and travel time
-			routeBetweenID := fmt.Sprintf("%s-%s-route", stations[i].StationDetailID, stations[j].StationDetailID)
-			routeBetween := models.RouteBetween{
-				RouteBetweenID: routeBetweenID,
-				TravelTime:     matrix.Durations[i][j],
-				Distance:       matrix.Distances[i][j],
-			}
-			routeBetweens = append(routeBetweens, routeBetween)
-
-			// Create StationPair that references the RouteBetween
-			pair := models.StationPair{
-				StationPairID:  fmt.Sprintf("%s-%s", stations[i].StationDetailID, stations[j].StationDetailID),
-				FstStationID:   stations[i].StationDetailID,
-				SndStationID:   stations[j].StationDetailID,
-				RouteBetweenID: routeBetweenID,
-				RouteBetween:   routeBetween,
-			}
-
-			pairs = append(pairs, pair)
-		}
+	if err != nil {
+		return fiber.NewError(fiber.StatusBadGateway, fmt.Sprintf("%s matrix failed: %v", provider.Name(), err))
 	}
 
 	// Final response (includes RouteBetween data in each StationPair)
-	result := models.NetworkModel{
-		Name:           req.NetworkName,
-		StationDetails: stations,
-		StationPairs:   pairs,
+	c.Set("X-Routing-Provider", provider.Name())
+	result := buildNetworkResponse{
+		NetworkModel: models.NetworkModel{
+			Name:           req.NetworkName,
+			StationDetails: stations,
+			StationPairs:   pairs,
+		},
+		RoutingProvider: provider.Name(),
+		RoutingFallback: fallback,
 	}
 
 	return c.JSON(result)
 }
 
-// GetRouteGeometry returns a LineString between two coordinates using ORS when available,
-// otherwise falls back to a straight line.
-// Request body: {"start": [lon, lat], "end": [lon, lat]}
+// GetRouteGeometry returns a LineString between two coordinates using the routing provider,
+// which is a straight line when the haversine provider (or ROUTING_FALLBACK) is used.
+// Request body: {"start": [lon, lat], "end": [lon, lat], "routing_provider": "osrm"}
 func GetRouteGeometry(c *fiber.Ctx) error {
-	orsKey := os.Getenv("ORS_API_KEY")
-	if orsKey == "" {
-		return fiber.NewError(fiber.StatusInternalServerError, "ORS_API_KEY is missing; road geometry unavailable")
-	}
-
 	var body struct {
-		Start [2]float64 `json:"start"`
-		End   [2]float64 `json:"end"`
+		Start           [2]float64 `json:"start"`
+		End             [2]float64 `json:"end"`
+		RoutingProvider string     `json:"routing_provider"`
 	}
 
 	if err := c.BodyParser(&body); err != nil {
 		return fiber.NewError(fiber.StatusBadRequest, "Invalid JSON: "+err.Error())
 	}
+	provider, _, err := resolveRoutingProvider(body.RoutingProvider)
+	if err != nil {
+		return err
+	}
 
 	// Soft timeout guard for the single route (longer: 12s) and do not silently fallback
 	type routeRes struct {
@@ -275,31 +264,46 @@ func GetRouteGeometry(c *fiber.Ctx) error {
 	}
 	ch := make(chan routeRes, 1)
 	go func() {
-		r, e := services.OrsRoute(body.Start, body.End, orsKey)
+		r, e := provider.Route(body.Start, body.End)
 		ch <- routeRes{c: r, err: e}
 	}()
 	select {
 	case rr := <-ch:
 		if rr.err != nil {
-			return fiber.NewError(fiber.StatusBadGateway, "ORS route error: "+rr.err.Error())
+			return fiber.NewError(fiber.StatusBadGateway, provider.Name()+" route error: "+rr.err.Error())
 		}
 		if len(rr.c) == 0 {
-			return fiber.NewError(fiber.StatusBadGateway, "ORS returned empty geometry")
+			return fiber.NewError(fiber.StatusBadGateway, provider.Name()+" returned empty geometry")
 		}
 		return c.JSON(models.GeoLineString{
 			Type:        "LineString",
 			Coordinates: rr.c,
 		})
 	case <-time.After(12 * time.Second):
-		return fiber.NewError(fiber.StatusGatewayTimeout, "ORS route request timed out")
+		return fiber.NewError(fiber.StatusGatewayTimeout, provider.Name()+" route request timed out")
+	}
+}
+
+// InvalidateTravelCache ลบระยะทาง / เส้นทางที่ cache ไว้ ให้ build network ครั้งหน้าขอ routing provider ใหม่
+// Query: provider, profile (ว่าง = ทุกตัว), expired_only=true (ลบเฉพาะที่หมดอายุตาม TRAVEL_CACHE_TTL)
+// ไม่ระบุ filter เลยต้องส่ง all=true เพื่อยืนยันว่าจะลบ cache ทั้งหมด
+func InvalidateTravelCache(c *fiber.Ctx) error {
+	deleted, err := services.InvalidateTravelCache(c.Query("provider"), c.Query("profile"), c.QueryBool("expired_only"), c.QueryBool("all"))
+	if errors.Is(err, services.ErrTravelCacheFilterRequired) {
+		return fiber.NewError(fiber.StatusBadRequest, "provider, profile or expired_only is required; pass all=true to clear the whole travel cache")
 	}
+	if err != nil {
+		return fiber.NewError(fiber.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(fiber.Map{"deleted": deleted})
 }
 
-// ComputeRouteSegments computes route polylines for consecutive station points using ORS.
+// ComputeRouteSegments computes route polylines for consecutive station points using the routing provider.
 // Request body:
 //
 //	{
-//	  "points": [{"id":"S1","coord":[lon,lat]}, {"id":"S2","coord":[lon,lat]}, ...]
+//	  "points": [{"id":"S1","coord":[lon,lat]}, {"id":"S2","coord":[lon,lat]}, ...],
+//	  "routing_provider": "ors"
 //	}
 //
 // Response:
@@ -308,17 +312,13 @@ func GetRouteGeometry(c *fiber.Ctx) error {
 //	  "segments": [{"from":"S1","to":"S2","coords":[[lon,lat], ...]}, ...]
 //	}
 func ComputeRouteSegments(c *fiber.Ctx) error {
-	orsKey := os.Getenv("ORS_API_KEY")
-	if orsKey == "" {
-		return fiber.NewError(fiber.StatusInternalServerError, "ORS_API_KEY is missing; road geometry unavailable")
-	}
-
 	type routePoint struct {
 		ID    string     `json:"id"`
 		Coord [2]float64 `json:"coord"`
 	}
 	var body struct {
-		Points []routePoint `json:"points"`
+		Points          []routePoint `json:"points"`
+		RoutingProvider string       `json:"routing_provider"`
 	}
 
 	if err := c.BodyParser(&body); err != nil {
@@ -327,6 +327,10 @@ func ComputeRouteSegments(c *fiber.Ctx) error {
 	if len(body.Points) < 2 {
 		return fiber.NewError(fiber.StatusBadRequest, "At least 2 points are required")
 	}
+	provider, _, err := resolveRoutingProvider(body.RoutingProvider)
+	if err != nil {
+		return err
+	}
 
 	type seg struct {
 		From   string       `json:"from"`
@@ -346,7 +350,7 @@ func ComputeRouteSegments(c *fiber.Ctx) error {
 		}
 		ch := make(chan routeRes, 1)
 		go func(s, e [2]float64) {
-			r, err := services.OrsRoute(s, e, orsKey)
+			r, err := provider.Route(s, e)
 			ch <- routeRes{c: r, err: err}
 		}(start.Coord, end.Coord)
 
@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"DeSS_T_Backend-go/models"
+	"DeSS_T_Backend-go/services"
+	"encoding/json"
+	"errors"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// UploadObservationDatasets เก็บค่าดิบจาก workbook ไว้กับ configuration เพื่อ refit ภายหลัง
+// form: file, kind (alighting | interarrival), day_type (ว่าง = ทุกวัน), station_map (ไม่ส่ง = ใช้ชื่อ station ของ configuration)
+// kind=interarrival รองรับ mode=timestamps เหมือน upload ของ guest
+func UploadObservationDatasets(c *fiber.Ctx) error {
+	configDetailID := c.Params("id")
+	if configDetailID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ต้องระบุ configuration_detail_id"})
+	}
+
+	kind, err := services.ParseObservationKind(c.FormValue("kind", c.Query("kind")))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	dayType, err := models.NormalizeDayType(c.FormValue("day_type", c.Query("day_type")))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "day_type ไม่ถูกต้อง", "detail": err.Error()})
+	}
+
+	f, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "file missing"})
+	}
+
+	var stationMap map[string]string
+	if stationMapStr := c.FormValue("station_map"); stationMapStr != "" {
+		if err := json.Unmarshal([]byte(stationMapStr), &stationMap); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid station_map"})
+		}
+	} else {
+		stationMap, err = services.ConfigurationStationMap(configDetailID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "ไม่พบข้อมูล Configuration Detail นี้ในระบบ"})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "เกิดข้อผิดพลาดในการดึงข้อมูล", "detail": err.Error()})
+		}
+	}
+
+	reader, err := f.Open()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "cannot open file", "detail": err.Error()})
+	}
+	defer reader.Close()
+
+	var data models.Data
+	var report *models.ValidationReport
+	if c.FormValue("mode", c.Query("mode")) == "timestamps" {
+		data, err = readArrivalTimestampUpload(c, f, reader, stationMap)
+	} else {
+		data, report, err = readDistributionUpload(c, f, reader, stationMap, kind)
+	}
+	if err != nil {
+		return uploadError(c, err, report, fiber.StatusBadRequest)
+	}
+
+	result, err := services.SaveObservationDatasets(configDetailID, kind, dayType, data)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "ไม่พบข้อมูล Configuration Detail นี้ในระบบ"})
+		}
+		if errors.Is(err, services.ErrUnknownStation) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "มี station ที่ไม่อยู่ใน configuration นี้", "detail": err.Error()})
+		}
+		log.Printf("❌ Save observation error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "ไม่สามารถบันทึกข้อมูลได้", "detail": err.Error()})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":    "บันทึก observation dataset สำเร็จ",
+		"data":       result,
+		"validation": report,
+	})
+}
+
+// GetObservationDatasets ดึงรายการ dataset ของ configuration (?kind=, ?day_type=, ?values=true เพื่อดูค่าดิบ)
+func GetObservationDatasets(c *fiber.Ctx) error {
+	configDetailID := c.Params("id")
+	if configDetailID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ต้องระบุ configuration_detail_id"})
+	}
+
+	kind := ""
+	if raw := c.Query("kind"); raw != "" {
+		k, err := services.ParseObservationKind(raw)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		kind = string(k)
+	}
+
+	var dayType *string
+	if raw, ok := c.Queries()["day_type"]; ok {
+		dt, err := models.NormalizeDayType(raw)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "day_type ไม่ถูกต้อง", "detail": err.Error()})
+		}
+		dayType = &dt
+	}
+
+	result, err := services.GetObservationDatasets(configDetailID, kind, dayType, c.QueryBool("values", false))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "เกิดข้อผิดพลาดในการดึงข้อมูล", "detail": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"data": result})
+}
+
+// RefitObservationDatasets fit ใหม่จาก dataset ที่เก็บไว้ (apply=true เพื่อเขียนทับ distribution ของ configuration)
+func RefitObservationDatasets(c *fiber.Ctx) error {
+	configDetailID := c.Params("id")
+	if configDetailID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ต้องระบุ configuration_detail_id"})
+	}
+
+	var req models.RefitRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "รูปแบบข้อมูลไม่ถูกต้อง", "detail": err.Error()})
+	}
+
+	result, err := services.RefitObservationDatasets(configDetailID, req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefit) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "ไม่พบข้อมูลสำหรับ refit", "detail": err.Error()})
+		}
+		log.Printf("❌ Refit error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "ไม่สามารถ refit ได้", "detail": err.Error()})
+	}
+
+	return c.JSON(result)
+}
@@ -2,16 +2,19 @@ package controllers
 
 import (
 	"DeSS_T_Backend-go/models"
+	"DeSS_T_Backend-go/services"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
-func UploadGuestSchedulefile(c *fiber.Ctx) error {
-	// ดึง scenarioID จากพารามิเตอร์
-	scenarioID := c.Params("scenarioID")
-	if scenarioID == "" {
-		return fiber.NewError(fiber.StatusBadRequest, "scenarioID is required")
-	}
+// readScheduleUpload อ่านไฟล์ตารางเวลา (xlsx / CSV / TSV) แล้วตอบกลับพร้อมรายงานรายเซลล์
+// cell ที่อ่านไม่ได้หรือชื่อ route ที่ไม่รู้จัก → 422 พร้อม report
+func readScheduleUpload(c *fiber.Ctx, scenarioID string, routePaths map[string]string) error {
 	// รับไฟล์
 	f, err := c.FormFile("file")
 	if err != nil {
@@ -25,11 +28,108 @@ func UploadGuestSchedulefile(c *fiber.Ctx) error {
 	}
 	defer reader.Close()
 
-	// อ่าน Excel → JSON (แก้ฟังก์ชันให้รับ io.Reader)
-	jsonData, err := models.ScheduleExcelToJsonReader(reader, scenarioID)
+	format, err := models.DetectUploadFormat(f.Filename, f.Header.Get("Content-Type"))
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 	}
-	
+
+	// อ่าน Excel / CSV / TSV → JSON
+	var jsonData models.Paserschedule
+	if format == models.FormatXLSX {
+		jsonData, err = models.ScheduleExcelToJsonReader(reader, scenarioID, routePaths)
+	} else {
+		jsonData, err = models.ScheduleDelimitedToJsonReader(reader, format, scenarioID, routePaths)
+	}
+	if err != nil {
+		return uploadError(c, err, jsonData.Validation, 400)
+	}
+
 	return c.JSON(jsonData)
-}
\ No newline at end of file
+}
+
+// UploadGuestSchedulefile อ่านตารางเวลาของ guest
+// route_map (ไม่บังคับ): ชื่อ route path → route_path_id ถ้าไม่ส่งจะใช้ "<ชื่อ>-<scenarioID>"
+func UploadGuestSchedulefile(c *fiber.Ctx) error {
+	// ดึง scenarioID จากพารามิเตอร์
+	scenarioID := c.Params("scenarioID")
+	if scenarioID == "" {
+		return fiber.NewError(fiber.StatusBadRequest, "scenarioID is required")
+	}
+
+	var routePaths map[string]string
+	if routeMapStr := c.FormValue("route_map"); routeMapStr != "" {
+		if err := json.Unmarshal([]byte(routeMapStr), &routePaths); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid route_map"})
+		}
+	}
+
+	return readScheduleUpload(c, scenarioID, routePaths)
+}
+
+// UploadRouteScenarioSchedule อ่านตารางเวลาโดยจับคู่หัวคอลัมน์กับชื่อ route path ของ route scenario
+func UploadRouteScenarioSchedule(c *fiber.Ctx) error {
+	routeScenarioID := c.Params("id")
+	if routeScenarioID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ต้องระบุ route_scenario_id"})
+	}
+
+	routePaths, err := services.RouteScenarioPathMap(routeScenarioID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "ไม่พบข้อมูล Route Scenario นี้ในระบบ"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "เกิดข้อผิดพลาดในการดึงข้อมูล", "detail": err.Error()})
+	}
+
+	return readScheduleUpload(c, routeScenarioID, routePaths)
+}
+
+// PreviewHeadwaySchedule สร้างเวลาออกรถจากกฎ headway โดยไม่บันทึก (ใช้ดูผลก่อนบันทึก)
+func PreviewHeadwaySchedule(c *fiber.Ctx) error {
+	var rule models.HeadwayRule
+	if err := c.BodyParser(&rule); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "รูปแบบข้อมูลไม่ถูกต้อง", "detail": err.Error()})
+	}
+
+	departures, err := models.GenerateHeadwaySchedule(rule.FirstDeparture, rule.LastDeparture, rule.HeadwayBands)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"route_path_id": rule.RoutePathID,
+		"schedule_list": strings.Join(departures, ","),
+		"departures":    len(departures),
+	})
+}
+
+// SaveScheduleRule บันทึกกฎ headway ของเส้นทางใน scenario และสร้าง ScheduleList ใหม่
+// ส่งกฎเดิมซ้ำพร้อมค่าที่แก้ = แก้กฎ (schedule ของ route + day_type เดียวกันถูกแทนที่)
+func SaveScheduleRule(c *fiber.Ctx) error {
+	scenarioDetailID := c.Params("id")
+	if scenarioDetailID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ต้องระบุ scenario_detail_id"})
+	}
+
+	var rule models.HeadwayRule
+	if err := c.BodyParser(&rule); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "รูปแบบข้อมูลไม่ถูกต้อง", "detail": err.Error()})
+	}
+
+	result, err := services.SaveScheduleRule(scenarioDetailID, rule)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidScheduleRule) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "กฎ headway ของตารางเวลาไม่ถูกต้อง", "detail": err.Error()})
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "ไม่พบข้อมูล Scenario Detail นี้ในระบบ"})
+		}
+		log.Printf("❌ Save schedule rule error: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "ไม่สามารถบันทึกข้อมูลได้", "detail": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "บันทึกกฎ headway และสร้างตารางเวลาสำเร็จ",
+		"data":    result,
+	})
+}
@@ -3,8 +3,11 @@ package controllers
 import (
 	"DeSS_T_Backend-go/models"
 	"DeSS_T_Backend-go/services"
+	"errors"
+	"log"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
 func TransformSimulationHandler(c *fiber.Ctx) error {
@@ -17,12 +20,19 @@ func TransformSimulationHandler(c *fiber.Ctx) error {
 		})
 	}
 
-	result := services.TransformSimulationRequest(
+	result, err := services.TransformSimulationRequest(
 		req.ScenarioDetail,
 		req.ConfigurationDetail,
         req.TimePeriods,
 		req.TimeSlot,
+		req.TimeWindows,
+		req.DayType,
 	)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 
 	return c.JSON(result)
 }
@@ -37,20 +47,92 @@ func RunSimulationHandler(c *fiber.Ctx) error {
 	}
 
 	// Transform the request first
-	transformedData := services.TransformSimulationRequest(
+	transformedData, err := services.TransformSimulationRequest(
 		req.ScenarioDetail,
 		req.ConfigurationDetail,
 		req.TimePeriods,
 		req.TimeSlot,
+		req.TimeWindows,
+		req.DayType,
 	)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// เที่ยวที่ engine จะไม่ปล่อยเพราะรถที่วิ่งอยู่ครบ MaxBus แล้ว (ผลจะขาดเที่ยวเหล่านั้น) ให้ผู้ใช้รู้ก่อน
+	// ยกเว้นตั้งใจจำลองแบบนั้น, รอบไป-กลับที่รถไม่พอแนบเป็นคำเตือนในผลลัพธ์
+	fleetWarnings, err := services.CheckRunFleetFeasibility(transformedData, req.AllowInfeasibleSchedule)
+	if err != nil {
+		var fleetErr *services.FleetFeasibilityError
+		if errors.As(err, &fleetErr) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":      "จำนวนรถไม่พอสำหรับตารางเวลา",
+				"detail":     err.Error(),
+				"violations": fleetErr.Violations,
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 
 	// Call Python simulation service with transformed data
-	result, err := services.CallPythonSimulation(transformedData)
+	result, err := services.ExecuteSimulation(transformedData)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to run simulation: " + err.Error(),
 		})
 	}
 
+	// เก็บ run ไว้อ้างอิงภายหลัง (calibration ฯลฯ) — ถ้าเก็บไม่ได้ก็ยังส่งผลลัพธ์กลับตามปกติ
+	if run, err := services.SaveSimulationRun(
+		req.ScenarioDetail.ScenarioDetailID,
+		transformedData.TimePeriod,
+		req.TimeSlot,
+		transformedData.DayType,
+		result,
+	); err != nil {
+		log.Printf("⚠️ could not store simulation run: %v", err)
+	} else {
+		result["simulation_run_id"] = run.ID
+	}
+	if len(fleetWarnings) > 0 {
+		result["fleet_warnings"] = fleetWarnings
+	}
+
 	return c.JSON(result)
 }
+
+// GetBusTimelineHandler คืนตำแหน่งรถตามเวลาของ run ที่เก็บไว้ สำหรับ animation บนแผนที่
+// Query: ?resolution=<seconds> (default 30)
+func GetBusTimelineHandler(c *fiber.Ctx) error {
+	runID := c.Params("run_id")
+	if runID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "run_id is required",
+		})
+	}
+
+	resolution := c.QueryInt("resolution", 30)
+	if resolution <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "resolution must be a positive number of seconds",
+		})
+	}
+
+	timeline, err := services.BuildBusTimeline(runID, resolution)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "simulation run not found",
+			})
+		}
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(timeline)
+}
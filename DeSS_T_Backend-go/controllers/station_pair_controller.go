@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"DeSS_T_Backend-go/services"
+	"errors"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// EnsureConfigurationStationPairs คืน station pair ของคู่ที่อยู่ติดกันในเส้นทาง และคำนวณคู่ที่ยังไม่มี
+// ใช้กับ network model ที่สร้างด้วย pair_mode knn / radius / routes เพื่อดูระยะทาง/เวลาของคู่ใหม่ล่วงหน้า
+// (ตอนบันทึก scenario และตอนจำลอง backend คำนวณคู่ที่ขาดจาก FstStation/SndStation ของ order ให้เองอยู่แล้ว)
+// Request body: {"routes": [["station_detail_id", ...]], "routing_provider": "osrm"}
+func EnsureConfigurationStationPairs(c *fiber.Ctx) error {
+	configDetailID := c.Params("id")
+	if configDetailID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ต้องระบุ configuration_detail_id"})
+	}
+
+	var body struct {
+		Routes          [][]string `json:"routes"`
+		RoutingProvider string     `json:"routing_provider"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "รูปแบบข้อมูลไม่ถูกต้อง", "detail": err.Error()})
+	}
+
+	provider, fallback, err := services.ResolveRoutingProvider(body.RoutingProvider)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "routing provider ใช้งานไม่ได้", "detail": err.Error()})
+	}
+
+	pairs, created, err := services.EnsureStationPairs(configDetailID, body.Routes, provider)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "ไม่พบข้อมูล Configuration Detail นี้ในระบบ"})
+		case errors.Is(err, services.ErrUnknownStation):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "มี station ที่ไม่อยู่ใน configuration นี้", "detail": err.Error()})
+		case errors.Is(err, services.ErrInvalidPairRequest):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ข้อมูลเส้นทางไม่ถูกต้อง", "detail": err.Error()})
+		}
+		log.Printf("❌ Ensure station pairs error: %v", err)
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{"error": "ไม่สามารถคำนวณระยะทางระหว่าง station ได้", "detail": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"station_pairs":    pairs,
+		"created":          created,
+		"routing_provider": provider.Name(),
+		"routing_fallback": fallback,
+	})
+}
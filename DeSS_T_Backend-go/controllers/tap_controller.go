@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"DeSS_T_Backend-go/fitting"
+	"DeSS_T_Backend-go/models"
+	"DeSS_T_Backend-go/services"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime/multipart"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// readTapUpload อ่านไฟล์แตะบัตร (CSV/TSV/xlsx) แล้วแบ่งตาม time_ranges
+// option: unit=seconds, gap_seconds (รวมการแตะบัตรที่ป้ายเดียวกันเป็นการจอดหนึ่งครั้ง), max_trip_minutes, od=true
+func readTapUpload(
+	c *fiber.Ctx,
+	f *multipart.FileHeader,
+	reader io.Reader,
+	stopMap map[string]string,
+) (models.TapImportResult, error) {
+
+	timeRanges, err := models.ParseTimeRanges(c.FormValue("time_ranges", c.Query("time_ranges")))
+	if err != nil {
+		return models.TapImportResult{}, err
+	}
+
+	opts := models.TapOptions{
+		TimeRanges:  timeRanges,
+		UnitSeconds: strings.EqualFold(c.FormValue("unit", c.Query("unit")), "seconds"),
+		IncludeOD:   strings.EqualFold(c.FormValue("od", c.Query("od")), "true"),
+	}
+	if raw := c.FormValue("gap_seconds", c.Query("gap_seconds")); raw != "" {
+		if opts.AlightingGapSeconds, err = strconv.Atoi(raw); err != nil || opts.AlightingGapSeconds <= 0 {
+			return models.TapImportResult{}, errors.New("gap_seconds must be a positive integer")
+		}
+	}
+	if raw := c.FormValue("max_trip_minutes", c.Query("max_trip_minutes")); raw != "" {
+		if opts.MaxTripMinutes, err = strconv.Atoi(raw); err != nil || opts.MaxTripMinutes <= 0 {
+			return models.TapImportResult{}, errors.New("max_trip_minutes must be a positive integer")
+		}
+	}
+
+	format, err := models.DetectUploadFormat(f.Filename, f.Header.Get("Content-Type"))
+	if err != nil {
+		return models.TapImportResult{}, err
+	}
+
+	rows, err := models.ReadUploadRows(reader, format)
+	if err != nil {
+		return models.TapImportResult{}, err
+	}
+
+	return models.TapTransactionsToData(rows, stopMap, opts)
+}
+
+// fitTapImport fit ทั้ง interarrival และ alighting เมื่อขอ fit=true
+func fitTapImport(c *fiber.Ctx, result models.TapImportResult) (fiber.Map, error) {
+	engine, criterion, err := parseFitOptions(c)
+	if err != nil {
+		return nil, err
+	}
+
+	fits := fiber.Map{}
+	if len(result.Interarrival.Data) > 0 {
+		fit, err := services.FitDistributions(fitting.KindInterarrival, result.Interarrival, engine, criterion)
+		if err != nil {
+			return nil, err
+		}
+		fits[string(fitting.KindInterarrival)] = fit
+	}
+	if len(result.Alighting.Data) > 0 {
+		fit, err := services.FitDistributions(fitting.KindAlighting, result.Alighting, engine, criterion)
+		if err != nil {
+			return nil, err
+		}
+		fits[string(fitting.KindAlighting)] = fit
+	}
+	return fits, nil
+}
+
+// UploadGuestTapImport แปลงข้อมูลแตะบัตรเป็นตัวอย่าง interarrival / alighting (และ OD) โดยใช้ station_map
+// station_map: รหัสป้ายหรือชื่อป้ายในไฟล์ → station id
+func UploadGuestTapImport(c *fiber.Ctx) error {
+	f, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "file missing"})
+	}
+
+	stationMapStr := c.FormValue("station_map")
+	if stationMapStr == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "station_map missing"})
+	}
+	var stopMap map[string]string
+	if err := json.Unmarshal([]byte(stationMapStr), &stopMap); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid station_map"})
+	}
+
+	reader, err := f.Open()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "cannot open file", "detail": err.Error()})
+	}
+	defer reader.Close()
+
+	result, err := readTapUpload(c, f, reader, stopMap)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	response := fiber.Map{"data": result}
+	if strings.EqualFold(c.FormValue("fit", c.Query("fit")), "true") {
+		fits, err := fitTapImport(c, result)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+		}
+		response["fit"] = fits
+	}
+
+	return c.JSON(response)
+}
+
+// UploadConfigurationTapImport เหมือน UploadGuestTapImport แต่จับคู่ป้ายกับ station ของ configuration
+// (station_id_osm หรือชื่อ) และ save=true เก็บเป็น observation dataset ของ day_type ที่ระบุ (ว่าง = ทุกวัน) สำหรับ refit
+func UploadConfigurationTapImport(c *fiber.Ctx) error {
+	configDetailID := c.Params("id")
+	if configDetailID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ต้องระบุ configuration_detail_id"})
+	}
+
+	dayType, err := models.NormalizeDayType(c.FormValue("day_type", c.Query("day_type")))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "day_type ไม่ถูกต้อง", "detail": err.Error()})
+	}
+
+	f, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "file missing"})
+	}
+
+	stopMap, err := services.ConfigurationStopMap(configDetailID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "ไม่พบข้อมูล Configuration Detail นี้ในระบบ"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "เกิดข้อผิดพลาดในการดึงข้อมูล", "detail": err.Error()})
+	}
+
+	reader, err := f.Open()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "cannot open file", "detail": err.Error()})
+	}
+	defer reader.Close()
+
+	result, err := readTapUpload(c, f, reader, stopMap)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	response := fiber.Map{"data": result}
+
+	if strings.EqualFold(c.FormValue("save", c.Query("save")), "true") {
+		saved, err := services.SaveTapObservations(configDetailID, dayType, result)
+		if err != nil {
+			log.Printf("❌ Save tap observations error: %v", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "ไม่สามารถบันทึกข้อมูลได้", "detail": err.Error()})
+		}
+		response["saved"] = saved
+	}
+
+	if strings.EqualFold(c.FormValue("fit", c.Query("fit")), "true") {
+		fits, err := fitTapImport(c, result)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": err.Error()})
+		}
+		response["fit"] = fits
+	}
+
+	return c.JSON(response)
+}
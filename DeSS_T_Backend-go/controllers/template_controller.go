@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"DeSS_T_Backend-go/models"
+	"DeSS_T_Backend-go/services"
+	"bytes"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// GetDistributionTemplate ดาวน์โหลด template .xlsx สำหรับ upload ข้อมูล alighting / interarrival
+// ?time_ranges= (ไม่ส่ง = ใช้ time period ที่ configuration มีอยู่)
+func GetDistributionTemplate(c *fiber.Ctx) error {
+	configDetailID := c.Params("id")
+	if configDetailID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ต้องระบุ configuration_detail_id"})
+	}
+
+	kind, err := services.ParseObservationKind(c.Params("kind"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var timeRanges []string
+	if raw := c.Query("time_ranges"); raw != "" {
+		if timeRanges, err = models.ParseTimeRanges(raw); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
+
+	buf, err := services.DistributionTemplateFor(configDetailID, kind, timeRanges)
+	if err != nil {
+		return templateError(c, err, "ไม่พบข้อมูล Configuration Detail นี้ในระบบ")
+	}
+
+	return sendWorkbook(c, buf, string(kind)+"_template.xlsx")
+}
+
+// GetScheduleTemplate ดาวน์โหลด template .xlsx ตารางเวลาออกรถของ route scenario
+func GetScheduleTemplate(c *fiber.Ctx) error {
+	routeScenarioID := c.Params("id")
+	if routeScenarioID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ต้องระบุ route_scenario_id"})
+	}
+
+	buf, err := services.ScheduleTemplateFor(routeScenarioID)
+	if err != nil {
+		return templateError(c, err, "ไม่พบข้อมูล Route Scenario นี้ในระบบ")
+	}
+
+	return sendWorkbook(c, buf, "schedule_template.xlsx")
+}
+
+func templateError(c *fiber.Ctx, err error, notFound string) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": notFound})
+	}
+	if errors.Is(err, services.ErrInvalidTemplate) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "ไม่สามารถสร้าง template ได้", "detail": err.Error()})
+}
+
+func sendWorkbook(c *fiber.Ctx, buf *bytes.Buffer, filename string) error {
+	// Attachment ตั้ง Content-Type ตามนามสกุลไฟล์ให้ด้วย
+	c.Attachment(filename)
+	return c.Send(buf.Bytes())
+}
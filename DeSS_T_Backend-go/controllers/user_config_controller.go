@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"DeSS_T_Backend-go/fitting"
 	"DeSS_T_Backend-go/model_database"
 	"DeSS_T_Backend-go/models"
 	"DeSS_T_Backend-go/services"
@@ -33,6 +34,22 @@ func CreateUserConfiguration(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "ต้องระบุ create_by"})
 	}
 
+	// ✅ ตรวจ distribution / argument list ทุกแถวกับ registry
+	if cd := configInput.ConfigurationDetail; cd != nil {
+		if err := services.NormalizeConfigurationDayTypes(cd); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error":  "day_type ไม่ถูกต้อง",
+				"detail": err.Error(),
+			})
+		}
+		if err := services.ValidateConfigurationDistributions(cd.AlightingData, cd.InterArrivalData); err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error":  "distribution ไม่ถูกต้อง",
+				"detail": err.Error(),
+			})
+		}
+	}
+
 	// 💾 เรียก Service เพื่อบันทึกข้อมูล
 	log.Println("💾 กำลังเริ่มขั้นตอนการ Gen ID ใหม่และบันทึกข้อมูล...")
 	result, err := services.SaveUserConfiguration(configInput)
@@ -128,6 +145,8 @@ func GetConfigurationDetail(c *fiber.Ctx) error {
 			Distribution:          ad.Distribution,
 			ArgumentList:          ad.ArgumentList,
 			StationID:             ad.StationDetailID,
+			DayType:               ad.DayType,
+			Parameters:            fitting.ParameterMap(ad.Distribution, ad.ArgumentList),
 			
 		})
 	}
@@ -142,6 +161,8 @@ func GetConfigurationDetail(c *fiber.Ctx) error {
 			Distribution:          ia.Distribution,
 			ArgumentList:          ia.ArgumentList,
 			StationID:             ia.StationDetailID,
+			DayType:               ia.DayType,
+			Parameters:            fitting.ParameterMap(ia.Distribution, ia.ArgumentList),
 			
 		})
 	}
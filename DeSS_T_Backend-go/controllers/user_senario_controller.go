@@ -5,6 +5,8 @@ import (
 	"DeSS_T_Backend-go/models"
 	"DeSS_T_Backend-go/services"
 	"errors"
+	"fmt"
+	"log"
 	"strings"
 	"time"
 
@@ -62,9 +64,76 @@ func validateScenarioRouteScheduleConsistency(input model_database.UserScenario)
 		}
 	}
 
+	if err := services.ValidateBusInformationBands(sd.BusScenario.BusInformations); err != nil {
+		return fmt.Errorf("invalid bus information bands: %w", err)
+	}
+
 	return nil
 }
 
+// scenarioConsistencyError ตอบ 400 ของ validateScenarioRouteScheduleConsistency
+func scenarioConsistencyError(c *fiber.Ctx, err error) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error":  "ข้อมูล route และ schedule ไม่สอดคล้องกัน",
+		"detail": err.Error(),
+	})
+}
+
+// scenarioFleetWarnings คืนช่วงเวลาที่รถไม่พอเมื่อคิดรอบไป-กลับ เป็นคำเตือนตอนบันทึก (ไม่ปฏิเสธ)
+// ตรวจไม่ได้ (เช่น database มีปัญหา) จะ log แล้วบันทึกต่อตามปกติ
+func scenarioFleetWarnings(input model_database.UserScenario) []services.FleetViolation {
+	warnings, err := services.CheckScenarioFleetFeasibility(input.ScenarioDetail)
+	if err != nil {
+		log.Printf("⚠️ fleet feasibility check failed: %v", err)
+		return nil
+	}
+	return warnings
+}
+
+// validateScenarioRouteTopology คำนวณ station pair ที่เส้นทางใช้แต่ network model ยังไม่มี
+// แล้วตรวจว่าลำดับ station pair ของทุกเส้นทางต่อกันจริง (order ใน input ถูกแก้ให้อ้าง pair ที่สร้างใหม่)
+// คืน error ที่เขียน response ไปแล้ว (ให้ handler return ต่อได้ทันที) หรือ nil ถ้าผ่าน
+func validateScenarioRouteTopology(c *fiber.Ctx, input model_database.UserScenario) error {
+	err := services.ResolveScenarioStationPairs(input.ScenarioDetail)
+	if err == nil {
+		err = services.ValidateScenarioRouteTopology(input.ScenarioDetail)
+	}
+	if err == nil {
+		return nil
+	}
+
+	var topoErr *services.RouteTopologyError
+	if errors.As(err, &topoErr) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":       "ลำดับเส้นทางไม่ต่อเนื่องหรือไม่ถูกต้อง",
+			"detail":      topoErr.Error(),
+			"broken_link": topoErr,
+		})
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "ไม่พบ configuration_detail_id ที่อ้างอิง",
+			"detail": err.Error(),
+		})
+	}
+	if errors.Is(err, services.ErrUnknownStation) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "มี station ที่ไม่อยู่ใน configuration นี้",
+			"detail": err.Error(),
+		})
+	}
+	if errors.Is(err, services.ErrRoutingProviderUnavailable) {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error":  "ไม่สามารถคำนวณระยะทางระหว่าง station ได้",
+			"detail": err.Error(),
+		})
+	}
+	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error":  "เกิดข้อผิดพลาดในการตรวจสอบลำดับเส้นทาง",
+		"detail": err.Error(),
+	})
+}
+
 func CreateUserScenario(c *fiber.Ctx) error {
 	var input model_database.UserScenario
 
@@ -77,12 +146,23 @@ func CreateUserScenario(c *fiber.Ctx) error {
 	}
 
 	if err := validateScenarioRouteScheduleConsistency(input); err != nil {
+		return scenarioConsistencyError(c, err)
+	}
+
+	if err := validateScenarioRouteTopology(c, input); err != nil {
+		return err
+	}
+
+	if err := services.ApplyScheduleRules(input.ScenarioDetail.BusScenario.ScheduleDatas); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":  "ข้อมูล route และ schedule ไม่สอดคล้องกัน",
+			"error":  "กฎ headway ของตารางเวลาไม่ถูกต้อง",
 			"detail": err.Error(),
 		})
 	}
 
+	// ตรวจรถกับ input ก่อนบันทึก (CreateUserScenario เปลี่ยน id ของ input)
+	fleetWarnings := scenarioFleetWarnings(input)
+
 	// 2. เรียกใช้ Service เพื่อบันทึกข้อมูล
 	result, err := services.CreateUserScenario(input)
 	if err != nil {
@@ -93,10 +173,14 @@ func CreateUserScenario(c *fiber.Ctx) error {
 	}
 
 	// 3. ส่งผลลัพธ์กลับ
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+	body := fiber.Map{
 		"message": "สร้าง User Scenario สำเร็จ",
 		"data":    result,
-	})
+	}
+	if len(fleetWarnings) > 0 {
+		body["fleet_warnings"] = fleetWarnings
+	}
+	return c.Status(fiber.StatusCreated).JSON(body)
 }
 
 func GetUserScenarios(c *fiber.Ctx) error {
@@ -247,8 +331,16 @@ func EditUserScenario(c *fiber.Ctx) error {
 	}
 
 	if err := validateScenarioRouteScheduleConsistency(input); err != nil {
+		return scenarioConsistencyError(c, err)
+	}
+
+	if err := validateScenarioRouteTopology(c, input); err != nil {
+		return err
+	}
+
+	if err := services.ApplyScheduleRules(input.ScenarioDetail.BusScenario.ScheduleDatas); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":  "ข้อมูล route และ schedule ไม่สอดคล้องกัน",
+			"error":  "กฎ headway ของตารางเวลาไม่ถูกต้อง",
 			"detail": err.Error(),
 		})
 	}
@@ -257,6 +349,9 @@ func EditUserScenario(c *fiber.Ctx) error {
 	// เพื่อให้เวลาสร้างใหม่ มันจะไปสวมรอยเป็น ID เดิม ไม่ใช่เกิดเป็น ID ใหม่เอี่ยม
 	input.ID = scenarioID
 
+	// ตรวจรถกับ input ก่อนลบของเดิมและบันทึกใหม่
+	fleetWarnings := scenarioFleetWarnings(input)
+
 	// 3. สั่งลบข้อมูลเก่า (เรียกใช้ Service Delete)
 	err := services.DeleteUserScenarioByID(scenarioID)
 	if err != nil {
@@ -282,8 +377,12 @@ func EditUserScenario(c *fiber.Ctx) error {
 	}
 
 	// 5. ส่งผลลัพธ์กลับ
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+	body := fiber.Map{
 		"message":       "อัปเดต User Scenario สำเร็จเรียบร้อย",
 		"user_scenario": result,
-	})
+	}
+	if len(fleetWarnings) > 0 {
+		body["fleet_warnings"] = fleetWarnings
+	}
+	return c.Status(fiber.StatusOK).JSON(body)
 }
@@ -0,0 +1,443 @@
+package fitting
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ชื่อ distribution ต้องตรงกับที่ Python ใช้ (simulation engine อ่านชื่อแบบไม่สนตัวพิมพ์)
+const (
+	Exponential = "Exponential"
+	Gamma       = "Gamma"
+	Weibull     = "Weibull"
+	Lognormal   = "Lognormal"
+	Normal      = "Normal"
+	Poisson     = "Poisson"
+	Uniform     = "Uniform"
+)
+
+// Candidates คือ distribution ที่ลอง fit ตามลำดับ
+var Candidates = []string{Exponential, Gamma, Weibull, Lognormal, Normal, Poisson, Uniform}
+
+// CanonicalName คืนชื่อ distribution ที่ fit ได้ตามที่ใช้ในระบบ (ไม่สนตัวพิมพ์) หรือ false ถ้าไม่รู้จัก
+func CanonicalName(name string) (string, bool) {
+	for _, c := range append([]string{Empirical}, Candidates...) {
+		if strings.EqualFold(c, strings.TrimSpace(name)) {
+			return c, true
+		}
+	}
+	return "", false
+}
+
+// เหมือนฝั่ง Python: shape ต่ำกว่านี้หางยาวเกินไปจนสุ่มได้ค่ามหาศาล จึงไม่ใช้
+const minShape = 0.2
+
+var errNotApplicable = errors.New("distribution does not apply to the data")
+
+// distribution คือ distribution ที่ fit แล้ว
+type distribution interface {
+	Name() string
+	Params() []float64
+	LogPDF(x float64) float64
+	CDF(x float64) float64
+	Discrete() bool
+	ArgumentList() string
+}
+
+// fitDistribution ประมาณ parameter แบบ maximum likelihood
+func fitDistribution(name string, values []float64) (distribution, error) {
+	switch name {
+	case Exponential:
+		return fitExponential(values)
+	case Gamma:
+		return fitGamma(values)
+	case Weibull:
+		return fitWeibull(values)
+	case Lognormal:
+		return fitLognormal(values)
+	case Normal:
+		return fitNormal(values)
+	case Poisson:
+		return fitPoisson(values)
+	case Uniform:
+		return fitUniform(values)
+	case Empirical:
+		return fitEmpirical(values, CompactQuantiles)
+	}
+	return nil, fmt.Errorf("unknown distribution %q", name)
+}
+
+// ---------------- Exponential ----------------
+
+type exponentialDist struct{ rate float64 }
+
+func fitExponential(values []float64) (distribution, error) {
+	if minOf(values) < 0 {
+		return nil, errNotApplicable
+	}
+	m := mean(values)
+	if m <= 0 {
+		return nil, errNotApplicable
+	}
+	return exponentialDist{rate: 1 / m}, nil
+}
+
+func (d exponentialDist) Name() string      { return Exponential }
+func (d exponentialDist) Params() []float64 { return []float64{d.rate} }
+func (d exponentialDist) Discrete() bool    { return false }
+
+func (d exponentialDist) LogPDF(x float64) float64 {
+	if x < 0 {
+		return math.Inf(-1)
+	}
+	return math.Log(d.rate) - d.rate*x
+}
+
+func (d exponentialDist) CDF(x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	return 1 - math.Exp(-d.rate*x)
+}
+
+func (d exponentialDist) ArgumentList() string {
+	return fmt.Sprintf("rate=%.4f, loc=%.4f", d.rate, 0.0)
+}
+
+// ---------------- Gamma ----------------
+
+type gammaDist struct{ shape, scale float64 }
+
+// fitGamma แก้ log(k) - ψ(k) = log(mean) - mean(log x) ด้วย Newton
+func fitGamma(values []float64) (distribution, error) {
+	if minOf(values) <= 0 {
+		return nil, errNotApplicable
+	}
+	m := mean(values)
+	s := math.Log(m) - meanLog(values)
+	if s <= 0 {
+		return nil, errNotApplicable
+	}
+
+	k := (3 - s + math.Sqrt((s-3)*(s-3)+24*s)) / (12 * s)
+	for i := 0; i < 100; i++ {
+		f := math.Log(k) - digamma(k) - s
+		df := 1/k - trigamma(k)
+		next := k - f/df
+		if next <= 0 {
+			next = k / 2
+		}
+		if math.Abs(next-k) < 1e-10*k {
+			k = next
+			break
+		}
+		k = next
+	}
+
+	if k < minShape {
+		return nil, errNotApplicable
+	}
+	return gammaDist{shape: k, scale: m / k}, nil
+}
+
+func (d gammaDist) Name() string      { return Gamma }
+func (d gammaDist) Params() []float64 { return []float64{d.shape, d.scale} }
+func (d gammaDist) Discrete() bool    { return false }
+
+func (d gammaDist) LogPDF(x float64) float64 {
+	if x <= 0 {
+		return math.Inf(-1)
+	}
+	lg, _ := math.Lgamma(d.shape)
+	return (d.shape-1)*math.Log(x) - x/d.scale - lg - d.shape*math.Log(d.scale)
+}
+
+func (d gammaDist) CDF(x float64) float64 {
+	return regGammaP(d.shape, x/d.scale)
+}
+
+func (d gammaDist) ArgumentList() string {
+	return fmt.Sprintf("shape=%.4f, loc=%.4f, scale=%.4f", d.shape, 0.0, d.scale)
+}
+
+// ---------------- Weibull ----------------
+
+type weibullDist struct{ shape, scale float64 }
+
+// fitWeibull แก้ Σx^k·ln x / Σx^k - 1/k - mean(ln x) = 0 ด้วย Newton (ตกไป bisection ถ้าหลุดช่วง)
+func fitWeibull(values []float64) (distribution, error) {
+	if minOf(values) <= 0 {
+		return nil, errNotApplicable
+	}
+	if maxOf(values) == minOf(values) {
+		return nil, errNotApplicable
+	}
+
+	ml := meanLog(values)
+	// ใช้ x / max กันค่า x^k ล้น
+	xmax := maxOf(values)
+
+	g := func(k float64) (float64, float64) {
+		var a, b, c float64
+		for _, v := range values {
+			lx := math.Log(v / xmax)
+			p := math.Pow(v/xmax, k)
+			a += p
+			b += p * lx
+			c += p * lx * lx
+		}
+		f := b/a - 1/k - (ml - math.Log(xmax))
+		df := (c*a-b*b)/(a*a) + 1/(k*k)
+		return f, df
+	}
+
+	lo, hi := 1e-3, 100.0
+	k := 1.2
+	for i := 0; i < 200; i++ {
+		f, df := g(k)
+		if math.Abs(f) < 1e-12 {
+			break
+		}
+		if f > 0 {
+			hi = k
+		} else {
+			lo = k
+		}
+		next := k - f/df
+		if next <= lo || next >= hi || math.IsNaN(next) {
+			next = (lo + hi) / 2
+		}
+		if math.Abs(next-k) < 1e-10*k {
+			k = next
+			break
+		}
+		k = next
+	}
+
+	if k < minShape {
+		return nil, errNotApplicable
+	}
+
+	sum := 0.0
+	for _, v := range values {
+		sum += math.Pow(v/xmax, k)
+	}
+	scale := xmax * math.Pow(sum/float64(len(values)), 1/k)
+
+	return weibullDist{shape: k, scale: scale}, nil
+}
+
+func (d weibullDist) Name() string      { return Weibull }
+func (d weibullDist) Params() []float64 { return []float64{d.shape, d.scale} }
+func (d weibullDist) Discrete() bool    { return false }
+
+func (d weibullDist) LogPDF(x float64) float64 {
+	if x <= 0 {
+		return math.Inf(-1)
+	}
+	z := x / d.scale
+	return math.Log(d.shape/d.scale) + (d.shape-1)*math.Log(z) - math.Pow(z, d.shape)
+}
+
+func (d weibullDist) CDF(x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	return 1 - math.Exp(-math.Pow(x/d.scale, d.shape))
+}
+
+func (d weibullDist) ArgumentList() string {
+	return fmt.Sprintf("shape=%.4f, loc=%.4f, scale=%.4f", d.shape, 0.0, d.scale)
+}
+
+// ---------------- Lognormal ----------------
+
+type lognormalDist struct{ mu, sigma float64 }
+
+func fitLognormal(values []float64) (distribution, error) {
+	if minOf(values) <= 0 {
+		return nil, errNotApplicable
+	}
+	mu := meanLog(values)
+	ss := 0.0
+	for _, v := range values {
+		d := math.Log(v) - mu
+		ss += d * d
+	}
+	sigma := math.Sqrt(ss / float64(len(values)))
+	if sigma <= 0 {
+		return nil, errNotApplicable
+	}
+	return lognormalDist{mu: mu, sigma: sigma}, nil
+}
+
+func (d lognormalDist) Name() string      { return Lognormal }
+func (d lognormalDist) Params() []float64 { return []float64{d.mu, d.sigma} }
+func (d lognormalDist) Discrete() bool    { return false }
+
+func (d lognormalDist) LogPDF(x float64) float64 {
+	if x <= 0 {
+		return math.Inf(-1)
+	}
+	z := (math.Log(x) - d.mu) / d.sigma
+	return -math.Log(x*d.sigma*math.Sqrt(2*math.Pi)) - z*z/2
+}
+
+func (d lognormalDist) CDF(x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	return 0.5 * math.Erfc(-(math.Log(x)-d.mu)/(d.sigma*math.Sqrt2))
+}
+
+func (d lognormalDist) ArgumentList() string {
+	return fmt.Sprintf("mu=%.4f, sigma=%.4f", d.mu, d.sigma)
+}
+
+// ---------------- Normal ----------------
+
+type normalDist struct{ mean, std float64 }
+
+func fitNormal(values []float64) (distribution, error) {
+	m := mean(values)
+	ss := 0.0
+	for _, v := range values {
+		ss += (v - m) * (v - m)
+	}
+	std := math.Sqrt(ss / float64(len(values)))
+	if std <= 0 {
+		return nil, errNotApplicable
+	}
+	return normalDist{mean: m, std: std}, nil
+}
+
+func (d normalDist) Name() string      { return Normal }
+func (d normalDist) Params() []float64 { return []float64{d.mean, d.std} }
+func (d normalDist) Discrete() bool    { return false }
+
+func (d normalDist) LogPDF(x float64) float64 {
+	z := (x - d.mean) / d.std
+	return -math.Log(d.std*math.Sqrt(2*math.Pi)) - z*z/2
+}
+
+func (d normalDist) CDF(x float64) float64 {
+	return 0.5 * math.Erfc(-(x-d.mean)/(d.std*math.Sqrt2))
+}
+
+func (d normalDist) ArgumentList() string {
+	return fmt.Sprintf("mean=%.4f, std=%.4f", d.mean, d.std)
+}
+
+// ---------------- Poisson ----------------
+
+type poissonDist struct{ lambda float64 }
+
+// fitPoisson ใช้ได้เฉพาะข้อมูลที่เป็นจำนวนเต็มไม่ติดลบ (เช่น จำนวนคนลง)
+func fitPoisson(values []float64) (distribution, error) {
+	for _, v := range values {
+		if v < 0 || v != math.Trunc(v) {
+			return nil, errNotApplicable
+		}
+	}
+	m := mean(values)
+	if m <= 0 {
+		return nil, errNotApplicable
+	}
+	return poissonDist{lambda: m}, nil
+}
+
+func (d poissonDist) Name() string      { return Poisson }
+func (d poissonDist) Params() []float64 { return []float64{d.lambda} }
+func (d poissonDist) Discrete() bool    { return true }
+
+func (d poissonDist) LogPDF(x float64) float64 {
+	if x < 0 || x != math.Trunc(x) {
+		return math.Inf(-1)
+	}
+	lf, _ := math.Lgamma(x + 1)
+	return x*math.Log(d.lambda) - d.lambda - lf
+}
+
+func (d poissonDist) CDF(x float64) float64 {
+	if x < 0 {
+		return 0
+	}
+	return regGammaQ(math.Floor(x)+1, d.lambda)
+}
+
+func (d poissonDist) ArgumentList() string {
+	return fmt.Sprintf("lambda=%.4f", d.lambda)
+}
+
+// ---------------- Uniform ----------------
+
+type uniformDist struct{ min, max float64 }
+
+func fitUniform(values []float64) (distribution, error) {
+	lo, hi := minOf(values), maxOf(values)
+	if hi <= lo {
+		return nil, errNotApplicable
+	}
+	return uniformDist{min: lo, max: hi}, nil
+}
+
+func (d uniformDist) Name() string      { return Uniform }
+func (d uniformDist) Params() []float64 { return []float64{d.min, d.max} }
+func (d uniformDist) Discrete() bool    { return false }
+
+func (d uniformDist) LogPDF(x float64) float64 {
+	if x < d.min || x > d.max {
+		return math.Inf(-1)
+	}
+	return -math.Log(d.max - d.min)
+}
+
+func (d uniformDist) CDF(x float64) float64 {
+	switch {
+	case x <= d.min:
+		return 0
+	case x >= d.max:
+		return 1
+	}
+	return (x - d.min) / (d.max - d.min)
+}
+
+func (d uniformDist) ArgumentList() string {
+	return fmt.Sprintf("min=%.4f, max=%.4f", d.min, d.max)
+}
+
+// ---------------- helpers ----------------
+
+func mean(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func meanLog(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += math.Log(v)
+	}
+	return sum / float64(len(values))
+}
+
+func minOf(values []float64) float64 {
+	m := math.Inf(1)
+	for _, v := range values {
+		m = math.Min(m, v)
+	}
+	return m
+}
+
+func maxOf(values []float64) float64 {
+	m := math.Inf(-1)
+	for _, v := range values {
+		m = math.Max(m, v)
+	}
+	return m
+}
@@ -0,0 +1,182 @@
+package fitting
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Empirical คือ distribution จากตัวอย่างจริง ArgumentList เป็นได้สองแบบ
+//   - ตาราง quantile: "q0=1.2000, q5=1.8000, ..., q100=9.5000" (simulation สุ่มแบบ inverse CDF เชิงเส้น)
+//   - อ้างอิง dataset ที่เก็บไว้: "dataset=<observation_dataset_id>" (transformer แปลงเป็นตาราง quantile ก่อนส่ง)
+const Empirical = "Empirical"
+
+const (
+	CompactQuantiles = 21  // ทุก 5% ใช้ตอน fit ให้ ArgumentList สั้นพอแก้ด้วยมือได้
+	DenseQuantiles   = 101 // ทุก 1% ใช้ตอนแปลง dataset ที่อ้างอิง
+)
+
+// ต้องมีตัวอย่างอย่างน้อยเท่านี้ ไม่งั้นตาราง quantile เป็นแค่ noise
+const minEmpiricalSample = 20
+
+// ถ้าทุก family มี KS p-value ต่ำกว่านี้ถือว่า fit ไม่ผ่าน แล้วเสนอ Empirical แทน
+const gofAlpha = 0.05
+
+type empiricalDist struct {
+	probs  []float64 // 0..1 เรียงจากน้อยไปมาก
+	values []float64 // quantile ที่ probs เดียวกัน (ไม่ลดลง)
+}
+
+// fitEmpirical สร้างตาราง quantile แบบ linear interpolation ของตัวอย่าง (Hyndman & Fan type 7)
+func fitEmpirical(values []float64, points int) (distribution, error) {
+	if len(values) < 2 || points < 2 {
+		return nil, errNotApplicable
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	if sorted[0] == sorted[len(sorted)-1] {
+		return nil, errNotApplicable
+	}
+
+	d := empiricalDist{probs: make([]float64, points), values: make([]float64, points)}
+	for i := 0; i < points; i++ {
+		p := float64(i) / float64(points-1)
+		pos := p * float64(len(sorted)-1)
+		lo := int(pos)
+		q := sorted[lo]
+		if lo+1 < len(sorted) {
+			q += (sorted[lo+1] - sorted[lo]) * (pos - float64(lo))
+		}
+		d.probs[i] = p
+		d.values[i] = q
+	}
+	return d, nil
+}
+
+// EmpiricalArgumentList คืนตาราง quantile ของตัวอย่างในรูปแบบ ArgumentList
+func EmpiricalArgumentList(values []float64, points int) (string, error) {
+	d, err := fitEmpirical(values, points)
+	if err != nil {
+		return "", fmt.Errorf("empirical distribution needs at least 2 distinct values")
+	}
+	return d.ArgumentList(), nil
+}
+
+func (d empiricalDist) Name() string      { return Empirical }
+func (d empiricalDist) Params() []float64 { return d.values }
+func (d empiricalDist) Discrete() bool    { return false }
+
+func (d empiricalDist) CDF(x float64) float64 {
+	n := len(d.values)
+	if x < d.values[0] {
+		return 0
+	}
+	if x >= d.values[n-1] {
+		return 1
+	}
+	i := sort.Search(n, func(i int) bool { return d.values[i] > x }) - 1
+	width := d.values[i+1] - d.values[i]
+	if width <= 0 {
+		return d.probs[i+1]
+	}
+	return d.probs[i] + (d.probs[i+1]-d.probs[i])*(x-d.values[i])/width
+}
+
+// LogPDF เป็นความหนาแน่นคงที่ในแต่ละช่วง quantile
+// ช่วงที่กว้างเป็นศูนย์ (ค่าซ้ำ) ใช้ความกว้างขั้นต่ำ 1/1000 ของพิสัย ไม่ให้ likelihood เป็นอนันต์
+func (d empiricalDist) LogPDF(x float64) float64 {
+	n := len(d.values)
+	if x < d.values[0] || x > d.values[n-1] {
+		return math.Inf(-1)
+	}
+	minWidth := (d.values[n-1] - d.values[0]) / 1000
+	i := sort.Search(n, func(i int) bool { return d.values[i] >= x })
+	if i == 0 {
+		i = 1
+	}
+	width := math.Max(d.values[i]-d.values[i-1], minWidth)
+	return math.Log((d.probs[i] - d.probs[i-1]) / width)
+}
+
+func (d empiricalDist) ArgumentList() string {
+	parts := make([]string, len(d.values))
+	for i, v := range d.values {
+		// ปัดเศษ floating point ของ percentile (0.55*100 = 55.00000000000001)
+		pct := math.Round(d.probs[i]*100*1e6) / 1e6
+		parts[i] = fmt.Sprintf("q%s=%.4f", strconv.FormatFloat(pct, 'f', -1, 64), v)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// parseEmpirical ตรวจ ArgumentList ของ Empirical (ตาราง quantile หรือ dataset=<id>)
+func parseEmpirical(argumentList string) (Parsed, error) {
+	fail := func(format string, args ...interface{}) (Parsed, error) {
+		return Parsed{}, fmt.Errorf("%w: %s: %s", ErrInvalidDistribution, Empirical, fmt.Sprintf(format, args...))
+	}
+
+	type point struct{ p, v float64 }
+	points := make([]point, 0)
+	params := make(map[string]float64)
+	dataset := ""
+
+	for _, kv := range strings.Split(argumentList, ",") {
+		key, raw, found := strings.Cut(kv, "=")
+		key, raw = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(raw)
+		if !found || key == "" {
+			return fail("expected key=value, got %q", strings.TrimSpace(kv))
+		}
+
+		if key == "dataset" {
+			if raw == "" {
+				return fail("dataset id is empty")
+			}
+			dataset = raw
+			continue
+		}
+
+		if !strings.HasPrefix(key, "q") {
+			return fail("unknown parameter %q (use q0..q100 or dataset)", key)
+		}
+		p, err := strconv.ParseFloat(key[1:], 64)
+		if err != nil || p < 0 || p > 100 {
+			return fail("quantile key %q must be q0..q100", key)
+		}
+		if _, dup := params[key]; dup {
+			return fail("parameter %q given twice", key)
+		}
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return fail("%s must be a number, got %q", key, raw)
+		}
+		params[key] = v
+		points = append(points, point{p: p, v: v})
+	}
+
+	if dataset != "" {
+		if len(points) > 0 {
+			return fail("use either dataset or a quantile table, not both")
+		}
+		return Parsed{Name: Empirical, Params: map[string]float64{}, Dataset: dataset}, nil
+	}
+
+	if len(points) < 2 {
+		return fail("quantile table needs at least q0 and q100")
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].p < points[j].p })
+	if points[0].p != 0 || points[len(points)-1].p != 100 {
+		return fail("quantile table must start at q0 and end at q100")
+	}
+	for i := 1; i < len(points); i++ {
+		if points[i].v < points[i-1].v {
+			return fail("quantiles must not decrease (q%g=%g < q%g=%g)", points[i].p, points[i].v, points[i-1].p, points[i-1].v)
+		}
+	}
+	if points[0].v < 0 {
+		return fail("q0 must be >= 0, got %g", points[0].v)
+	}
+
+	return Parsed{Name: Empirical, Params: params}, nil
+}
@@ -0,0 +1,444 @@
+package fitting
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"DeSS_T_Backend-go/models"
+)
+
+// Criterion คือเกณฑ์ที่ใช้จัดอันดับ distribution (ค่าน้อยดีกว่าทุกเกณฑ์)
+type Criterion string
+
+const (
+	CriterionAIC       Criterion = "aic"
+	CriterionBIC       Criterion = "bic"
+	CriterionKS        Criterion = "ks"
+	CriterionChiSquare Criterion = "chi2"
+)
+
+// Kind บอกว่าข้อมูลเป็นแบบไหน เพราะกรณีพิเศษ (ไม่มีคนมา / ไม่มีคนลง) ต่างกัน
+type Kind string
+
+const (
+	KindInterarrival Kind = "interarrival"
+	KindAlighting    Kind = "alighting"
+)
+
+// ParseCriterion แปลงค่าจาก request ว่างคือ AIC
+func ParseCriterion(s string) (Criterion, error) {
+	switch c := Criterion(strings.ToLower(strings.TrimSpace(s))); c {
+	case "":
+		return CriterionAIC, nil
+	case CriterionAIC, CriterionBIC, CriterionKS, CriterionChiSquare:
+		return c, nil
+	}
+	return "", fmt.Errorf("unknown criterion %q (use aic, bic, ks or chi2)", s)
+}
+
+const (
+	pdfCurvePoints  = 100
+	maxDiscreteBins = 60
+)
+
+// FitValues fit ทุก distribution ที่ใช้กับข้อมูลได้ แล้วเรียงจากดีที่สุดตาม criterion
+func FitValues(values []float64, criterion Criterion) ([]models.FitCandidate, error) {
+	return FitCandidates(values, Candidates, criterion)
+}
+
+// FitCandidates เหมือน FitValues แต่เลือกเฉพาะ distribution ที่ระบุ
+func FitCandidates(values []float64, names []string, criterion Criterion) ([]models.FitCandidate, error) {
+	if len(values) < 2 {
+		return nil, fmt.Errorf("need at least 2 values to fit, got %d", len(values))
+	}
+
+	results := make([]models.FitCandidate, 0, len(names))
+	for _, name := range names {
+		d, err := fitDistribution(name, values)
+		if err != nil {
+			continue
+		}
+		results = append(results, evaluate(d, values))
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no distribution fits the data")
+	}
+
+	rankResults(results, criterion)
+	return results, nil
+}
+
+func evaluate(d distribution, values []float64) models.FitCandidate {
+	n := float64(len(values))
+	k := float64(len(d.Params()))
+
+	ll := 0.0
+	for _, v := range values {
+		ll += d.LogPDF(v)
+	}
+
+	ks := ksStatistic(d, values)
+	chi, df := chiSquareStatistic(d, values)
+	chiP := 0.0
+	if df > 0 {
+		chiP = chiSquareSurvival(chi, df)
+	}
+
+	return models.FitCandidate{
+		Distribution:    d.Name(),
+		ArgumentList:    d.ArgumentList(),
+		Params:          d.Params(),
+		SampleSize:      len(values),
+		LogLikelihood:   ll,
+		AIC:             2*k - 2*ll,
+		BIC:             k*math.Log(n) - 2*ll,
+		KS:              ks,
+		KSPValue:        ksPValue(ks, len(values)),
+		ChiSquare:       chi,
+		ChiSquareDF:     df,
+		ChiSquarePValue: chiP,
+		PDF:             pdfCurve(d, values),
+	}
+}
+
+func rankResults(results []models.FitCandidate, criterion Criterion) {
+	score := func(r models.FitCandidate) float64 {
+		switch criterion {
+		case CriterionBIC:
+			return r.BIC
+		case CriterionKS:
+			return r.KS
+		case CriterionChiSquare:
+			if r.ChiSquareDF <= 0 {
+				return math.Inf(1)
+			}
+			// เทียบข้าม df ต่างกันด้วย statistic/df
+			return r.ChiSquare / float64(r.ChiSquareDF)
+		}
+		return r.AIC
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		si, sj := score(results[i]), score(results[j])
+		if si != sj {
+			return si < sj
+		}
+		return results[i].AIC < results[j].AIC
+	})
+}
+
+// ksStatistic คือ sup |F_n(x) - F(x)|
+func ksStatistic(d distribution, values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	n := float64(len(sorted))
+
+	dMax := 0.0
+	if d.Discrete() {
+		// เทียบที่แต่ละค่าที่ไม่ซ้ำ ทั้งก่อนและหลังกระโดด
+		for i := 0; i < len(sorted); {
+			j := i
+			for j < len(sorted) && sorted[j] == sorted[i] {
+				j++
+			}
+			below := math.Abs(float64(i)/n - d.CDF(sorted[i]-1))
+			at := math.Abs(float64(j)/n - d.CDF(sorted[i]))
+			dMax = math.Max(dMax, math.Max(below, at))
+			i = j
+		}
+		return dMax
+	}
+
+	for i, v := range sorted {
+		f := d.CDF(v)
+		dMax = math.Max(dMax, math.Max(float64(i+1)/n-f, f-float64(i)/n))
+	}
+	return dMax
+}
+
+// chiSquareStatistic แบ่ง bin (ต่อเนื่อง = กว้างเท่ากัน, ไม่ต่อเนื่อง = ทีละค่า)
+// แล้วรวม bin ที่ค่าคาดหวังน้อยกว่า 5 เข้าด้วยกัน
+func chiSquareStatistic(d distribution, values []float64) (float64, int) {
+	n := float64(len(values))
+
+	edges := binEdges(values, d.Discrete())
+	if len(edges) > 0 {
+		edges = edges[1 : len(edges)-1]
+	}
+
+	// bin สุดท้ายเปิดถึง +inf และ bin แรกเริ่มจาก -inf
+	observed := make([]float64, len(edges)+1)
+	for _, v := range values {
+		observed[sort.SearchFloat64s(edges, v)]++
+	}
+	expected := make([]float64, len(edges)+1)
+	prev := 0.0
+	for i, e := range edges {
+		c := d.CDF(e)
+		expected[i] = n * (c - prev)
+		prev = c
+	}
+	expected[len(edges)] = n * (1 - prev)
+
+	var obsMerged, expMerged []float64
+	accO, accE := 0.0, 0.0
+	for i := range observed {
+		accO += observed[i]
+		accE += expected[i]
+		if accE >= 5 {
+			obsMerged = append(obsMerged, accO)
+			expMerged = append(expMerged, accE)
+			accO, accE = 0, 0
+		}
+	}
+	if accE > 0 || accO > 0 {
+		if len(expMerged) == 0 {
+			obsMerged = append(obsMerged, accO)
+			expMerged = append(expMerged, accE)
+		} else {
+			obsMerged[len(obsMerged)-1] += accO
+			expMerged[len(expMerged)-1] += accE
+		}
+	}
+
+	stat := 0.0
+	for i := range obsMerged {
+		if expMerged[i] > 0 {
+			diff := obsMerged[i] - expMerged[i]
+			stat += diff * diff / expMerged[i]
+		}
+	}
+
+	df := len(obsMerged) - 1 - len(d.Params())
+	if df < 0 {
+		df = 0
+	}
+	return stat, df
+}
+
+// binEdges คืนขอบ bin ทั้งหมด (รวมขอบซ้ายสุด/ขวาสุด)
+// ไม่ต่อเนื่อง = ทีละจำนวนเต็ม (ถ้าช่วงไม่กว้างเกิน maxDiscreteBins), ต่อเนื่อง = กว้างเท่ากัน √n bin (5-20)
+func binEdges(values []float64, discrete bool) []float64 {
+	lo, hi := minOf(values), maxOf(values)
+
+	if discrete && hi-lo < maxDiscreteBins {
+		edges := make([]float64, 0, int(hi-lo)+2)
+		for v := lo; v <= hi+1; v++ {
+			edges = append(edges, v-0.5)
+		}
+		return edges
+	}
+
+	bins := int(math.Ceil(math.Sqrt(float64(len(values)))))
+	bins = max(5, min(bins, 20))
+	width := (hi - lo) / float64(bins)
+	if width <= 0 {
+		return nil
+	}
+
+	edges := make([]float64, bins+1)
+	for i := range edges {
+		edges[i] = lo + width*float64(i)
+	}
+	edges[bins] = hi
+	return edges
+}
+
+// Histogram นับข้อมูลตาม bin เดียวกับที่ใช้ทดสอบ chi-square
+func Histogram(values []float64, discrete bool) []models.HistogramBin {
+	edges := binEdges(values, discrete)
+	if len(edges) < 2 {
+		return nil
+	}
+
+	n := float64(len(values))
+	bins := make([]models.HistogramBin, len(edges)-1)
+	for i := range bins {
+		bins[i] = models.HistogramBin{Lower: edges[i], Upper: edges[i+1]}
+	}
+	for _, v := range values {
+		i := sort.SearchFloat64s(edges[1:len(edges)-1], v)
+		bins[i].Count++
+	}
+	for i := range bins {
+		bins[i].Density = float64(bins[i].Count) / (n * (bins[i].Upper - bins[i].Lower))
+	}
+	return bins
+}
+
+// pdfCurve คืนจุดของ PDF ตลอดช่วงข้อมูล (Poisson คืน pmf ที่จำนวนเต็ม)
+func pdfCurve(d distribution, values []float64) []models.CurvePoint {
+	lo, hi := minOf(values), maxOf(values)
+
+	if d.Discrete() {
+		points := make([]models.CurvePoint, 0, int(hi-lo)+1)
+		for x := lo; x <= hi && len(points) < pdfCurvePoints*10; x++ {
+			points = append(points, models.CurvePoint{X: x, Y: math.Exp(d.LogPDF(x))})
+		}
+		return points
+	}
+
+	points := make([]models.CurvePoint, 0, pdfCurvePoints)
+	step := (hi - lo) / float64(pdfCurvePoints-1)
+	for i := 0; i < pdfCurvePoints; i++ {
+		x := lo + step*float64(i)
+		points = append(points, models.CurvePoint{X: x, Y: math.Exp(d.LogPDF(x))})
+	}
+	return points
+}
+
+// FitItemFor เลือก distribution ที่ดีที่สุดแล้วคืนค่าในรูปแบบเดียวกับ Python
+// กรณีพิเศษเหมือนฝั่ง Python: interarrival ที่เป็น 0 ทั้งหมดคือ "No Arrival",
+// alighting ที่เป็น 0 (หรือเกือบ 0) หรือมีค่าเดียวคือ "Constant"
+func FitItemFor(values []float64, kind Kind, criterion Criterion) models.FitItem {
+	return FitItemWith(values, kind, criterion, nil)
+}
+
+// FitItemWith เหมือน FitItemFor แต่จำกัด distribution ที่ลองได้ (nil = ทุกตัว)
+func FitItemWith(values []float64, kind Kind, criterion Criterion, only []string) models.FitItem {
+	if kind == KindAlighting {
+		if len(values) == 0 || mean(values) < 0.01 {
+			return specialItem(Constant, fmt.Sprintf("value=%.4f", 0.0))
+		}
+	} else {
+		allZero := true
+		for _, v := range values {
+			if v != 0 {
+				allZero = false
+				break
+			}
+		}
+		if allZero {
+			return specialItem(NoArrival, "value=9999999.0")
+		}
+		values = trimUpperOutliers(values)
+	}
+
+	if minOf(values) == maxOf(values) {
+		return specialItem(Constant, fmt.Sprintf("value=%.4f", values[0]))
+	}
+
+	// alighting ที่มีค่าไม่หลากหลาย (≤ 3 ค่า) เป็นข้อมูลนับ ใช้ Poisson อย่างเดียวเหมือน Python
+	names := Candidates
+	if only != nil {
+		names = only
+	} else if kind == KindAlighting && countDistinct(values) <= 3 {
+		names = []string{Poisson}
+	}
+
+	results, err := FitCandidates(values, names, criterion)
+	if err != nil {
+		return specialItem(Constant, fmt.Sprintf("value=%.4f", mean(values)))
+	}
+
+	// ทุก family ไม่ผ่าน KS test (เช่นช่วงห่างที่มีหลาย mode) → ใช้ Empirical จากตัวอย่างเองแทน
+	// เฉพาะ interarrival: จำนวนคนลงถูกปัดเศษทิ้งใน simulation การสุ่มแบบต่อเนื่องจะได้ค่าต่ำเกินจริง
+	if only == nil && kind == KindInterarrival && allFailGoodnessOfFit(results, len(values)) {
+		if d, err := fitEmpirical(values, CompactQuantiles); err == nil {
+			results = append([]models.FitCandidate{evaluate(d, values)}, results...)
+		}
+	}
+
+	// ผู้ชนะต้องเป็นตัวที่ simulation engine สร้างได้ ตัวอื่นยังอยู่ใน Candidates ให้เปรียบเทียบ
+	best := results[0]
+	if only == nil {
+		for _, r := range results {
+			if IsSimulated(r.Distribution) {
+				best = r
+				break
+			}
+		}
+	}
+
+	return models.FitItem{
+		Distribution: best.Distribution,
+		ArgumentList: best.ArgumentList,
+		Parameters:   ParameterMap(best.Distribution, best.ArgumentList),
+		SampleSize:   len(values),
+		Candidates:   results,
+		Histogram:    Histogram(values, isIntegral(values)),
+	}
+}
+
+func allFailGoodnessOfFit(results []models.FitCandidate, n int) bool {
+	if n < minEmpiricalSample {
+		return false
+	}
+	for _, r := range results {
+		if r.KSPValue >= gofAlpha {
+			return false
+		}
+	}
+	return true
+}
+
+// specialItem คือผลของกรณีพิเศษที่ไม่ได้ fit (ไม่มี diagnostics)
+func specialItem(name, argumentList string) models.FitItem {
+	return models.FitItem{
+		Distribution: name,
+		ArgumentList: argumentList,
+		Parameters:   ParameterMap(name, argumentList),
+	}
+}
+
+func countDistinct(values []float64) int {
+	seen := make(map[float64]struct{}, len(values))
+	for _, v := range values {
+		seen[v] = struct{}{}
+	}
+	return len(seen)
+}
+
+func isIntegral(values []float64) bool {
+	for _, v := range values {
+		if v != math.Trunc(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// FitData fit ทุก station/time range ใน workbook ที่ parse แล้ว
+func FitData(data models.Data, kind Kind, criterion Criterion) models.DataFitResponse {
+	items := make([]models.FitItem, 0, len(data.Data))
+
+	for _, item := range data.Data {
+		values := make([]float64, 0, len(item.Records))
+		for _, rec := range item.Records {
+			values = append(values, rec.NumericValue)
+		}
+
+		fit := FitItemFor(values, kind, criterion)
+		fit.Station = item.Station
+		fit.TimeRange = item.TimeRange
+		items = append(items, fit)
+	}
+
+	return models.DataFitResponse{DataFitResponse: items}
+}
+
+// trimUpperOutliers ตัดค่าที่เกิน percentile 99 ออก (เมื่อมีข้อมูลมากกว่า 10 ค่า) เหมือนฝั่ง Python
+func trimUpperOutliers(values []float64) []float64 {
+	if len(values) <= 10 {
+		return values
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	pos := 0.99 * float64(len(sorted)-1)
+	i := int(pos)
+	limit := sorted[i]
+	if i+1 < len(sorted) {
+		limit += (sorted[i+1] - sorted[i]) * (pos - float64(i))
+	}
+
+	trimmed := make([]float64, 0, len(values))
+	for _, v := range values {
+		if v <= limit {
+			trimmed = append(trimmed, v)
+		}
+	}
+	return trimmed
+}
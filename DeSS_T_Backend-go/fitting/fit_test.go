@@ -0,0 +1,202 @@
+package fitting
+
+import (
+	"math"
+	"testing"
+
+	"DeSS_T_Backend-go/models"
+)
+
+// ค่าอ้างอิงในไฟล์นี้มาจากสูตรปิด (ψ ของจำนวนเต็ม/ครึ่ง, P(a, x) ของ a จำนวนเต็ม, erf)
+// และค่าวิกฤตมาตรฐานของ chi-square / Kolmogorov ซึ่งตรงกับ scipy.stats (chi2.sf, kstwobign.sf)
+
+func assertClose(t *testing.T, name string, got, want, relTol float64) {
+	t.Helper()
+	if math.Abs(got-want) > relTol*math.Max(1, math.Abs(want)) {
+		t.Errorf("%s = %.12g, want %.12g", name, got, want)
+	}
+}
+
+func TestDigammaTrigamma(t *testing.T) {
+	const euler = 0.5772156649015329
+	cases := []struct {
+		x, digamma, trigamma float64
+	}{
+		{0.5, -euler - 2*math.Ln2, math.Pi * math.Pi / 2},
+		{1, -euler, math.Pi * math.Pi / 6},
+		{2, 1 - euler, math.Pi*math.Pi/6 - 1},
+		{3, 1.5 - euler, math.Pi*math.Pi/6 - 1.25},
+		{10, 2.251752589066721, 0.10516633568168565},
+	}
+	// asymptotic series เริ่มที่ x ≥ 6 จึงคลาดได้ราว 1e-10 (พอสำหรับ Newton ของ fitGamma)
+	for _, tc := range cases {
+		assertClose(t, "digamma", digamma(tc.x), tc.digamma, 1e-9)
+		assertClose(t, "trigamma", trigamma(tc.x), tc.trigamma, 1e-9)
+	}
+}
+
+func TestRegularizedIncompleteGamma(t *testing.T) {
+	cases := []struct {
+		name string
+		a, x float64
+		want float64
+	}{
+		{"exponential", 1, 0.7, 1 - math.Exp(-0.7)},
+		{"series a=5 x=2", 5, 2, 0.052653017343711084},
+		{"continued fraction a=5 x=10", 5, 10, 0.970747311923039},
+		{"half series", 0.5, 0.3, math.Erf(math.Sqrt(0.3))},
+		{"half continued fraction", 0.5, 4, math.Erf(2)},
+		{"x = 0", 2, 0, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assertClose(t, "P", regGammaP(tc.a, tc.x), tc.want, 1e-12)
+			assertClose(t, "Q", regGammaQ(tc.a, tc.x), 1-tc.want, 1e-12)
+		})
+	}
+}
+
+func TestChiSquareSurvival(t *testing.T) {
+	cases := []struct {
+		x    float64
+		df   int
+		want float64
+	}{
+		{3.841458820694124, 1, 0.05},
+		{6.634896601021214, 1, 0.01},
+		{5.991464547107979, 2, 0.05},
+		{18.307038053275146, 10, 0.05},
+		{0, 3, 1},
+	}
+	for _, tc := range cases {
+		assertClose(t, "chi2.sf", chiSquareSurvival(tc.x, tc.df), tc.want, 1e-10)
+	}
+	if !math.IsNaN(chiSquareSurvival(1, 0)) {
+		t.Error("df = 0 should give NaN")
+	}
+}
+
+func TestKSPValue(t *testing.T) {
+	// ksPValue ใช้ λ = (√n + 0.12 + 0.11/√n)·d กับการแจกแจง Kolmogorov
+	const n = 100
+	scale := math.Sqrt(n) + 0.12 + 0.11/math.Sqrt(n)
+	cases := []struct {
+		lambda, want float64
+	}{
+		{1.3580986393225505, 0.05},
+		{1.6276236115189502, 0.01},
+		{1.0, 0.26999967167735456},
+	}
+	for _, tc := range cases {
+		assertClose(t, "ks p-value", ksPValue(tc.lambda/scale, n), tc.want, 1e-9)
+	}
+	if got := ksPValue(0, n); got != 1 {
+		t.Errorf("ks p-value at d = 0 is %v, want 1", got)
+	}
+}
+
+// สองจุด {1, t} ที่ AM/GM = exp(ln k − ψ(k)) ทำให้ MLE shape ของ gamma เท่ากับ k พอดี
+func TestFitGamma(t *testing.T) {
+	cases := []struct {
+		shape, t, scale float64
+	}{
+		{0.5, 48.7349841892578, 49.7349841892578},
+		{1, 10.594487119892737, 5.797243559946368},
+		{2, 4.6541483373485315, 1.4135370843371329},
+		{3, 3.39075805490396, 0.73179300915066},
+	}
+	for _, tc := range cases {
+		d, err := fitGamma([]float64{1, tc.t})
+		if err != nil {
+			t.Fatalf("shape %v: %v", tc.shape, err)
+		}
+		g := d.(gammaDist)
+		assertClose(t, "gamma shape", g.shape, tc.shape, 1e-9)
+		assertClose(t, "gamma scale", g.scale, tc.scale, 1e-9)
+	}
+
+	if _, err := fitGamma([]float64{0, 1, 2}); err == nil {
+		t.Error("gamma should not fit values that include 0")
+	}
+}
+
+// สองจุด {1, t} ที่ t หาด้วย bisection จากสมการ MLE ของ weibull ที่ shape กำหนด
+func TestFitWeibull(t *testing.T) {
+	cases := []struct {
+		shape, t, scale float64
+	}{
+		{0.8, 20.069406696140007, 9.406252763269876},
+		{1.5, 4.950910605374602, 3.3048788510986844},
+		{3.0, 2.2250641800574202, 1.817932576059597},
+	}
+	for _, tc := range cases {
+		d, err := fitWeibull([]float64{1, tc.t})
+		if err != nil {
+			t.Fatalf("shape %v: %v", tc.shape, err)
+		}
+		w := d.(weibullDist)
+		assertClose(t, "weibull shape", w.shape, tc.shape, 1e-9)
+		assertClose(t, "weibull scale", w.scale, tc.scale, 1e-9)
+	}
+
+	if _, err := fitWeibull([]float64{2, 2, 2}); err == nil {
+		t.Error("weibull should not fit constant values")
+	}
+}
+
+func TestEvaluateInformationCriteria(t *testing.T) {
+	// mean 5, MLE std 2
+	values := []float64{2, 4, 4, 4, 5, 5, 7, 9}
+	n := float64(len(values))
+
+	cases := []struct {
+		name string
+		ll   float64
+		k    float64
+	}{
+		{Normal, -n / 2 * (math.Log(2*math.Pi*4) + 1), 2},
+		{Exponential, -n*math.Log(5) - n, 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			results, err := FitCandidates(values, []string{tc.name}, CriterionAIC)
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := results[0]
+			assertClose(t, "log likelihood", r.LogLikelihood, tc.ll, 1e-12)
+			assertClose(t, "AIC", r.AIC, 2*tc.k-2*tc.ll, 1e-12)
+			assertClose(t, "BIC", r.BIC, tc.k*math.Log(n)-2*tc.ll, 1e-12)
+		})
+	}
+}
+
+func TestRankResults(t *testing.T) {
+	candidates := func() []models.FitCandidate {
+		return []models.FitCandidate{
+			{Distribution: "A", AIC: 10, BIC: 30, KS: 0.20, ChiSquare: 4, ChiSquareDF: 2},
+			{Distribution: "B", AIC: 12, BIC: 20, KS: 0.10, ChiSquare: 9, ChiSquareDF: 3},
+			{Distribution: "C", AIC: 11, BIC: 25, KS: 0.10, ChiSquare: 1, ChiSquareDF: 0},
+		}
+	}
+	cases := []struct {
+		criterion Criterion
+		want      string
+	}{
+		{CriterionAIC, "ACB"},
+		{CriterionBIC, "BCA"},
+		{CriterionKS, "CBA"},        // KS เท่ากัน ตัดสินด้วย AIC
+		{CriterionChiSquare, "ABC"}, // statistic/df: 2 < 3, df = 0 อยู่ท้าย
+	}
+	for _, tc := range cases {
+		results := candidates()
+		rankResults(results, tc.criterion)
+		got := ""
+		for _, r := range results {
+			got += r.Distribution
+		}
+		if got != tc.want {
+			t.Errorf("%s: order = %s, want %s", tc.criterion, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package fitting
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ชื่อพิเศษที่ไม่ได้มาจากการ fit แต่ simulation engine รู้จัก
+const (
+	Constant    = "Constant"
+	NoArrival   = "No Arrival"
+	NoAlighting = "No Alighting" // ชื่อเก่าของ Python alighting fitter เมื่อไม่มีข้อมูล = Constant 0
+)
+
+// ErrInvalidDistribution ครอบ error ทุกแบบของ ParseDistribution
+var ErrInvalidDistribution = errors.New("invalid distribution")
+
+// Param คือ parameter หนึ่งตัวของ distribution
+type Param struct {
+	Name         string   `json:"name"`
+	Aliases      []string `json:"aliases,omitempty"`
+	Min          *float64 `json:"min,omitempty"`
+	MinExclusive bool     `json:"min_exclusive,omitempty"` // ต้องมากกว่า Min (ไม่ใช่มากกว่าหรือเท่ากับ)
+	Optional     bool     `json:"optional,omitempty"`
+	Default      float64  `json:"default,omitempty"`
+}
+
+// Spec คือ distribution ที่ระบบรู้จัก
+// Simulated = simulation engine (Python build_distribution) สร้างได้ ตัวที่ false ใช้ได้แค่ดูผล fit
+type Spec struct {
+	Name      string  `json:"name"`
+	Params    []Param `json:"params"`
+	Simulated bool    `json:"simulated"`
+	Format    string  `json:"format,omitempty"` // รูปแบบ ArgumentList ของตัวที่ parameter ไม่ตายตัว
+
+	check func(p map[string]float64) error
+	parse func(argumentList string) (Parsed, error) // ใช้แทนการตรวจตาม Params
+}
+
+// Parsed คือ distribution ที่ parse ArgumentList แล้ว (ชื่อ parameter เป็นชื่อหลัก ไม่ใช่ alias)
+type Parsed struct {
+	Name    string
+	Params  map[string]float64
+	Dataset string // Empirical ที่อ้างอิง observation dataset แทนตาราง quantile
+}
+
+func bound(v float64) *float64 { return &v }
+
+var locParam = Param{Name: "loc", Optional: true}
+
+var registry = []Spec{
+	{Name: Constant, Simulated: true, Params: []Param{
+		{Name: "value", Min: bound(0)},
+	}},
+	{Name: NoArrival, Simulated: true, Params: []Param{
+		{Name: "value", Optional: true, Default: 9999999.0},
+	}},
+	{Name: NoAlighting, Simulated: true, Params: []Param{
+		{Name: "value", Optional: true, Min: bound(0)},
+	}},
+	{Name: Poisson, Simulated: true, Params: []Param{
+		{Name: "lambda", Min: bound(0), MinExclusive: true},
+	}},
+	{Name: Exponential, Simulated: true, Params: []Param{
+		{Name: "rate", Min: bound(0), MinExclusive: true},
+		locParam,
+	}},
+	{Name: Gamma, Simulated: true, Params: []Param{
+		{Name: "shape", Min: bound(0), MinExclusive: true},
+		locParam,
+		{Name: "scale", Min: bound(0), MinExclusive: true},
+	}},
+	{Name: Weibull, Simulated: true, Params: []Param{
+		{Name: "shape", Min: bound(0), MinExclusive: true},
+		locParam,
+		{Name: "scale", Min: bound(0), MinExclusive: true},
+	}},
+	{Name: Uniform, Simulated: true, Params: []Param{
+		{Name: "min", Aliases: []string{"low"}},
+		{Name: "max", Aliases: []string{"high"}},
+		locParam,
+	}, check: func(p map[string]float64) error {
+		if p["min"] > p["max"] {
+			return fmt.Errorf("min (%g) must not exceed max (%g)", p["min"], p["max"])
+		}
+		return nil
+	}},
+	{Name: Empirical, Simulated: true, Params: []Param{},
+		Format: "q0=<min>, q5=..., ..., q100=<max> or dataset=<observation_dataset_id>",
+		parse:  parseEmpirical},
+	{Name: Lognormal, Params: []Param{
+		{Name: "mu"},
+		{Name: "sigma", Min: bound(0), MinExclusive: true},
+	}},
+	{Name: Normal, Params: []Param{
+		{Name: "mean"},
+		{Name: "std", Min: bound(0), MinExclusive: true},
+	}},
+}
+
+// Specs คืน distribution ทั้งหมดที่ระบบรู้จัก
+func Specs() []Spec {
+	out := make([]Spec, len(registry))
+	copy(out, registry)
+	return out
+}
+
+// Lookup หา spec ตามชื่อ (ไม่สนตัวพิมพ์ เหมือน simulation engine)
+func Lookup(name string) (Spec, bool) {
+	for _, s := range registry {
+		if strings.EqualFold(s.Name, strings.TrimSpace(name)) {
+			return s, true
+		}
+	}
+	return Spec{}, false
+}
+
+// IsSimulated บอกว่า simulation engine สร้าง distribution ชื่อนี้ได้หรือไม่
+func IsSimulated(name string) bool {
+	s, ok := Lookup(name)
+	return ok && s.Simulated
+}
+
+// ParseDistribution ตรวจชื่อและ ArgumentList ("key=value, key=value") ตาม registry
+// parameter ที่ไม่รู้จัก, ซ้ำ, ขาด, ไม่ใช่ตัวเลข หรืออยู่นอกช่วง ถือว่าผิดทั้งหมด
+func ParseDistribution(name, argumentList string) (Parsed, error) {
+	spec, ok := Lookup(name)
+	if !ok {
+		return Parsed{}, fmt.Errorf("%w: unknown distribution %q", ErrInvalidDistribution, name)
+	}
+	if spec.parse != nil {
+		return spec.parse(argumentList)
+	}
+
+	params := make(map[string]float64, len(spec.Params))
+	if strings.TrimSpace(argumentList) != "" {
+		for _, kv := range strings.Split(argumentList, ",") {
+			key, raw, found := strings.Cut(kv, "=")
+			key = strings.ToLower(strings.TrimSpace(key))
+			if !found || key == "" {
+				return Parsed{}, fmt.Errorf("%w: %s: expected key=value, got %q", ErrInvalidDistribution, spec.Name, strings.TrimSpace(kv))
+			}
+
+			p, ok := spec.param(key)
+			if !ok {
+				return Parsed{}, fmt.Errorf("%w: %s: unknown parameter %q", ErrInvalidDistribution, spec.Name, key)
+			}
+			if _, dup := params[p.Name]; dup {
+				return Parsed{}, fmt.Errorf("%w: %s: parameter %q given twice", ErrInvalidDistribution, spec.Name, p.Name)
+			}
+
+			v, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+			if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+				return Parsed{}, fmt.Errorf("%w: %s: %s must be a number, got %q", ErrInvalidDistribution, spec.Name, p.Name, strings.TrimSpace(raw))
+			}
+			params[p.Name] = v
+		}
+	}
+
+	for _, p := range spec.Params {
+		v, ok := params[p.Name]
+		if !ok {
+			if !p.Optional {
+				return Parsed{}, fmt.Errorf("%w: %s: missing parameter %q", ErrInvalidDistribution, spec.Name, p.Name)
+			}
+			params[p.Name] = p.Default
+			continue
+		}
+		if p.Min != nil && (v < *p.Min || (p.MinExclusive && v == *p.Min)) {
+			op := ">="
+			if p.MinExclusive {
+				op = ">"
+			}
+			return Parsed{}, fmt.Errorf("%w: %s: %s must be %s %g, got %g", ErrInvalidDistribution, spec.Name, p.Name, op, *p.Min, v)
+		}
+	}
+
+	if spec.check != nil {
+		if err := spec.check(params); err != nil {
+			return Parsed{}, fmt.Errorf("%w: %s: %v", ErrInvalidDistribution, spec.Name, err)
+		}
+	}
+
+	return Parsed{Name: spec.Name, Params: params}, nil
+}
+
+// ParseSimulated เหมือน ParseDistribution แต่ต้องเป็นตัวที่ simulation engine สร้างได้ด้วย
+func ParseSimulated(name, argumentList string) (Parsed, error) {
+	parsed, err := ParseDistribution(name, argumentList)
+	if err != nil {
+		return Parsed{}, err
+	}
+	if !IsSimulated(parsed.Name) {
+		return Parsed{}, fmt.Errorf("%w: %s is not supported by the simulation engine", ErrInvalidDistribution, parsed.Name)
+	}
+	return parsed, nil
+}
+
+// ParameterMap คืน parameter ที่ parse แล้วสำหรับใส่ใน response (nil ถ้า parse ไม่ได้ เช่นข้อมูลเก่า)
+func ParameterMap(name, argumentList string) map[string]float64 {
+	parsed, err := ParseDistribution(name, argumentList)
+	if err != nil {
+		return nil
+	}
+	return parsed.Params
+}
+
+func (s Spec) param(key string) (Param, bool) {
+	for _, p := range s.Params {
+		if p.Name == key {
+			return p, true
+		}
+		for _, a := range p.Aliases {
+			if a == key {
+				return p, true
+			}
+		}
+	}
+	return Param{}, false
+}
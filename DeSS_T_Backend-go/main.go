@@ -3,6 +3,7 @@ package main
 import (
 	"DeSS_T_Backend-go/config"
 	"DeSS_T_Backend-go/routes"
+	"DeSS_T_Backend-go/services"
 	"fmt"
 	"log"
 	"os"
@@ -19,12 +20,11 @@ func main() {
 		log.Printf("⚠️ Warning: Could not load .env from %s: %v\n", envPath, err)
 	}
 
-	// Verify ORS_API_KEY is loaded
-	orsKey := os.Getenv("ORS_API_KEY")
-	if orsKey == "" {
-		fmt.Println("❌ ORS_API_KEY is empty - backend will use straight lines only")
+	// Verify the routing provider (ROUTING_PROVIDER: ors / osrm / valhalla / osm / haversine) is configured
+	if provider, err := services.NewRoutingProvider(services.DefaultRoutingProviderName()); err != nil {
+		fmt.Printf("❌ %v - network requests without routing_provider will fail (set ROUTING_FALLBACK=haversine to use offline estimates)\n", err)
 	} else {
-		fmt.Println("✅ ORS_API_KEY loaded successfully")
+		fmt.Printf("✅ Routing provider %s loaded successfully\n", provider.Name())
 	}
 
 	// Resolve upload directory (Docker uses UPLOAD_DIR; local defaults to ./uploads)
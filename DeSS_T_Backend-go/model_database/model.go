@@ -106,11 +106,27 @@ type ScheduleData struct {
     ScheduleList  string `json:"schedule_list"`
     RoutePathID   string `json:"route_path_id"`
     BusScenarioID string `json:"bus_scenario_id"`
+    DayType       string `json:"day_type" gorm:"column:day_type;default:''"` // ว่าง = ทุกวัน
+
+    // กฎ headway (ถ้ามี) ScheduleList จะถูกสร้างใหม่จากกฎทุกครั้งที่บันทึก
+    FirstDeparture string                `json:"first_departure,omitempty" gorm:"column:first_departure"`
+    LastDeparture  string                `json:"last_departure,omitempty" gorm:"column:last_departure"`
+    HeadwayBands   []ScheduleHeadwayBand `gorm:"foreignKey:ScheduleDataID;constraint:OnDelete:CASCADE;" json:"headway_bands,omitempty"`
 
     RoutePath   *RoutePath   `gorm:"foreignKey:RoutePathID;constraint:OnDelete:CASCADE;"`
     BusScenario *BusScenario `gorm:"foreignKey:BusScenarioID;constraint:OnDelete:CASCADE;"`
 }
 
+// ------------------- SCHEDULE HEADWAY BAND --------------------
+type ScheduleHeadwayBand struct {
+    ID             string `gorm:"primaryKey" json:"schedule_headway_band_id"`
+    ScheduleDataID string `json:"schedule_data_id" gorm:"column:schedule_data_id"`
+    TimeRange      string `json:"time_range" gorm:"column:time_range"`
+    HeadwayMinutes int    `json:"headway_minutes" gorm:"column:headway_minutes"`
+
+    ScheduleData *ScheduleData `gorm:"foreignKey:ScheduleDataID;constraint:OnDelete:CASCADE;" json:"-"`
+}
+
 // ------------------- BUS INFORMATION --------------------
 type BusInformation struct {
     ID            string  `gorm:"primaryKey" json:"bus_information_id"`
@@ -124,6 +140,23 @@ type BusInformation struct {
 
     RoutePath   *RoutePath   `gorm:"foreignKey:RoutePathID;constraint:OnDelete:CASCADE;"`
     BusScenario *BusScenario `gorm:"foreignKey:BusScenarioID;constraint:OnDelete:CASCADE;"`
+
+    Bands []BusInformationBand `gorm:"foreignKey:BusInformationID;constraint:OnDelete:CASCADE;" json:"bands"`
+}
+
+// ------------------- BUS INFORMATION BAND --------------------
+// ค่าของรถที่ใช้เฉพาะช่วงเวลา (เช่น peak เพิ่มจำนวนรถ) ถ้าไม่มี band ที่ตรงจะใช้ค่าใน BusInformation
+type BusInformationBand struct {
+    ID               string  `gorm:"primaryKey" json:"bus_information_band_id"`
+    BusInformationID string  `json:"bus_information_id" gorm:"column:bus_information_id"`
+    TimeRange        string  `json:"time_range" gorm:"column:time_range"`
+    Speed            float32 `json:"speed"`
+    MaxDis           float32 `json:"max_dis"`
+    MaxBus           int     `json:"max_bus"`
+    Capacity         int     `json:"capacity"`
+    AvgTravelTime    float32 `json:"avg_travel_time"`
+
+    BusInformation *BusInformation `gorm:"foreignKey:BusInformationID;constraint:OnDelete:CASCADE;" json:"-"`
 }
 
 // ------------------- ROUTE SCENARIO --------------------
@@ -142,6 +175,7 @@ type RoutePath struct {
     RouteScenarioID string         `json:"route_scenario_id"`
     Route           string         `gorm:"column:route;type:geometry(LineString,4326);<-:false" json:"-"`
     RouteJSON       LineStringData `gorm:"-" json:"route"`
+    AllowRevisit    bool           `gorm:"column:allow_revisit;default:false" json:"allow_revisit"` // เส้นทางเลขแปด
 
     RouteScenario   *RouteScenario   `gorm:"foreignKey:RouteScenarioID;constraint:OnDelete:CASCADE;" json:"-"`
     Orders          []Order          `gorm:"foreignKey:RoutePathID;constraint:OnDelete:CASCADE;" json:"orders"` // 🛠️ แก้ไขตรงนี้: เพิ่ม json tag
@@ -206,6 +240,25 @@ type ConfigurationDetail struct {
     ScenarioDetails      []ScenarioDetail      `gorm:"foreignKey:ConfigurationDetailID;constraint:OnDelete:CASCADE;"`
     AlightingData        []AlightingData       `gorm:"foreignKey:ConfigurationDetailID;constraint:OnDelete:CASCADE;" json:"alighting_datas"`
     InterArrivalData     []InterArrivalData    `gorm:"foreignKey:ConfigurationDetailID;constraint:OnDelete:CASCADE;" json:"interarrival_datas"`
+    ObservationDatasets  []ObservationDataset  `gorm:"foreignKey:ConfigurationDetailID;constraint:OnDelete:CASCADE;" json:"-"`
+}
+
+// ------------------- OBSERVATION DATASET --------------------
+// ค่าดิบที่ใช้ fit distribution (ต่อ station / time period) เก็บไว้เพื่อ refit โดยไม่ต้อง upload ใหม่
+type ObservationDataset struct {
+    ID                    string    `gorm:"primaryKey" json:"observation_dataset_id"`
+    ConfigurationDetailID string    `json:"configuration_detail_id" gorm:"column:configuration_detail_id;index"`
+    StationDetailID       string    `json:"station_id" gorm:"column:station_detail_id;index"`
+    Kind                  string    `json:"kind" gorm:"column:kind"` // alighting | interarrival
+    TimePeriod            string    `json:"time_period" gorm:"column:time_period"`
+    DayType               string    `json:"day_type" gorm:"column:day_type;default:''"` // ว่าง = ทุกวัน
+    SampleSize            int       `json:"sample_size" gorm:"column:sample_size"`
+    Values                string    `json:"-" gorm:"column:values;type:jsonb"` // []float64
+    Timestamps            *string   `json:"-" gorm:"column:timestamps;type:jsonb"` // []int วินาทีของวันคู่กับ Values (nil = แหล่งข้อมูลไม่มีเวลา)
+    CreatedAt             time.Time `json:"created_at"`
+
+    StationDetail       *StationDetail       `gorm:"foreignKey:StationDetailID;constraint:OnDelete:CASCADE;" json:"-"`
+    ConfigurationDetail *ConfigurationDetail `gorm:"foreignKey:ConfigurationDetailID;constraint:OnDelete:CASCADE;" json:"-"`
 }
 
 // ------------------- ALIGHTING DATA --------------------
@@ -216,6 +269,7 @@ type AlightingData struct {
     Distribution          string `json:"distribution" gorm:"column:distribution_name"`
     ArgumentList          string `json:"argument_list" gorm:"column:argument_list"`
     StationDetailID       string `json:"station_id" gorm:"column:station_detail_id"`
+    DayType               string `json:"day_type" gorm:"column:day_type;default:''"` // ว่าง = ทุกวัน
 
     StationDetail       *StationDetail       `gorm:"foreignKey:StationDetailID;constraint:OnDelete:CASCADE;"`
     ConfigurationDetail *ConfigurationDetail `gorm:"foreignKey:ConfigurationDetailID;constraint:OnDelete:CASCADE;" json:"configuration_detail"`
@@ -229,6 +283,7 @@ type InterArrivalData struct {
     Distribution          string `json:"distribution" gorm:"column:distribution_name"`
     ArgumentList          string `json:"argument_list" gorm:"column:argument_list"`
     StationDetailID       string `json:"station_id" gorm:"column:station_detail_id"`
+    DayType               string `json:"day_type" gorm:"column:day_type;default:''"` // ว่าง = ทุกวัน
 
     StationDetail       *StationDetail       `gorm:"foreignKey:StationDetailID;constraint:OnDelete:CASCADE;"`
     ConfigurationDetail *ConfigurationDetail `gorm:"foreignKey:ConfigurationDetailID;constraint:OnDelete:CASCADE;" json:"configuration_detail"`
@@ -264,12 +319,13 @@ type StationDetail struct {
 }
 
 // ------------------- STATION PAIR --------------------
+// คู่ (network_model_id, fst_station_id, snd_station_id) ไม่ซ้ำกัน
 type StationPair struct {
     ID             string `gorm:"primaryKey" json:"StationPairID"`
-    FstStationID   string `json:"FstStation" gorm:"column:fst_station_id"`
-    SndStationID   string `json:"SndStation" gorm:"column:snd_station_id"`
+    FstStationID   string `json:"FstStation" gorm:"column:fst_station_id;uniqueIndex:idx_station_pairs_network_fst_snd,priority:2"`
+    SndStationID   string `json:"SndStation" gorm:"column:snd_station_id;uniqueIndex:idx_station_pairs_network_fst_snd,priority:3"`
     RouteBetweenID string `json:"route_between_id" gorm:"column:route_between_id"`
-    NetworkModelID string `json:"network_model_id" gorm:"column:network_model_id"`
+    NetworkModelID string `json:"network_model_id" gorm:"column:network_model_id;uniqueIndex:idx_station_pairs_network_fst_snd,priority:1"`
 
     // ✅ ใช้ Pointer เพื่อป้องกัน Circular Dependency
     FstStation   *StationDetail `gorm:"foreignKey:FstStationID;constraint:OnDelete:CASCADE;" json:"-"`
@@ -289,6 +345,50 @@ type RouteBetween struct {
     StationPairs []StationPair `gorm:"foreignKey:RouteBetweenID;constraint:OnDelete:CASCADE;" json:"-"`
 }
 
+// ------------------- SIMULATION RUN --------------------
+// เก็บผลลัพธ์ดิบจาก Python simulation ไว้ให้ calibration / animation อ้างอิงย้อนหลังได้
+// ScenarioDetailID เป็น pointer เพราะ guest run ไม่มี scenario ใน DB
+type SimulationRun struct {
+    ID               string    `gorm:"primaryKey" json:"simulation_run_id"`
+    ScenarioDetailID *string   `json:"scenario_detail_id" gorm:"column:scenario_detail_id;index"`
+    TimePeriods      string    `json:"time_periods" gorm:"column:time_periods"`
+    TimeSlot         string    `json:"time_slot" gorm:"column:time_slot"`
+    DayType          string    `json:"day_type" gorm:"column:day_type"`
+    Result           string    `json:"-" gorm:"column:result;type:jsonb"`
+    CreatedAt        time.Time `json:"created_at"`
+}
+
+// ------------------- CALIBRATION REPORT --------------------
+type CalibrationReport struct {
+    ID               string    `gorm:"primaryKey" json:"calibration_report_id"`
+    ScenarioDetailID string    `json:"scenario_detail_id" gorm:"column:scenario_detail_id;index"`
+    SimulationRunID  string    `json:"simulation_run_id" gorm:"column:simulation_run_id"`
+    TimePeriod       string    `json:"time_period" gorm:"column:time_period"`
+    Observed         string    `json:"-" gorm:"column:observed;type:jsonb"`
+    Metrics          string    `json:"-" gorm:"column:metrics;type:jsonb"`
+    CreatedAt        time.Time `json:"created_at"`
+
+    ScenarioDetail *ScenarioDetail `gorm:"foreignKey:ScenarioDetailID;constraint:OnDelete:CASCADE;" json:"-"`
+    SimulationRun  *SimulationRun  `gorm:"foreignKey:SimulationRunID;constraint:OnDelete:CASCADE;" json:"-"`
+}
+
+// ------------------- TRAVEL CACHE --------------------
+// ระยะทาง / เวลา / เส้นทางระหว่างจุดที่ routing provider เคยคำนวณแล้ว (ไม่ต้องขอซ้ำทุกครั้งที่ build network)
+// key คือ provider + server (hash ของ base URL) + profile + พิกัดปัดทศนิยม 5 ตำแหน่ง ("lon,lat") ของต้นทางและปลายทาง
+// ค่า matrix กับ geometry มีเวลาบันทึกแยกกัน หมดอายุตาม TRAVEL_CACHE_TTL
+type TravelCache struct {
+    Provider         string     `gorm:"primaryKey;column:provider" json:"provider"`
+    Server           string     `gorm:"primaryKey;column:server" json:"server"`
+    Profile          string     `gorm:"primaryKey;column:profile" json:"profile"`
+    SrcKey           string     `gorm:"primaryKey;column:src_key" json:"src_key"`
+    DstKey           string     `gorm:"primaryKey;column:dst_key" json:"dst_key"`
+    Distance         *float64   `json:"distance" gorm:"column:distance"`
+    Duration         *float64   `json:"duration" gorm:"column:duration"`
+    MatrixCachedAt   *time.Time `json:"matrix_cached_at" gorm:"column:matrix_cached_at"`
+    Geometry         *string    `json:"-" gorm:"column:geometry;type:jsonb"`
+    GeometryCachedAt *time.Time `json:"geometry_cached_at" gorm:"column:geometry_cached_at"`
+}
+
 // โครงสร้างรับ GeoJSON จาก Frontend
 type LocationData struct {
     Type        string    `json:"type"`
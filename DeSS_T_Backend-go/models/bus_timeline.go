@@ -0,0 +1,27 @@
+package models
+
+// ---------------- BusTimeline ----------------
+// time-indexed bus positions of one simulation run, for map animation
+
+type BusTimeline struct {
+	SimulationRunID   string         `json:"simulation_run_id"`
+	ResolutionSeconds int            `json:"resolution_seconds"`
+	StartTime         string         `json:"start_time"`
+	EndTime           string         `json:"end_time"`
+	Frames            []BusTimeFrame `json:"frames"`
+}
+
+type BusTimeFrame struct {
+	Time  string        `json:"time"`
+	Buses []BusPosition `json:"buses"`
+}
+
+type BusPosition struct {
+	BusID     string     `json:"bus_id"`
+	RouteID   string     `json:"route_id"`
+	Position  [2]float64 `json:"position"` // [lon, lat]
+	Status    string     `json:"status"`   // "moving" | "dwelling"
+	Load      int        `json:"load"`
+	Capacity  int        `json:"capacity"`
+	Occupancy float64    `json:"occupancy"` // load / capacity, 0 when capacity is unknown
+}
@@ -0,0 +1,80 @@
+package models
+
+// ------------------ CalibrationRequest ------------------
+// observed field data for one scenario + period, compared against a stored
+// run (SimulationRunID) or a new run built from Configuration/Scenario
+
+type CalibrationRequest struct {
+    ScenarioDetailID    string              `json:"scenario_detail_id"`
+    SimulationRunID     string              `json:"simulation_run_id,omitempty"`
+    ConfigurationDetail ConfigurationDetail `json:"configuration"`
+    ScenarioDetail      ScenarioDetail      `json:"scenario"`
+    TimePeriods         string              `json:"time_periods"`
+    TimeSlot            string              `json:"time_slot"`
+    TimeWindows         []TimeWindow        `json:"time_windows,omitempty"`
+    Observed            ObservedData        `json:"observed"`
+    AllowInfeasibleSchedule bool            `json:"allow_infeasible_schedule,omitempty"` // เหมือน ProjectSimulationRequest ใช้เมื่อไม่ได้ระบุ simulation_run_id
+}
+
+// ------------------ ObservedData ------------------
+
+type ObservedData struct {
+    Stations []ObservedStation `json:"stations"`
+    Routes   []ObservedRoute   `json:"routes"`
+}
+
+// SlotName is optional; when empty the simulated value is averaged over all slots
+type ObservedStation struct {
+    StationName        string   `json:"station_name"`
+    SlotName           string   `json:"slot_name,omitempty"`
+    AverageWaitingTime *float64 `json:"average_waiting_time,omitempty"`
+    AverageQueueLength *float64 `json:"average_queue_length,omitempty"`
+}
+
+type ObservedRoute struct {
+    RouteID           string   `json:"route_id"`
+    SlotName          string   `json:"slot_name,omitempty"`
+    AverageTravelTime *float64 `json:"average_travel_time,omitempty"`
+}
+
+// ------------------ ErrorMetrics ------------------
+
+type ErrorMetrics struct {
+    Count int     `json:"count"`
+    MAE   float64 `json:"mae"`
+    RMSE  float64 `json:"rmse"`
+    MAPE  float64 `json:"mape"` // percent, observations equal to 0 are skipped
+    Bias  float64 `json:"bias"` // mean(simulated - observed)
+}
+
+type CalibrationResidual struct {
+    Key       string  `json:"key"`
+    SlotName  string  `json:"slot_name,omitempty"`
+    Observed  float64 `json:"observed"`
+    Simulated float64 `json:"simulated"`
+    Error     float64 `json:"error"`
+}
+
+type CalibrationMetric struct {
+    Metrics   ErrorMetrics          `json:"metrics"`
+    Residuals []CalibrationResidual `json:"residuals"`
+    Unmatched []string              `json:"unmatched,omitempty"`
+}
+
+// ------------------ CalibrationReport ------------------
+
+type CalibrationMetrics struct {
+    WaitingTime CalibrationMetric `json:"waiting_time"`
+    QueueLength CalibrationMetric `json:"queue_length"`
+    TravelTime  CalibrationMetric `json:"travel_time"`
+}
+
+type CalibrationReport struct {
+    CalibrationReportID string             `json:"calibration_report_id"`
+    ScenarioDetailID    string             `json:"scenario_detail_id"`
+    SimulationRunID     string             `json:"simulation_run_id"`
+    TimePeriod          string             `json:"time_period"`
+    CreatedAt           string             `json:"created_at"`
+    Observed            ObservedData       `json:"observed"`
+    Metrics             CalibrationMetrics `json:"metrics"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ประเภทวันของข้อมูล demand / schedule
+// ว่าง = ใช้ได้ทุกวัน (ข้อมูลเดิมก่อนมี day type) ชื่ออื่นนอกจากนี้ถือเป็น calendar ที่ผู้ใช้กำหนดเอง เช่น "school_term"
+const (
+	DayTypeAll           = ""
+	DayTypeWeekday       = "weekday"
+	DayTypeSaturday      = "saturday"
+	DayTypeSundayHoliday = "sunday_holiday"
+)
+
+var dayTypeAliases = map[string]string{
+	"weekdays": DayTypeWeekday,
+	"mon_fri":  DayTypeWeekday,
+	"sat":      DayTypeSaturday,
+	"sunday":   DayTypeSundayHoliday,
+	"sun":      DayTypeSundayHoliday,
+	"holiday":  DayTypeSundayHoliday,
+}
+
+var dayTypePattern = regexp.MustCompile(`^[a-z0-9_]{1,40}$`)
+
+// NormalizeDayType แปลงชื่อ day type ให้อยู่ในรูปเดียวกัน (ตัวเล็ก, "-" และช่องว่างเป็น "_", alias เป็นชื่อหลัก)
+func NormalizeDayType(s string) (string, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" || s == "all" {
+		return DayTypeAll, nil
+	}
+	s = strings.NewReplacer("-", "_", " ", "_").Replace(s)
+	if canonical, ok := dayTypeAliases[s]; ok {
+		return canonical, nil
+	}
+	if !dayTypePattern.MatchString(s) {
+		return "", fmt.Errorf("invalid day_type %q (use weekday, saturday, sunday_holiday or a custom name of letters, digits and _)", s)
+	}
+	return s, nil
+}
+
+// MatchesDayType บอกว่าข้อมูลที่มี day type นี้ใช้กับวันที่ขอได้หรือไม่ (ข้อมูลที่ไม่ระบุใช้ได้ทุกวัน)
+func MatchesDayType(recordDayType, requested string) bool {
+	return recordDayType == DayTypeAll || recordDayType == requested
+}
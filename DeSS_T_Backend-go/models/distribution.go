@@ -28,10 +28,50 @@ type FitItem struct {
     TimeRange    string `json:"Time_Range"`   // matches Python
     Distribution string `json:"Distribution"`
     ArgumentList string `json:"ArgumentList"`
+    Parameters   map[string]float64 `json:"Parameters,omitempty"` // ArgumentList ที่ parse แล้ว
+
+    // diagnostics (ไม่มีเมื่อเป็นกรณีพิเศษ เช่น No Arrival / Constant)
+    SampleSize int            `json:"SampleSize,omitempty"`
+    Candidates []FitCandidate `json:"Candidates,omitempty"` // เรียงจากดีที่สุด
+    Histogram  []HistogramBin `json:"Histogram,omitempty"`
+}
+
+// ------------------ FitCandidate ------------------
+
+type FitCandidate struct {
+    Distribution    string       `json:"Distribution"`
+    ArgumentList    string       `json:"ArgumentList"` // ใช้บันทึกลง AlightingData/InterArrivalData ได้ตรงๆ
+    Params          []float64    `json:"Params"`
+    SampleSize      int          `json:"SampleSize"`
+    LogLikelihood   float64      `json:"LogLikelihood"`
+    AIC             float64      `json:"AIC"`
+    BIC             float64      `json:"BIC"`
+    KS              float64      `json:"KS"`
+    KSPValue        float64      `json:"KS_PValue"`
+    ChiSquare       float64      `json:"ChiSquare"`
+    ChiSquareDF     int          `json:"ChiSquare_DF"`     // 0 = bin ไม่พอสำหรับทดสอบ
+    ChiSquarePValue float64      `json:"ChiSquare_PValue"`
+    PDF             []CurvePoint `json:"PDF"`              // pmf สำหรับ Poisson
+}
+
+// ------------------ HistogramBin ------------------
+
+type HistogramBin struct {
+    Lower   float64 `json:"Lower"`
+    Upper   float64 `json:"Upper"`
+    Count   int     `json:"Count"`
+    Density float64 `json:"Density"` // Count / (n * width) เทียบกับ PDF ได้ตรงๆ
+}
+
+type CurvePoint struct {
+    X float64 `json:"X"`
+    Y float64 `json:"Y"`
 }
 
 // ------------------ DataFitResponse ------------------
 
 type DataFitResponse struct {
     DataFitResponse []FitItem `json:"DataFitResponse"`
+    Engine          string    `json:"engine,omitempty"` // python | go
+    Validation      *ValidationReport `json:"validation,omitempty"` // มีเมื่อ upload แบบ strict
 }
@@ -0,0 +1,978 @@
+package models
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ค่าเริ่มต้นของ GTFSOptions (GTFS ไม่มีข้อมูลความจุ/ระยะวิ่งของรถ)
+const (
+	DefaultGTFSCapacity      = 60
+	DefaultGTFSMaxDistanceKm = 300
+	DefaultGTFSRouteColor    = "#2563EB"
+	gtfsFallbackSpeedKmh     = 20 // ใช้เมื่อ feed ไม่มีเวลาเดินทางที่ใช้ได้เลย
+	maxGTFSWarnings          = 50
+)
+
+type GTFSOptions struct {
+	RouteIDs      []string // ว่าง = ทุกเส้นทางใน routes.txt
+	Capacity      int
+	MaxDistanceKm float64
+	ReferenceDate string // YYYYMMDD; ว่าง = วันที่มีเที่ยววิ่งมากที่สุดตาม calendar.txt
+}
+
+type GTFSStop struct {
+	StopID string  `json:"stop_id"`
+	Name   string  `json:"name"`
+	Lat    float64 `json:"lat"`
+	Lon    float64 `json:"lon"`
+}
+
+// GTFSSegment คือช่วงระหว่างป้ายที่ติดกันหนึ่งช่วง (กลายเป็น StationPair + RouteBetween)
+type GTFSSegment struct {
+	FromStopID     string  `json:"from_stop_id"`
+	ToStopID       string  `json:"to_stop_id"`
+	TravelSeconds  float64 `json:"travel_seconds"`  // median ของทุกเที่ยวที่วิ่งผ่านช่วงนี้
+	DistanceMeters float64 `json:"distance_meters"` // ตาม shapes.txt ถ้ามี ไม่งั้นเป็นระยะเส้นตรง
+	Samples        int     `json:"samples"`
+}
+
+type GTFSSchedule struct {
+	DayType    string   `json:"day_type"`
+	Departures []string `json:"departures"`
+}
+
+// GTFSPattern คือลำดับป้ายหนึ่งแบบของเส้นทาง (route + direction + ลำดับป้าย) กลายเป็น RoutePath หนึ่งเส้น
+type GTFSPattern struct {
+	RouteID          string         `json:"route_id"`
+	DirectionID      string         `json:"direction_id"`
+	Name             string         `json:"name"`
+	Color            string         `json:"color"`
+	StopIDs          []string       `json:"stop_ids"`
+	Segments         []int          `json:"segments"` // index ใน GTFSImportPlan.Segments ตามลำดับการวิ่ง
+	Shape            [][]float64    `json:"-"`        // [lon, lat]
+	Schedules        []GTFSSchedule `json:"schedules"`
+	Trips            int            `json:"trips"`
+	MaxBus           int            `json:"max_bus"`
+	SpeedKmh         float64        `json:"speed_kmh"`
+	AvgTravelMinutes float64        `json:"avg_travel_minutes"`
+	LengthKm         float64        `json:"length_km"`
+}
+
+type GTFSImportSummary struct {
+	Stops         int      `json:"stops"`
+	Routes        int      `json:"routes"`
+	Patterns      int      `json:"patterns"`
+	Trips         int      `json:"trips"`
+	Segments      int      `json:"segments"`
+	SkippedTrips  int      `json:"skipped_trips"`
+	ReferenceDate string   `json:"reference_date,omitempty"` // วันอ้างอิง (YYYYMMDD) ที่ใช้เลือก service จาก calendar.txt
+	Warnings      []string `json:"warnings,omitempty"`
+}
+
+// GTFSImportPlan คือข้อมูลที่แปลงจาก feed แล้ว ยังไม่ผูกกับ database
+type GTFSImportPlan struct {
+	Stops    []GTFSStop        `json:"stops"`
+	Segments []GTFSSegment     `json:"segments"`
+	Patterns []GTFSPattern     `json:"patterns"`
+	Options  GTFSOptions       `json:"-"`
+	Summary  GTFSImportSummary `json:"summary"`
+}
+
+// GTFSImportResult คือ id ของ configuration / scenario ที่สร้างจาก feed
+type GTFSImportResult struct {
+	UserConfigurationID   string            `json:"user_configuration_id"`
+	ConfigurationDetailID string            `json:"configuration_detail_id"`
+	UserScenarioID        string            `json:"user_scenario_id"`
+	ScenarioDetailID      string            `json:"scenario_detail_id"`
+	Summary               GTFSImportSummary `json:"summary"`
+}
+
+// gtfsTable คือไฟล์ .txt หนึ่งไฟล์ใน feed (header → index)
+type gtfsTable struct {
+	name   string
+	header map[string]int
+	rows   [][]string
+}
+
+func (t *gtfsTable) require(cols ...string) error {
+	for _, col := range cols {
+		if _, ok := t.header[col]; !ok {
+			return fmt.Errorf("%s: missing column %s", t.name, col)
+		}
+	}
+	return nil
+}
+
+func (t *gtfsTable) get(row []string, col string) string {
+	i, ok := t.header[col]
+	if !ok || i >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[i])
+}
+
+type gtfsWarnings struct {
+	list    []string
+	dropped int
+}
+
+func (w *gtfsWarnings) add(format string, args ...interface{}) {
+	if len(w.list) >= maxGTFSWarnings {
+		w.dropped++
+		return
+	}
+	w.list = append(w.list, fmt.Sprintf(format, args...))
+}
+
+func (w *gtfsWarnings) result() []string {
+	if w.dropped > 0 {
+		return append(w.list, fmt.Sprintf("... and %d more warnings", w.dropped))
+	}
+	return w.list
+}
+
+// readGTFSTables อ่านไฟล์ที่ใช้จาก zip (ไฟล์อยู่ใน sub folder ก็ได้)
+func readGTFSTables(r io.ReaderAt, size int64) (map[string]*gtfsTable, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("read GTFS zip: %w", err)
+	}
+
+	wanted := map[string]bool{
+		"stops.txt": true, "routes.txt": true, "trips.txt": true,
+		"stop_times.txt": true, "calendar.txt": true, "shapes.txt": true,
+	}
+
+	tables := make(map[string]*gtfsTable)
+	for _, f := range zr.File {
+		name := strings.ToLower(path.Base(f.Name))
+		if !wanted[name] || tables[name] != nil {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("open %s: %w", name, err)
+		}
+		rows, err := ReadDelimitedRows(rc, FormatCSV)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		t := &gtfsTable{name: name, header: make(map[string]int)}
+		if len(rows) > 0 {
+			for i, h := range rows[0] {
+				t.header[strings.ToLower(strings.TrimSpace(h))] = i
+			}
+			t.rows = rows[1:]
+		}
+		tables[name] = t
+	}
+
+	for _, name := range []string{"stops.txt", "routes.txt", "trips.txt", "stop_times.txt"} {
+		if tables[name] == nil {
+			return nil, fmt.Errorf("GTFS feed is missing %s", name)
+		}
+	}
+	return tables, nil
+}
+
+// parseGTFSTime แปลง "H:MM:SS" เป็นวินาที (GTFS ใช้เกิน 24:00:00 ได้สำหรับเที่ยวหลังเที่ยงคืน)
+func parseGTFSTime(raw string) (int, bool) {
+	parts := strings.Split(strings.TrimSpace(raw), ":")
+	if len(parts) != 3 {
+		return 0, false
+	}
+	h, err1 := strconv.Atoi(parts[0])
+	m, err2 := strconv.Atoi(parts[1])
+	s, err3 := strconv.Atoi(parts[2])
+	if err1 != nil || err2 != nil || err3 != nil || h < 0 || m < 0 || m > 59 || s < 0 || s > 59 {
+		return 0, false
+	}
+	return h*3600 + m*60 + s, true
+}
+
+// HaversineMeters คือระยะเส้นตรงบนผิวโลก
+func HaversineMeters(lat1, lon1, lat2, lon2 float64) float64 {
+	const earthRadius = 6371000.0
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+func medianFloat(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	s := append([]float64(nil), values...)
+	sort.Float64s(s)
+	if len(s)%2 == 1 {
+		return s[len(s)/2]
+	}
+	return (s[len(s)/2-1] + s[len(s)/2]) / 2
+}
+
+type gtfsStopTime struct {
+	seq      int
+	stopID   string
+	arr, dep int // -1 = ไม่ได้ระบุ (ป้ายที่ไม่ใช่ timepoint)
+}
+
+type gtfsTrip struct {
+	id, routeID, serviceID, directionID, headsign, shapeID string
+	stops                                                  []gtfsStopTime
+}
+
+type gtfsPatternBuild struct {
+	trips []*gtfsTrip
+}
+
+// GTFSToImportPlan อ่าน GTFS zip (stops, routes, trips, stop_times และ calendar, shapes ถ้ามี)
+// แล้วแปลงเป็นป้าย, ช่วงระหว่างป้าย, รูปแบบเส้นทาง และตารางเวลาออกรถตาม day type
+//   - เที่ยวที่ลำดับป้ายเหมือนกันในเส้นทาง/ทิศทางเดียวกันรวมเป็น pattern เดียว
+//   - เวลาเดินทางของช่วงคือ median ของ (arrival ป้ายถัดไป − departure ป้ายนี้) ของทุกเที่ยว
+//   - calendar.txt: ใช้เฉพาะ service ที่วิ่งในสัปดาห์ (จันทร์–อาทิตย์) ของวันอ้างอิง ตาม start_date/end_date
+//     แล้วจันทร์–ศุกร์ → weekday, เสาร์ → saturday, อาทิตย์ → sunday_holiday (ไม่ใช้ calendar_dates.txt)
+func GTFSToImportPlan(r io.ReaderAt, size int64, opts GTFSOptions) (GTFSImportPlan, error) {
+	plan := GTFSImportPlan{Options: opts}
+	if plan.Options.Capacity <= 0 {
+		plan.Options.Capacity = DefaultGTFSCapacity
+	}
+	if plan.Options.MaxDistanceKm <= 0 {
+		plan.Options.MaxDistanceKm = DefaultGTFSMaxDistanceKm
+	}
+
+	tables, err := readGTFSTables(r, size)
+	if err != nil {
+		return plan, err
+	}
+	warn := &gtfsWarnings{}
+
+	// --- stops ---
+	stopsT := tables["stops.txt"]
+	if err := stopsT.require("stop_id", "stop_lat", "stop_lon"); err != nil {
+		return plan, err
+	}
+	stops := make(map[string]GTFSStop, len(stopsT.rows))
+	for i, row := range stopsT.rows {
+		id := stopsT.get(row, "stop_id")
+		if id == "" {
+			continue
+		}
+		rawLat, rawLon := stopsT.get(row, "stop_lat"), stopsT.get(row, "stop_lon")
+		if rawLat == "" && rawLon == "" {
+			continue // node / boarding area ไม่มีพิกัดได้ และไม่ถูกใช้ใน stop_times
+		}
+		lat, err1 := strconv.ParseFloat(rawLat, 64)
+		lon, err2 := strconv.ParseFloat(rawLon, 64)
+		if err1 != nil || err2 != nil {
+			warn.add("stops.txt row %d (%s): invalid stop_lat/stop_lon", i+2, id)
+			continue
+		}
+		name := stopsT.get(row, "stop_name")
+		if name == "" {
+			name = id
+		}
+		stops[id] = GTFSStop{StopID: id, Name: name, Lat: lat, Lon: lon}
+	}
+
+	// --- routes ---
+	routesT := tables["routes.txt"]
+	if err := routesT.require("route_id"); err != nil {
+		return plan, err
+	}
+	type gtfsRoute struct {
+		name, color string
+		order       int
+	}
+	routes := make(map[string]gtfsRoute, len(routesT.rows))
+	for i, row := range routesT.rows {
+		id := routesT.get(row, "route_id")
+		name := routesT.get(row, "route_short_name")
+		if name == "" {
+			name = routesT.get(row, "route_long_name")
+		}
+		if name == "" {
+			name = id
+		}
+		color := DefaultGTFSRouteColor
+		if c := strings.TrimPrefix(routesT.get(row, "route_color"), "#"); len(c) == 6 {
+			color = "#" + strings.ToUpper(c)
+		}
+		routes[id] = gtfsRoute{name: name, color: color, order: i}
+	}
+
+	selected := make(map[string]bool)
+	for _, id := range opts.RouteIDs {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		if _, ok := routes[id]; !ok {
+			return plan, fmt.Errorf("route_id %q is not in routes.txt", id)
+		}
+		selected[id] = true
+	}
+
+	// --- trips ---
+	tripsT := tables["trips.txt"]
+	if err := tripsT.require("route_id", "service_id", "trip_id"); err != nil {
+		return plan, err
+	}
+	trips := make(map[string]*gtfsTrip, len(tripsT.rows))
+	for _, row := range tripsT.rows {
+		t := &gtfsTrip{
+			id:          tripsT.get(row, "trip_id"),
+			routeID:     tripsT.get(row, "route_id"),
+			serviceID:   tripsT.get(row, "service_id"),
+			directionID: tripsT.get(row, "direction_id"),
+			headsign:    tripsT.get(row, "trip_headsign"),
+			shapeID:     tripsT.get(row, "shape_id"),
+		}
+		if _, ok := routes[t.routeID]; !ok {
+			warn.add("trip %s: route_id %s is not in routes.txt", t.id, t.routeID)
+			continue
+		}
+		if len(selected) > 0 && !selected[t.routeID] {
+			continue
+		}
+		trips[t.id] = t
+	}
+
+	// --- stop_times ---
+	stT := tables["stop_times.txt"]
+	if err := stT.require("trip_id", "stop_id", "stop_sequence", "arrival_time", "departure_time"); err != nil {
+		return plan, err
+	}
+	for i, row := range stT.rows {
+		t := trips[stT.get(row, "trip_id")]
+		if t == nil {
+			continue
+		}
+		seq, err := strconv.Atoi(stT.get(row, "stop_sequence"))
+		if err != nil {
+			return plan, fmt.Errorf("stop_times.txt row %d: invalid stop_sequence %q", i+2, stT.get(row, "stop_sequence"))
+		}
+		st := gtfsStopTime{seq: seq, stopID: stT.get(row, "stop_id"), arr: -1, dep: -1}
+		if _, ok := stops[st.stopID]; !ok {
+			return plan, fmt.Errorf("stop_times.txt row %d: stop_id %q is not in stops.txt", i+2, st.stopID)
+		}
+		for col, dst := range map[string]*int{"arrival_time": &st.arr, "departure_time": &st.dep} {
+			raw := stT.get(row, col)
+			if raw == "" {
+				continue
+			}
+			sec, ok := parseGTFSTime(raw)
+			if !ok {
+				return plan, fmt.Errorf("stop_times.txt row %d: invalid %s %q", i+2, col, raw)
+			}
+			*dst = sec
+		}
+		t.stops = append(t.stops, st)
+	}
+
+	// --- calendar ---
+	serviceDays := map[string][]string{}
+	calendarT := tables["calendar.txt"]
+	if calendarT == nil {
+		warn.add("calendar.txt not found: all trips are imported for every day")
+		if opts.ReferenceDate != "" {
+			warn.add("reference date %s is ignored without calendar.txt", opts.ReferenceDate)
+		}
+	} else {
+		services, err := readGTFSCalendar(calendarT)
+		if err != nil {
+			return plan, err
+		}
+
+		tripsPerService := make(map[string]int)
+		for _, t := range trips {
+			tripsPerService[t.serviceID]++
+		}
+
+		var ref time.Time
+		if opts.ReferenceDate != "" {
+			if ref, err = time.Parse(gtfsDateLayout, strings.TrimSpace(opts.ReferenceDate)); err != nil {
+				return plan, fmt.Errorf("invalid reference date %q, expected YYYYMMDD", opts.ReferenceDate)
+			}
+		} else {
+			ref = busiestGTFSDate(services, tripsPerService)
+		}
+		plan.Summary.ReferenceDate = ref.Format(gtfsDateLayout)
+
+		serviceDays = gtfsServiceDaysInWeek(services, ref)
+		for _, svc := range services {
+			if len(serviceDays[svc.id]) == 0 && tripsPerService[svc.id] > 0 {
+				warn.add("service_id %s (%s-%s) does not run in the week of %s: %d trips left out",
+					svc.id, svc.start.Format(gtfsDateLayout), svc.end.Format(gtfsDateLayout), plan.Summary.ReferenceDate, tripsPerService[svc.id])
+			}
+		}
+	}
+
+	// --- จัดเที่ยวเป็น pattern ---
+	patternsByKey := make(map[string]*gtfsPatternBuild)
+	var builds []*gtfsPatternBuild
+	tripIDs := make([]string, 0, len(trips))
+	for id := range trips {
+		tripIDs = append(tripIDs, id)
+	}
+	sort.Strings(tripIDs)
+
+	skipped := 0
+	for _, id := range tripIDs {
+		t := trips[id]
+		if calendarT != nil && len(serviceDays[t.serviceID]) == 0 {
+			if _, ok := serviceDays[t.serviceID]; !ok {
+				warn.add("trip %s: service_id %s is not in calendar.txt (calendar_dates.txt is not used)", t.id, t.serviceID)
+			}
+			skipped++
+			continue
+		}
+		if err := normalizeGTFSTrip(t, stops); err != nil {
+			warn.add("trip %s: %v", t.id, err)
+			skipped++
+			continue
+		}
+
+		ids := make([]string, len(t.stops))
+		for i, st := range t.stops {
+			ids[i] = st.stopID
+		}
+		key := t.routeID + "\x00" + t.directionID + "\x00" + strings.Join(ids, "\x00")
+		b := patternsByKey[key]
+		if b == nil {
+			b = &gtfsPatternBuild{}
+			patternsByKey[key] = b
+			builds = append(builds, b)
+		}
+		b.trips = append(b.trips, t)
+	}
+
+	sort.SliceStable(builds, func(i, j int) bool {
+		a, b := builds[i].trips[0], builds[j].trips[0]
+		if routes[a.routeID].order != routes[b.routeID].order {
+			return routes[a.routeID].order < routes[b.routeID].order
+		}
+		if a.directionID != b.directionID {
+			return a.directionID < b.directionID
+		}
+		return len(builds[i].trips) > len(builds[j].trips)
+	})
+
+	// --- ช่วงระหว่างป้าย: เก็บเวลาเดินทางของทุกเที่ยว ---
+	segmentIndex := make(map[string]int)
+	var segSamples [][]float64
+	var totalMeters, totalSeconds float64
+	for _, b := range builds {
+		for _, t := range b.trips {
+			for i := 0; i+1 < len(t.stops); i++ {
+				from, to := t.stops[i], t.stops[i+1]
+				key := from.stopID + "\x00" + to.stopID
+				idx, ok := segmentIndex[key]
+				if !ok {
+					idx = len(plan.Segments)
+					segmentIndex[key] = idx
+					plan.Segments = append(plan.Segments, GTFSSegment{FromStopID: from.stopID, ToStopID: to.stopID})
+					segSamples = append(segSamples, nil)
+				}
+				d := float64(to.arr - from.dep)
+				segSamples[idx] = append(segSamples[idx], d)
+				if d > 0 {
+					a, z := stops[from.stopID], stops[to.stopID]
+					totalMeters += HaversineMeters(a.Lat, a.Lon, z.Lat, z.Lon)
+					totalSeconds += d
+				}
+			}
+		}
+	}
+
+	shapes := readGTFSShapes(tables["shapes.txt"], builds, warn)
+
+	// --- pattern → RoutePath ---
+	usedStops := make(map[string]bool)
+	usedRoutes := make(map[string]bool)
+	nameCount := make(map[string]int)
+	for _, b := range builds {
+		first := b.trips[0]
+		p := GTFSPattern{
+			RouteID:     first.routeID,
+			DirectionID: first.directionID,
+			Color:       routes[first.routeID].color,
+			Trips:       len(b.trips),
+		}
+		usedRoutes[p.RouteID] = true
+
+		p.Name = routes[p.RouteID].name
+		if first.headsign != "" {
+			p.Name += " - " + first.headsign
+		} else if p.DirectionID != "" {
+			p.Name += " (" + p.DirectionID + ")"
+		}
+		nameCount[p.Name]++
+		if n := nameCount[p.Name]; n > 1 {
+			p.Name = fmt.Sprintf("%s #%d", p.Name, n)
+		}
+
+		for _, st := range first.stops {
+			p.StopIDs = append(p.StopIDs, st.stopID)
+			usedStops[st.stopID] = true
+		}
+		for i := 0; i+1 < len(p.StopIDs); i++ {
+			p.Segments = append(p.Segments, segmentIndex[p.StopIDs[i]+"\x00"+p.StopIDs[i+1]])
+		}
+
+		// ระยะตาม shape ของ pattern (ใช้ shape ที่เที่ยวส่วนใหญ่ใช้)
+		p.Shape = shapes[mostCommonShape(b.trips)]
+		var stopDist []float64
+		if len(p.Shape) >= 2 {
+			stopDist = distanceAlongShape(p.Shape, p.StopIDs, stops)
+		} else {
+			for _, id := range p.StopIDs {
+				p.Shape = append(p.Shape, []float64{stops[id].Lon, stops[id].Lat})
+			}
+		}
+		for i, idx := range p.Segments {
+			seg := &plan.Segments[idx]
+			a, z := stops[seg.FromStopID], stops[seg.ToStopID]
+			straight := HaversineMeters(a.Lat, a.Lon, z.Lat, z.Lon)
+			if stopDist != nil {
+				// ระยะตาม shape ที่สั้นกว่าเส้นตรงแปลว่าจับป้ายลง shape ผิด
+				if d := stopDist[i+1] - stopDist[i]; d >= straight*0.95 && seg.DistanceMeters == 0 {
+					seg.DistanceMeters = d
+				}
+			}
+			if seg.DistanceMeters == 0 {
+				seg.DistanceMeters = straight
+			}
+			p.LengthKm += seg.DistanceMeters / 1000
+		}
+
+		fillGTFSPatternStats(&p, b.trips, serviceDays, calendarT != nil, warn)
+		plan.Summary.Trips += p.Trips
+		plan.Patterns = append(plan.Patterns, p)
+	}
+
+	// --- เวลาเดินทางของช่วง (หลังรู้ระยะแล้ว) ---
+	fallbackSpeed := gtfsFallbackSpeedKmh / 3.6 // m/s
+	if totalSeconds > 0 && totalMeters > 0 {
+		fallbackSpeed = totalMeters / totalSeconds
+	}
+	for i := range plan.Segments {
+		seg := &plan.Segments[i]
+		seg.Samples = len(segSamples[i])
+		seg.TravelSeconds = medianFloat(segSamples[i])
+		if seg.TravelSeconds <= 0 {
+			// ป้ายที่ตารางเวลาให้เวลาเดียวกัน (ปัดเป็นนาที) ประมาณจากระยะแทน
+			seg.TravelSeconds = math.Round(seg.DistanceMeters / fallbackSpeed)
+		}
+		if seg.TravelSeconds <= 0 {
+			seg.TravelSeconds = 1
+		}
+	}
+	for i := range plan.Patterns {
+		p := &plan.Patterns[i]
+		if p.SpeedKmh <= 0 {
+			p.SpeedKmh = math.Round(fallbackSpeed*3.6*10) / 10
+		}
+	}
+
+	for id := range usedStops {
+		plan.Stops = append(plan.Stops, stops[id])
+	}
+	sort.Slice(plan.Stops, func(i, j int) bool { return plan.Stops[i].StopID < plan.Stops[j].StopID })
+
+	if len(plan.Patterns) == 0 {
+		return plan, fmt.Errorf("GTFS feed has no usable trips")
+	}
+
+	plan.Summary.Stops = len(plan.Stops)
+	plan.Summary.Routes = len(usedRoutes)
+	plan.Summary.Patterns = len(plan.Patterns)
+	plan.Summary.Segments = len(plan.Segments)
+	plan.Summary.SkippedTrips = skipped
+	plan.Summary.Warnings = warn.result()
+	return plan, nil
+}
+
+// normalizeGTFSTrip เรียง stop_times, รวมป้ายซ้ำที่ติดกัน และเติมเวลาป้ายที่ไม่ได้ระบุ (เฉลี่ยตามระยะ)
+// ผ่านป้ายเดิมซ้ำได้ (ไป-กลับ, เลขแปด) แต่ปฏิเสธเที่ยวที่วิ่งช่วง A→B ทิศเดิมซ้ำ (RoutePath ใช้ pair ทิศเดียวกันได้ครั้งเดียว)
+func normalizeGTFSTrip(t *gtfsTrip, stops map[string]GTFSStop) error {
+	sort.Slice(t.stops, func(i, j int) bool { return t.stops[i].seq < t.stops[j].seq })
+
+	merged := t.stops[:0]
+	for _, st := range t.stops {
+		if n := len(merged); n > 0 && merged[n-1].stopID == st.stopID {
+			if st.dep >= 0 {
+				merged[n-1].dep = st.dep
+			}
+			continue
+		}
+		merged = append(merged, st)
+	}
+	t.stops = merged
+
+	if len(t.stops) < 2 {
+		return fmt.Errorf("has fewer than 2 stops")
+	}
+	travelled := make(map[[2]string]bool, len(t.stops))
+	for i := 1; i < len(t.stops); i++ {
+		leg := [2]string{t.stops[i-1].stopID, t.stops[i].stopID}
+		if travelled[leg] {
+			return fmt.Errorf("travels %s → %s twice", leg[0], leg[1])
+		}
+		travelled[leg] = true
+	}
+	for i := range t.stops {
+		st := &t.stops[i]
+		if st.arr < 0 {
+			st.arr = st.dep
+		}
+		if st.dep < 0 {
+			st.dep = st.arr
+		}
+	}
+	last := len(t.stops) - 1
+	if t.stops[0].dep < 0 || t.stops[last].arr < 0 {
+		return fmt.Errorf("first and last stop must have times")
+	}
+
+	// เติมเวลาของป้ายที่ไม่ใช่ timepoint ตามสัดส่วนระยะระหว่าง timepoint
+	cum := make([]float64, len(t.stops))
+	for i := 1; i < len(t.stops); i++ {
+		a, b := stops[t.stops[i-1].stopID], stops[t.stops[i].stopID]
+		cum[i] = cum[i-1] + HaversineMeters(a.Lat, a.Lon, b.Lat, b.Lon)
+	}
+	prev := 0
+	for i := 1; i <= last; i++ {
+		if t.stops[i].arr < 0 {
+			continue
+		}
+		for k := prev + 1; k < i; k++ {
+			ratio := 0.0
+			if span := cum[i] - cum[prev]; span > 0 {
+				ratio = (cum[k] - cum[prev]) / span
+			}
+			sec := t.stops[prev].dep + int(math.Round(ratio*float64(t.stops[i].arr-t.stops[prev].dep)))
+			t.stops[k].arr, t.stops[k].dep = sec, sec
+		}
+		prev = i
+	}
+
+	for i := 1; i <= last; i++ {
+		if t.stops[i].arr < t.stops[i-1].dep {
+			return fmt.Errorf("arrival at stop %s is before departure from the previous stop", t.stops[i].stopID)
+		}
+	}
+	return nil
+}
+
+// readGTFSShapes อ่านเฉพาะ shape ที่ pattern ใช้ คืน shape_id → [lon, lat] ตาม shape_pt_sequence
+func readGTFSShapes(t *gtfsTable, builds []*gtfsPatternBuild, warn *gtfsWarnings) map[string][][]float64 {
+	shapes := make(map[string][][]float64)
+	if t == nil {
+		return shapes
+	}
+	if err := t.require("shape_id", "shape_pt_lat", "shape_pt_lon", "shape_pt_sequence"); err != nil {
+		warn.add("%v: route geometry falls back to stop locations", err)
+		return shapes
+	}
+
+	used := make(map[string]bool)
+	for _, b := range builds {
+		if id := mostCommonShape(b.trips); id != "" {
+			used[id] = true
+		}
+	}
+
+	type point struct {
+		seq      int
+		lon, lat float64
+	}
+	points := make(map[string][]point)
+	for _, row := range t.rows {
+		id := t.get(row, "shape_id")
+		if !used[id] {
+			continue
+		}
+		seq, err1 := strconv.Atoi(t.get(row, "shape_pt_sequence"))
+		lat, err2 := strconv.ParseFloat(t.get(row, "shape_pt_lat"), 64)
+		lon, err3 := strconv.ParseFloat(t.get(row, "shape_pt_lon"), 64)
+		if err1 != nil || err2 != nil || err3 != nil {
+			continue
+		}
+		points[id] = append(points[id], point{seq, lon, lat})
+	}
+
+	ids := make([]string, 0, len(used))
+	for id := range used {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		pts := points[id]
+		if len(pts) < 2 {
+			warn.add("shape %s has fewer than 2 points: route geometry falls back to stop locations", id)
+			continue
+		}
+		sort.Slice(pts, func(i, j int) bool { return pts[i].seq < pts[j].seq })
+		line := make([][]float64, len(pts))
+		for i, p := range pts {
+			line[i] = []float64{p.lon, p.lat}
+		}
+		shapes[id] = line
+	}
+	return shapes
+}
+
+func mostCommonShape(trips []*gtfsTrip) string {
+	count := make(map[string]int)
+	best := ""
+	for _, t := range trips {
+		if t.shapeID == "" {
+			continue
+		}
+		count[t.shapeID]++
+		if count[t.shapeID] > count[best] || (count[t.shapeID] == count[best] && t.shapeID < best) {
+			best = t.shapeID
+		}
+	}
+	return best
+}
+
+// distanceAlongShape คืนระยะสะสม (เมตร) ตาม shape ของแต่ละป้าย
+// จับป้ายกับจุดของ shape ที่ใกล้ที่สุดโดยค้นไปข้างหน้าเท่านั้น (shape ที่วนผ่านจุดเดิมจึงไม่ย้อน)
+func distanceAlongShape(shape [][]float64, stopIDs []string, stops map[string]GTFSStop) []float64 {
+	cum := make([]float64, len(shape))
+	for i := 1; i < len(shape); i++ {
+		cum[i] = cum[i-1] + HaversineMeters(shape[i-1][1], shape[i-1][0], shape[i][1], shape[i][0])
+	}
+
+	dist := make([]float64, len(stopIDs))
+	from := 0
+	for i, id := range stopIDs {
+		st := stops[id]
+		best, bestD := from, math.Inf(1)
+		for k := from; k < len(shape); k++ {
+			if d := HaversineMeters(st.Lat, st.Lon, shape[k][1], shape[k][0]); d < bestD {
+				best, bestD = k, d
+			}
+		}
+		dist[i] = cum[best]
+		from = best
+	}
+	return dist
+}
+
+const gtfsDateLayout = "20060102"
+
+// gtfsService คือหนึ่งแถวของ calendar.txt
+type gtfsService struct {
+	id         string
+	weekdays   [7]bool // index ตาม time.Weekday
+	start, end time.Time
+}
+
+func (s gtfsService) runsOn(d time.Time) bool {
+	return !d.Before(s.start) && !d.After(s.end) && s.weekdays[d.Weekday()]
+}
+
+func readGTFSCalendar(t *gtfsTable) ([]gtfsService, error) {
+	if err := t.require("service_id", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday", "start_date", "end_date"); err != nil {
+		return nil, err
+	}
+	cols := [7]string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
+
+	services := make([]gtfsService, 0, len(t.rows))
+	for i, row := range t.rows {
+		svc := gtfsService{id: t.get(row, "service_id")}
+		for d, col := range cols {
+			svc.weekdays[d] = t.get(row, col) == "1"
+		}
+		var err1, err2 error
+		svc.start, err1 = time.Parse(gtfsDateLayout, t.get(row, "start_date"))
+		svc.end, err2 = time.Parse(gtfsDateLayout, t.get(row, "end_date"))
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("calendar.txt row %d (%s): invalid start_date/end_date, expected YYYYMMDD", i+2, svc.id)
+		}
+		services = append(services, svc)
+	}
+	return services, nil
+}
+
+// busiestGTFSDate คืนวันที่มีเที่ยววิ่งมากที่สุดในช่วงของ calendar.txt (เท่ากันเลือกวันแรก)
+func busiestGTFSDate(services []gtfsService, tripsPerService map[string]int) time.Time {
+	var first, last time.Time
+	for i, svc := range services {
+		if i == 0 || svc.start.Before(first) {
+			first = svc.start
+		}
+		if i == 0 || svc.end.After(last) {
+			last = svc.end
+		}
+	}
+
+	best, bestTrips := first, -1
+	for d := first; !d.After(last); d = d.AddDate(0, 0, 1) {
+		n := 0
+		for _, svc := range services {
+			if svc.runsOn(d) {
+				n += tripsPerService[svc.id]
+			}
+		}
+		if n > bestTrips {
+			best, bestTrips = d, n
+		}
+	}
+	return best
+}
+
+// gtfsServiceDaysInWeek คืน day type ที่แต่ละ service วิ่งในสัปดาห์ (จันทร์–อาทิตย์) ของวันอ้างอิง
+// ทุก service มี key ใน map (ไม่วิ่งเลย = slice ว่าง) เพื่อแยกจาก service_id ที่ไม่อยู่ใน calendar.txt
+func gtfsServiceDaysInWeek(services []gtfsService, ref time.Time) map[string][]string {
+	monday := ref.AddDate(0, 0, -((int(ref.Weekday()) + 6) % 7))
+
+	days := make(map[string][]string, len(services))
+	for _, svc := range services {
+		seen := make(map[string]bool)
+		list := []string{}
+		for i := 0; i < 7; i++ {
+			d := monday.AddDate(0, 0, i)
+			if !svc.runsOn(d) {
+				continue
+			}
+			dt := DayTypeWeekday
+			switch d.Weekday() {
+			case time.Saturday:
+				dt = DayTypeSaturday
+			case time.Sunday:
+				dt = DayTypeSundayHoliday
+			}
+			if !seen[dt] {
+				seen[dt] = true
+				list = append(list, dt)
+			}
+		}
+		days[svc.id] = append(days[svc.id], list...)
+	}
+	return days
+}
+
+// fillGTFSPatternStats คำนวณตารางเวลาออกรถตาม day type และค่าของ BusInformation
+func fillGTFSPatternStats(
+	p *GTFSPattern,
+	trips []*gtfsTrip,
+	serviceDays map[string][]string,
+	hasCalendar bool,
+	warn *gtfsWarnings,
+) {
+
+	const allDays = "\x00all"
+	departures := make(map[string]map[int]bool) // day type → เวลาออก (วินาที)
+	intervals := make(map[string][][2]int)      // day type → [ออก, ถึง] สำหรับหาจำนวนรถพร้อมกัน
+	var durations []float64
+
+	for _, t := range trips {
+		start, end := t.stops[0].dep, t.stops[len(t.stops)-1].arr
+		durations = append(durations, float64(end-start))
+
+		days := []string{allDays}
+		if hasCalendar {
+			days = serviceDays[t.serviceID]
+		}
+		for _, d := range days {
+			intervals[d] = append(intervals[d], [2]int{start, end})
+		}
+
+		if start >= 24*3600 {
+			warn.add("trip %s starts at %s (after midnight): left out of the schedule", t.id, formatDepartureTime(start))
+			continue
+		}
+		for _, d := range days {
+			if departures[d] == nil {
+				departures[d] = make(map[int]bool)
+			}
+			departures[d][start] = true
+		}
+	}
+
+	p.AvgTravelMinutes = math.Round(medianFloat(durations)/60*10) / 10
+	if sec := medianFloat(durations); sec > 0 {
+		p.SpeedKmh = math.Round(p.LengthKm/(sec/3600)*10) / 10
+	}
+
+	for _, list := range intervals {
+		if n := maxConcurrent(list); n > p.MaxBus {
+			p.MaxBus = n
+		}
+	}
+	if p.MaxBus == 0 {
+		p.MaxBus = 1
+	}
+
+	sortedClock := func(set map[int]bool) []string {
+		secs := make([]int, 0, len(set))
+		for s := range set {
+			secs = append(secs, s)
+		}
+		sort.Ints(secs)
+		out := make([]string, len(secs))
+		for i, s := range secs {
+			out[i] = formatDepartureTime(s)
+		}
+		return out
+	}
+
+	if set, ok := departures[allDays]; ok {
+		p.Schedules = []GTFSSchedule{{DayType: DayTypeAll, Departures: sortedClock(set)}}
+		return
+	}
+
+	standard := []string{DayTypeWeekday, DayTypeSaturday, DayTypeSundayHoliday}
+	for _, d := range standard {
+		if set, ok := departures[d]; ok {
+			p.Schedules = append(p.Schedules, GTFSSchedule{DayType: d, Departures: sortedClock(set)})
+		}
+	}
+	// วิ่งเหมือนกันทุกวัน → ตารางเดียวที่ใช้ได้ทุกวัน
+	if len(p.Schedules) == len(standard) &&
+		strings.Join(p.Schedules[0].Departures, ",") == strings.Join(p.Schedules[1].Departures, ",") &&
+		strings.Join(p.Schedules[0].Departures, ",") == strings.Join(p.Schedules[2].Departures, ",") {
+		p.Schedules = []GTFSSchedule{{DayType: DayTypeAll, Departures: p.Schedules[0].Departures}}
+	}
+}
+
+// maxConcurrent คือจำนวนเที่ยวที่วิ่งพร้อมกันมากที่สุด (ประมาณจำนวนรถที่ต้องใช้ ไม่รวมเวลาพักปลายทาง)
+func maxConcurrent(intervals [][2]int) int {
+	type event struct{ at, delta int }
+	events := make([]event, 0, 2*len(intervals))
+	for _, iv := range intervals {
+		events = append(events, event{iv[0], 1}, event{iv[1], -1})
+	}
+	// รถที่ถึงปลายทางพร้อมกับอีกคันออกถือว่าใช้ต่อได้
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].at != events[j].at {
+			return events[i].at < events[j].at
+		}
+		return events[i].delta < events[j].delta
+	})
+	n, best := 0, 0
+	for _, e := range events {
+		n += e.delta
+		if n > best {
+			best = n
+		}
+	}
+	return best
+}
@@ -0,0 +1,357 @@
+package models
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultGTFSTimezone = "Asia/Bangkok"
+	gtfsAgencyID        = "1"
+	gtfsRouteTypeBus    = "3"
+)
+
+var gtfsColorPattern = regexp.MustCompile(`^#?([0-9A-Fa-f]{6})$`)
+
+type GTFSExportStop struct {
+	ID   string
+	Name string
+	Lat  float64
+	Lon  float64
+}
+
+// GTFSExportRoute คือ RoutePath หนึ่งเส้น: ลำดับป้าย, เวลาเดินทางแต่ละช่วง (วินาที) และเวลาออกรถตาม day type
+type GTFSExportRoute struct {
+	ID            string
+	Name          string
+	Color         string
+	StopIDs       []string
+	TravelSeconds []float64 // len = len(StopIDs)-1
+	Shape         [][2]float64
+	Schedules     []GTFSSchedule
+}
+
+type GTFSExportFeed struct {
+	AgencyName string
+	AgencyURL  string
+	Timezone   string
+	StartDate  string // YYYYMMDD
+	EndDate    string // YYYYMMDD
+	Stops      []GTFSExportStop
+	Routes     []GTFSExportRoute
+}
+
+// gtfsDayFlags คือวันที่ service วิ่ง (จันทร์..อาทิตย์)
+type gtfsDayFlags [7]bool
+
+var gtfsStandardDays = map[string]gtfsDayFlags{
+	DayTypeWeekday:       {true, true, true, true, true, false, false},
+	DayTypeSaturday:      {false, false, false, false, false, true, false},
+	DayTypeSundayHoliday: {false, false, false, false, false, false, true},
+}
+
+// serviceID ตั้งชื่อ service ตามวันที่วิ่ง เช่น "weekday", "saturday_sunday_holiday", "all"
+func (f gtfsDayFlags) serviceID() string {
+	var names []string
+	for _, dt := range []string{DayTypeWeekday, DayTypeSaturday, DayTypeSundayHoliday} {
+		std := gtfsStandardDays[dt]
+		covered := true
+		for i := range std {
+			if std[i] && !f[i] {
+				covered = false
+			}
+		}
+		if covered {
+			names = append(names, dt)
+		}
+	}
+	if len(names) == 3 {
+		return "all"
+	}
+	return strings.Join(names, "_")
+}
+
+// validateGTFSAgencyURL ตรวจว่า agency_url เป็น URL เต็ม (http / https) ตามที่ GTFS กำหนด
+func validateGTFSAgencyURL(raw string) error {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return fmt.Errorf("agency_url is required")
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("agency_url %q must be a full http(s) URL", raw)
+	}
+	return nil
+}
+
+func gtfsClock(sec int) string {
+	return fmt.Sprintf("%02d:%02d:%02d", sec/3600, sec%3600/60, sec%60)
+}
+
+// WriteGTFS เขียน GTFS zip (agency, stops, routes, trips, stop_times, calendar, shapes)
+//   - เที่ยวหนึ่งเที่ยวต่อเวลาออกรถหนึ่งค่า เวลาถึงป้ายถัดไป = เวลาออก + เวลาเดินทางสะสม (ไม่มีเวลาจอด)
+//   - schedule ที่ไม่ระบุ day type วิ่งทุกวันที่เส้นทางนั้นไม่มี schedule เฉพาะ day type
+//   - day type ที่ผู้ใช้ตั้งเอง (ไม่ใช่ weekday/saturday/sunday_holiday) ไม่มีวันที่ใน calendar จึงไม่ถูก export
+//
+// agency_name, agency_url (http / https) และ agency_timezone เป็น field บังคับของ GTFS จึงต้องมีครบ
+// feed ที่ได้ตรวจด้วย MobilityData GTFS validator ได้:
+//
+//	java -jar gtfs-validator-cli.jar -i gtfs.zip -o validation-report
+//
+// คืนคำเตือนของข้อมูลที่ถูกข้าม
+func WriteGTFS(w io.Writer, feed GTFSExportFeed) ([]string, error) {
+	var warnings []string
+
+	if strings.TrimSpace(feed.AgencyName) == "" {
+		return nil, fmt.Errorf("agency_name is required")
+	}
+	if err := validateGTFSAgencyURL(feed.AgencyURL); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(feed.Timezone) == "" {
+		return nil, fmt.Errorf("agency_timezone is required")
+	}
+
+	stopByID := make(map[string]GTFSExportStop, len(feed.Stops))
+	for _, s := range feed.Stops {
+		stopByID[s.ID] = s
+	}
+
+	type trip struct {
+		routeID, serviceID, tripID, shapeID string
+		departure                           int
+		route                               *GTFSExportRoute
+	}
+	var trips []trip
+	services := make(map[string]gtfsDayFlags)
+	usedStops := make(map[string]bool)
+
+	for ri := range feed.Routes {
+		r := &feed.Routes[ri]
+		if len(r.StopIDs) < 2 || len(r.TravelSeconds) != len(r.StopIDs)-1 {
+			return nil, fmt.Errorf("route %q: stops and travel times do not match", r.Name)
+		}
+		for _, id := range r.StopIDs {
+			if _, ok := stopByID[id]; !ok {
+				return nil, fmt.Errorf("route %q: stop %s is not in the configuration", r.Name, id)
+			}
+		}
+
+		// วันที่มี schedule เฉพาะ day type แล้ว (schedule ทุกวันจะไม่วิ่งวันนั้น)
+		var typed gtfsDayFlags
+		for _, sch := range r.Schedules {
+			if std, ok := gtfsStandardDays[sch.DayType]; ok {
+				for i := range typed {
+					typed[i] = typed[i] || std[i]
+				}
+			}
+		}
+
+		shapeID := ""
+		if len(r.Shape) >= 2 {
+			shapeID = fmt.Sprintf("shape_%d", ri+1)
+		}
+
+		routeHasTrips := false
+		for _, sch := range r.Schedules {
+			var days gtfsDayFlags
+			if sch.DayType == DayTypeAll {
+				for i := range days {
+					days[i] = !typed[i]
+				}
+			} else if std, ok := gtfsStandardDays[sch.DayType]; ok {
+				days = std
+			} else {
+				warnings = append(warnings, fmt.Sprintf("route %q: day_type %q has no calendar days and is not exported", r.Name, sch.DayType))
+				continue
+			}
+			serviceID := days.serviceID()
+			if serviceID == "" {
+				continue // schedule ทุกวันที่ถูก schedule เฉพาะ day type แทนที่หมดแล้ว
+			}
+			services[serviceID] = days
+
+			seen := make(map[int]bool)
+			for _, raw := range sch.Departures {
+				if strings.TrimSpace(raw) == "" {
+					continue
+				}
+				sec, _, err := parseDepartureTime(raw, false)
+				if err != nil {
+					warnings = append(warnings, fmt.Sprintf("route %q: %v", r.Name, err))
+					continue
+				}
+				if seen[sec] {
+					continue
+				}
+				seen[sec] = true
+				trips = append(trips, trip{
+					routeID:   r.ID,
+					serviceID: serviceID,
+					shapeID:   shapeID,
+					departure: sec,
+					route:     r,
+				})
+				routeHasTrips = true
+			}
+		}
+		if !routeHasTrips {
+			warnings = append(warnings, fmt.Sprintf("route %q has no departures and is not exported", r.Name))
+			continue
+		}
+		for _, id := range r.StopIDs {
+			usedStops[id] = true
+		}
+	}
+
+	if len(trips) == 0 {
+		return warnings, fmt.Errorf("scenario has no departures to export")
+	}
+
+	sort.SliceStable(trips, func(i, j int) bool {
+		if trips[i].routeID != trips[j].routeID {
+			return trips[i].routeID < trips[j].routeID
+		}
+		if trips[i].serviceID != trips[j].serviceID {
+			return trips[i].serviceID < trips[j].serviceID
+		}
+		return trips[i].departure < trips[j].departure
+	})
+	tripIDCount := make(map[string]int, len(trips))
+	for i := range trips {
+		id := fmt.Sprintf("%s_%s_%s", trips[i].routeID, trips[i].serviceID, strings.ReplaceAll(gtfsClock(trips[i].departure), ":", ""))
+		tripIDCount[id]++
+		if n := tripIDCount[id]; n > 1 {
+			id = fmt.Sprintf("%s_%d", id, n)
+		}
+		trips[i].tripID = id
+	}
+
+	zw := zip.NewWriter(w)
+	writeFile := func(name string, rows [][]string) error {
+		f, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		cw := csv.NewWriter(f)
+		if err := cw.WriteAll(rows); err != nil {
+			return fmt.Errorf("write %s: %w", name, err)
+		}
+		return nil
+	}
+	ff := func(v float64) string { return strconv.FormatFloat(v, 'f', 6, 64) }
+
+	// agency.txt
+	if err := writeFile("agency.txt", [][]string{
+		{"agency_id", "agency_name", "agency_url", "agency_timezone"},
+		{gtfsAgencyID, feed.AgencyName, feed.AgencyURL, feed.Timezone},
+	}); err != nil {
+		return nil, err
+	}
+
+	// stops.txt (เฉพาะป้ายที่มีเส้นทางผ่าน)
+	stopRows := [][]string{{"stop_id", "stop_name", "stop_lat", "stop_lon"}}
+	for _, s := range feed.Stops {
+		if usedStops[s.ID] {
+			stopRows = append(stopRows, []string{s.ID, s.Name, ff(s.Lat), ff(s.Lon)})
+		}
+	}
+	if err := writeFile("stops.txt", stopRows); err != nil {
+		return nil, err
+	}
+
+	// routes.txt
+	routeRows := [][]string{{"route_id", "agency_id", "route_short_name", "route_long_name", "route_type", "route_color"}}
+	exported := make(map[string]bool)
+	for _, t := range trips {
+		if exported[t.routeID] {
+			continue
+		}
+		exported[t.routeID] = true
+		color := ""
+		if m := gtfsColorPattern.FindStringSubmatch(strings.TrimSpace(t.route.Color)); m != nil {
+			color = strings.ToUpper(m[1])
+		}
+		routeRows = append(routeRows, []string{t.routeID, gtfsAgencyID, "", t.route.Name, gtfsRouteTypeBus, color})
+	}
+	if err := writeFile("routes.txt", routeRows); err != nil {
+		return nil, err
+	}
+
+	// trips.txt + stop_times.txt
+	tripRows := [][]string{{"route_id", "service_id", "trip_id", "shape_id"}}
+	stopTimeRows := [][]string{{"trip_id", "arrival_time", "departure_time", "stop_id", "stop_sequence", "timepoint"}}
+	for _, t := range trips {
+		tripRows = append(tripRows, []string{t.routeID, t.serviceID, t.tripID, t.shapeID})
+
+		cum := 0.0
+		for i, stopID := range t.route.StopIDs {
+			if i > 0 {
+				cum += t.route.TravelSeconds[i-1]
+			}
+			at := gtfsClock(t.departure + int(math.Round(cum)))
+			stopTimeRows = append(stopTimeRows, []string{t.tripID, at, at, stopID, strconv.Itoa(i + 1), "0"})
+		}
+		// เวลาที่ป้ายต้นทางคือเวลาออกรถจริงตามตาราง
+		stopTimeRows[len(stopTimeRows)-len(t.route.StopIDs)][5] = "1"
+	}
+	if err := writeFile("trips.txt", tripRows); err != nil {
+		return nil, err
+	}
+	if err := writeFile("stop_times.txt", stopTimeRows); err != nil {
+		return nil, err
+	}
+
+	// calendar.txt
+	serviceIDs := make([]string, 0, len(services))
+	for id := range services {
+		serviceIDs = append(serviceIDs, id)
+	}
+	sort.Strings(serviceIDs)
+	calendarRows := [][]string{{"service_id", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday", "start_date", "end_date"}}
+	for _, id := range serviceIDs {
+		row := []string{id}
+		for _, on := range services[id] {
+			if on {
+				row = append(row, "1")
+			} else {
+				row = append(row, "0")
+			}
+		}
+		calendarRows = append(calendarRows, append(row, feed.StartDate, feed.EndDate))
+	}
+	if err := writeFile("calendar.txt", calendarRows); err != nil {
+		return nil, err
+	}
+
+	// shapes.txt
+	shapeRows := [][]string{{"shape_id", "shape_pt_lat", "shape_pt_lon", "shape_pt_sequence"}}
+	written := make(map[string]bool)
+	for _, t := range trips {
+		if t.shapeID == "" || written[t.shapeID] {
+			continue
+		}
+		written[t.shapeID] = true
+		for i, pt := range t.route.Shape {
+			shapeRows = append(shapeRows, []string{t.shapeID, ff(pt[1]), ff(pt[0]), strconv.Itoa(i + 1)})
+		}
+	}
+	if len(shapeRows) > 1 {
+		if err := writeFile("shapes.txt", shapeRows); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return warnings, nil
+}
@@ -0,0 +1,213 @@
+package models
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func testGTFSExportFeed() GTFSExportFeed {
+	return GTFSExportFeed{
+		AgencyName: "Test Transit",
+		AgencyURL:  "https://transit.example.org",
+		Timezone:   DefaultGTFSTimezone,
+		StartDate:  "20260101",
+		EndDate:    "20261231",
+		Stops: []GTFSExportStop{
+			{ID: "A", Name: "Stop A", Lat: 13.7000, Lon: 100.5000},
+			{ID: "B", Name: "Stop B", Lat: 13.7100, Lon: 100.5100},
+			{ID: "C", Name: "Stop C", Lat: 13.7200, Lon: 100.5200},
+			{ID: "D", Name: "Unused", Lat: 13.7300, Lon: 100.5300},
+		},
+		Routes: []GTFSExportRoute{
+			{
+				ID:            "R1",
+				Name:          "Line 1",
+				Color:         "#ff0000",
+				StopIDs:       []string{"A", "B", "C"},
+				TravelSeconds: []float64{120, 180},
+				Shape:         [][2]float64{{100.5000, 13.7000}, {100.5100, 13.7100}, {100.5200, 13.7200}},
+				Schedules: []GTFSSchedule{
+					{DayType: DayTypeWeekday, Departures: []string{"07:30", "07:00"}},
+					{DayType: DayTypeSaturday, Departures: []string{"08:00"}},
+				},
+			},
+			{
+				// ไป-กลับ ผ่านป้าย A สองครั้ง
+				ID:            "R2",
+				Name:          "Loop",
+				StopIDs:       []string{"A", "B", "A"},
+				TravelSeconds: []float64{120, 150},
+				Schedules:     []GTFSSchedule{{DayType: DayTypeAll, Departures: []string{"09:00"}}},
+			},
+		},
+	}
+}
+
+func readGTFSZip(t *testing.T, data []byte) map[string][][]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("open zip: %v", err)
+	}
+	files := make(map[string][][]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		rows, err := csv.NewReader(rc).ReadAll()
+		rc.Close()
+		if err != nil {
+			t.Fatalf("read %s: %v", f.Name, err)
+		}
+		files[f.Name] = rows
+	}
+	return files
+}
+
+func TestWriteGTFSRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	warnings, err := WriteGTFS(&buf, testGTFSExportFeed())
+	if err != nil {
+		t.Fatalf("WriteGTFS: %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Fatalf("unexpected warnings: %v", warnings)
+	}
+
+	plan, err := GTFSToImportPlan(bytes.NewReader(buf.Bytes()), int64(buf.Len()), GTFSOptions{})
+	if err != nil {
+		t.Fatalf("GTFSToImportPlan: %v", err)
+	}
+	if len(plan.Summary.Warnings) != 0 {
+		t.Fatalf("import warnings: %v", plan.Summary.Warnings)
+	}
+
+	if len(plan.Stops) != 3 {
+		t.Fatalf("stops = %d, want 3 (unused stop is not exported)", len(plan.Stops))
+	}
+
+	patterns := make(map[string]GTFSPattern)
+	for _, p := range plan.Patterns {
+		patterns[p.RouteID] = p
+	}
+	if len(patterns) != 2 {
+		t.Fatalf("patterns = %+v, want R1 and R2", plan.Patterns)
+	}
+
+	r1 := patterns["R1"]
+	if !reflect.DeepEqual(r1.StopIDs, []string{"A", "B", "C"}) {
+		t.Errorf("R1 stops = %v", r1.StopIDs)
+	}
+	if r1.Color != "#FF0000" {
+		t.Errorf("R1 color = %q", r1.Color)
+	}
+	wantR1 := []GTFSSchedule{
+		{DayType: DayTypeWeekday, Departures: []string{"07:00", "07:30"}},
+		{DayType: DayTypeSaturday, Departures: []string{"08:00"}},
+	}
+	if !reflect.DeepEqual(r1.Schedules, wantR1) {
+		t.Errorf("R1 schedules = %+v, want %+v", r1.Schedules, wantR1)
+	}
+	if len(r1.Shape) != 3 {
+		t.Errorf("R1 shape has %d points, want 3", len(r1.Shape))
+	}
+
+	r2 := patterns["R2"]
+	if !reflect.DeepEqual(r2.StopIDs, []string{"A", "B", "A"}) {
+		t.Errorf("R2 stops = %v", r2.StopIDs)
+	}
+	wantR2 := []GTFSSchedule{{DayType: DayTypeAll, Departures: []string{"09:00"}}}
+	if !reflect.DeepEqual(r2.Schedules, wantR2) {
+		t.Errorf("R2 schedules = %+v, want %+v", r2.Schedules, wantR2)
+	}
+
+	travel := make(map[string]float64)
+	for _, seg := range plan.Segments {
+		travel[seg.FromStopID+">"+seg.ToStopID] = seg.TravelSeconds
+	}
+	for pair, want := range map[string]float64{"A>B": 120, "B>C": 180, "B>A": 150} {
+		if got, ok := travel[pair]; !ok || got != want {
+			t.Errorf("segment %s travel = %v (found %v), want %v", pair, got, ok, want)
+		}
+	}
+}
+
+// TestWriteGTFSRequiredFields ตรวจ field บังคับที่ GTFS validator ตรวจ
+func TestWriteGTFSRequiredFields(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := WriteGTFS(&buf, testGTFSExportFeed()); err != nil {
+		t.Fatalf("WriteGTFS: %v", err)
+	}
+	files := readGTFSZip(t, buf.Bytes())
+
+	required := map[string][]string{
+		"agency.txt":     {"agency_name", "agency_url", "agency_timezone"},
+		"stops.txt":      {"stop_id", "stop_name", "stop_lat", "stop_lon"},
+		"routes.txt":     {"route_id", "route_type"},
+		"trips.txt":      {"route_id", "service_id", "trip_id"},
+		"stop_times.txt": {"trip_id", "arrival_time", "departure_time", "stop_id", "stop_sequence"},
+		"calendar.txt":   {"service_id", "monday", "sunday", "start_date", "end_date"},
+		"shapes.txt":     {"shape_id", "shape_pt_lat", "shape_pt_lon", "shape_pt_sequence"},
+	}
+	for name, cols := range required {
+		rows, ok := files[name]
+		if !ok || len(rows) < 2 {
+			t.Errorf("%s is missing or empty", name)
+			continue
+		}
+		index := make(map[string]int)
+		for i, h := range rows[0] {
+			index[h] = i
+		}
+		for _, col := range cols {
+			i, ok := index[col]
+			if !ok {
+				t.Errorf("%s: missing column %s", name, col)
+				continue
+			}
+			for r, row := range rows[1:] {
+				if strings.TrimSpace(row[i]) == "" {
+					t.Errorf("%s row %d: %s is empty", name, r+2, col)
+				}
+			}
+		}
+	}
+
+	if got := files["agency.txt"][1][2]; got != "https://transit.example.org" {
+		t.Errorf("agency_url = %q", got)
+	}
+
+	// เวลาใน stop_times ของแต่ละเที่ยวต้องไม่ลดลง และ stop_sequence เพิ่มขึ้น
+	type stopTime struct {
+		at  string
+		seq int
+	}
+	last := make(map[string]stopTime)
+	for _, row := range files["stop_times.txt"][1:] {
+		seq, err := strconv.Atoi(row[4])
+		if err != nil {
+			t.Fatalf("stop_sequence %q: %v", row[4], err)
+		}
+		cur := stopTime{at: row[1], seq: seq}
+		if prev, ok := last[row[0]]; ok && (cur.at < prev.at || cur.seq <= prev.seq) {
+			t.Errorf("trip %s: stop_time %+v after %+v", row[0], cur, prev)
+		}
+		last[row[0]] = cur
+	}
+}
+
+func TestWriteGTFSAgencyURL(t *testing.T) {
+	for _, raw := range []string{"", "example.com", "ftp://example.com", "https://"} {
+		feed := testGTFSExportFeed()
+		feed.AgencyURL = raw
+		if _, err := WriteGTFS(&bytes.Buffer{}, feed); err == nil {
+			t.Errorf("agency_url %q: expected an error", raw)
+		}
+	}
+}
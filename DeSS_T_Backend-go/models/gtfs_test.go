@@ -0,0 +1,119 @@
+package models
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testGTFSZip สร้าง feed จากเนื้อหาไฟล์ (แต่ละไฟล์เป็น CSV)
+func testGTFSZip(t *testing.T, files map[string]string) *bytes.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(strings.TrimSpace(content) + "\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+// ตารางเดินรถสองชุด: ภาคเรียน (มี.ค.–เม.ย., 2 เที่ยว/วันธรรมดา + 1 เที่ยววันเสาร์) และปิดภาค (พ.ค., 1 เที่ยว/วัน)
+func testGTFSCalendarFeed(t *testing.T) *bytes.Reader {
+	return testGTFSZip(t, map[string]string{
+		"stops.txt": `
+stop_id,stop_name,stop_lat,stop_lon
+A,Stop A,13.70,100.50
+B,Stop B,13.71,100.51`,
+		"routes.txt": `
+route_id,route_short_name
+R1,Line 1`,
+		"trips.txt": `
+route_id,service_id,trip_id
+R1,TERM_WD,t1
+R1,TERM_WD,t2
+R1,TERM_SAT,t3
+R1,BREAK,t4`,
+		"stop_times.txt": `
+trip_id,arrival_time,departure_time,stop_id,stop_sequence
+t1,07:00:00,07:00:00,A,1
+t1,07:10:00,07:10:00,B,2
+t2,08:00:00,08:00:00,A,1
+t2,08:10:00,08:10:00,B,2
+t3,09:00:00,09:00:00,A,1
+t3,09:10:00,09:10:00,B,2
+t4,10:00:00,10:00:00,A,1
+t4,10:10:00,10:10:00,B,2`,
+		"calendar.txt": `
+service_id,monday,tuesday,wednesday,thursday,friday,saturday,sunday,start_date,end_date
+TERM_WD,1,1,1,1,1,0,0,20260301,20260430
+TERM_SAT,0,0,0,0,0,1,0,20260301,20260430
+BREAK,1,1,1,1,1,1,1,20260501,20260531`,
+	})
+}
+
+func TestGTFSToImportPlanReferenceDate(t *testing.T) {
+	cases := []struct {
+		name      string
+		refDate   string
+		wantDate  string
+		schedules []GTFSSchedule
+		skipped   int
+	}{
+		{
+			name:     "default is the date with the most trips",
+			wantDate: "20260302",
+			schedules: []GTFSSchedule{
+				{DayType: DayTypeWeekday, Departures: []string{"07:00", "08:00"}},
+				{DayType: DayTypeSaturday, Departures: []string{"09:00"}},
+			},
+			skipped: 1,
+		},
+		{
+			name:     "chosen date in the break",
+			refDate:  "20260515",
+			wantDate: "20260515",
+			// วิ่งเหมือนกันทุกวันจึงรวมเป็นตารางเดียว
+			schedules: []GTFSSchedule{{DayType: DayTypeAll, Departures: []string{"10:00"}}},
+			skipped:   3,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			feed := testGTFSCalendarFeed(t)
+			plan, err := GTFSToImportPlan(feed, feed.Size(), GTFSOptions{ReferenceDate: tc.refDate})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if plan.Summary.ReferenceDate != tc.wantDate {
+				t.Errorf("reference date = %s, want %s", plan.Summary.ReferenceDate, tc.wantDate)
+			}
+			if plan.Summary.SkippedTrips != tc.skipped {
+				t.Errorf("skipped = %d, want %d", plan.Summary.SkippedTrips, tc.skipped)
+			}
+			if len(plan.Patterns) != 1 || !reflect.DeepEqual(plan.Patterns[0].Schedules, tc.schedules) {
+				t.Fatalf("patterns = %+v, want schedules %+v", plan.Patterns, tc.schedules)
+			}
+			if !strings.Contains(strings.Join(plan.Summary.Warnings, "\n"), "left out") {
+				t.Errorf("warnings = %v, want the services left out", plan.Summary.Warnings)
+			}
+		})
+	}
+}
+
+func TestGTFSToImportPlanInvalidReferenceDate(t *testing.T) {
+	feed := testGTFSCalendarFeed(t)
+	if _, err := GTFSToImportPlan(feed, feed.Size(), GTFSOptions{ReferenceDate: "2026-05-15"}); err == nil {
+		t.Fatal("expected an error for a reference date that is not YYYYMMDD")
+	}
+}
@@ -103,6 +103,8 @@ type RoutePath struct {
 	Name        string `json:"name"`
 	Color       string `json:"color"`
 	Route       GeoLineString `json:"route"`
+	// เส้นทางเลขแปด: ผ่าน station เดิมซ้ำโดยไม่ใช่ขากลับได้ (ต้องเปิดเองต่อเส้นทาง)
+	AllowRevisit bool `json:"allow_revisit"`
 
 	Orders []Order `json:"orders"`
 
@@ -155,6 +157,11 @@ type ScheduleData struct {
 	ScheduleList   string `json:"schedule_list"`
 	RoutePathID    string `json:"route_path_id"`
 	BusScenarioID  string `json:"bus_scenario_id"`
+	DayType        string `json:"day_type,omitempty"` // ว่าง = ทุกวัน
+	// กฎ headway ที่ใช้สร้าง ScheduleList (ว่าง = ป้อนเวลาออกรถเอง)
+	FirstDeparture string                `json:"first_departure,omitempty"`
+	LastDeparture  string                `json:"last_departure,omitempty"`
+	HeadwayBands   []ScheduleHeadwayBand `json:"headway_bands,omitempty"`
 
 	// RoutePath RoutePath `json:"route_path_detail"`
 }
@@ -173,9 +180,36 @@ type BusInformation struct {
 	BusScenarioID    string  `json:"bus_scenario_id"`
 	RoutePathID      string  `json:"route_path_id"`
 
+	Bands []BusInformationBand `json:"bands,omitempty"`
+
 	// RoutePath RoutePath `json:"route_path_detail"`
 }
 
+// ======================================================
+// SCHEDULE HEADWAY BAND
+// ======================================================
+
+type ScheduleHeadwayBand struct {
+	ScheduleHeadwayBandID string `json:"schedule_headway_band_id,omitempty"`
+	TimeRange             string `json:"time_range"`
+	HeadwayMinutes        int    `json:"headway_minutes"`
+}
+
+// ======================================================
+// BUS INFORMATION BAND
+// ======================================================
+
+type BusInformationBand struct {
+	BusInformationBandID string  `json:"bus_information_band_id"`
+	BusInformationID     string  `json:"bus_information_id"`
+	TimeRange            string  `json:"time_range"`
+	Speed                float32 `json:"speed"`
+	MaxDis               float32 `json:"max_dis"`
+	MaxBus               int     `json:"max_bus"`
+	Capacity             int     `json:"capacity"`
+	AvgTravelTime        float32 `json:"avg_travel_time"`
+}
+
 // ======================================================
 // ALIGHTING DATA
 // ======================================================
@@ -187,6 +221,9 @@ type AlightingData struct {
 	Distribution          string `json:"distribution"`
 	ArgumentList          string `json:"argument_list"`
 	StationID             string `json:"station_id"`
+	DayType               string `json:"day_type,omitempty"` // ว่าง = ทุกวัน
+	// ArgumentList ที่ parse แล้ว (ใน response เท่านั้น)
+	Parameters map[string]float64 `json:"parameters,omitempty"`
 
 	// StationDetail StationDetail `json:"station_detail"`
 }
@@ -202,6 +239,9 @@ type InterArrivalData struct {
 	Distribution          string `json:"distribution"`
 	ArgumentList          string `json:"argument_list"`
 	StationID             string `json:"station_id"`
+	DayType               string `json:"day_type,omitempty"` // ว่าง = ทุกวัน
+	// ArgumentList ที่ parse แล้ว (ใน response เท่านั้น)
+	Parameters map[string]float64 `json:"parameters,omitempty"`
 
 	// StationDetail StationDetail `json:"station_detail"`
 }
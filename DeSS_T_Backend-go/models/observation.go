@@ -0,0 +1,38 @@
+package models
+
+// ---------------- ObservationDataset ----------------
+
+type ObservationDataset struct {
+	ObservationDatasetID  string    `json:"observation_dataset_id"`
+	ConfigurationDetailID string    `json:"configuration_detail_id"`
+	StationID             string    `json:"station_id"`
+	Kind                  string    `json:"kind"`
+	TimePeriod            string    `json:"time_period"`
+	DayType               string    `json:"day_type"`
+	SampleSize            int       `json:"sample_size"`
+	Timed                 bool      `json:"timed"` // มีเวลาของแต่ละตัวอย่าง refit แบ่ง time range ใหม่ได้อิสระ
+	Values                []float64 `json:"values,omitempty"`
+	CreatedAt             string    `json:"created_at"`
+}
+
+// ---------------- RefitRequest ----------------
+
+type RefitRequest struct {
+	Kind         string   `json:"kind"`                   // alighting | interarrival
+	StationIDs   []string `json:"station_ids,omitempty"`  // ว่าง = ทุก station ที่มี dataset
+	Distribution string   `json:"distribution,omitempty"` // บังคับ family (ว่าง = เลือกตัวที่ดีที่สุด)
+	Criterion    string   `json:"criterion,omitempty"`
+	TimeRanges   []string `json:"time_ranges,omitempty"` // ว่าง = ใช้ time period เดิมของ dataset
+	Apply        bool     `json:"apply"`                 // true = เขียนผลทับ AlightingData/InterArrivalData
+	DayType      string   `json:"day_type,omitempty"`    // day type ของ dataset ที่ใช้และของแถวที่เขียนทับ (ว่าง = ทุกวัน)
+}
+
+// ---------------- RefitResponse ----------------
+
+type RefitResponse struct {
+	Kind            string    `json:"kind"`
+	DataFitResponse []FitItem `json:"DataFitResponse"`
+	Applied         bool      `json:"applied"`
+	// dataset ที่ไม่ทับ time range ใหม่เลย (จึงไม่ได้ใช้) เป็น station@time period
+	Unassigned []string `json:"unassigned,omitempty"`
+}
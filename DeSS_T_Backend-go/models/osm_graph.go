@@ -0,0 +1,506 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ความเร็วเริ่มต้น (km/h) ของถนนที่รถวิ่งได้ตาม tag highway เมื่อไม่มี maxspeed
+var osmHighwaySpeedKmh = map[string]float64{
+	"motorway":       90,
+	"motorway_link":  45,
+	"trunk":          80,
+	"trunk_link":     40,
+	"primary":        60,
+	"primary_link":   30,
+	"secondary":      50,
+	"secondary_link": 25,
+	"tertiary":       40,
+	"tertiary_link":  20,
+	"unclassified":   30,
+	"residential":    25,
+	"living_street":  10,
+	"service":        15,
+	"road":           25,
+}
+
+const (
+	osmGridCellDeg   = 0.01 // ขนาดช่อง index สำหรับหา node ใกล้สุด (~1.1 km)
+	MaxRoadSnapMeter = 2000 // station ที่ห่างถนนเกินนี้ถือว่าอยู่นอกพื้นที่ของ extract
+)
+
+// ErrNoRoadPath คือคู่จุดที่ไม่มีเส้นทางถนนเชื่อมกัน (เช่น ติด oneway หรือคนละเกาะ)
+var ErrNoRoadPath = errors.New("no road path between points")
+
+// RoadGraph คือกราฟถนนที่รถวิ่งได้จาก OSM extract (edge เก็บแบบ CSR, น้ำหนักคือเวลาเดินทาง)
+type RoadGraph struct {
+	lat, lon   []float64
+	first      []int32 // edge ของ node i อยู่ที่ [first[i], first[i+1])
+	to         []int32
+	distM      []float32
+	durS       []float32
+	maxSpeedMs float64
+	grid       map[[2]int32][]int32 // เฉพาะ node ใน component ที่ใหญ่ที่สุด
+}
+
+// RoadPath คือเส้นทางที่เร็วที่สุดระหว่างสองจุด (พิกัด [lon, lat])
+type RoadPath struct {
+	DistanceM   float64
+	DurationS   float64
+	Coordinates [][2]float64
+}
+
+func (g *RoadGraph) Nodes() int { return len(g.lat) }
+func (g *RoadGraph) Edges() int { return len(g.to) }
+
+// osmWayProfile คืนความเร็ว (km/h) และทิศ oneway (1 ตามลำดับ node, -1 สวนลำดับ, 0 สองทาง) ของ way ที่รถวิ่งได้
+func osmWayProfile(tags map[string]string) (speedKmh float64, oneway int, ok bool) {
+	hw := tags["highway"]
+	speedKmh, ok = osmHighwaySpeedKmh[hw]
+	if !ok || tags["area"] == "yes" {
+		return 0, 0, false
+	}
+	for _, k := range []string{"access", "vehicle", "motor_vehicle"} {
+		if v := tags[k]; v == "no" || v == "private" {
+			return 0, 0, false
+		}
+	}
+
+	if v := parseOSMMaxSpeed(tags["maxspeed"]); v > 0 {
+		speedKmh = v
+	}
+
+	switch tags["oneway"] {
+	case "yes", "true", "1":
+		oneway = 1
+	case "-1", "reverse":
+		oneway = -1
+	case "no", "false", "0":
+		oneway = 0
+	default:
+		if hw == "motorway" || tags["junction"] == "roundabout" || tags["junction"] == "circular" {
+			oneway = 1
+		}
+	}
+	return speedKmh, oneway, true
+}
+
+// parseOSMMaxSpeed อ่าน maxspeed เช่น "50", "50 km/h", "30 mph" (ค่าอื่นเช่น "TH:urban" คืน 0)
+func parseOSMMaxSpeed(raw string) float64 {
+	raw = strings.TrimSpace(strings.ToLower(raw))
+	factor := 1.0
+	if strings.HasSuffix(raw, "mph") {
+		factor = 1.609344
+		raw = strings.TrimSpace(strings.TrimSuffix(raw, "mph"))
+	}
+	raw = strings.TrimSpace(strings.TrimSuffix(raw, "km/h"))
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil || v <= 0 || v > 150 {
+		return 0
+	}
+	return v * factor
+}
+
+// LoadRoadGraph อ่าน OSM extract (.osm.pbf หรือ .osm XML) แล้วสร้างกราฟถนนที่รถวิ่งได้
+// อ่านไฟล์สองรอบ: รอบแรกเก็บ way ที่เป็นถนน รอบสองเก็บพิกัดเฉพาะ node ที่ถนนใช้
+func LoadRoadGraph(path string) (*RoadGraph, error) {
+	type osmWay struct {
+		refs     []int64
+		speedKmh float64
+		oneway   int
+	}
+	var ways []osmWay
+	index := make(map[int64]int32)
+
+	err := scanOSMFile(path, osmHandler{way: func(refs []int64, tags map[string]string) {
+		speed, oneway, ok := osmWayProfile(tags)
+		if !ok || len(refs) < 2 {
+			return
+		}
+		ways = append(ways, osmWay{refs: append([]int64(nil), refs...), speedKmh: speed, oneway: oneway})
+		for _, id := range refs {
+			if _, seen := index[id]; !seen {
+				index[id] = int32(len(index))
+			}
+		}
+	}})
+	if err != nil {
+		return nil, err
+	}
+	if len(ways) == 0 {
+		return nil, fmt.Errorf("%s has no drivable roads", path)
+	}
+
+	n := len(index)
+	g := &RoadGraph{lat: make([]float64, n), lon: make([]float64, n)}
+	have := make([]bool, n)
+	err = scanOSMFile(path, osmHandler{node: func(id int64, lat, lon float64) {
+		if i, ok := index[id]; ok {
+			g.lat[i], g.lon[i], have[i] = lat, lon, true
+		}
+	}})
+	if err != nil {
+		return nil, err
+	}
+
+	// edge ระหว่าง node ที่ติดกันใน way (node ที่ถูกตัดออกจาก extract ข้ามไป)
+	type edge struct {
+		from, to    int32
+		dist, durat float32
+	}
+	var edges []edge
+	for _, w := range ways {
+		speedMs := w.speedKmh / 3.6
+		g.maxSpeedMs = math.Max(g.maxSpeedMs, speedMs)
+		for k := 1; k < len(w.refs); k++ {
+			a, b := index[w.refs[k-1]], index[w.refs[k]]
+			if a == b || !have[a] || !have[b] {
+				continue
+			}
+			d := HaversineMeters(g.lat[a], g.lon[a], g.lat[b], g.lon[b])
+			e := edge{dist: float32(d), durat: float32(d / speedMs)}
+			if w.oneway >= 0 {
+				e.from, e.to = a, b
+				edges = append(edges, e)
+			}
+			if w.oneway <= 0 {
+				e.from, e.to = b, a
+				edges = append(edges, e)
+			}
+		}
+	}
+	if len(edges) == 0 {
+		return nil, fmt.Errorf("%s has no road segments with node coordinates", path)
+	}
+
+	g.first = make([]int32, n+1)
+	for _, e := range edges {
+		g.first[e.from+1]++
+	}
+	for i := 1; i <= n; i++ {
+		g.first[i] += g.first[i-1]
+	}
+	g.to = make([]int32, len(edges))
+	g.distM = make([]float32, len(edges))
+	g.durS = make([]float32, len(edges))
+	fill := append([]int32(nil), g.first[:n]...)
+	for _, e := range edges {
+		k := fill[e.from]
+		fill[e.from]++
+		g.to[k], g.distM[k], g.durS[k] = e.to, e.dist, e.durat
+	}
+
+	used := make([]bool, n)
+	for _, e := range edges {
+		used[e.from], used[e.to] = true, true
+	}
+	g.buildGrid(used)
+	return g, nil
+}
+
+// buildGrid ทำ index ของ node ใน component (ไม่สนทิศ) ที่ใหญ่ที่สุด
+// station จึงไม่ถูก snap ไปติดถนนย่อยที่ไม่เชื่อมกับโครงข่ายหลัก
+func (g *RoadGraph) buildGrid(used []bool) {
+	n := len(g.lat)
+	parent := make([]int32, n)
+	for i := range parent {
+		parent[i] = int32(i)
+	}
+	find := func(x int32) int32 {
+		for parent[x] != x {
+			parent[x] = parent[parent[x]]
+			x = parent[x]
+		}
+		return x
+	}
+	for a := 0; a < n; a++ {
+		for k := g.first[a]; k < g.first[a+1]; k++ {
+			if ra, rb := find(int32(a)), find(g.to[k]); ra != rb {
+				parent[ra] = rb
+			}
+		}
+	}
+
+	size := make(map[int32]int)
+	largest := int32(-1)
+	for i := 0; i < n; i++ {
+		if !used[i] {
+			continue
+		}
+		r := find(int32(i))
+		size[r]++
+		if size[r] > size[largest] {
+			largest = r
+		}
+	}
+
+	g.grid = make(map[[2]int32][]int32)
+	for i := 0; i < n; i++ {
+		if used[i] && find(int32(i)) == largest {
+			c := g.cellOf(g.lon[i], g.lat[i])
+			g.grid[c] = append(g.grid[c], int32(i))
+		}
+	}
+}
+
+func (g *RoadGraph) cellOf(lon, lat float64) [2]int32 {
+	return [2]int32{int32(math.Floor(lon / osmGridCellDeg)), int32(math.Floor(lat / osmGridCellDeg))}
+}
+
+// Snap หา node ของถนนที่ใกล้จุด [lon, lat] ที่สุด
+func (g *RoadGraph) Snap(pt [2]float64) (int32, error) {
+	lon, lat := pt[0], pt[1]
+	c := g.cellOf(lon, lat)
+	cellMeters := osmGridCellDeg * 111320 * math.Max(math.Cos(lat*math.Pi/180), 0.01)
+	maxRing := int(math.Ceil(MaxRoadSnapMeter/cellMeters)) + 1
+
+	best, bestD := int32(-1), math.Inf(1)
+	for r := 0; r <= maxRing; r++ {
+		for dx := -r; dx <= r; dx++ {
+			for dy := -r; dy <= r; dy++ {
+				if dx != -r && dx != r && dy != -r && dy != r {
+					continue // เฉพาะขอบของวงที่ r
+				}
+				for _, i := range g.grid[[2]int32{c[0] + int32(dx), c[1] + int32(dy)}] {
+					if d := HaversineMeters(lat, lon, g.lat[i], g.lon[i]); d < bestD {
+						best, bestD = i, d
+					}
+				}
+			}
+		}
+		// ทุกจุดที่ห่างไม่เกิน r ช่องถูกตรวจแล้ว
+		if best >= 0 && bestD <= float64(r)*cellMeters {
+			break
+		}
+	}
+	if best < 0 || bestD > MaxRoadSnapMeter {
+		return -1, fmt.Errorf("point (%.6f, %.6f) is more than %d m from the road network", lon, lat, MaxRoadSnapMeter)
+	}
+	return best, nil
+}
+
+/* ───────────────────────────── SEARCH ───────────────────────────── */
+
+type roadQueueItem struct {
+	node int32
+	key  float64
+}
+
+// roadQueue คือ binary min-heap ตาม key (ไม่ใช้ container/heap เพื่อเลี่ยง interface ทุกครั้งที่ push/pop)
+type roadQueue []roadQueueItem
+
+func (q *roadQueue) push(it roadQueueItem) {
+	*q = append(*q, it)
+	h := *q
+	for i := len(h) - 1; i > 0; {
+		parent := (i - 1) / 2
+		if h[parent].key <= h[i].key {
+			break
+		}
+		h[parent], h[i] = h[i], h[parent]
+		i = parent
+	}
+}
+
+func (q *roadQueue) pop() roadQueueItem {
+	h := *q
+	top := h[0]
+	last := len(h) - 1
+	h[0] = h[last]
+	h = h[:last]
+	for i := 0; ; {
+		small, l, r := i, 2*i+1, 2*i+2
+		if l < len(h) && h[l].key < h[small].key {
+			small = l
+		}
+		if r < len(h) && h[r].key < h[small].key {
+			small = r
+		}
+		if small == i {
+			break
+		}
+		h[i], h[small] = h[small], h[i]
+		i = small
+	}
+	*q = h
+	return top
+}
+
+// roadSearch คือ state ของการค้นหาหนึ่งครั้ง ใช้ซ้ำได้ (reset เฉพาะ node ที่แตะ)
+type roadSearch struct {
+	g       *RoadGraph
+	dur     []float64
+	dist    []float64
+	prev    []int32
+	done    []bool
+	touched []int32
+	queue   roadQueue
+}
+
+func (g *RoadGraph) newSearch() *roadSearch {
+	n := len(g.lat)
+	s := &roadSearch{g: g, dur: make([]float64, n), dist: make([]float64, n), prev: make([]int32, n), done: make([]bool, n)}
+	for i := 0; i < n; i++ {
+		s.dur[i] = math.Inf(1)
+		s.prev[i] = -1
+	}
+	return s
+}
+
+func (s *roadSearch) reset() {
+	for _, i := range s.touched {
+		s.dur[i], s.dist[i], s.prev[i], s.done[i] = math.Inf(1), 0, -1, false
+	}
+	s.touched = s.touched[:0]
+	s.queue = s.queue[:0]
+}
+
+// run ค้นหาเส้นทางเร็วสุดจาก src (Dijkstra เมื่อ heuristic == nil, ไม่งั้น A*)
+// stop ถูกเรียกเมื่อ node ถูก settle และหยุดค้นหาเมื่อคืน true
+func (s *roadSearch) run(src int32, heuristic func(int32) float64, stop func(int32) bool) {
+	s.reset()
+	g := s.g
+	s.dur[src] = 0
+	s.touched = append(s.touched, src)
+	s.queue.push(roadQueueItem{node: src})
+
+	for len(s.queue) > 0 {
+		it := s.queue.pop()
+		u := it.node
+		if s.done[u] {
+			continue
+		}
+		s.done[u] = true
+		if stop(u) {
+			return
+		}
+		for k := g.first[u]; k < g.first[u+1]; k++ {
+			v := g.to[k]
+			nd := s.dur[u] + float64(g.durS[k])
+			if nd >= s.dur[v] {
+				continue
+			}
+			if math.IsInf(s.dur[v], 1) {
+				s.touched = append(s.touched, v)
+			}
+			s.dur[v], s.dist[v], s.prev[v] = nd, s.dist[u]+float64(g.distM[k]), u
+			key := nd
+			if heuristic != nil {
+				key += heuristic(v)
+			}
+			s.queue.push(roadQueueItem{node: v, key: key})
+		}
+	}
+}
+
+// Route หาเส้นทางเร็วสุดระหว่างสองจุด [lon, lat] ด้วย A* (heuristic = ระยะตรง / ความเร็วสูงสุดในกราฟ)
+// พิกัดต้นและปลายของเส้นทางคือจุดที่ขอ ต่อกับ node ที่ snap ได้
+func (g *RoadGraph) Route(start, end [2]float64) (RoadPath, error) {
+	src, err := g.Snap(start)
+	if err != nil {
+		return RoadPath{}, err
+	}
+	dst, err := g.Snap(end)
+	if err != nil {
+		return RoadPath{}, err
+	}
+
+	s := g.newSearch()
+	dLat, dLon := g.lat[dst], g.lon[dst]
+	s.run(src,
+		func(v int32) float64 { return HaversineMeters(g.lat[v], g.lon[v], dLat, dLon) / g.maxSpeedMs },
+		func(v int32) bool { return v == dst },
+	)
+	if !s.done[dst] {
+		return RoadPath{}, ErrNoRoadPath
+	}
+
+	var nodes []int32
+	for v := dst; v >= 0; v = s.prev[v] {
+		nodes = append(nodes, v)
+	}
+	coords := make([][2]float64, 0, len(nodes)+2)
+	coords = append(coords, start)
+	for i := len(nodes) - 1; i >= 0; i-- {
+		pt := [2]float64{g.lon[nodes[i]], g.lat[nodes[i]]}
+		if pt != coords[len(coords)-1] {
+			coords = append(coords, pt)
+		}
+	}
+	if end != coords[len(coords)-1] {
+		coords = append(coords, end)
+	}
+	return RoadPath{DistanceM: s.dist[dst], DurationS: s.dur[dst], Coordinates: coords}, nil
+}
+
+// Matrix คำนวณระยะ (เมตร) และเวลา (วินาที) ของเส้นทางเร็วสุดระหว่างทุกคู่จุด
+func (g *RoadGraph) Matrix(points [][2]float64) (dist, dur [][]float64, err error) {
+	return g.Table(points, points)
+}
+
+// Table คำนวณเส้นทางเร็วสุดจาก sources แต่ละจุดไป destinations แต่ละจุด
+// ใช้ Dijkstra หนึ่งรอบต่อจุดต้นทาง (หยุดเมื่อถึงทุกปลายทาง) กระจายตามจำนวน CPU
+// คู่ที่ไม่มีเส้นทางเชื่อมได้ค่า -1
+func (g *RoadGraph) Table(sources, destinations [][2]float64) (dist, dur [][]float64, err error) {
+	snap := func(points [][2]float64, label string) ([]int32, error) {
+		out := make([]int32, len(points))
+		for i, pt := range points {
+			if out[i], err = g.Snap(pt); err != nil {
+				return nil, fmt.Errorf("%s %d: %w", label, i, err)
+			}
+		}
+		return out, nil
+	}
+	srcNodes, err := snap(sources, "source")
+	if err != nil {
+		return nil, nil, err
+	}
+	dstNodes, err := snap(destinations, "destination")
+	if err != nil {
+		return nil, nil, err
+	}
+	targets := make(map[int32]bool, len(dstNodes))
+	for _, t := range dstNodes {
+		targets[t] = true
+	}
+
+	n, cols := len(sources), len(destinations)
+	dist, dur = make([][]float64, n), make([][]float64, n)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU() && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s := g.newSearch()
+			for i := range jobs {
+				remaining := len(targets)
+				s.run(srcNodes[i], nil, func(v int32) bool {
+					if targets[v] {
+						remaining--
+					}
+					return remaining == 0
+				})
+				dist[i], dur[i] = make([]float64, cols), make([]float64, cols)
+				for j, t := range dstNodes {
+					if s.done[t] {
+						dist[i][j], dur[i][j] = s.dist[t], s.dur[t]
+					} else {
+						dist[i][j], dur[i][j] = -1, -1
+					}
+				}
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return dist, dur, nil
+}
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// fixture: ถนน residential สองทาง 1-2-3 และ primary oneway 3→4 (maxspeed 50), ทางเท้า 4-5 ไม่นับเป็นถนน
+var testRoadPoints = map[int64][2]float64{
+	1: {100.50, 13.70}, 2: {100.51, 13.70}, 3: {100.52, 13.70}, 4: {100.53, 13.70},
+}
+
+func testRoadLeg(a, b int64) float64 {
+	return HaversineMeters(testRoadPoints[a][1], testRoadPoints[a][0], testRoadPoints[b][1], testRoadPoints[b][0])
+}
+
+func TestOSMWayProfile(t *testing.T) {
+	cases := []struct {
+		tags   map[string]string
+		speed  float64
+		oneway int
+		ok     bool
+	}{
+		{map[string]string{"highway": "residential"}, 25, 0, true},
+		{map[string]string{"highway": "primary", "maxspeed": "30 mph"}, 30 * 1.609344, 0, true},
+		{map[string]string{"highway": "primary", "maxspeed": "TH:urban"}, 60, 0, true},
+		{map[string]string{"highway": "secondary", "oneway": "-1"}, 50, -1, true},
+		{map[string]string{"highway": "motorway"}, 90, 1, true},
+		{map[string]string{"highway": "motorway", "oneway": "no"}, 90, 0, true},
+		{map[string]string{"highway": "tertiary", "junction": "roundabout"}, 40, 1, true},
+		{map[string]string{"highway": "footway"}, 0, 0, false},
+		{map[string]string{"highway": "service", "access": "private"}, 0, 0, false},
+		{map[string]string{"highway": "residential", "area": "yes"}, 0, 0, false},
+	}
+	for _, tc := range cases {
+		speed, oneway, ok := osmWayProfile(tc.tags)
+		if ok != tc.ok || oneway != tc.oneway || math.Abs(speed-tc.speed) > 1e-9 {
+			t.Errorf("%v: got (%v, %d, %v), want (%v, %d, %v)", tc.tags, speed, oneway, ok, tc.speed, tc.oneway, tc.ok)
+		}
+	}
+}
+
+func loadTestRoadGraphs(t *testing.T) map[string]*RoadGraph {
+	t.Helper()
+	graphs := make(map[string]*RoadGraph)
+	for name, path := range map[string]string{"xml": testOSMFixture, "pbf": writeTestPBF(t)} {
+		g, err := LoadRoadGraph(path)
+		if err != nil {
+			t.Fatalf("LoadRoadGraph(%s): %v", name, err)
+		}
+		// node 5 อยู่บนทางเท้าเท่านั้น; edge: 1↔2, 2↔3 สองทาง + 3→4 ทางเดียว
+		if g.Nodes() != 4 || g.Edges() != 5 {
+			t.Fatalf("%s: nodes = %d, edges = %d, want 4 and 5", name, g.Nodes(), g.Edges())
+		}
+		graphs[name] = g
+	}
+	return graphs
+}
+
+func TestRoadGraphRoute(t *testing.T) {
+	residential, primary := 25/3.6, 50/3.6
+	wantDist := testRoadLeg(1, 2) + testRoadLeg(2, 3) + testRoadLeg(3, 4)
+	wantDur := (testRoadLeg(1, 2)+testRoadLeg(2, 3))/residential + testRoadLeg(3, 4)/primary
+
+	for name, g := range loadTestRoadGraphs(t) {
+		t.Run(name, func(t *testing.T) {
+			// จุดต้นทางอยู่เหนือ node 1 เล็กน้อย ต้องเป็นพิกัดแรกของเส้นทาง
+			start := [2]float64{100.50, 13.7005}
+			path, err := g.Route(start, testRoadPoints[4])
+			if err != nil {
+				t.Fatalf("Route: %v", err)
+			}
+			if math.Abs(path.DistanceM-wantDist) > 0.5 || math.Abs(path.DurationS-wantDur) > 0.5 {
+				t.Errorf("route = %.1f m / %.1f s, want %.1f m / %.1f s", path.DistanceM, path.DurationS, wantDist, wantDur)
+			}
+			// pbf มีความคลาดเคลื่อนของ float จึงอาจมีจุดปลายซ้ำกับ node 4 ต่อท้าย
+			coords := path.Coordinates
+			if len(coords) < 5 || coords[0] != start || coords[len(coords)-1] != testRoadPoints[4] {
+				t.Errorf("coordinates = %v, want start, nodes 1-4, end", coords)
+			}
+
+			// สวน oneway ไม่ได้
+			if _, err := g.Route(testRoadPoints[4], testRoadPoints[1]); !errors.Is(err, ErrNoRoadPath) {
+				t.Errorf("reverse route err = %v, want ErrNoRoadPath", err)
+			}
+			// ไกลจากถนนเกิน MaxRoadSnapMeter
+			if _, err := g.Route([2]float64{101.0, 14.0}, testRoadPoints[1]); err == nil {
+				t.Error("expected a snap error for a point far from the roads")
+			}
+		})
+	}
+}
+
+func TestRoadGraphTable(t *testing.T) {
+	residential, primary := 25/3.6, 50/3.6
+
+	for name, g := range loadTestRoadGraphs(t) {
+		t.Run(name, func(t *testing.T) {
+			sources := [][2]float64{testRoadPoints[1], testRoadPoints[4]}
+			destinations := [][2]float64{testRoadPoints[2], testRoadPoints[4], testRoadPoints[1]}
+			dist, dur, err := g.Table(sources, destinations)
+			if err != nil {
+				t.Fatalf("Table: %v", err)
+			}
+
+			d12, d23, d34 := testRoadLeg(1, 2), testRoadLeg(2, 3), testRoadLeg(3, 4)
+			wantDist := [][]float64{
+				{d12, d12 + d23 + d34, 0},
+				{-1, 0, -1}, // จาก node 4 ออกไม่ได้
+			}
+			wantDur := [][]float64{
+				{d12 / residential, (d12+d23)/residential + d34/primary, 0},
+				{-1, 0, -1},
+			}
+			for i := range wantDist {
+				for j := range wantDist[i] {
+					if math.Abs(dist[i][j]-wantDist[i][j]) > 0.5 || math.Abs(dur[i][j]-wantDur[i][j]) > 0.5 {
+						t.Errorf("[%d][%d] = %.1f m / %.1f s, want %.1f m / %.1f s",
+							i, j, dist[i][j], dur[i][j], wantDist[i][j], wantDur[i][j])
+					}
+				}
+			}
+
+			// Matrix คือ Table ของจุดชุดเดียวกัน
+			mDist, _, err := g.Matrix(sources)
+			if err != nil {
+				t.Fatalf("Matrix: %v", err)
+			}
+			if mDist[0][1] != dist[0][1] || mDist[1][0] != -1 {
+				t.Errorf("matrix = %v", mDist)
+			}
+		})
+	}
+}
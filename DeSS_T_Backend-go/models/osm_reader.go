@@ -0,0 +1,491 @@
+package models
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// osmHandler รับ element ระหว่างอ่าน OSM extract (callback ที่เป็น nil จะข้าม element ชนิดนั้น)
+// refs ของ way ใช้ buffer ร่วมกัน ต้อง copy ถ้าจะเก็บไว้
+type osmHandler struct {
+	node func(id int64, lat, lon float64)
+	way  func(refs []int64, tags map[string]string)
+}
+
+// scanOSMFile อ่าน OSM extract ทั้งไฟล์ รองรับ .osm.pbf และ .osm (XML) โดยดูจาก byte แรกของไฟล์
+func scanOSMFile(path string, h osmHandler) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	br := bufio.NewReaderSize(f, 1<<20)
+	head, _ := br.Peek(64)
+	head = bytes.TrimLeft(bytes.TrimPrefix(head, []byte("\ufeff")), " \t\r\n")
+	if len(head) > 0 && head[0] == '<' {
+		return scanOSMXML(br, h)
+	}
+	return scanOSMPBF(br, h)
+}
+
+/* ─────────────────────────────── XML ─────────────────────────────── */
+
+func scanOSMXML(r io.Reader, h osmHandler) error {
+	d := xml.NewDecoder(r)
+	var refs []int64
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("osm xml: %w", err)
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		switch se.Name.Local {
+		case "node":
+			if h.node != nil {
+				var id int64
+				var lat, lon float64
+				for _, a := range se.Attr {
+					switch a.Name.Local {
+					case "id":
+						id, _ = strconv.ParseInt(a.Value, 10, 64)
+					case "lat":
+						lat, _ = strconv.ParseFloat(a.Value, 64)
+					case "lon":
+						lon, _ = strconv.ParseFloat(a.Value, 64)
+					}
+				}
+				h.node(id, lat, lon)
+			}
+			if err := d.Skip(); err != nil {
+				return fmt.Errorf("osm xml: %w", err)
+			}
+		case "way":
+			if h.way == nil {
+				if err := d.Skip(); err != nil {
+					return fmt.Errorf("osm xml: %w", err)
+				}
+				continue
+			}
+			refs = refs[:0]
+			tags := make(map[string]string)
+			for {
+				tok, err := d.Token()
+				if err != nil {
+					return fmt.Errorf("osm xml: %w", err)
+				}
+				if end, ok := tok.(xml.EndElement); ok && end.Name.Local == "way" {
+					break
+				}
+				child, ok := tok.(xml.StartElement)
+				if !ok {
+					continue
+				}
+				switch child.Name.Local {
+				case "nd":
+					for _, a := range child.Attr {
+						if a.Name.Local == "ref" {
+							ref, _ := strconv.ParseInt(a.Value, 10, 64)
+							refs = append(refs, ref)
+						}
+					}
+				case "tag":
+					var k, v string
+					for _, a := range child.Attr {
+						switch a.Name.Local {
+						case "k":
+							k = a.Value
+						case "v":
+							v = a.Value
+						}
+					}
+					tags[k] = v
+				}
+			}
+			h.way(refs, tags)
+		case "relation":
+			if err := d.Skip(); err != nil {
+				return fmt.Errorf("osm xml: %w", err)
+			}
+		}
+	}
+}
+
+/* ─────────────────────────────── PBF ─────────────────────────────── */
+
+const (
+	osmPBFMaxHeaderSize = 64 * 1024
+	osmPBFMaxBlobSize   = 32 * 1024 * 1024
+)
+
+var osmPBFSupportedFeatures = map[string]bool{"OsmSchema-V0.6": true, "DenseNodes": true}
+
+// pbfBuf อ่าน protobuf wire format (เฉพาะที่ไฟล์ OSM ใช้)
+type pbfBuf struct {
+	b []byte
+	i int
+}
+
+var errPBFTruncated = errors.New("truncated protobuf message")
+
+func (p *pbfBuf) varint() (uint64, error) {
+	v, n := binary.Uvarint(p.b[p.i:])
+	if n <= 0 {
+		return 0, errPBFTruncated
+	}
+	p.i += n
+	return v, nil
+}
+
+// next คืน field ถัดไป: wire 0/1/5 อยู่ใน v, wire 2 อยู่ใน b; จบ message คืน io.EOF
+func (p *pbfBuf) next() (num int, wire int, v uint64, b []byte, err error) {
+	if p.i >= len(p.b) {
+		return 0, 0, 0, nil, io.EOF
+	}
+	key, err := p.varint()
+	if err != nil {
+		return 0, 0, 0, nil, err
+	}
+	num, wire = int(key>>3), int(key&7)
+	switch wire {
+	case 0:
+		v, err = p.varint()
+	case 1:
+		if p.i+8 > len(p.b) {
+			return 0, 0, 0, nil, errPBFTruncated
+		}
+		v = binary.LittleEndian.Uint64(p.b[p.i:])
+		p.i += 8
+	case 2:
+		var n uint64
+		if n, err = p.varint(); err == nil {
+			if n > uint64(len(p.b)-p.i) {
+				return 0, 0, 0, nil, errPBFTruncated
+			}
+			b = p.b[p.i : p.i+int(n)]
+			p.i += int(n)
+		}
+	case 5:
+		if p.i+4 > len(p.b) {
+			return 0, 0, 0, nil, errPBFTruncated
+		}
+		v = uint64(binary.LittleEndian.Uint32(p.b[p.i:]))
+		p.i += 4
+	default:
+		err = fmt.Errorf("unsupported protobuf wire type %d", wire)
+	}
+	return num, wire, v, b, err
+}
+
+// appendVarints รับ repeated field ได้ทั้งแบบ packed (wire 2) และไม่ packed (wire 0)
+func appendVarints(dst []uint64, wire int, v uint64, b []byte) ([]uint64, error) {
+	if wire == 0 {
+		return append(dst, v), nil
+	}
+	p := pbfBuf{b: b}
+	for p.i < len(p.b) {
+		x, err := p.varint()
+		if err != nil {
+			return dst, err
+		}
+		dst = append(dst, x)
+	}
+	return dst, nil
+}
+
+func unzigzag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func scanOSMPBF(r io.Reader, h osmHandler) error {
+	var lenBuf [4]byte
+	for block := 0; ; block++ {
+		if _, err := io.ReadFull(r, lenBuf[:]); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("osm pbf block %d: %w", block, err)
+		}
+		headerSize := binary.BigEndian.Uint32(lenBuf[:])
+		if headerSize > osmPBFMaxHeaderSize {
+			return fmt.Errorf("osm pbf block %d: header too large (%d bytes), file is not an OSM PBF extract", block, headerSize)
+		}
+		header := make([]byte, headerSize)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return fmt.Errorf("osm pbf block %d: %w", block, err)
+		}
+
+		var blobType string
+		var blobSize uint64
+		hp := pbfBuf{b: header}
+		for {
+			num, _, v, b, err := hp.next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("osm pbf block %d header: %w", block, err)
+			}
+			switch num {
+			case 1:
+				blobType = string(b)
+			case 3:
+				blobSize = v
+			}
+		}
+		if blobSize > osmPBFMaxBlobSize {
+			return fmt.Errorf("osm pbf block %d: blob too large (%d bytes)", block, blobSize)
+		}
+		blob := make([]byte, blobSize)
+		if _, err := io.ReadFull(r, blob); err != nil {
+			return fmt.Errorf("osm pbf block %d: %w", block, err)
+		}
+
+		switch blobType {
+		case "OSMHeader":
+			data, err := pbfBlobData(blob)
+			if err != nil {
+				return fmt.Errorf("osm pbf header: %w", err)
+			}
+			if err := pbfCheckHeader(data); err != nil {
+				return err
+			}
+		case "OSMData":
+			data, err := pbfBlobData(blob)
+			if err != nil {
+				return fmt.Errorf("osm pbf block %d: %w", block, err)
+			}
+			if err := pbfPrimitiveBlock(data, h); err != nil {
+				return fmt.Errorf("osm pbf block %d: %w", block, err)
+			}
+		}
+	}
+}
+
+// pbfBlobData แตก Blob (รองรับ raw และ zlib ซึ่งเป็นค่าที่ osmium / osmosis ใช้)
+func pbfBlobData(blob []byte) ([]byte, error) {
+	var rawSize uint64
+	p := pbfBuf{b: blob}
+	for {
+		num, _, v, b, err := p.next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("empty blob")
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch num {
+		case 1:
+			return b, nil
+		case 2:
+			rawSize = v
+		case 3:
+			zr, err := zlib.NewReader(bytes.NewReader(b))
+			if err != nil {
+				return nil, err
+			}
+			if rawSize > osmPBFMaxBlobSize {
+				return nil, fmt.Errorf("blob too large (%d bytes)", rawSize)
+			}
+			out := bytes.NewBuffer(make([]byte, 0, rawSize))
+			if _, err := io.Copy(out, io.LimitReader(zr, osmPBFMaxBlobSize)); err != nil {
+				return nil, err
+			}
+			return out.Bytes(), nil
+		case 4, 5, 6, 7:
+			return nil, fmt.Errorf("unsupported blob compression (field %d); re-export the extract with zlib", num)
+		}
+	}
+}
+
+func pbfCheckHeader(data []byte) error {
+	p := pbfBuf{b: data}
+	for {
+		num, _, _, b, err := p.next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("osm pbf header: %w", err)
+		}
+		if num == 4 && !osmPBFSupportedFeatures[string(b)] {
+			return fmt.Errorf("osm pbf requires unsupported feature %q", string(b))
+		}
+	}
+}
+
+func pbfPrimitiveBlock(data []byte, h osmHandler) error {
+	var stringTable [][]byte
+	var groups [][]byte
+	granularity, latOffset, lonOffset := int64(100), int64(0), int64(0)
+
+	p := pbfBuf{b: data}
+	for {
+		num, _, v, b, err := p.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		switch num {
+		case 1:
+			sp := pbfBuf{b: b}
+			for {
+				snum, _, _, sb, err := sp.next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					return err
+				}
+				if snum == 1 {
+					stringTable = append(stringTable, sb)
+				}
+			}
+		case 2:
+			groups = append(groups, b)
+		case 17:
+			granularity = int64(v)
+		case 19:
+			latOffset = int64(v)
+		case 20:
+			lonOffset = int64(v)
+		}
+	}
+
+	coord := func(offset, value int64) float64 {
+		return 1e-9 * float64(offset+granularity*value)
+	}
+	str := func(i uint64) string {
+		if i < uint64(len(stringTable)) {
+			return string(stringTable[i])
+		}
+		return ""
+	}
+
+	var refs []int64
+	var scratch [3][]uint64
+	for _, g := range groups {
+		gp := pbfBuf{b: g}
+		for {
+			num, _, _, b, err := gp.next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+
+			switch {
+			case num == 1 && h.node != nil: // Node
+				var id, lat, lon int64
+				np := pbfBuf{b: b}
+				for {
+					f, _, v, _, err := np.next()
+					if err == io.EOF {
+						break
+					}
+					if err != nil {
+						return err
+					}
+					switch f {
+					case 1:
+						id = unzigzag(v)
+					case 8:
+						lat = unzigzag(v)
+					case 9:
+						lon = unzigzag(v)
+					}
+				}
+				h.node(id, coord(latOffset, lat), coord(lonOffset, lon))
+
+			case num == 2 && h.node != nil: // DenseNodes (delta encoded)
+				ids, lats, lons := scratch[0][:0], scratch[1][:0], scratch[2][:0]
+				dp := pbfBuf{b: b}
+				for {
+					f, wire, v, fb, err := dp.next()
+					if err == io.EOF {
+						break
+					}
+					if err != nil {
+						return err
+					}
+					switch f {
+					case 1:
+						ids, err = appendVarints(ids, wire, v, fb)
+					case 8:
+						lats, err = appendVarints(lats, wire, v, fb)
+					case 9:
+						lons, err = appendVarints(lons, wire, v, fb)
+					}
+					if err != nil {
+						return err
+					}
+				}
+				if len(lats) != len(ids) || len(lons) != len(ids) {
+					return fmt.Errorf("dense nodes: %d ids but %d/%d coordinates", len(ids), len(lats), len(lons))
+				}
+				var id, lat, lon int64
+				for i := range ids {
+					id += unzigzag(ids[i])
+					lat += unzigzag(lats[i])
+					lon += unzigzag(lons[i])
+					h.node(id, coord(latOffset, lat), coord(lonOffset, lon))
+				}
+				scratch[0], scratch[1], scratch[2] = ids, lats, lons
+
+			case num == 3 && h.way != nil: // Way
+				keys, vals, deltas := scratch[0][:0], scratch[1][:0], scratch[2][:0]
+				wp := pbfBuf{b: b}
+				for {
+					f, wire, v, fb, err := wp.next()
+					if err == io.EOF {
+						break
+					}
+					if err != nil {
+						return err
+					}
+					switch f {
+					case 2:
+						keys, err = appendVarints(keys, wire, v, fb)
+					case 3:
+						vals, err = appendVarints(vals, wire, v, fb)
+					case 8:
+						deltas, err = appendVarints(deltas, wire, v, fb)
+					}
+					if err != nil {
+						return err
+					}
+				}
+				tags := make(map[string]string, len(keys))
+				for i := 0; i < len(keys) && i < len(vals); i++ {
+					tags[str(keys[i])] = str(vals[i])
+				}
+				refs = refs[:0]
+				var ref int64
+				for _, d := range deltas {
+					ref += unzigzag(d)
+					refs = append(refs, ref)
+				}
+				h.way(refs, tags)
+				scratch[0], scratch[1], scratch[2] = keys, vals, deltas
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package models
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testOSMFixture = "testdata/road_graph.osm"
+
+/* ──────────────────────── protobuf encoder ──────────────────────── */
+
+func pbVarint(dst []byte, v uint64) []byte {
+	return binary.AppendUvarint(dst, v)
+}
+
+func pbZigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+func pbUint(dst []byte, num int, v uint64) []byte {
+	return pbVarint(pbVarint(dst, uint64(num)<<3), v)
+}
+
+func pbBytes(dst []byte, num int, b []byte) []byte {
+	dst = pbVarint(dst, uint64(num)<<3|2)
+	return append(pbVarint(dst, uint64(len(b))), b...)
+}
+
+func pbPacked(dst []byte, num int, vs []uint64) []byte {
+	var body []byte
+	for _, v := range vs {
+		body = pbVarint(body, v)
+	}
+	return pbBytes(dst, num, body)
+}
+
+// pbDelta เข้ารหัส sint64 แบบ delta ตามที่ DenseNodes และ Way.refs ใช้
+func pbDelta(vs []int64) []uint64 {
+	out := make([]uint64, len(vs))
+	var prev int64
+	for i, v := range vs {
+		out[i] = pbZigzag(v - prev)
+		prev = v
+	}
+	return out
+}
+
+// pbFileBlock คือ BlobHeader + Blob หนึ่งก้อนของไฟล์ .osm.pbf
+func pbFileBlock(t *testing.T, blobType string, data []byte, compress bool) []byte {
+	t.Helper()
+	var blob []byte
+	if compress {
+		var z bytes.Buffer
+		zw := zlib.NewWriter(&z)
+		zw.Write(data)
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		blob = pbUint(blob, 2, uint64(len(data)))
+		blob = pbBytes(blob, 3, z.Bytes())
+	} else {
+		blob = pbBytes(blob, 1, data)
+	}
+	header := pbBytes(nil, 1, []byte(blobType))
+	header = pbUint(header, 3, uint64(len(blob)))
+
+	out := binary.BigEndian.AppendUint32(nil, uint32(len(header)))
+	return append(append(out, header...), blob...)
+}
+
+// writeTestPBF เขียน fixture เดียวกับ testdata/road_graph.osm เป็น .osm.pbf
+// node 1-3 และ 5 เป็น DenseNodes, node 4 เป็น Node ธรรมดา, พิกัดใช้ lat_offset / lon_offset
+func writeTestPBF(t *testing.T) string {
+	t.Helper()
+	const granularity, latOffset, lonOffset = 100, 13_000_000_000, 100_000_000_000
+	enc := func(deg float64, offset int64) int64 {
+		return (int64(math.Round(deg*1e9)) - offset) / granularity
+	}
+
+	strs := []string{"", "highway", "residential", "primary", "oneway", "yes", "maxspeed", "50", "footway"}
+	str := make(map[string]uint64)
+	var table []byte
+	for i, s := range strs {
+		str[s] = uint64(i)
+		table = pbBytes(table, 1, []byte(s))
+	}
+
+	ids := []int64{1, 2, 3, 5}
+	lons := []float64{100.50, 100.51, 100.52, 100.54}
+	var lats, lonsEnc []int64
+	for _, lon := range lons {
+		lats = append(lats, enc(13.70, latOffset))
+		lonsEnc = append(lonsEnc, enc(lon, lonOffset))
+	}
+	var dense []byte
+	dense = pbPacked(dense, 1, pbDelta(ids))
+	dense = pbPacked(dense, 8, pbDelta(lats))
+	dense = pbPacked(dense, 9, pbDelta(lonsEnc))
+	denseGroup := pbBytes(nil, 2, dense)
+
+	var node []byte
+	node = pbUint(node, 1, pbZigzag(4))
+	node = pbUint(node, 8, pbZigzag(enc(13.70, latOffset)))
+	node = pbUint(node, 9, pbZigzag(enc(100.53, lonOffset)))
+	nodeGroup := pbBytes(nil, 1, node)
+
+	way := func(id uint64, refs []int64, tags ...string) []byte {
+		var keys, vals []uint64
+		for i := 0; i < len(tags); i += 2 {
+			keys = append(keys, str[tags[i]])
+			vals = append(vals, str[tags[i+1]])
+		}
+		w := pbUint(nil, 1, id)
+		w = pbPacked(w, 2, keys)
+		w = pbPacked(w, 3, vals)
+		return pbPacked(w, 8, pbDelta(refs))
+	}
+	var wayGroup []byte
+	wayGroup = pbBytes(wayGroup, 3, way(10, []int64{1, 2, 3}, "highway", "residential"))
+	wayGroup = pbBytes(wayGroup, 3, way(11, []int64{3, 4}, "highway", "primary", "oneway", "yes", "maxspeed", "50"))
+	wayGroup = pbBytes(wayGroup, 3, way(12, []int64{4, 5}, "highway", "footway"))
+
+	var block []byte
+	block = pbBytes(block, 1, table)
+	block = pbBytes(block, 2, denseGroup)
+	block = pbBytes(block, 2, nodeGroup)
+	block = pbBytes(block, 2, wayGroup)
+	block = pbUint(block, 17, granularity)
+	block = pbUint(block, 19, latOffset)
+	block = pbUint(block, 20, lonOffset)
+
+	var header []byte
+	header = pbBytes(header, 4, []byte("OsmSchema-V0.6"))
+	header = pbBytes(header, 4, []byte("DenseNodes"))
+
+	var file []byte
+	file = append(file, pbFileBlock(t, "OSMHeader", header, false)...)
+	file = append(file, pbFileBlock(t, "OSMData", block, true)...)
+
+	path := filepath.Join(t.TempDir(), "road_graph.osm.pbf")
+	if err := os.WriteFile(path, file, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+/* ────────────────────────────── tests ────────────────────────────── */
+
+type testOSMWay struct {
+	refs []int64
+	tags map[string]string
+}
+
+func scanTestOSM(t *testing.T, path string) (map[int64][2]float64, []testOSMWay) {
+	t.Helper()
+	nodes := make(map[int64][2]float64)
+	var ways []testOSMWay
+	err := scanOSMFile(path, osmHandler{
+		node: func(id int64, lat, lon float64) { nodes[id] = [2]float64{lon, lat} },
+		way: func(refs []int64, tags map[string]string) {
+			ways = append(ways, testOSMWay{refs: append([]int64(nil), refs...), tags: tags})
+		},
+	})
+	if err != nil {
+		t.Fatalf("scanOSMFile(%s): %v", path, err)
+	}
+	return nodes, ways
+}
+
+func TestScanOSMXMLAndPBFAgree(t *testing.T) {
+	wantNodes := map[int64][2]float64{
+		1: {100.50, 13.70}, 2: {100.51, 13.70}, 3: {100.52, 13.70}, 4: {100.53, 13.70}, 5: {100.54, 13.70},
+	}
+	wantWays := []testOSMWay{
+		{refs: []int64{1, 2, 3}, tags: map[string]string{"highway": "residential"}},
+		{refs: []int64{3, 4}, tags: map[string]string{"highway": "primary", "oneway": "yes", "maxspeed": "50"}},
+		{refs: []int64{4, 5}, tags: map[string]string{"highway": "footway"}},
+	}
+
+	for name, path := range map[string]string{"xml": testOSMFixture, "pbf": writeTestPBF(t)} {
+		t.Run(name, func(t *testing.T) {
+			nodes, ways := scanTestOSM(t, path)
+			if len(nodes) != len(wantNodes) {
+				t.Fatalf("nodes = %v, want %v", nodes, wantNodes)
+			}
+			for id, want := range wantNodes {
+				got, ok := nodes[id]
+				if !ok || math.Abs(got[0]-want[0]) > 1e-9 || math.Abs(got[1]-want[1]) > 1e-9 {
+					t.Errorf("node %d = %v, want %v", id, got, want)
+				}
+			}
+			if !reflect.DeepEqual(ways, wantWays) {
+				t.Errorf("ways = %+v, want %+v", ways, wantWays)
+			}
+		})
+	}
+}
+
+func TestScanOSMPBFRejectsUnsupportedFeature(t *testing.T) {
+	header := pbBytes(nil, 4, []byte("HistoricalInformation"))
+	path := filepath.Join(t.TempDir(), "history.osm.pbf")
+	if err := os.WriteFile(path, pbFileBlock(t, "OSMHeader", header, false), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := scanOSMFile(path, osmHandler{}); err == nil {
+		t.Error("expected an error for an unsupported required feature")
+	}
+}
+
+func TestPBFPrimitiveBlockRejectsMismatchedDenseNodes(t *testing.T) {
+	var dense []byte
+	dense = pbPacked(dense, 1, pbDelta([]int64{1, 2}))
+	dense = pbPacked(dense, 8, pbDelta([]int64{0}))
+	dense = pbPacked(dense, 9, pbDelta([]int64{0, 0}))
+	block := pbBytes(nil, 2, pbBytes(nil, 2, dense))
+
+	if err := pbfPrimitiveBlock(block, osmHandler{node: func(int64, float64, float64) {}}); err == nil {
+		t.Error("expected an error for dense nodes with missing coordinates")
+	}
+}
+
+func TestPBFPrimitiveBlockTruncated(t *testing.T) {
+	block := pbBytes(nil, 2, []byte{0x12, 0x05, 0x01})
+	if err := pbfPrimitiveBlock(block, osmHandler{node: func(int64, float64, float64) {}}); err == nil {
+		t.Error("expected an error for a truncated group")
+	}
+}
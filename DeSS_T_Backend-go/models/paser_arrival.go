@@ -0,0 +1,248 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// รูปแบบเวลาที่รับได้ในคอลัมน์ timestamp (ไม่มี timezone ถือเป็นเวลาท้องถิ่นของข้อมูล)
+var arrivalTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"02/01/2006 15:04:05",
+	"02/01/2006 15:04",
+	"15:04:05",
+	"15:04",
+}
+
+type arrivalRange struct {
+	label      string
+	start, end int // วินาทีนับจากเที่ยงคืน [start, end)
+}
+
+// ParseTimeRanges รับ time range เป็น JSON array หรือคั่นด้วย comma เช่น "08:00-09:00,09:00-10:00"
+func ParseTimeRanges(raw string) ([]string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, fmt.Errorf("time_ranges is required")
+	}
+
+	var ranges []string
+	if strings.HasPrefix(raw, "[") {
+		if err := json.Unmarshal([]byte(raw), &ranges); err != nil {
+			return nil, fmt.Errorf("invalid time_ranges: %w", err)
+		}
+	} else {
+		ranges = strings.Split(raw, ",")
+	}
+
+	out := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		if r = strings.TrimSpace(r); r != "" {
+			out = append(out, r)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("time_ranges is required")
+	}
+	return out, nil
+}
+
+// ReadUploadRows อ่านแถวจาก sheet แรกของ xlsx (ค่าดิบ เพื่อให้วันเวลาเป็น serial number) หรือจาก CSV/TSV
+func ReadUploadRows(r io.Reader, format string) ([][]string, error) {
+	if format != FormatXLSX {
+		return ReadDelimitedRows(r, format)
+	}
+
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, fmt.Errorf("excel has no sheet")
+	}
+	return f.GetRows(sheets[0], excelize.Options{RawCellValue: true})
+}
+
+// ArrivalTimestampsToData แปลงเวลามาถึงของผู้โดยสาร (station, timestamp) เป็นช่วงห่างระหว่างคนที่มาติดกัน
+//   - แบ่งตาม station, วัน และ time range; ช่วงห่างที่ข้าม time range หรือข้ามวันจะถูกทิ้ง
+//   - หน่วยของช่วงห่างเป็นนาที (ตรงกับ simulation) หรือวินาทีถ้า unitSeconds
+//   - time range ที่มีคนมาไม่ถึง 2 คนจะได้ค่า 0 เหมือนคอลัมน์ว่างใน Excel (fit เป็น No Arrival)
+func ArrivalTimestampsToData(
+	rows [][]string,
+	timeRanges []string,
+	stationNameToID map[string]string,
+	unitSeconds bool,
+) (Data, error) {
+
+	if len(rows) < 2 {
+		return Data{}, fmt.Errorf("file has no arrival records")
+	}
+
+	ranges := make([]arrivalRange, 0, len(timeRanges))
+	for _, tr := range timeRanges {
+		start, end, err := parseArrivalRange(tr)
+		if err != nil {
+			return Data{}, err
+		}
+		ranges = append(ranges, arrivalRange{label: strings.TrimSpace(tr), start: start, end: end})
+	}
+
+	stationCol, timeCol := arrivalColumns(rows[0])
+	if stationCol < 0 || timeCol < 0 {
+		return Data{}, fmt.Errorf("header must contain station and timestamp columns")
+	}
+
+	// station → "วันที่|range index" → เวลาที่มาถึง (วินาทีในวันนั้น)
+	stations := make([]string, 0)
+	buckets := make(map[string]map[string][]int)
+
+	for i, row := range rows[1:] {
+		if stationCol >= len(row) || timeCol >= len(row) {
+			continue
+		}
+		station := strings.TrimSpace(row[stationCol])
+		rawTime := strings.TrimSpace(row[timeCol])
+		if station == "" || rawTime == "" {
+			continue
+		}
+
+		ts, err := parseArrivalTime(rawTime)
+		if err != nil {
+			return Data{}, fmt.Errorf("row %d: %w", i+2, err)
+		}
+
+		stationID := resolveStationID(station, stationNameToID)
+		if _, ok := buckets[stationID]; !ok {
+			buckets[stationID] = make(map[string][]int)
+			stations = append(stations, stationID)
+		}
+
+		sec := ts.Hour()*3600 + ts.Minute()*60 + ts.Second()
+		for ri, r := range ranges {
+			if sec >= r.start && sec < r.end {
+				key := ts.Format("2006-01-02") + "|" + strconv.Itoa(ri)
+				buckets[stationID][key] = append(buckets[stationID][key], sec)
+				break
+			}
+		}
+	}
+
+	divisor := 60.0
+	if unitSeconds {
+		divisor = 1
+	}
+
+	output := Data{}
+	recordID := 1
+
+	for _, stationID := range stations {
+		for ri, r := range ranges {
+			recs := []Record{}
+
+			days := make([]string, 0)
+			for key := range buckets[stationID] {
+				if strings.HasSuffix(key, "|"+strconv.Itoa(ri)) {
+					days = append(days, key)
+				}
+			}
+			sort.Strings(days)
+
+			for _, key := range days {
+				times := buckets[stationID][key]
+				sort.Ints(times)
+				for j := 1; j < len(times); j++ {
+					recs = append(recs, Record{
+						RecordID:     recordID,
+						NumericValue: float64(times[j]-times[j-1]) / divisor,
+						TimeOfDay:    times[j],
+						Timed:        true,
+					})
+					recordID++
+				}
+			}
+
+			if len(recs) == 0 {
+				recs = append(recs, Record{RecordID: recordID, NumericValue: 0, Placeholder: true})
+				recordID++
+			}
+
+			output.Data = append(output.Data, Item{
+				Station:   stationID,
+				TimeRange: r.label,
+				Records:   recs,
+			})
+		}
+	}
+
+	return output, nil
+}
+
+func arrivalColumns(header []string) (int, int) {
+	stationCol, timeCol := -1, -1
+	for i, h := range header {
+		key := strings.NewReplacer(" ", "", "_", "", "-", "").Replace(strings.ToLower(strings.TrimSpace(h)))
+		switch key {
+		case "station", "stationname", "stationid":
+			stationCol = i
+		case "timestamp", "datetime", "arrivaltime", "arrival", "time":
+			timeCol = i
+		}
+	}
+	return stationCol, timeCol
+}
+
+func parseArrivalRange(tr string) (int, int, error) {
+	parts := strings.Split(strings.TrimSpace(tr), "-")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid time range %q, expected HH:MM-HH:MM", tr)
+	}
+	start, err1 := time.Parse("15:04", strings.TrimSpace(parts[0]))
+	end, err2 := time.Parse("15:04", strings.TrimSpace(parts[1]))
+	endSec := end.Hour()*3600 + end.Minute()*60
+	if strings.TrimSpace(parts[1]) == "24:00" {
+		endSec, err2 = 24*3600, nil
+	}
+	if err1 != nil || err2 != nil {
+		return 0, 0, fmt.Errorf("invalid time range %q, expected HH:MM-HH:MM", tr)
+	}
+	startSec := start.Hour()*3600 + start.Minute()*60
+	if startSec >= endSec {
+		return 0, 0, fmt.Errorf("invalid time range %q, start must be before end", tr)
+	}
+	return startSec, endSec, nil
+}
+
+// parseArrivalTime รับทั้งข้อความวันเวลาและ serial number ของ Excel
+func parseArrivalTime(raw string) (time.Time, error) {
+	for _, layout := range arrivalTimeLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t, nil
+		}
+	}
+
+	if serial, err := strconv.ParseFloat(raw, 64); err == nil {
+		if serial < 1 {
+			// เวลาอย่างเดียว (เศษของวัน)
+			return time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(serial * 24 * float64(time.Hour)).Round(time.Second)), nil
+		}
+		t, err := excelize.ExcelDateToTime(serial, false)
+		if err == nil {
+			return t.Round(time.Second), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("cannot parse timestamp %q", raw)
+}
@@ -0,0 +1,29 @@
+package models
+
+import "testing"
+
+func TestArrivalTimestampsKeepSampleTimes(t *testing.T) {
+	rows := [][]string{
+		{"station", "timestamp"},
+		{"s1", "2024-01-01 07:00:00"},
+		{"s1", "2024-01-01 07:05:00"},
+		{"s1", "2024-01-01 07:45:30"},
+	}
+	data, err := ArrivalTimestampsToData(rows, []string{"07:00-08:00"}, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Data) != 1 || len(data.Data[0].Records) != 2 {
+		t.Fatalf("data = %+v, want one item with two gaps", data)
+	}
+	// แต่ละช่วงห่างมีเวลาของการมาถึงครั้งหลัง
+	for i, want := range []struct {
+		value float64
+		at    int
+	}{{5, 7*3600 + 5*60}, {40.5, 7*3600 + 45*60 + 30}} {
+		rec := data.Data[0].Records[i]
+		if !rec.Timed || rec.TimeOfDay != want.at || rec.NumericValue != want.value {
+			t.Errorf("record %d = %+v, want %v min at %d s", i, rec, want.value, want.at)
+		}
+	}
+}
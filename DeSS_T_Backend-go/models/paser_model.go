@@ -11,6 +11,12 @@ import (
 type Record struct {
     RecordID     int     `json:"Record_ID"`
     NumericValue float64 `json:"Numeric_Value"`
+    // Placeholder คือค่า 0 ที่ parser ใส่แทนคอลัมน์/ช่วงที่ไม่มีข้อมูล (ให้ fit ได้ผล No Arrival / 0)
+    // ไม่ใช่ค่าที่วัดได้จริง จึงไม่ถูกเก็บเป็น observation
+    Placeholder bool `json:"-"`
+    // เวลาของตัวอย่าง (วินาทีนับจากเที่ยงคืน) เมื่อแหล่งข้อมูลมีเวลาจริง ใช้แบ่ง time range ใหม่ตอน refit
+    TimeOfDay int  `json:"-"`
+    Timed     bool `json:"-"`
 }
 
 type Item struct {
@@ -96,68 +102,88 @@ func DistributionExcelToJSONReader(
 
     for _, sheet := range f.GetSheetList() {
 
-        sheetKey := strings.TrimSpace(sheet)
-
-        stationID := sheetKey
-        if id, ok := stationNameToID[sheetKey]; ok {
-            stationID = id
-        }
-
         rows, err := f.GetRows(sheet)
-        if err != nil || len(rows) == 0 {
+        if err != nil {
             continue
         }
 
-        headers := rows[0]
-        if len(headers) == 0 {
-            continue
-        }
+        appendDistributionSheet(&output, resolveStationID(sheet, stationNameToID), rows, &recordID)
+    }
 
-        for colIndex, header := range headers {
-            if strings.TrimSpace(header) == "" {
-                continue
-            }
+    return output, nil
+}
 
-            recs := []Record{}
+// resolveStationID แปลงชื่อ station (ชื่อ sheet / ชื่อไฟล์ / คอลัมน์ station) เป็น ID ถ้ามีใน map
+func resolveStationID(name string, stationNameToID map[string]string) string {
+    key := strings.TrimSpace(name)
+    if id, ok := stationNameToID[key]; ok {
+        return id
+    }
+    return key
+}
 
-            for rowIndex := 1; rowIndex < len(rows); rowIndex++ {
-                if colIndex >= len(rows[rowIndex]) {
-                    continue
-                }
+// appendDistributionSheet อ่าน sheet แบบกว้าง: แถวแรกเป็น time range แต่ละคอลัมน์คือค่าของช่วงนั้น
+func appendDistributionSheet(output *Data, stationID string, rows [][]string, recordID *int) {
+    if len(rows) == 0 {
+        return
+    }
 
-                val := strings.TrimSpace(rows[rowIndex][colIndex])
-                if val == "" {
-                    continue
-                }
+    headers := rows[0]
+    if len(headers) == 0 {
+        return
+    }
 
-                var num float64
-                if _, err := fmt.Sscanf(val, "%f", &num); err != nil {
-                    continue
-                }
+    for colIndex, header := range headers {
+        if strings.TrimSpace(header) == "" {
+            continue
+        }
 
-                recs = append(recs, Record{
-                    RecordID:     recordID,
-                    NumericValue: num,
-                })
-                recordID++
+        recs := []Record{}
+
+        for rowIndex := 1; rowIndex < len(rows); rowIndex++ {
+            if colIndex >= len(rows[rowIndex]) {
+                continue
             }
 
-            // ⭐ กรณี sheet ว่าง → เติมค่า 0
-            if len(recs) == 0 {
-                recs = append(recs, Record{
-                    RecordID:     recordID,
-                    NumericValue: 0,
-                })
-                recordID++
+            num, ok := parseNumericCell(rows[rowIndex][colIndex])
+            if !ok {
+                continue
             }
 
-            output.Data = append(output.Data, Item{
-                Station:   stationID,
-                TimeRange: header,
-                Records:   recs,
+            recs = append(recs, Record{
+                RecordID:     *recordID,
+                NumericValue: num,
+            })
+            *recordID++
+        }
+
+        // ⭐ กรณี sheet ว่าง → เติมค่า 0
+        if len(recs) == 0 {
+            recs = append(recs, Record{
+                RecordID:     *recordID,
+                NumericValue: 0,
+                Placeholder:  true,
             })
+            *recordID++
         }
+
+        output.Data = append(output.Data, Item{
+            Station:   stationID,
+            TimeRange: header,
+            Records:   recs,
+        })
     }
+}
 
-    return output, nil
+func parseNumericCell(val string) (float64, bool) {
+    val = strings.TrimSpace(val)
+    if val == "" {
+        return 0, false
+    }
+
+    var num float64
+    if _, err := fmt.Sscanf(val, "%f", &num); err != nil {
+        return 0, false
+    }
+    return num, true
 }
@@ -3,8 +3,12 @@ package models
 import (
 	"fmt"
 	"io"
+	"math"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/xuri/excelize/v2"
 )
@@ -17,16 +21,18 @@ type PaserscheduleData struct {
 
 type Paserschedule struct {
 	PaserscheduleData []PaserscheduleData `json:"ScheduleData"`
+	Validation        *ValidationReport   `json:"validation,omitempty"`
 }
 
-
-
-
-func ScheduleExcelToJsonReader(r io.Reader, scenarioID string) (Paserschedule, error) {
+// ScheduleExcelToJsonReader อ่าน workbook ตารางเวลา (sheet เดียว แถวแรกเป็นชื่อ route path)
+// routePaths: ชื่อ route path → route_path_id (nil = ยังไม่มี route ในระบบ ใช้ "<ชื่อ>-<scenarioID>" แบบเดิม)
+// ถ้ามี cell ที่อ่านไม่ได้จะคืน ErrValidationFailed พร้อมรายงานใน Validation
+func ScheduleExcelToJsonReader(r io.Reader, scenarioID string, routePaths map[string]string) (Paserschedule, error) {
 	f, err := excelize.OpenReader(r)
     if err != nil {
         return Paserschedule{}, err
     }
+	defer f.Close()
 
 	sheets := f.GetSheetList()
 	if len(sheets) != 1 {
@@ -35,47 +41,284 @@ func ScheduleExcelToJsonReader(r io.Reader, scenarioID string) (Paserschedule, e
 
 	sheetName := sheets[0]
 
-	rows, err := f.GetRows(sheetName)
+	// ค่าดิบ: cell รูปแบบเวลาจะได้เศษของวัน ไม่ใช่ข้อความตาม format ของเครื่องที่สร้างไฟล์
+	rows, err := f.GetRows(sheetName, excelize.Options{RawCellValue: true})
 	if err != nil {
 		return Paserschedule{}, err
 	}
 
+	return scheduleRowsToJson(rows, sheetName, scenarioID, routePaths, scheduleTimeCells(f, sheetName, rows))
+}
+
+// scheduleTimeCells คืน cell (เช่น "B3") ที่เป็นตัวเลขและจัดรูปแบบเป็นเวลาใน Excel
+// เฉพาะ cell เหล่านี้ที่ค่าดิบเป็นเศษของวันโดยเจตนา (0.3 = 07:12) ค่าอื่นที่ดูเหมือนเศษของวันต้องเตือน
+func scheduleTimeCells(f *excelize.File, sheet string, rows [][]string) map[string]bool {
+	cells := make(map[string]bool)
+	styleIsTime := make(map[int]bool)
+	for row := 1; row < len(rows); row++ {
+		for col, raw := range rows[row] {
+			if strings.TrimSpace(raw) == "" {
+				continue
+			}
+			ref := cellRef(col, row)
+			if t, err := f.GetCellType(sheet, ref); err != nil || (t != excelize.CellTypeUnset && t != excelize.CellTypeNumber) {
+				continue
+			}
+			styleID, err := f.GetCellStyle(sheet, ref)
+			if err != nil {
+				continue
+			}
+			isTime, ok := styleIsTime[styleID]
+			if !ok {
+				if style, err := f.GetStyle(styleID); err == nil {
+					isTime = isTimeNumFmt(style)
+				}
+				styleIsTime[styleID] = isTime
+			}
+			if isTime {
+				cells[ref] = true
+			}
+		}
+	}
+	return cells
+}
+
+// isTimeNumFmt บอกว่า number format แสดงเวลา (built-in 18-22, 45-47 หรือ custom ที่มี h / s นอกข้อความในเครื่องหมายคำพูด)
+func isTimeNumFmt(style *excelize.Style) bool {
+	if style.CustomNumFmt != nil {
+		format := quotedFormatText.ReplaceAllString(*style.CustomNumFmt, "")
+		return strings.ContainsAny(strings.ToLower(format), "hs")
+	}
+	switch style.NumFmt {
+	case 18, 19, 20, 21, 22, 45, 46, 47:
+		return true
+	}
+	return false
+}
+
+var quotedFormatText = regexp.MustCompile(`"[^"]*"|\\.`)
+
+// ScheduleDelimitedToJsonReader อ่าน CSV/TSV ที่มีรูปแบบเดียวกับ sheet ของ Excel
+func ScheduleDelimitedToJsonReader(r io.Reader, format string, scenarioID string, routePaths map[string]string) (Paserschedule, error) {
+	rows, err := ReadDelimitedRows(r, format)
+	if err != nil {
+		return Paserschedule{}, err
+	}
+
+	return scheduleRowsToJson(rows, format, scenarioID, routePaths, nil)
+}
+
+type scheduleDeparture struct {
+	sec  int
+	cell string
+}
+
+// scheduleRowsToJson: แถวแรกคือชื่อเส้นทาง แต่ละคอลัมน์คือเวลาออกรถของเส้นทางนั้น
+// เวลาถูกแปลงเป็น HH:MM (หรือ HH:MM:SS ถ้ามีวินาที) เรียงและตัดตัวซ้ำต่อเส้นทาง
+// timeCells: cell ที่ Excel จัดรูปแบบเป็นเวลา (nil สำหรับ CSV)
+func scheduleRowsToJson(rows [][]string, sheet, scenarioID string, routePaths map[string]string, timeCells map[string]bool) (Paserschedule, error) {
 	if len(rows) < 2 {
 		return Paserschedule{}, fmt.Errorf("excel has no schedule data")
 	}
 
-	var result Paserschedule
-	colCount := len(rows[0])
-	scheduleDataID := 1
+	report := ValidationReport{Valid: true, Issues: []ValidationIssue{}}
+
+	routeByName := make(map[string]string, len(routePaths))
+	for name, id := range routePaths {
+		routeByName[routeNameKey(name)] = id
+	}
+
+	type routeColumn struct {
+		name       string
+		headerCell string
+		departures []scheduleDeparture
+	}
+	columns := make(map[string]*routeColumn)
+	order := make([]string, 0)
+
+	colCount := 0
+	for _, row := range rows {
+		if len(row) > colCount {
+			colCount = len(row)
+		}
+	}
+
 	for col := 0; col < colCount; col++ {
-		routePathName := strings.TrimSpace(rows[0][col])
+		routePathName := ""
+		if col < len(rows[0]) {
+			routePathName = strings.TrimSpace(rows[0][col])
+		}
 		if routePathName == "" {
+			for row := 1; row < len(rows); row++ {
+				if col < len(rows[row]) && strings.TrimSpace(rows[row][col]) != "" {
+					report.add(sheet, cellRef(col, row), SeverityWarning, "departure without a route path header is ignored")
+					break
+				}
+			}
 			continue
 		}
 
-		var times []string
+		routePathID := fmt.Sprintf("%s-%s", routePathName, scenarioID)
+		if routePaths != nil {
+			id, ok := routeByName[routeNameKey(routePathName)]
+			if !ok {
+				report.add(sheet, cellRef(col, 0), SeverityError, "unknown route path %q (not in this route scenario)", routePathName)
+				continue
+			}
+			routePathID = id
+		}
+
+		column, seen := columns[routePathID]
+		if seen {
+			report.add(sheet, cellRef(col, 0), SeverityWarning, "route path %q already has a column at %s; departures are merged", routePathName, column.headerCell)
+		} else {
+			column = &routeColumn{name: routePathName, headerCell: cellRef(col, 0)}
+			columns[routePathID] = column
+			order = append(order, routePathID)
+		}
+
 		for row := 1; row < len(rows); row++ {
 			if col >= len(rows[row]) {
 				continue
 			}
+			raw := strings.TrimSpace(rows[row][col])
+			if raw == "" {
+				continue
+			}
+
+			ref := cellRef(col, row)
+			sec, note, err := parseDepartureTime(raw, timeCells[ref])
+			if err != nil {
+				report.add(sheet, ref, SeverityError, "%v", err)
+				continue
+			}
+			if note != "" {
+				report.add(sheet, ref, SeverityWarning, "%s", note)
+			}
+			column.departures = append(column.departures, scheduleDeparture{sec: sec, cell: ref})
+		}
+	}
+
+	var result Paserschedule
+	scheduleDataID := 1
+	for _, routePathID := range order {
+		column := columns[routePathID]
 
-			timeValue := strings.TrimSpace(rows[row][col])
-			if timeValue != "" {
-				times = append(times, timeValue)
+		if !sort.SliceIsSorted(column.departures, func(i, j int) bool { return column.departures[i].sec < column.departures[j].sec }) {
+			report.add(sheet, column.headerCell, SeverityWarning, "departures of %q are not in time order and have been sorted", column.name)
+		}
+		sort.SliceStable(column.departures, func(i, j int) bool { return column.departures[i].sec < column.departures[j].sec })
+
+		times := make([]string, 0, len(column.departures))
+		for i, d := range column.departures {
+			if i > 0 && d.sec == column.departures[i-1].sec {
+				report.add(sheet, d.cell, SeverityWarning, "duplicate departure %s (also at %s) is removed", formatDepartureTime(d.sec), column.departures[i-1].cell)
+				continue
 			}
+			times = append(times, formatDepartureTime(d.sec))
+		}
+		if len(times) == 0 {
+			report.add(sheet, column.headerCell, SeverityWarning, "route path %q has no departures", column.name)
 		}
 
 		result.PaserscheduleData = append(result.PaserscheduleData, PaserscheduleData{
 			ScheduleDataID: strconv.Itoa(scheduleDataID),
-			RoutePathID:    fmt.Sprintf("%s-%s", routePathName, scenarioID),
+			RoutePathID:    routePathID,
 			ScheduleList:   strings.Join(times, ","),
 		})
 		scheduleDataID++
 	}
 
+	if routePaths != nil {
+		names := make([]string, 0)
+		for name, id := range routePaths {
+			if _, ok := columns[id]; !ok {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			report.add(sheet, "", SeverityWarning, "route path %q has no column in the file", name)
+		}
+	}
+
+	result.Validation = &report
+	if !report.Valid {
+		return result, ErrValidationFailed
+	}
 	return result, nil
 }
 
+// routeNameKey เทียบชื่อ route แบบไม่สนตัวพิมพ์และช่องว่างซ้ำ
+func routeNameKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// "7.30" / "7.3" / "07.30" / "0.30" ที่พิมพ์แทน 07:30 / 00:30 (Excel เก็บ 7.30 เป็นตัวเลข 7.3)
+var dottedClockPattern = regexp.MustCompile(`^(\d{1,2})\.(\d{1,2})$`)
+
+var departureTimeLayouts = []string{"15:04", "15:04:05", "3:04 PM", "3:04PM", "3:04:05 PM", "3:04 pm", "3:04pm"}
+
+// parseDepartureTime แปลงเวลาออกรถเป็นวินาทีของวัน รองรับ
+//   - ข้อความ H:MM, HH:MM:SS, 7:30 PM
+//   - "7.30" / "7.3" / "0.30" (จุดแทน :) เมื่อ cell ไม่ได้จัดรูปแบบเป็นเวลา
+//   - เศษของวันจาก cell รูปแบบเวลาใน Excel (0.3125 = 07:30) และ serial วันที่+เวลา (ใช้เฉพาะเวลา)
+//
+// timeCell = cell ตัวเลขที่ Excel จัดรูปแบบเป็นเวลา อ่านเศษของวันได้โดยไม่เตือน
+// ค่าอื่นที่อ่านเป็นเศษของวันจะมีคำเตือน
+// note คือคำเตือนเมื่อแปลงได้แต่ควรตรวจ
+func parseDepartureTime(raw string, timeCell bool) (sec int, note string, err error) {
+	raw = strings.TrimSpace(raw)
+
+	for _, layout := range departureTimeLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t.Hour()*3600 + t.Minute()*60 + t.Second(), "", nil
+		}
+	}
+
+	if m := dottedClockPattern.FindStringSubmatch(raw); m != nil && !timeCell {
+		h, _ := strconv.Atoi(m[1])
+		minute, _ := strconv.Atoi(m[2])
+		if len(m[2]) == 1 {
+			minute *= 10
+		}
+		if h <= 23 && minute <= 59 {
+			return h*3600 + minute*60, fmt.Sprintf("%q read as %s", raw, formatDepartureTime(h*3600+minute*60)), nil
+		}
+	}
+
+	num, perr := strconv.ParseFloat(raw, 64)
+	if perr != nil || math.IsNaN(num) || math.IsInf(num, 0) || num < 0 {
+		return 0, "", fmt.Errorf("%q is not a time (expected HH:MM)", raw)
+	}
+	if num < 1 {
+		sec := int(math.Round(num*86400)) % 86400
+		if !timeCell {
+			return sec, fmt.Sprintf("%q read as a fraction of a day (%s); use HH:MM or a time-formatted cell", raw, formatDepartureTime(sec)), nil
+		}
+		return sec, "", nil
+	}
+
+	// serial วันที่+เวลา (ค่าตัวเลขที่มีทศนิยมหลายตำแหน่ง)
+	if num >= 60 && num != math.Trunc(num) {
+		frac := num - math.Trunc(num)
+		sec := int(math.Round(frac*86400)) % 86400
+		return sec, fmt.Sprintf("date part of %q is ignored, read as %s", raw, formatDepartureTime(sec)), nil
+	}
+	return 0, "", fmt.Errorf("%q is not a time (expected HH:MM)", raw)
+}
+
+// formatDepartureTime คืน HH:MM หรือ HH:MM:SS ถ้ามีวินาที
+func formatDepartureTime(sec int) string {
+	if sec%60 != 0 {
+		return fmt.Sprintf("%02d:%02d:%02d", sec/3600, sec%3600/60, sec%60)
+	}
+	return fmt.Sprintf("%02d:%02d", sec/3600, sec%3600/60)
+}
+
+
+
+
 
 
 // func ScheduleExcelToJson(path string, projectID string) (Paserschedule, error) {
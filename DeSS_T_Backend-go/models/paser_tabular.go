@@ -0,0 +1,178 @@
+package models
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"mime"
+	"path/filepath"
+	"strings"
+)
+
+// รูปแบบไฟล์ที่รับได้ในการ upload
+const (
+	FormatXLSX = "xlsx"
+	FormatCSV  = "csv"
+	FormatTSV  = "tsv"
+)
+
+// DetectUploadFormat ดูจากนามสกุลไฟล์ก่อน แล้วค่อยดู content type
+// (browser บางตัวส่ง CSV มาเป็น application/vnd.ms-excel) ถ้าไม่รู้จักเลยถือว่าเป็น xlsx เหมือนเดิม
+func DetectUploadFormat(filename, contentType string) (string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".xlsx", ".xlsm":
+		return FormatXLSX, nil
+	case ".csv":
+		return FormatCSV, nil
+	case ".tsv", ".tab":
+		return FormatTSV, nil
+	case ".xls":
+		return "", fmt.Errorf("legacy .xls is not supported, save the file as .xlsx or .csv")
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch strings.ToLower(mediaType) {
+	case "text/csv", "application/csv":
+		return FormatCSV, nil
+	case "text/tab-separated-values":
+		return FormatTSV, nil
+	}
+
+	return FormatXLSX, nil
+}
+
+// ReadDelimitedRows อ่าน CSV/TSV ทั้งไฟล์เป็นแถว (ตัด BOM ของไฟล์ที่ export จาก Excel)
+func ReadDelimitedRows(r io.Reader, format string) ([][]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	if format == FormatTSV {
+		reader.Comma = '\t'
+	}
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("read %s: %w", format, err)
+	}
+
+	if len(rows) > 0 && len(rows[0]) > 0 {
+		rows[0][0] = strings.TrimPrefix(rows[0][0], "\ufeff")
+	}
+
+	return rows, nil
+}
+
+// DistributionDelimitedToJSONReader อ่าน CSV/TSV ให้ได้ผลเหมือน DistributionExcelToJSONReader
+//   - แบบยาว: มีคอลัมน์ station, time range และ value (หนึ่งแถวต่อหนึ่งค่า)
+//   - แบบกว้าง (หนึ่งไฟล์ต่อ station): เหมือน sheet ของ Excel โดยใช้ stationName เป็นชื่อ sheet
+func DistributionDelimitedToJSONReader(
+	r io.Reader,
+	format string,
+	stationName string,
+	stationNameToID map[string]string,
+) (Data, error) {
+
+	rows, err := ReadDelimitedRows(r, format)
+	if err != nil {
+		return Data{}, err
+	}
+
+	output := Data{}
+	recordID := 1
+
+	if len(rows) == 0 {
+		return output, nil
+	}
+
+	if stationCol, rangeCol, valueCol, ok := longFormatColumns(rows[0]); ok {
+		appendDistributionLongRows(&output, rows[1:], stationCol, rangeCol, valueCol, stationNameToID, &recordID)
+		return output, nil
+	}
+
+	if strings.TrimSpace(stationName) == "" {
+		return Data{}, fmt.Errorf("station is required for a one-station file (or use station, time_range, value columns)")
+	}
+
+	appendDistributionSheet(&output, resolveStationID(stationName, stationNameToID), rows, &recordID)
+	return output, nil
+}
+
+// longFormatColumns หาคอลัมน์ station / time range / value จาก header (ไม่สนตัวพิมพ์, ช่องว่าง, _)
+func longFormatColumns(header []string) (int, int, int, bool) {
+	stationCol, rangeCol, valueCol := -1, -1, -1
+
+	for i, h := range header {
+		key := strings.NewReplacer(" ", "", "_", "", "-", "").Replace(strings.ToLower(strings.TrimSpace(h)))
+		switch key {
+		case "station", "stationname", "stationid":
+			stationCol = i
+		case "timerange", "timeperiod", "period":
+			rangeCol = i
+		case "value", "numericvalue":
+			valueCol = i
+		}
+	}
+
+	return stationCol, rangeCol, valueCol, stationCol >= 0 && rangeCol >= 0 && valueCol >= 0
+}
+
+// appendDistributionLongRows จัดกลุ่มตาม (station, time range) ตามลำดับที่พบครั้งแรก
+// กลุ่มที่ไม่มีค่าตัวเลขเลยจะเติม 0 เหมือนคอลัมน์ว่างใน Excel
+func appendDistributionLongRows(
+	output *Data,
+	rows [][]string,
+	stationCol, rangeCol, valueCol int,
+	stationNameToID map[string]string,
+	recordID *int,
+) {
+
+	index := make(map[string]int)
+	cell := func(row []string, col int) string {
+		if col < len(row) {
+			return strings.TrimSpace(row[col])
+		}
+		return ""
+	}
+
+	for _, row := range rows {
+		station := cell(row, stationCol)
+		timeRange := cell(row, rangeCol)
+		if station == "" || timeRange == "" {
+			continue
+		}
+
+		stationID := resolveStationID(station, stationNameToID)
+		key := stationID + "|" + timeRange
+		i, exists := index[key]
+		if !exists {
+			output.Data = append(output.Data, Item{
+				Station:   stationID,
+				TimeRange: timeRange,
+				Records:   []Record{},
+			})
+			i = len(output.Data) - 1
+			index[key] = i
+		}
+
+		num, ok := parseNumericCell(cell(row, valueCol))
+		if !ok {
+			continue
+		}
+		output.Data[i].Records = append(output.Data[i].Records, Record{
+			RecordID:     *recordID,
+			NumericValue: num,
+		})
+		*recordID++
+	}
+
+	for i := range output.Data {
+		if len(output.Data[i].Records) == 0 {
+			output.Data[i].Records = append(output.Data[i].Records, Record{
+				RecordID:     *recordID,
+				NumericValue: 0,
+				Placeholder:  true,
+			})
+			*recordID++
+		}
+	}
+}
@@ -0,0 +1,378 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ค่าเริ่มต้นของ TapOptions
+const (
+	DefaultAlightingGapSeconds = 120 // tap-off ที่ห่างกันไม่เกินนี้ถือเป็นรถคันเดียวกัน
+	DefaultMaxTripMinutes      = 180 // tap-on → tap-off ที่นานกว่านี้ไม่นับเป็นเที่ยวเดียวกัน
+	maxTapProblems             = 20
+)
+
+type TapOptions struct {
+	TimeRanges          []string
+	UnitSeconds         bool // ช่วงห่าง interarrival เป็นวินาที (ค่าเริ่มต้นเป็นนาที)
+	AlightingGapSeconds int
+	MaxTripMinutes      int
+	IncludeOD           bool
+}
+
+type ODEntry struct {
+	TimeRange   string `json:"time_range"`
+	Origin      string `json:"origin"`
+	Destination string `json:"destination"`
+	Trips       int    `json:"trips"`
+}
+
+type TapImportSummary struct {
+	Rows            int      `json:"rows"`
+	TapOns          int      `json:"tap_ons"`
+	TapOffs         int      `json:"tap_offs"`
+	Skipped         int      `json:"skipped"`
+	UnknownStops    []string `json:"unknown_stops,omitempty"`
+	Trips           int      `json:"trips,omitempty"`
+	UnmatchedTapOns int      `json:"unmatched_tap_ons,omitempty"`
+	Problems        []string `json:"problems,omitempty"` // แสดงแค่ maxTapProblems แถวแรก
+}
+
+// TapImportResult ได้ข้อมูลรูปแบบเดียวกับที่ parse จาก workbook จึงส่งเข้า fitting ได้ทันที
+type TapImportResult struct {
+	Interarrival Data             `json:"interarrival"`
+	Alighting    Data             `json:"alighting"`
+	ODMatrix     []ODEntry        `json:"od_matrix,omitempty"`
+	Summary      TapImportSummary `json:"summary"`
+}
+
+type tap struct {
+	card, station, vehicle string
+	at                     time.Time
+	on                     bool
+}
+
+// TapTransactionsToData แปลงข้อมูลแตะบัตร (card, time, stop, tap type) เป็นข้อมูลสำหรับ fit
+//   - interarrival: ช่วงห่างของ tap-on ที่ station เดียวกัน (เวลาขึ้นรถแทนเวลามาถึงป้าย)
+//   - alighting: การแตะบัตร (ทั้งขึ้นและลง) ที่ station เดียวกันและห่างกันไม่เกิน AlightingGapSeconds
+//     (และรถคันเดียวกันถ้ามีคอลัมน์ vehicle) รวมเป็นการจอดหนึ่งครั้ง จำนวน tap-off ของแต่ละครั้งคือหนึ่งค่า
+//     การจอดที่มีแต่คนขึ้นนับเป็น 0 คนลง
+//   - OD: จับ tap-on กับ tap-off ถัดไปของบัตรใบเดียวกันในวันเดียวกัน แบ่งตาม time range ของ tap-on
+//
+// stopToStation แปลงรหัสป้าย / ชื่อป้ายเป็น station_detail_id แถวที่ไม่รู้จักป้ายจะถูกข้าม
+func TapTransactionsToData(
+	rows [][]string,
+	stopToStation map[string]string,
+	opts TapOptions,
+) (TapImportResult, error) {
+
+	result := TapImportResult{}
+	if len(rows) < 2 {
+		return result, fmt.Errorf("file has no tap records")
+	}
+
+	ranges := make([]arrivalRange, 0, len(opts.TimeRanges))
+	for _, tr := range opts.TimeRanges {
+		start, end, err := parseArrivalRange(tr)
+		if err != nil {
+			return result, err
+		}
+		ranges = append(ranges, arrivalRange{label: strings.TrimSpace(tr), start: start, end: end})
+	}
+	if len(ranges) == 0 {
+		return result, fmt.Errorf("time_ranges is required")
+	}
+
+	cols := tapColumns(rows[0])
+	if cols.time < 0 || cols.stop < 0 || cols.tapType < 0 {
+		return result, fmt.Errorf("header must contain time, stop and tap type columns")
+	}
+	if opts.IncludeOD && cols.card < 0 {
+		return result, fmt.Errorf("a card id column is required for the OD matrix")
+	}
+	if opts.AlightingGapSeconds <= 0 {
+		opts.AlightingGapSeconds = DefaultAlightingGapSeconds
+	}
+	if opts.MaxTripMinutes <= 0 {
+		opts.MaxTripMinutes = DefaultMaxTripMinutes
+	}
+
+	summary := &result.Summary
+	skip := func(row int, format string, args ...interface{}) {
+		summary.Skipped++
+		if len(summary.Problems) < maxTapProblems {
+			summary.Problems = append(summary.Problems, fmt.Sprintf("row %d: ", row)+fmt.Sprintf(format, args...))
+		}
+	}
+	cell := func(row []string, col int) string {
+		if col >= 0 && col < len(row) {
+			return strings.TrimSpace(row[col])
+		}
+		return ""
+	}
+
+	unknownStops := make(map[string]bool)
+	taps := make([]tap, 0, len(rows)-1)
+
+	for i, row := range rows[1:] {
+		rowNum := i + 2
+		rawTime, stop, rawType := cell(row, cols.time), cell(row, cols.stop), cell(row, cols.tapType)
+		if rawTime == "" && stop == "" && rawType == "" {
+			continue
+		}
+		summary.Rows++
+
+		at, err := parseArrivalTime(rawTime)
+		if err != nil {
+			skip(rowNum, "%v", err)
+			continue
+		}
+		on, ok := parseTapType(rawType)
+		if !ok {
+			skip(rowNum, "unknown tap type %q", rawType)
+			continue
+		}
+		station, ok := stopToStation[stop]
+		if !ok {
+			unknownStops[stop] = true
+			skip(rowNum, "unknown stop %q", stop)
+			continue
+		}
+
+		taps = append(taps, tap{
+			card:    cell(row, cols.card),
+			station: station,
+			vehicle: cell(row, cols.vehicle),
+			at:      at,
+			on:      on,
+		})
+		if on {
+			summary.TapOns++
+		} else {
+			summary.TapOffs++
+		}
+	}
+
+	for stop := range unknownStops {
+		summary.UnknownStops = append(summary.UnknownStops, stop)
+	}
+	sort.Strings(summary.UnknownStops)
+
+	sort.SliceStable(taps, func(i, j int) bool { return taps[i].at.Before(taps[j].at) })
+
+	// ---------- interarrival: ใช้ตัวแปลงเวลามาถึงเดิมกับ tap-on ----------
+	arrivals := [][]string{{"station", "timestamp"}}
+	for _, t := range taps {
+		if t.on {
+			arrivals = append(arrivals, []string{t.station, t.at.Format("2006-01-02 15:04:05")})
+		}
+	}
+	if len(arrivals) > 1 {
+		data, err := ArrivalTimestampsToData(arrivals, opts.TimeRanges, nil, opts.UnitSeconds)
+		if err != nil {
+			return result, err
+		}
+		result.Interarrival = data
+	}
+
+	result.Alighting = tapsToAlighting(taps, ranges, time.Duration(opts.AlightingGapSeconds)*time.Second)
+
+	if opts.IncludeOD {
+		result.ODMatrix = tapsToOD(taps, ranges, time.Duration(opts.MaxTripMinutes)*time.Minute, summary)
+	}
+
+	return result, nil
+}
+
+// tapsToAlighting รวมการแตะบัตรที่ติดกันที่ป้ายเดียวกันเป็นการจอดหนึ่งครั้งแล้วนับจำนวนคนลง
+// tap-on บอกว่ารถจอดแม้ไม่มีใครลง การจอดนั้นจึงได้ค่า 0 (ไม่เช่นนั้น distribution จะไม่มีค่า 0 เลย)
+func tapsToAlighting(taps []tap, ranges []arrivalRange, gap time.Duration) Data {
+	type stopEvent struct {
+		last  time.Time
+		first time.Time
+		count int
+	}
+
+	stations := make([]string, 0)
+	// station → key ของรถ (vehicle หรือ "") → การจอดที่กำลังนับอยู่
+	open := make(map[string]map[string]*stopEvent)
+	counts := make(map[string]map[int][]Record) // station → range index → จำนวนคนลงของแต่ละครั้ง
+
+	flush := func(station string, ev *stopEvent) {
+		if ri := rangeIndexOf(ev.first, ranges); ri >= 0 {
+			counts[station][ri] = append(counts[station][ri], Record{
+				NumericValue: float64(ev.count),
+				TimeOfDay:    secondOfDay(ev.first),
+				Timed:        true,
+			})
+		}
+	}
+
+	for _, t := range taps {
+		alight := 0
+		if !t.on {
+			alight = 1
+		}
+		if _, ok := open[t.station]; !ok {
+			open[t.station] = make(map[string]*stopEvent)
+			counts[t.station] = make(map[int][]Record)
+			stations = append(stations, t.station)
+		}
+
+		ev := open[t.station][t.vehicle]
+		if ev != nil && t.at.Sub(ev.last) <= gap && sameDay(ev.last, t.at) {
+			ev.count += alight
+			ev.last = t.at
+			continue
+		}
+		if ev != nil {
+			flush(t.station, ev)
+		}
+		open[t.station][t.vehicle] = &stopEvent{first: t.at, last: t.at, count: alight}
+	}
+
+	for _, station := range stations {
+		vehicles := make([]string, 0, len(open[station]))
+		for v := range open[station] {
+			vehicles = append(vehicles, v)
+		}
+		sort.Strings(vehicles)
+		for _, v := range vehicles {
+			flush(station, open[station][v])
+		}
+	}
+
+	output := Data{}
+	recordID := 1
+	for _, station := range stations {
+		for ri, r := range ranges {
+			recs := []Record{}
+			for _, rec := range counts[station][ri] {
+				rec.RecordID = recordID
+				recs = append(recs, rec)
+				recordID++
+			}
+			// ไม่มีคนลงเลยในช่วงนี้ → 0 เหมือนคอลัมน์ว่างใน Excel
+			if len(recs) == 0 {
+				recs = append(recs, Record{RecordID: recordID, NumericValue: 0, Placeholder: true})
+				recordID++
+			}
+			output.Data = append(output.Data, Item{Station: station, TimeRange: r.label, Records: recs})
+		}
+	}
+	return output
+}
+
+// tapsToOD จับคู่ tap-on กับ tap-off ถัดไปของบัตรเดียวกัน (taps ต้องเรียงตามเวลาแล้ว)
+func tapsToOD(taps []tap, ranges []arrivalRange, maxTrip time.Duration, summary *TapImportSummary) []ODEntry {
+	pending := make(map[string]*tap)
+	trips := make(map[string]int)
+
+	for i := range taps {
+		t := &taps[i]
+		if t.card == "" {
+			continue
+		}
+		if t.on {
+			if pending[t.card] != nil {
+				summary.UnmatchedTapOns++
+			}
+			pending[t.card] = t
+			continue
+		}
+
+		origin := pending[t.card]
+		if origin == nil {
+			continue
+		}
+		delete(pending, t.card)
+		if !sameDay(origin.at, t.at) || t.at.Sub(origin.at) > maxTrip || origin.station == t.station {
+			summary.UnmatchedTapOns++
+			continue
+		}
+		ri := rangeIndexOf(origin.at, ranges)
+		if ri < 0 {
+			continue
+		}
+		trips[strconv.Itoa(ri)+"|"+origin.station+"|"+t.station]++
+		summary.Trips++
+	}
+	summary.UnmatchedTapOns += len(pending)
+
+	entries := make([]ODEntry, 0, len(trips))
+	index := make(map[string]int, len(trips))
+	for key, n := range trips {
+		parts := strings.SplitN(key, "|", 3)
+		ri, _ := strconv.Atoi(parts[0])
+		index[ranges[ri].label] = ri
+		entries = append(entries, ODEntry{TimeRange: ranges[ri].label, Origin: parts[1], Destination: parts[2], Trips: n})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.TimeRange != b.TimeRange {
+			return index[a.TimeRange] < index[b.TimeRange]
+		}
+		if a.Origin != b.Origin {
+			return a.Origin < b.Origin
+		}
+		return a.Destination < b.Destination
+	})
+	return entries
+}
+
+type tapColumnIndex struct {
+	card, time, stop, tapType, vehicle int
+}
+
+func tapColumns(header []string) tapColumnIndex {
+	cols := tapColumnIndex{-1, -1, -1, -1, -1}
+	for i, h := range header {
+		key := strings.NewReplacer(" ", "", "_", "", "-", "").Replace(strings.ToLower(strings.TrimSpace(h)))
+		switch key {
+		case "card", "cardid", "cardno", "cardnumber", "token", "tokenid":
+			cols.card = i
+		case "time", "timestamp", "datetime", "taptime", "transactiontime":
+			cols.time = i
+		case "stop", "stopcode", "stopid", "station", "stationid", "stationname", "stationidosm":
+			cols.stop = i
+		case "taptype", "type", "direction", "event", "transactiontype":
+			cols.tapType = i
+		case "vehicle", "vehicleid", "bus", "busid":
+			cols.vehicle = i
+		}
+	}
+	return cols
+}
+
+// parseTapType คืน true = tap-on (ขึ้น), false = tap-off (ลง)
+func parseTapType(raw string) (bool, bool) {
+	key := strings.NewReplacer(" ", "", "_", "", "-", "").Replace(strings.ToLower(strings.TrimSpace(raw)))
+	switch key {
+	case "on", "in", "tapon", "tapin", "entry", "board", "boarding", "checkin":
+		return true, true
+	case "off", "out", "tapoff", "tapout", "exit", "alight", "alighting", "checkout":
+		return false, true
+	}
+	return false, false
+}
+
+func rangeIndexOf(t time.Time, ranges []arrivalRange) int {
+	sec := secondOfDay(t)
+	for i, r := range ranges {
+		if sec >= r.start && sec < r.end {
+			return i
+		}
+	}
+	return -1
+}
+
+func secondOfDay(t time.Time) int {
+	return t.Hour()*3600 + t.Minute()*60 + t.Second()
+}
+
+func sameDay(a, b time.Time) bool {
+	return a.Format("2006-01-02") == b.Format("2006-01-02")
+}
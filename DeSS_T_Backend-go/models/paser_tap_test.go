@@ -0,0 +1,47 @@
+package models
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestTapTransactionsAlightingCalls(t *testing.T) {
+	rows := [][]string{
+		{"card", "time", "stop", "tap type", "vehicle"},
+		// รถ b1 จอดที่ s1: ขึ้น 2 ลง 1
+		{"c1", "2024-01-01 07:00:00", "S1", "on", "b1"},
+		{"c2", "2024-01-01 07:00:30", "S1", "off", "b1"},
+		{"c3", "2024-01-01 07:01:00", "S1", "on", "b1"},
+		// รถ b2 จอดที่ s1 ช่วงเดียวกัน มีแต่คนขึ้น → 0 คนลง
+		{"c4", "2024-01-01 07:00:40", "S1", "on", "b2"},
+		// b1 กลับมาที่ s1 อีกรอบ ลง 2
+		{"c5", "2024-01-01 07:30:00", "S1", "off", "b1"},
+		{"c6", "2024-01-01 07:30:20", "S1", "off", "b1"},
+		// s2 มีแต่คนขึ้น
+		{"c7", "2024-01-01 07:10:00", "S2", "on", "b1"},
+	}
+	stops := map[string]string{"S1": "s1", "S2": "s2"}
+
+	result, err := TapTransactionsToData(rows, stops, TapOptions{TimeRanges: []string{"07:00-08:00"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string][]float64)
+	for _, item := range result.Alighting.Data {
+		for _, rec := range item.Records {
+			if rec.Placeholder || !rec.Timed {
+				t.Errorf("%s: record %+v should be a timed bus call", item.Station, rec)
+			}
+			got[item.Station] = append(got[item.Station], rec.NumericValue)
+		}
+	}
+	want := map[string][]float64{"s1": {0, 1, 2}, "s2": {0}}
+	for station := range want {
+		sort.Float64s(got[station])
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("alighting = %v, want %v", got, want)
+	}
+}
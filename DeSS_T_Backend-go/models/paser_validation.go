@@ -0,0 +1,301 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ระดับของปัญหาใน ValidationReport: error = upload ไม่ผ่าน, warning = อ่านได้แต่ควรตรวจ
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// ErrValidationFailed คือ upload ที่ strict mode พบปัญหาระดับ error
+var ErrValidationFailed = errors.New("upload has validation errors")
+
+type ValidationIssue struct {
+	Sheet    string `json:"sheet"`
+	Cell     string `json:"cell,omitempty"` // ว่าง = ปัญหาของทั้ง sheet
+	Problem  string `json:"problem"`
+	Severity string `json:"severity"`
+}
+
+type ValidationReport struct {
+	Valid    bool              `json:"valid"`
+	Errors   int               `json:"errors"`
+	Warnings int               `json:"warnings"`
+	Issues   []ValidationIssue `json:"issues"`
+}
+
+func (r *ValidationReport) add(sheet, cell, severity, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, ValidationIssue{
+		Sheet:    sheet,
+		Cell:     cell,
+		Problem:  fmt.Sprintf(format, args...),
+		Severity: severity,
+	})
+	if severity == SeverityError {
+		r.Errors++
+	} else {
+		r.Warnings++
+	}
+	r.Valid = r.Errors == 0
+}
+
+// DistributionExcelToJSONStrict เหมือน DistributionExcelToJSONReader แต่ตรวจทุก cell แล้วคืนรายงาน
+// ถ้ามีปัญหาระดับ error จะคืน ErrValidationFailed พร้อมรายงาน (Data ที่ได้ไม่ควรนำไปใช้)
+// integerValues = ค่าควรเป็นจำนวนเต็ม (จำนวนคนลง) ค่าทศนิยมจะเป็น warning
+func DistributionExcelToJSONStrict(
+	r io.Reader,
+	stationNameToID map[string]string,
+	integerValues bool,
+) (Data, ValidationReport, error) {
+
+	report := ValidationReport{Valid: true, Issues: []ValidationIssue{}}
+
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return Data{}, report, err
+	}
+	defer f.Close()
+
+	output := Data{}
+	recordID := 1
+
+	for _, sheet := range f.GetSheetList() {
+		rows, err := f.GetRows(sheet)
+		if err != nil {
+			report.add(sheet, "", SeverityError, "cannot read sheet: %v", err)
+			continue
+		}
+
+		validateStationName(&report, sheet, stationNameToID)
+		validateDistributionSheet(&report, sheet, rows, integerValues)
+		appendDistributionSheet(&output, resolveStationID(sheet, stationNameToID), rows, &recordID)
+	}
+
+	if !report.Valid {
+		return output, report, ErrValidationFailed
+	}
+	return output, report, nil
+}
+
+// DistributionDelimitedToJSONStrict เหมือน DistributionDelimitedToJSONReader แต่ตรวจแบบ strict
+// ชื่อ sheet ในรายงานคือชื่อ station (แบบกว้าง) หรือชนิดไฟล์ (แบบยาว)
+func DistributionDelimitedToJSONStrict(
+	r io.Reader,
+	format string,
+	stationName string,
+	stationNameToID map[string]string,
+	integerValues bool,
+) (Data, ValidationReport, error) {
+
+	report := ValidationReport{Valid: true, Issues: []ValidationIssue{}}
+
+	rows, err := ReadDelimitedRows(r, format)
+	if err != nil {
+		return Data{}, report, err
+	}
+
+	output := Data{}
+	recordID := 1
+
+	if len(rows) == 0 {
+		report.add(format, "", SeverityError, "file is empty")
+		return output, report, ErrValidationFailed
+	}
+
+	if stationCol, rangeCol, valueCol, ok := longFormatColumns(rows[0]); ok {
+		validateDistributionLongRows(&report, format, rows, stationCol, rangeCol, valueCol, stationNameToID, integerValues)
+		appendDistributionLongRows(&output, rows[1:], stationCol, rangeCol, valueCol, stationNameToID, &recordID)
+	} else {
+		if strings.TrimSpace(stationName) == "" {
+			return Data{}, report, fmt.Errorf("station is required for a one-station file (or use station, time_range, value columns)")
+		}
+		validateStationName(&report, stationName, stationNameToID)
+		validateDistributionSheet(&report, stationName, rows, integerValues)
+		appendDistributionSheet(&output, resolveStationID(stationName, stationNameToID), rows, &recordID)
+	}
+
+	if !report.Valid {
+		return output, report, ErrValidationFailed
+	}
+	return output, report, nil
+}
+
+// validateStationName: ชื่อ sheet / ชื่อ station ต้องอยู่ใน station_map (ไม่ปล่อยผ่านเป็น ID ดิบ)
+func validateStationName(report *ValidationReport, sheet string, stationNameToID map[string]string) {
+	if _, ok := stationNameToID[strings.TrimSpace(sheet)]; !ok {
+		report.add(sheet, "", SeverityError, "unknown station %q (not in station_map)", strings.TrimSpace(sheet))
+	}
+}
+
+// validateDistributionSheet ตรวจ sheet แบบกว้าง: header ต้องเป็น HH:MM-HH:MM ไม่ซ้ำ, ค่าต้องเป็นตัวเลขไม่ติดลบ
+func validateDistributionSheet(report *ValidationReport, sheet string, rows [][]string, integerValues bool) {
+	if len(rows) == 0 || len(rows[0]) == 0 {
+		report.add(sheet, "", SeverityError, "sheet is empty (time ranges are expected in row 1)")
+		return
+	}
+
+	type span struct {
+		cell       string
+		start, end int
+	}
+	headers := rows[0]
+	seen := make(map[string]string)
+	spans := make([]span, 0, len(headers))
+	hasHeader := false
+
+	for col, header := range headers {
+		ref := cellRef(col, 0)
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+		hasHeader = true
+
+		if first, dup := seen[header]; dup {
+			report.add(sheet, ref, SeverityError, "time range %q is already used in %s", header, first)
+			continue
+		}
+		seen[header] = ref
+
+		start, end, err := parseArrivalRange(header)
+		if err != nil {
+			report.add(sheet, ref, SeverityError, "malformed time range header: %v", err)
+			continue
+		}
+		for _, s := range spans {
+			if start < s.end && s.start < end {
+				report.add(sheet, ref, SeverityWarning, "time range %q overlaps %s", header, s.cell)
+			}
+		}
+		spans = append(spans, span{cell: ref, start: start, end: end})
+	}
+	if !hasHeader {
+		report.add(sheet, "A1", SeverityError, "row 1 has no time range headers")
+		return
+	}
+
+	for col, header := range headers {
+		header = strings.TrimSpace(header)
+		values := 0
+
+		for row := 1; row < len(rows); row++ {
+			if col >= len(rows[row]) || strings.TrimSpace(rows[row][col]) == "" {
+				continue
+			}
+			ref := cellRef(col, row)
+			if header == "" {
+				report.add(sheet, ref, SeverityWarning, "value below an empty header is ignored")
+				continue
+			}
+			if validateValueCell(report, sheet, ref, rows[row][col], integerValues) {
+				values++
+			}
+		}
+
+		if header != "" && values == 0 {
+			report.add(sheet, cellRef(col, 0), SeverityWarning, "column %q has no values and will be read as a single 0", header)
+		}
+	}
+
+	// ค่าที่อยู่เลยคอลัมน์สุดท้ายของ header จะไม่ถูกอ่านเลย
+	for row := 1; row < len(rows); row++ {
+		for col := len(headers); col < len(rows[row]); col++ {
+			if strings.TrimSpace(rows[row][col]) != "" {
+				report.add(sheet, cellRef(col, row), SeverityWarning, "value without a time range header is ignored")
+			}
+		}
+	}
+}
+
+// validateDistributionLongRows ตรวจไฟล์แบบยาว (station, time range, value หนึ่งแถวต่อค่า)
+func validateDistributionLongRows(
+	report *ValidationReport,
+	sheet string,
+	rows [][]string,
+	stationCol, rangeCol, valueCol int,
+	stationNameToID map[string]string,
+	integerValues bool,
+) {
+
+	cell := func(row []string, col int) string {
+		if col < len(row) {
+			return strings.TrimSpace(row[col])
+		}
+		return ""
+	}
+
+	unknown := make(map[string]bool)
+	badRange := make(map[string]bool)
+	counts := make(map[string]int)
+	order := make([]string, 0)
+
+	for i, row := range rows[1:] {
+		rowIndex := i + 1
+		station, timeRange := cell(row, stationCol), cell(row, rangeCol)
+		if station == "" || timeRange == "" {
+			if cell(row, valueCol) != "" {
+				report.add(sheet, cellRef(valueCol, rowIndex), SeverityWarning, "row without station or time range is ignored")
+			}
+			continue
+		}
+
+		if _, ok := stationNameToID[station]; !ok && !unknown[station] {
+			unknown[station] = true
+			report.add(sheet, cellRef(stationCol, rowIndex), SeverityError, "unknown station %q (not in station_map)", station)
+		}
+		if _, _, err := parseArrivalRange(timeRange); err != nil && !badRange[timeRange] {
+			badRange[timeRange] = true
+			report.add(sheet, cellRef(rangeCol, rowIndex), SeverityError, "malformed time range: %v", err)
+		}
+
+		key := station + " " + timeRange
+		if _, ok := counts[key]; !ok {
+			order = append(order, key)
+			counts[key] = 0
+		}
+		if raw := cell(row, valueCol); raw != "" {
+			if validateValueCell(report, sheet, cellRef(valueCol, rowIndex), raw, integerValues) {
+				counts[key]++
+			}
+		}
+	}
+
+	for _, key := range order {
+		if counts[key] == 0 {
+			report.add(sheet, "", SeverityWarning, "%s has no values and will be read as a single 0", key)
+		}
+	}
+}
+
+// validateValueCell คืน true ถ้า cell เป็นค่าที่ใช้ได้
+func validateValueCell(report *ValidationReport, sheet, ref, raw string, integerValues bool) bool {
+	// strconv เข้มกว่า parseNumericCell (Sscanf อ่าน "12abc" ได้เป็น 12)
+	num, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+	if err != nil || math.IsNaN(num) || math.IsInf(num, 0) {
+		report.add(sheet, ref, SeverityError, "%q is not a number", strings.TrimSpace(raw))
+		return false
+	}
+	if num < 0 {
+		report.add(sheet, ref, SeverityError, "negative value %g", num)
+		return false
+	}
+	if integerValues && num != float64(int64(num)) {
+		report.add(sheet, ref, SeverityWarning, "value %g is not a whole number", num)
+	}
+	return true
+}
+
+func cellRef(col, row int) string {
+	ref, _ := excelize.CoordinatesToCellName(col+1, row+1)
+	return ref
+}
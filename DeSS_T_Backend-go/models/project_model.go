@@ -6,4 +6,15 @@ type ProjectSimulationRequest struct {
 	ScenarioDetail       ScenarioDetail      `json:"scenario"`
 	TimePeriods       string		   `json:"time_periods"`
 	TimeSlot		  string           `json:"time_slot"`
-}
\ No newline at end of file
+	TimeWindows       []TimeWindow     `json:"time_windows,omitempty"`
+	DayType           string           `json:"day_type,omitempty"` // ใช้เฉพาะข้อมูลของ day type นี้ (+ ข้อมูลที่ไม่ระบุ day type)
+	AllowInfeasibleSchedule bool       `json:"allow_infeasible_schedule,omitempty"` // จำลองต่อแม้รถไม่พอ (เที่ยวที่เกิน MaxBus จะไม่ออก)
+}
+
+// TimeWindow is a named "HH:MM-HH:MM" window. Without TimePeriods the windows
+// are simulated together as disjoint periods; with TimePeriods they are named
+// sub-periods of it and only used for reporting.
+type TimeWindow struct {
+	Name   string `json:"name"`
+	Period string `json:"period"`
+}
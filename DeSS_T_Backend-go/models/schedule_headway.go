@@ -0,0 +1,113 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HeadwayRule คือกฎสร้างตารางเวลาของเส้นทางหนึ่ง: ออกรถคันแรก/คันสุดท้าย และ headway ของแต่ละช่วงเวลา
+type HeadwayRule struct {
+	RoutePathID    string                `json:"route_path_id"`
+	DayType        string                `json:"day_type,omitempty"`
+	FirstDeparture string                `json:"first_departure"`
+	LastDeparture  string                `json:"last_departure"`
+	HeadwayBands   []ScheduleHeadwayBand `json:"headway_bands"`
+}
+
+// GenerateHeadwaySchedule สร้างเวลาออกรถ ("HH:MM") จากกฎ headway
+//   - เริ่มที่ first แล้วเว้นตาม headway ของช่วงที่เวลาปัจจุบันอยู่ (ข้ามเข้าช่วงใหม่ก็ใช้ headway ของช่วงใหม่ต่อ)
+//   - เวลาที่ไม่อยู่ในช่วงใดเลย → ไม่มีรถ จนถึงต้นช่วงถัดไป (ออกรถที่เวลาเริ่มช่วงนั้น)
+//   - last เป็นเที่ยวสุดท้ายเสมอ แม้ไม่ตรงกับรอบ headway
+func GenerateHeadwaySchedule(first, last string, bands []ScheduleHeadwayBand) ([]string, error) {
+	firstMin, err := parseDepartureMinute(first)
+	if err != nil {
+		return nil, fmt.Errorf("first_departure: %w", err)
+	}
+	lastMin, err := parseDepartureMinute(last)
+	if err != nil {
+		return nil, fmt.Errorf("last_departure: %w", err)
+	}
+	if firstMin > lastMin {
+		return nil, fmt.Errorf("first_departure %s is after last_departure %s", first, last)
+	}
+
+	type band struct {
+		raw                 string
+		start, end, headway int
+	}
+	parsed := make([]band, 0, len(bands))
+	for i, b := range bands {
+		startSec, endSec, err := parseArrivalRange(b.TimeRange)
+		if err != nil {
+			return nil, fmt.Errorf("headway_bands[%d]: %w", i, err)
+		}
+		if b.HeadwayMinutes <= 0 {
+			return nil, fmt.Errorf("headway_bands[%d]: headway_minutes must be a positive integer, got %d", i, b.HeadwayMinutes)
+		}
+		parsed = append(parsed, band{raw: strings.TrimSpace(b.TimeRange), start: startSec / 60, end: endSec / 60, headway: b.HeadwayMinutes})
+	}
+	if len(parsed) == 0 {
+		return nil, fmt.Errorf("at least one headway band is required")
+	}
+
+	sort.Slice(parsed, func(i, j int) bool { return parsed[i].start < parsed[j].start })
+	for i := 1; i < len(parsed); i++ {
+		if parsed[i].start < parsed[i-1].end {
+			return nil, fmt.Errorf("headway bands %s and %s overlap", parsed[i-1].raw, parsed[i].raw)
+		}
+	}
+
+	// inBand คืนเวลาแรกที่ >= m และอยู่ในช่วงใดช่วงหนึ่ง (-1 = ไม่มีช่วงเหลือแล้ว)
+	inBand := func(m int) int {
+		for _, b := range parsed {
+			if m < b.end {
+				if m < b.start {
+					return b.start
+				}
+				return m
+			}
+		}
+		return -1
+	}
+
+	departures := []string{minuteClock(firstMin)}
+	t := firstMin
+	for {
+		next := t
+		for _, b := range parsed {
+			if t >= b.start && t < b.end {
+				next = t + b.headway
+				break
+			}
+		}
+		if next == t {
+			next++ // t อยู่นอกทุกช่วง ไปหาต้นช่วงถัดไป
+		}
+		next = inBand(next)
+		if next < 0 || next > lastMin {
+			break
+		}
+		t = next
+		departures = append(departures, minuteClock(t))
+	}
+
+	if t < lastMin {
+		departures = append(departures, minuteClock(lastMin))
+	}
+	return departures, nil
+}
+
+// parseDepartureMinute แปลง "HH:MM" เป็นนาทีของวัน
+func parseDepartureMinute(raw string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(raw))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", raw)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func minuteClock(m int) string {
+	return fmt.Sprintf("%02d:%02d", m/60, m%60)
+}
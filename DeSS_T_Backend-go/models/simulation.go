@@ -3,6 +3,10 @@ package models
 type SimulationRequest struct {
 	TimePeriod string `json:"time_period"`
 	TimeSlot string `json:"time_slot"`
+	TimeWindows []TimeWindow `json:"time_windows,omitempty"`
+	DayType string `json:"day_type,omitempty"`
+	// ช่วงที่ต้องรัน engine แยกกัน (time_windows ไม่ต่อเนื่องและไม่มี time_periods) ไม่ได้ส่งไป Python
+	RunPeriods []string `json:"-"`
 	ConfigurationData ConfigurationData `json:"configuration_data"`
 	ScenarioData []ScenarioData `json:"scenario_data"`
 }
@@ -13,6 +17,17 @@ type ScenarioData struct {
 	RouteOrder string `json:"route_order"`
 	RouteSchedule []RouteSchedule `json:"route_schedule"`
 	RouteBusInformation RouteBusInformation `json:"bus_information"`
+	// ค่าของรถต่อ time window (engine เลือกตามเวลาออกรถ, เวลานอกทุก window ใช้ bus_information)
+	BusBands []RouteBusBand `json:"bus_bands,omitempty"`
+	// ค่ารถของเส้นทางพร้อม band ใช้เลือก band ใหม่ตอนตัด request เป็นช่วงย่อย ไม่ได้ส่งไป Python
+	BusSource *BusInformation `json:"-"`
+}
+
+// RouteBusBand คือค่าของรถที่เลือกให้ time window หนึ่ง
+type RouteBusBand struct {
+	Window string `json:"window"`
+	Period string `json:"period"`
+	RouteBusInformation RouteBusInformation `json:"bus_information"`
 }
 
 type RouteSchedule struct {
@@ -25,6 +40,7 @@ type RouteBusInformation struct {
 	MaxBus      int     `json:"max_bus"`
 	BusCapacity int     `json:"bus_capacity"`
 	AvgTravelTime float64 `json:"avg_travel_time"`
+	AppliedBand string `json:"applied_band"` // time_range of the band used, "default" when none matched
 }
 
 type ConfigurationData struct {
@@ -102,4 +118,55 @@ type ResultRoute struct {
     AverageWaitingTime    float64 `json:"average_waiting_time"`
     AverageQueueLength    float64 `json:"average_queue_length"`
     CustomersCount        int     `json:"customers_count"`
-}
\ No newline at end of file
+}
+// ---------------- SimulationResponse ----------------
+// full payload returned by the Python /api/simulate endpoint
+
+type SimulationResponse struct {
+    Result           string           `json:"result"`
+    SimulationResult SimulationResult `json:"simulation_result"`
+    Logs             []SimulationLog  `json:"logs"`
+    BusEvents        []BusEvent       `json:"bus_events,omitempty"`
+    WindowResults    []SimulationWindowResult `json:"window_results,omitempty"`
+    AppliedBusBands  []AppliedBusBand         `json:"applied_bus_bands,omitempty"`
+}
+
+// ---------------- AppliedBusBand ----------------
+
+type AppliedBusBand struct {
+    RouteID     string `json:"route_id"`
+    RouteName   string `json:"route_name"`
+    Window      string `json:"window,omitempty"`
+    Period      string `json:"period"`
+    AppliedBand string `json:"applied_band"`
+    BusCapacity int    `json:"bus_capacity"`
+}
+
+// ---------------- SimulationWindowResult ----------------
+// slot results aggregated into one named TimeWindow
+
+type SimulationWindowResult struct {
+    Name          string          `json:"name"`
+    Period        string          `json:"period"`
+    SlotNames     []string        `json:"slot_names"`
+    ResultStation []ResultStation `json:"result_station"`
+    ResultRoute   []ResultRoute   `json:"result_route"`
+}
+
+type SimulationLog struct {
+    Time      string `json:"time"`
+    Component string `json:"component"`
+    Message   string `json:"message"`
+}
+
+// ---------------- BusEvent ----------------
+// arrive/depart event of one bus at one station, emitted by the engine
+
+type BusEvent struct {
+    BusID     string `json:"bus_id"`
+    RouteID   string `json:"route_id"`
+    StationID string `json:"station_id"`
+    Type      string `json:"type"` // "arrive" | "depart"
+    Time      string `json:"time"` // HH:MM[:SS]
+    Load      int    `json:"load"` // passengers on board after the event
+}
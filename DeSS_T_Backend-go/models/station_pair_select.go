@@ -0,0 +1,157 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// โหมดการสร้าง station pair ของ network model
+//   - full:   ทุกคู่ (n×(n-1) แถว)
+//   - knn:    k station ที่ใกล้ที่สุดของแต่ละ station (ทั้งไปและกลับ)
+//   - radius: ทุกคู่ที่ห่างกันเป็นเส้นตรงไม่เกิน radius_m (ทั้งไปและกลับ)
+//   - routes: เฉพาะคู่ที่อยู่ติดกันในเส้นทางที่ร่างไว้ (ตามทิศของเส้นทาง)
+//
+// คู่ที่ไม่ได้สร้างตอนนี้ขอเพิ่มภายหลังได้เมื่อ route ของ scenario ต้องใช้
+const (
+	PairModeFull   = "full"
+	PairModeKNN    = "knn"
+	PairModeRadius = "radius"
+	PairModeRoutes = "routes"
+
+	DefaultPairK       = 8
+	DefaultPairRadiusM = 1500.0
+)
+
+type PairSelection struct {
+	Mode    string
+	K       int
+	RadiusM float64
+	Routes  [][]string // ลำดับ station id ของแต่ละเส้นทาง
+}
+
+// RoutePairs แตกเส้นทาง (ลำดับ station id) เป็นคู่ที่อยู่ติดกัน ไม่ซ้ำ เรียงตามที่พบ
+func RoutePairs(routes [][]string) [][2]string {
+	var out [][2]string
+	seen := make(map[[2]string]bool)
+	for _, r := range routes {
+		for i := 1; i < len(r); i++ {
+			p := [2]string{strings.TrimSpace(r[i-1]), strings.TrimSpace(r[i])}
+			if p[0] == "" || p[1] == "" || p[0] == p[1] || seen[p] {
+				continue
+			}
+			seen[p] = true
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// SelectStationPairs คืนคู่ (index ต้นทาง, index ปลายทาง) ของ stations ตามโหมด เรียงตามต้นทางแล้วปลายทาง
+func SelectStationPairs(stations []StationDetail, sel PairSelection) ([][2]int, error) {
+	n := len(stations)
+	mode := strings.ToLower(strings.TrimSpace(sel.Mode))
+	if mode == "" {
+		mode = PairModeFull
+	}
+
+	dist := func(i, j int) float64 {
+		a, b := stations[i].Location.Coordinates, stations[j].Location.Coordinates
+		return HaversineMeters(a[1], a[0], b[1], b[0])
+	}
+
+	set := make(map[[2]int]bool)
+	both := func(i, j int) {
+		set[[2]int{i, j}] = true
+		set[[2]int{j, i}] = true
+	}
+
+	switch mode {
+	case PairModeFull:
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				if i != j {
+					set[[2]int{i, j}] = true
+				}
+			}
+		}
+
+	case PairModeKNN:
+		k := sel.K
+		if k == 0 {
+			k = DefaultPairK
+		}
+		if k < 0 {
+			return nil, fmt.Errorf("k must be positive, got %d", sel.K)
+		}
+		others := make([]int, 0, n)
+		for i := 0; i < n; i++ {
+			others = others[:0]
+			for j := 0; j < n; j++ {
+				if j != i {
+					others = append(others, j)
+				}
+			}
+			sort.Slice(others, func(a, b int) bool { return dist(i, others[a]) < dist(i, others[b]) })
+			for _, j := range others[:minPairInt(k, len(others))] {
+				both(i, j)
+			}
+		}
+
+	case PairModeRadius:
+		radius := sel.RadiusM
+		if radius == 0 {
+			radius = DefaultPairRadiusM
+		}
+		if radius < 0 {
+			return nil, fmt.Errorf("radius_m must be positive, got %g", sel.RadiusM)
+		}
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				if dist(i, j) <= radius {
+					both(i, j)
+				}
+			}
+		}
+
+	case PairModeRoutes:
+		index := make(map[string]int, n)
+		for i, s := range stations {
+			index[s.StationDetailID] = i
+		}
+		pairs := RoutePairs(sel.Routes)
+		if len(pairs) == 0 {
+			return nil, fmt.Errorf("pair_mode %q needs route_stations with at least two stations", PairModeRoutes)
+		}
+		for _, p := range pairs {
+			i, ok1 := index[p[0]]
+			j, ok2 := index[p[1]]
+			if !ok1 || !ok2 {
+				return nil, fmt.Errorf("route_stations: station %s → %s is not in the station list", p[0], p[1])
+			}
+			set[[2]int{i, j}] = true
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown pair_mode %q (expected full, knn, radius or routes)", sel.Mode)
+	}
+
+	out := make([][2]int, 0, len(set))
+	for p := range set {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(a, b int) bool {
+		if out[a][0] != out[b][0] {
+			return out[a][0] < out[b][0]
+		}
+		return out[a][1] < out[b][1]
+	})
+	return out, nil
+}
+
+func minPairInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,198 @@
+package models
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// จำนวนแถวข้อมูลที่ใส่ data validation ไว้ให้ (ไม่รวม header)
+const templateRows = 1000
+
+const maxSheetNameLength = 31
+
+// StationSheetNames แปลงชื่อ station เป็นชื่อ sheet ที่ Excel รับได้ (≤ 31 ตัวอักษร ไม่มี []:*?/\ และไม่ซ้ำ)
+// ลำดับต้องเหมือนกันทั้งตอนสร้าง template และตอนแปลงชื่อ sheet กลับเป็น station
+func StationSheetNames(names []string) []string {
+	replacer := strings.NewReplacer("[", "(", "]", ")", ":", "-", "*", "-", "?", "", "/", "-", "\\", "-")
+	used := make(map[string]bool, len(names))
+	out := make([]string, 0, len(names))
+
+	for _, name := range names {
+		base := strings.Trim(replacer.Replace(strings.TrimSpace(name)), "'")
+		if base == "" {
+			base = "Station"
+		}
+		base = truncateRunes(base, maxSheetNameLength)
+
+		sheet := base
+		for n := 2; used[strings.ToLower(sheet)]; n++ {
+			suffix := fmt.Sprintf(" %d", n)
+			sheet = truncateRunes(base, maxSheetNameLength-len(suffix)) + suffix
+		}
+		used[strings.ToLower(sheet)] = true
+		out = append(out, sheet)
+	}
+
+	return out
+}
+
+// DistributionTemplate สร้าง workbook ตามรูปแบบที่ DistributionExcelToJSONReader อ่าน:
+// หนึ่ง sheet ต่อ station, แถวแรกเป็น time range, ค่าอยู่ด้านล่าง
+func DistributionTemplate(
+	stationNames []string,
+	timeRanges []string,
+	wholeNumbers bool,
+	prompt string,
+) (*bytes.Buffer, error) {
+
+	if len(stationNames) == 0 {
+		return nil, fmt.Errorf("configuration has no stations")
+	}
+	if len(timeRanges) == 0 {
+		return nil, fmt.Errorf("time_ranges is required")
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+
+	headerStyle, err := templateHeaderStyle(f)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, sheet := range StationSheetNames(stationNames) {
+		if err := templateSheet(f, i, sheet); err != nil {
+			return nil, err
+		}
+
+		for col, tr := range timeRanges {
+			cell, _ := excelize.CoordinatesToCellName(col+1, 1)
+			if err := f.SetCellStr(sheet, cell, strings.TrimSpace(tr)); err != nil {
+				return nil, err
+			}
+		}
+		if err := templateFormatHeader(f, sheet, len(timeRanges), headerStyle); err != nil {
+			return nil, err
+		}
+
+		dv := excelize.NewDataValidation(true)
+		dv.Sqref = templateDataRange(len(timeRanges))
+		valueType := excelize.DataValidationTypeDecimal
+		message := "ต้องเป็นตัวเลขที่ไม่ติดลบ"
+		if wholeNumbers {
+			valueType = excelize.DataValidationTypeWhole
+			message = "ต้องเป็นจำนวนเต็มที่ไม่ติดลบ"
+		}
+		if err := dv.SetRange(0, 1e9, valueType, excelize.DataValidationOperatorBetween); err != nil {
+			return nil, err
+		}
+		dv.SetError(excelize.DataValidationErrorStyleStop, "ค่าไม่ถูกต้อง", message)
+		if prompt != "" {
+			dv.SetInput(stationNames[i], prompt)
+		}
+		if err := f.AddDataValidation(sheet, dv); err != nil {
+			return nil, err
+		}
+	}
+
+	return f.WriteToBuffer()
+}
+
+// ScheduleTemplate สร้าง workbook ตามรูปแบบที่ ScheduleExcelToJsonReader อ่าน:
+// sheet เดียว แถวแรกเป็นชื่อ route path แต่ละคอลัมน์คือเวลาออกรถ (HH:MM)
+func ScheduleTemplate(routeNames []string) (*bytes.Buffer, error) {
+	if len(routeNames) == 0 {
+		return nil, fmt.Errorf("route scenario has no route paths")
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+
+	const sheet = "Schedule"
+	if err := templateSheet(f, 0, sheet); err != nil {
+		return nil, err
+	}
+
+	headerStyle, err := templateHeaderStyle(f)
+	if err != nil {
+		return nil, err
+	}
+	for col, name := range routeNames {
+		cell, _ := excelize.CoordinatesToCellName(col+1, 1)
+		if err := f.SetCellStr(sheet, cell, strings.TrimSpace(name)); err != nil {
+			return nil, err
+		}
+	}
+	if err := templateFormatHeader(f, sheet, len(routeNames), headerStyle); err != nil {
+		return nil, err
+	}
+
+	// รูปแบบ hh:mm ทำให้ค่าที่อ่านกลับมาเป็น "08:00" ไม่ใช่เศษของวัน
+	timeFormat := "hh:mm"
+	timeStyle, err := f.NewStyle(&excelize.Style{CustomNumFmt: &timeFormat})
+	if err != nil {
+		return nil, err
+	}
+	lastCell, _ := excelize.CoordinatesToCellName(len(routeNames), templateRows+1)
+	if err := f.SetCellStyle(sheet, "A2", lastCell, timeStyle); err != nil {
+		return nil, err
+	}
+
+	dv := excelize.NewDataValidation(true)
+	dv.Sqref = templateDataRange(len(routeNames))
+	// เวลาใน Excel คือเศษของวัน: 00:00 = 0, 23:59 = 1439/1440
+	if err := dv.SetRange(0.0, 1439.0/1440.0, excelize.DataValidationTypeTime, excelize.DataValidationOperatorBetween); err != nil {
+		return nil, err
+	}
+	dv.SetError(excelize.DataValidationErrorStyleStop, "เวลาไม่ถูกต้อง", "กรอกเวลาออกรถเป็น HH:MM ระหว่าง 00:00 ถึง 23:59")
+	dv.SetInput("เวลาออกรถ", "HH:MM เช่น 08:15 เรียงจากเช้าไปเย็น")
+	if err := f.AddDataValidation(sheet, dv); err != nil {
+		return nil, err
+	}
+
+	return f.WriteToBuffer()
+}
+
+// templateSheet ใช้ sheet เริ่มต้นของไฟล์ใหม่เป็น sheet แรก ที่เหลือสร้างเพิ่ม
+func templateSheet(f *excelize.File, index int, name string) error {
+	if index == 0 {
+		return f.SetSheetName(f.GetSheetName(0), name)
+	}
+	_, err := f.NewSheet(name)
+	return err
+}
+
+func templateHeaderStyle(f *excelize.File) (int, error) {
+	return f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"DDEBF7"}},
+	})
+}
+
+func templateFormatHeader(f *excelize.File, sheet string, cols int, style int) error {
+	lastHeader, _ := excelize.CoordinatesToCellName(cols, 1)
+	if err := f.SetCellStyle(sheet, "A1", lastHeader, style); err != nil {
+		return err
+	}
+	lastCol, _ := excelize.ColumnNumberToName(cols)
+	if err := f.SetColWidth(sheet, "A", lastCol, 14); err != nil {
+		return err
+	}
+	return f.SetPanes(sheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"})
+}
+
+func templateDataRange(cols int) string {
+	last, _ := excelize.CoordinatesToCellName(cols, templateRows+1)
+	return "A2:" + last
+}
+
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return strings.TrimSpace(string(r[:n]))
+}
@@ -25,6 +25,12 @@ func DatabaseRoutes(app *fiber.App) {
     //configuration-details
     api.Get("/configuration-details/:id", controllers.GetConfigurationDetail)
     api.Post("/upload/configuration-cover-img", controllers.UploadConfigurationCoverImg)
+    api.Post("/configuration-details/:id/observations", controllers.UploadObservationDatasets)
+    api.Get("/configuration-details/:id/observations", controllers.GetObservationDatasets)
+    api.Post("/configuration-details/:id/refit", controllers.RefitObservationDatasets)
+    api.Post("/configuration-details/:id/tap-import", controllers.UploadConfigurationTapImport)
+    api.Post("/configuration-details/:id/station-pairs", controllers.EnsureConfigurationStationPairs)
+    api.Get("/configuration-details/:id/template/:kind", controllers.GetDistributionTemplate)
 
     // // //public-scenarios
     // // api.Get("/public-scenarios/:user_id", controllers.GetPublicScenarios)
@@ -37,9 +43,18 @@ func DatabaseRoutes(app *fiber.App) {
     api.Post("/user-scenario-create",controllers.CreateUserScenario)
     api.Post("/user-scenario/:id",controllers.EditUserScenario)
     api.Delete("/user-scenario/:id",controllers.DeleteUserScenario)
+    api.Get("/user-scenario/:id/gtfs", controllers.ExportUserScenarioGTFS)
 
     // //scenario-details
     api.Get("/scenario-details/:id", controllers.GetScenarioDetails)
     api.Post("/upload/scenario-cover-img", controllers.UploadScenarioCoverImg)
+    api.Put("/scenario-details/:id/schedule-rules", controllers.SaveScheduleRule)
+
+    //gtfs
+    api.Post("/gtfs/import", controllers.ImportGTFSFeed)
+
+    //route-scenarios
+    api.Get("/route-scenarios/:id/template/schedule", controllers.GetScheduleTemplate)
+    api.Post("/route-scenarios/:id/schedule/upload", controllers.UploadRouteScenarioSchedule)
 
 }
@@ -7,7 +7,10 @@ import (
 )
 
 func SetupDistributionRoutes(app *fiber.App) {
+    app.Get("/api/distributions", controllers.GetDistributionRegistry)
     app.Post("/api/guest/alighting/distribution_fit", controllers.UploadGuestAlightingFit)
     app.Post("/api/guest/interarrival/distribution_fit", controllers.UploadGuestInterarrivalFit)
+    app.Post("/api/guest/tap/import", controllers.UploadGuestTapImport)
 	app.Post("/api/guest/schedule/upload/:scenarioID", controllers.UploadGuestSchedulefile )
+	app.Post("/api/guest/schedule/generate", controllers.PreviewHeadwaySchedule)
 }
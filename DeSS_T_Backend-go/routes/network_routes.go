@@ -12,6 +12,7 @@ func RegisterNetworkRoutes(app *fiber.App) {
 	app.Post("/api/network/save-configuration", controllers.SaveConfiguration)
 	app.Post("/api/network/route", controllers.GetRouteGeometry)
 	app.Post("/api/network/route-paths", controllers.ComputeRouteSegments)
+	app.Delete("/api/network/travel-cache", controllers.InvalidateTravelCache)
 	app.Post("/api/network/save-routes", controllers.SaveRoutes)
 	app.Post("/api/network/area-bounds", controllers.GetAreaBounds)
 	app.Post("/api/network/bus-stops", controllers.GetBusStops)
@@ -10,4 +10,7 @@ func SetupSimulationRoutes(app *fiber.App) {
 	simulation := app.Group("/api/simulation")
 	simulation.Post("/transform", controllers.TransformSimulationHandler)
 	simulation.Post("/run", controllers.RunSimulationHandler)
+	simulation.Post("/calibrate", controllers.CalibrateSimulationHandler)
+	simulation.Get("/calibration/:scenario_detail_id", controllers.GetCalibrationReports)
+	simulation.Get("/runs/:run_id/bus-timeline", controllers.GetBusTimelineHandler)
 }
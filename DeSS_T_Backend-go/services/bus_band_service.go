@@ -0,0 +1,140 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"DeSS_T_Backend-go/model_database"
+	"DeSS_T_Backend-go/models"
+)
+
+const defaultBusBand = "default"
+
+// selectBusBand เลือกค่าของรถสำหรับช่วงเวลาที่ขอ
+// ใช้ band ที่ซ้อนทับกับ periods มากที่สุด (เท่ากันเลือก band ที่เริ่มก่อน)
+// ถ้าไม่มี band ที่ซ้อนทับเลย ใช้ค่าหลักใน BusInformation
+func selectBusBand(bi models.BusInformation, periods []string) models.RouteBusInformation {
+	info := models.RouteBusInformation{
+		BusSpeed:      float64(bi.Speed),
+		MaxDistance:   float64(bi.MaxDis),
+		MaxBus:        bi.MaxBus,
+		BusCapacity:   bi.Capacity,
+		AvgTravelTime: float64(bi.AvgTravelTime),
+		AppliedBand:   defaultBusBand,
+	}
+
+	best, bestOverlap, bestStart := -1, 0, 0
+	for i, band := range bi.Bands {
+		bStart, bEnd, err := parsePeriod(band.TimeRange)
+		if err != nil {
+			continue
+		}
+
+		overlap := 0
+		for _, p := range periods {
+			pStart, pEnd, err := parsePeriod(p)
+			if err != nil {
+				continue
+			}
+			if lo, hi := max(bStart, pStart), min(bEnd, pEnd); hi > lo {
+				overlap += hi - lo
+			}
+		}
+
+		if overlap > bestOverlap || (overlap == bestOverlap && overlap > 0 && bStart < bestStart) {
+			best, bestOverlap, bestStart = i, overlap, bStart
+		}
+	}
+
+	if best < 0 {
+		return info
+	}
+
+	band := bi.Bands[best]
+	return models.RouteBusInformation{
+		BusSpeed:      float64(band.Speed),
+		MaxDistance:   float64(band.MaxDis),
+		MaxBus:        band.MaxBus,
+		BusCapacity:   band.Capacity,
+		AvgTravelTime: float64(band.AvgTravelTime),
+		AppliedBand:   strings.TrimSpace(band.TimeRange),
+	}
+}
+
+// selectWindowBusBands เลือก band แยกให้แต่ละ time window (เรียงตามเวลาเริ่ม)
+// window เช้าและเย็นจึงได้ค่ารถของช่วงตัวเอง แทนที่จะใช้ band เดียวทั้ง request
+func selectWindowBusBands(bi models.BusInformation, windows []models.TimeWindow) []models.RouteBusBand {
+	sorted := append([]models.TimeWindow(nil), windows...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, _, _ := parsePeriod(sorted[i].Period)
+		b, _, _ := parsePeriod(sorted[j].Period)
+		return a < b
+	})
+
+	out := make([]models.RouteBusBand, 0, len(sorted))
+	for _, w := range sorted {
+		out = append(out, models.RouteBusBand{
+			Window:              w.Name,
+			Period:              w.Period,
+			RouteBusInformation: selectBusBand(bi, []string{w.Period}),
+		})
+	}
+	return out
+}
+
+// AppliedBusBands สรุปว่าแต่ละเส้นทางใช้ band ไหนในแต่ละ time window (ไม่มี window = ทั้งช่วงที่จำลอง)
+func AppliedBusBands(data models.SimulationRequest) []models.AppliedBusBand {
+	applied := make([]models.AppliedBusBand, 0, len(data.ScenarioData))
+	for _, sd := range data.ScenarioData {
+		if len(sd.BusBands) == 0 {
+			applied = append(applied, models.AppliedBusBand{
+				RouteID:     sd.RouteID,
+				RouteName:   sd.RouteName,
+				Period:      data.TimePeriod,
+				AppliedBand: sd.RouteBusInformation.AppliedBand,
+				BusCapacity: sd.RouteBusInformation.BusCapacity,
+			})
+			continue
+		}
+		for _, b := range sd.BusBands {
+			applied = append(applied, models.AppliedBusBand{
+				RouteID:     sd.RouteID,
+				RouteName:   sd.RouteName,
+				Window:      b.Window,
+				Period:      b.Period,
+				AppliedBand: b.RouteBusInformation.AppliedBand,
+				BusCapacity: b.RouteBusInformation.BusCapacity,
+			})
+		}
+	}
+	return applied
+}
+
+// ValidateBusInformationBands ตรวจว่า time_range ของ band ถูกรูปแบบและไม่ซ้อนทับกันภายในเส้นทางเดียวกัน
+func ValidateBusInformationBands(infos []model_database.BusInformation) error {
+	type span struct {
+		start, end int
+		raw        string
+	}
+
+	for _, info := range infos {
+		spans := make([]span, 0, len(info.Bands))
+		for _, band := range info.Bands {
+			start, end, err := parsePeriod(band.TimeRange)
+			if err != nil {
+				return fmt.Errorf("route_path_id %s: %w", info.RoutePathID, err)
+			}
+			spans = append(spans, span{start: start, end: end, raw: strings.TrimSpace(band.TimeRange)})
+		}
+
+		sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+		for i := 1; i < len(spans); i++ {
+			if spans[i].start < spans[i-1].end {
+				return fmt.Errorf("route_path_id %s: bands %s and %s overlap", info.RoutePathID, spans[i-1].raw, spans[i].raw)
+			}
+		}
+	}
+
+	return nil
+}
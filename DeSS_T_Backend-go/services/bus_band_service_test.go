@@ -0,0 +1,72 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"DeSS_T_Backend-go/models"
+)
+
+// เส้นทางมี band ชั่วโมงเร่งด่วนเช้าและเย็น ค่าหลักใช้นอก band
+var testBusInformation = models.BusInformation{
+	Speed: 30, MaxDis: 100, MaxBus: 4, Capacity: 40, AvgTravelTime: 30,
+	Bands: []models.BusInformationBand{
+		{TimeRange: "07:00-09:00", Speed: 20, MaxDis: 100, MaxBus: 8, Capacity: 60, AvgTravelTime: 45},
+		{TimeRange: "16:00-19:00", Speed: 15, MaxDis: 100, MaxBus: 10, Capacity: 60, AvgTravelTime: 50},
+	},
+}
+
+func TestSelectWindowBusBands(t *testing.T) {
+	windows := []models.TimeWindow{
+		{Name: "evening", Period: "17:00-18:00"},
+		{Name: "midday", Period: "11:00-13:00"},
+		{Name: "morning", Period: "07:30-08:30"},
+	}
+	bands := selectWindowBusBands(testBusInformation, windows)
+
+	type got struct {
+		window, band string
+		maxBus       int
+	}
+	var gotBands []got
+	for _, b := range bands {
+		gotBands = append(gotBands, got{b.Window, b.RouteBusInformation.AppliedBand, b.RouteBusInformation.MaxBus})
+	}
+	want := []got{
+		{"morning", "07:00-09:00", 8},
+		{"midday", defaultBusBand, 4},
+		{"evening", "16:00-19:00", 10},
+	}
+	if !reflect.DeepEqual(gotBands, want) {
+		t.Errorf("bands = %+v, want %+v", gotBands, want)
+	}
+}
+
+func TestAppliedBusBandsPerWindow(t *testing.T) {
+	data := models.SimulationRequest{
+		TimePeriod: "07:00-19:00",
+		ScenarioData: []models.ScenarioData{
+			{
+				RouteID:             "r1",
+				RouteBusInformation: selectBusBand(testBusInformation, []string{"07:00-19:00"}),
+				BusBands: selectWindowBusBands(testBusInformation, []models.TimeWindow{
+					{Name: "morning", Period: "07:00-08:00"},
+					{Name: "evening", Period: "17:00-18:00"},
+				}),
+			},
+			{
+				RouteID:             "r2",
+				RouteBusInformation: selectBusBand(models.BusInformation{MaxBus: 2}, []string{"07:00-19:00"}),
+			},
+		},
+	}
+
+	want := []models.AppliedBusBand{
+		{RouteID: "r1", Window: "morning", Period: "07:00-08:00", AppliedBand: "07:00-09:00", BusCapacity: 60},
+		{RouteID: "r1", Window: "evening", Period: "17:00-18:00", AppliedBand: "16:00-19:00", BusCapacity: 60},
+		{RouteID: "r2", Period: "07:00-19:00", AppliedBand: defaultBusBand},
+	}
+	if got := AppliedBusBands(data); !reflect.DeepEqual(got, want) {
+		t.Errorf("applied = %+v, want %+v", got, want)
+	}
+}
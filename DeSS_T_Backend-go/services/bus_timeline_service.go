@@ -0,0 +1,334 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"DeSS_T_Backend-go/config"
+	"DeSS_T_Backend-go/model_database"
+	"DeSS_T_Backend-go/models"
+)
+
+const (
+	defaultTimelineResolution = 30
+	maxTimelineFrames         = 5000
+)
+
+// routeGeometry เก็บ geometry ของ RoutePath พร้อมระยะสะสม (หน่วยองศา เหมือน ST_LineLocatePoint)
+// stations/stationPos เรียงตาม order ของเส้นทาง station ที่ผ่านสองครั้ง (ไป-กลับ, วงรอบ) จึงมีสองตำแหน่ง
+type routeGeometry struct {
+	coords     [][2]float64
+	cumulative []float64
+	stations   []string  // station_detail_id ตามลำดับที่รถผ่าน
+	stationPos []float64 // fraction 0..1 ตามแนวเส้นทางของ stations[i]
+	capacity   int       // ความจุจาก BusInformation ใช้เมื่อ run ไม่ได้เก็บ band ที่ใช้จริง
+}
+
+// busTrack คือ event ของรถหนึ่งคันพร้อมลำดับ station บนเส้นทางที่แต่ละ event อยู่
+type busTrack struct {
+	events   []models.BusEvent
+	visits   []int // index ใน routeGeometry.stations, -1 = หาไม่เจอ
+	capacity int
+}
+
+// BuildBusTimeline สร้างตำแหน่งรถแต่ละคันตามเวลา โดย interpolate ตาม geometry ของ RoutePath
+// ใช้ bus_events จาก engine ที่เก็บอยู่ใน SimulationRun
+func BuildBusTimeline(runID string, resolutionSeconds int) (models.BusTimeline, error) {
+	if resolutionSeconds <= 0 {
+		resolutionSeconds = defaultTimelineResolution
+	}
+
+	run, response, err := GetSimulationRunByID(runID)
+	if err != nil {
+		return models.BusTimeline{}, err
+	}
+	if len(response.BusEvents) == 0 {
+		// run ที่เก็บก่อน engine ส่ง bus_events หรือ run ที่ไม่มีรถออกเลย
+		return models.BusTimeline{}, fmt.Errorf("simulation run %s has no bus events (runs stored before the engine recorded bus events must be simulated again)", runID)
+	}
+
+	// จัดกลุ่ม event ตามรถแต่ละคัน
+	eventsByBus := make(map[string][]models.BusEvent)
+	geometries := make(map[string]*routeGeometry)
+	for _, ev := range response.BusEvents {
+		key := ev.RouteID + "|" + ev.BusID
+		eventsByBus[key] = append(eventsByBus[key], ev)
+
+		if _, ok := geometries[ev.RouteID]; !ok {
+			geom, err := loadRouteGeometry(ev.RouteID)
+			if err != nil {
+				return models.BusTimeline{}, err
+			}
+			geometries[ev.RouteID] = geom
+		}
+	}
+
+	start, end := math.MaxInt32, 0
+	tracks := make(map[string]busTrack, len(eventsByBus))
+	for key, evs := range eventsByBus {
+		sort.SliceStable(evs, func(i, j int) bool {
+			return clockToSecond(evs[i].Time) < clockToSecond(evs[j].Time)
+		})
+		geom := geometries[evs[0].RouteID]
+		capacity := busCapacityAt(response.AppliedBusBands, evs[0].RouteID, clockToSecond(evs[0].Time))
+		if capacity == 0 {
+			capacity = geom.capacity
+		}
+		tracks[key] = busTrack{events: evs, visits: matchStationVisits(geom.stations, evs), capacity: capacity}
+
+		if t := clockToSecond(evs[0].Time); t < start {
+			start = t
+		}
+		if t := clockToSecond(evs[len(evs)-1].Time); t > end {
+			end = t
+		}
+	}
+
+	// ใช้ช่วงเวลาของ run ถ้ามี เพื่อให้ frame เริ่ม/จบตรงกับ period ที่ผู้ใช้เลือก
+	if pr := strings.Split(run.TimePeriods, "-"); len(pr) == 2 {
+		start = clockToSecond(pr[0])
+		end = clockToSecond(pr[1])
+	}
+	if end < start {
+		return models.BusTimeline{}, fmt.Errorf("invalid time period %q", run.TimePeriods)
+	}
+	if (end-start)/resolutionSeconds+1 > maxTimelineFrames {
+		return models.BusTimeline{}, fmt.Errorf("resolution %ds gives more than %d frames", resolutionSeconds, maxTimelineFrames)
+	}
+
+	busKeys := make([]string, 0, len(eventsByBus))
+	for key := range eventsByBus {
+		busKeys = append(busKeys, key)
+	}
+	sort.Strings(busKeys)
+
+	frames := make([]models.BusTimeFrame, 0, (end-start)/resolutionSeconds+1)
+	for t := start; t <= end; t += resolutionSeconds {
+		frame := models.BusTimeFrame{Time: secondToClock(t), Buses: []models.BusPosition{}}
+
+		for _, key := range busKeys {
+			track := tracks[key]
+			geom := geometries[track.events[0].RouteID]
+			if pos, ok := busPositionAt(track, geom, t); ok {
+				frame.Buses = append(frame.Buses, pos)
+			}
+		}
+
+		frames = append(frames, frame)
+	}
+
+	return models.BusTimeline{
+		SimulationRunID:   run.ID,
+		ResolutionSeconds: resolutionSeconds,
+		StartTime:         secondToClock(start),
+		EndTime:           secondToClock(end),
+		Frames:            frames,
+	}, nil
+}
+
+// loadRouteGeometry ดึง LineString ของ RoutePath และหาตำแหน่งของ station ตาม order ของเส้นทางด้วย PostGIS
+// แต่ละ station ค้นต่อจากตำแหน่งของ station ก่อนหน้า (ST_LineLocatePoint บน ST_LineSubstring)
+// station ที่ผ่านซ้ำจึงได้ตำแหน่งของรอบนั้น ไม่ใช่จุดแรกบนเส้นที่ใกล้ที่สุด
+func loadRouteGeometry(routeID string) (*routeGeometry, error) {
+	var wkt string
+	if err := config.DB.Raw("SELECT ST_AsText(route) FROM route_paths WHERE id = ?", routeID).Scan(&wkt).Error; err != nil {
+		return nil, fmt.Errorf("load geometry of route %s: %w", routeID, err)
+	}
+
+	line := parseWKTToGeoLineString(wkt)
+	if len(line.Coordinates) < 2 {
+		return nil, fmt.Errorf("route %s has no geometry", routeID)
+	}
+
+	geom := &routeGeometry{
+		coords:     line.Coordinates,
+		cumulative: make([]float64, len(line.Coordinates)),
+	}
+	for i := 1; i < len(geom.coords); i++ {
+		geom.cumulative[i] = geom.cumulative[i-1] + planarDistance(geom.coords[i-1], geom.coords[i])
+	}
+
+	var orders []model_database.Order
+	if err := config.DB.Preload("StationPair").Where("route_path_id = ?", routeID).Order(`"order"`).Find(&orders).Error; err != nil {
+		return nil, fmt.Errorf("load orders of route %s: %w", routeID, err)
+	}
+	for i, o := range orders {
+		if o.StationPair == nil {
+			return nil, fmt.Errorf("route %s order %d: unknown station pair %s", routeID, o.Order, o.StationPairID)
+		}
+		if i == 0 {
+			geom.stations = append(geom.stations, o.StationPair.FstStationID)
+		}
+		geom.stations = append(geom.stations, o.StationPair.SndStationID)
+	}
+
+	prev := 0.0
+	for _, stationID := range geom.stations {
+		frac := 1.0
+		if prev < 1 {
+			var f float64
+			err := config.DB.Raw(`
+				SELECT ST_LineLocatePoint(ST_LineSubstring(rp.route, ?, 1), sd.location)
+				FROM route_paths rp, station_details sd
+				WHERE rp.id = ? AND sd.id = ?`, prev, routeID, stationID).Scan(&f).Error
+			if err != nil {
+				return nil, fmt.Errorf("locate station %s on route %s: %w", stationID, routeID, err)
+			}
+			// fraction บน substring → fraction บนเส้นเต็ม
+			frac = prev + f*(1-prev)
+		}
+		geom.stationPos = append(geom.stationPos, frac)
+		prev = frac
+	}
+
+	var info model_database.BusInformation
+	if err := config.DB.Select("capacity").Where("route_path_id = ?", routeID).Limit(1).Find(&info).Error; err == nil {
+		geom.capacity = info.Capacity
+	}
+
+	return geom, nil
+}
+
+// busCapacityAt คืนความจุของ band ที่ run ใช้จริงกับเส้นทาง ณ เวลาที่รถออก
+// run ที่ไม่มี window มี band เดียวต่อเส้นทาง; 0 = run ไม่ได้เก็บ band ไว้
+func busCapacityAt(bands []models.AppliedBusBand, routeID string, departSecond int) int {
+	fallback := 0
+	for _, b := range bands {
+		if b.RouteID != routeID {
+			continue
+		}
+		if fallback == 0 {
+			fallback = b.BusCapacity
+		}
+		if timeRangeOfSecond(departSecond, []string{b.Period}) != "" {
+			return b.BusCapacity
+		}
+	}
+	return fallback
+}
+
+// matchStationVisits จับคู่ event กับ station บนเส้นทางตามลำดับ โดยค้นไปข้างหน้าจาก station ล่าสุด
+// (arrive/depart ที่ station เดิมอยู่ index เดียวกัน) ถ้าข้างหน้าไม่มีถือว่ารถเริ่มเที่ยวใหม่ ค้นจากต้นเส้น
+func matchStationVisits(stations []string, evs []models.BusEvent) []int {
+	visits := make([]int, len(evs))
+	cursor := 0
+	for i, ev := range evs {
+		visits[i] = -1
+		for _, from := range []int{cursor, 0} {
+			for k := from; k < len(stations); k++ {
+				if stations[k] == ev.StationID {
+					visits[i] = k
+					break
+				}
+			}
+			if visits[i] >= 0 {
+				break
+			}
+		}
+		if visits[i] >= 0 {
+			cursor = visits[i]
+		}
+	}
+	return visits
+}
+
+// busPositionAt หาตำแหน่งของรถ ณ เวลา t จาก event ก่อนหน้าและถัดไป
+// นอกช่วง event แรก-สุดท้ายถือว่ารถไม่ได้วิ่ง
+func busPositionAt(track busTrack, geom *routeGeometry, t int) (models.BusPosition, bool) {
+	evs := track.events
+	if geom == nil || t < clockToSecond(evs[0].Time) || t > clockToSecond(evs[len(evs)-1].Time) {
+		return models.BusPosition{}, false
+	}
+
+	idx := sort.Search(len(evs), func(i int) bool {
+		return clockToSecond(evs[i].Time) > t
+	}) - 1
+	if idx < 0 {
+		idx = 0
+	}
+
+	prev := evs[idx]
+	pos := models.BusPosition{
+		BusID:    prev.BusID,
+		RouteID:  prev.RouteID,
+		Load:     prev.Load,
+		Capacity: track.capacity,
+		Status:   "dwelling",
+	}
+
+	prevFrac := geom.visitFraction(track.visits[idx])
+	frac := prevFrac
+
+	// วิ่งต่อเฉพาะเมื่อ event ถัดไปอยู่ข้างหน้าบนเส้นทาง (ไม่ใช่ station เดิมหรือเที่ยวใหม่)
+	if idx+1 < len(evs) && track.visits[idx] >= 0 && track.visits[idx+1] > track.visits[idx] {
+		next := evs[idx+1]
+		nextFrac := geom.visitFraction(track.visits[idx+1])
+		t0, t1 := clockToSecond(prev.Time), clockToSecond(next.Time)
+		if t1 > t0 {
+			frac = prevFrac + (nextFrac-prevFrac)*float64(t-t0)/float64(t1-t0)
+		}
+		pos.Status = "moving"
+	}
+
+	pos.Position = geom.pointAt(frac)
+	if pos.Capacity > 0 {
+		pos.Occupancy = float64(pos.Load) / float64(pos.Capacity)
+	}
+
+	return pos, true
+}
+
+func (g *routeGeometry) visitFraction(visit int) float64 {
+	if visit < 0 || visit >= len(g.stationPos) {
+		return 0
+	}
+	return g.stationPos[visit]
+}
+
+func (g *routeGeometry) pointAt(frac float64) [2]float64 {
+	total := g.cumulative[len(g.cumulative)-1]
+	if total == 0 || frac <= 0 {
+		return g.coords[0]
+	}
+	if frac >= 1 {
+		return g.coords[len(g.coords)-1]
+	}
+
+	target := frac * total
+	i := sort.SearchFloat64s(g.cumulative, target)
+	if i == 0 {
+		return g.coords[0]
+	}
+
+	segLen := g.cumulative[i] - g.cumulative[i-1]
+	if segLen == 0 {
+		return g.coords[i]
+	}
+	ratio := (target - g.cumulative[i-1]) / segLen
+	a, b := g.coords[i-1], g.coords[i]
+	return [2]float64{
+		a[0] + (b[0]-a[0])*ratio,
+		a[1] + (b[1]-a[1])*ratio,
+	}
+}
+
+func planarDistance(a, b [2]float64) float64 {
+	return math.Hypot(b[0]-a[0], b[1]-a[1])
+}
+
+// clockToSecond แปลง HH:MM[:SS] เป็นวินาทีนับจากเที่ยงคืน
+func clockToSecond(t string) int {
+	t = strings.TrimSpace(t)
+	parts := strings.Split(t, ":")
+	sec := 0
+	if len(parts) > 2 {
+		sec = atoi(parts[2])
+	}
+	return timeToMinute(t)*60 + sec
+}
+
+func secondToClock(s int) string {
+	return fmt.Sprintf("%02d:%02d:%02d", s/3600, (s%3600)/60, s%60)
+}
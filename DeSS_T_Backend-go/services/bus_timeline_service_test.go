@@ -0,0 +1,94 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"DeSS_T_Backend-go/models"
+)
+
+func testBusEvents(stations string) []models.BusEvent {
+	var evs []models.BusEvent
+	for _, s := range strings.Fields(stations) {
+		evs = append(evs, models.BusEvent{StationID: s, Type: "arrive"}, models.BusEvent{StationID: s, Type: "depart"})
+	}
+	return evs
+}
+
+func TestMatchStationVisits(t *testing.T) {
+	cases := []struct {
+		name     string
+		route    string
+		events   string
+		expected []int
+	}{
+		{"straight", "A B C", "A B C", []int{0, 0, 1, 1, 2, 2}},
+		{"out and back", "A B C B A", "A B C B A", []int{0, 0, 1, 1, 2, 2, 3, 3, 4, 4}},
+		{"closed loop", "A B C A", "A B C A", []int{0, 0, 1, 1, 2, 2, 3, 3}},
+		{"second trip restarts", "A B", "A B A B", []int{0, 0, 1, 1, 0, 0, 1, 1}},
+		{"unknown station", "A B", "A X B", []int{0, 0, -1, -1, 1, 1}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := matchStationVisits(strings.Fields(tc.route), testBusEvents(tc.events))
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("visits = %v, want %v", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestBusPositionAtOutAndBack(t *testing.T) {
+	// เส้นตรง A(0) → C(1) แล้วกลับ; B อยู่กลางทางทั้งขาไปและขากลับ
+	geom := &routeGeometry{
+		coords:     [][2]float64{{0, 0}, {2, 0}, {0, 0}},
+		cumulative: []float64{0, 2, 4},
+		stations:   []string{"A", "B", "C", "B", "A"},
+		stationPos: []float64{0, 0.25, 0.5, 0.75, 1},
+	}
+	evs := []models.BusEvent{
+		{StationID: "A", Type: "depart", Time: "07:00"},
+		{StationID: "B", Type: "arrive", Time: "07:10"},
+		{StationID: "C", Type: "arrive", Time: "07:20"},
+		{StationID: "B", Type: "arrive", Time: "07:30", Load: 10},
+		{StationID: "A", Type: "arrive", Time: "07:40"},
+	}
+	track := busTrack{events: evs, visits: matchStationVisits(geom.stations, evs), capacity: 40}
+
+	pos, ok := busPositionAt(track, geom, clockToSecond("07:35"))
+	if !ok {
+		t.Fatal("bus should be running at 07:35")
+	}
+	// ครึ่งทางระหว่าง B ขากลับ (0.75) กับ A (1) ต้องอยู่ที่ x = 0.5 ไม่ใช่วิ่งย้อนไป B ขาไป
+	if pos.Position != [2]float64{0.5, 0} || pos.Status != "moving" {
+		t.Errorf("position = %v (%s), want [0.5 0] moving", pos.Position, pos.Status)
+	}
+	if pos.Capacity != 40 || pos.Occupancy != 0.25 {
+		t.Errorf("capacity = %d occupancy = %v, want 40 and 0.25", pos.Capacity, pos.Occupancy)
+	}
+}
+
+func TestBusCapacityAt(t *testing.T) {
+	bands := []models.AppliedBusBand{
+		{RouteID: "r1", Period: "07:00-09:00", BusCapacity: 60},
+		{RouteID: "r1", Period: "16:00-19:00", BusCapacity: 80},
+		{RouteID: "r2", Period: "07:00-19:00", BusCapacity: 30},
+	}
+	cases := []struct {
+		route  string
+		depart string
+		want   int
+	}{
+		{"r1", "07:30", 60},
+		{"r1", "17:00", 80},
+		{"r1", "12:00", 60}, // ไม่อยู่ใน window ใด ใช้ band แรกของเส้นทาง
+		{"r2", "08:00", 30},
+		{"r3", "08:00", 0},
+	}
+	for _, tc := range cases {
+		if got := busCapacityAt(bands, tc.route, clockToSecond(tc.depart)); got != tc.want {
+			t.Errorf("%s at %s: capacity = %d, want %d", tc.route, tc.depart, got, tc.want)
+		}
+	}
+}
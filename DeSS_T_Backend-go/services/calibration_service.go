@@ -0,0 +1,286 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"DeSS_T_Backend-go/config"
+	"DeSS_T_Backend-go/model_database"
+	"DeSS_T_Backend-go/models"
+)
+
+// ErrCalibrationScenarioMismatch คือ simulation run / scenario ที่ส่งมาไม่ใช่ของ scenario ที่จะบันทึกรายงาน (controller ตอบ 400)
+var ErrCalibrationScenarioMismatch = errors.New("simulation run does not belong to the scenario")
+
+// CreateCalibrationReport เทียบข้อมูลจริงกับผล simulation แล้วเก็บรายงานไว้กับ scenario
+// ถ้ามี SimulationRunID จะใช้ run เดิม ไม่งั้นจะรัน simulation ใหม่จาก configuration/scenario ที่ส่งมา
+func CreateCalibrationReport(req models.CalibrationRequest) (models.CalibrationReport, error) {
+	scenarioDetailID := strings.TrimSpace(req.ScenarioDetailID)
+	if scenarioDetailID == "" {
+		scenarioDetailID = strings.TrimSpace(req.ScenarioDetail.ScenarioDetailID)
+	}
+	if scenarioDetailID == "" {
+		return models.CalibrationReport{}, fmt.Errorf("scenario_detail_id is required")
+	}
+	if body := strings.TrimSpace(req.ScenarioDetail.ScenarioDetailID); body != "" && body != scenarioDetailID {
+		return models.CalibrationReport{}, fmt.Errorf("%w: scenario_detail.scenario_detail_id %s differs from scenario_detail_id %s", ErrCalibrationScenarioMismatch, body, scenarioDetailID)
+	}
+
+	var sd model_database.ScenarioDetail
+	if err := config.DB.Select("id").First(&sd, "id = ?", scenarioDetailID).Error; err != nil {
+		return models.CalibrationReport{}, err
+	}
+
+	var run model_database.SimulationRun
+	var response models.SimulationResponse
+	var err error
+
+	if runID := strings.TrimSpace(req.SimulationRunID); runID != "" {
+		run, response, err = GetSimulationRunByID(runID)
+		if err == nil && (run.ScenarioDetailID == nil || *run.ScenarioDetailID != scenarioDetailID) {
+			return models.CalibrationReport{}, fmt.Errorf("%w: run %s is not a run of scenario %s", ErrCalibrationScenarioMismatch, runID, scenarioDetailID)
+		}
+	} else {
+		run, response, err = RunAndStoreSimulation(models.ProjectSimulationRequest{
+			ConfigurationDetail: req.ConfigurationDetail,
+			ScenarioDetail:      req.ScenarioDetail,
+			TimePeriods:         req.TimePeriods,
+			TimeSlot:            req.TimeSlot,
+			TimeWindows:         req.TimeWindows,
+
+			AllowInfeasibleSchedule: req.AllowInfeasibleSchedule,
+		})
+	}
+	if err != nil {
+		return models.CalibrationReport{}, err
+	}
+
+	timePeriod := req.TimePeriods
+	if timePeriod == "" {
+		timePeriod = run.TimePeriods
+	}
+
+	metrics := ComputeCalibrationMetrics(req.Observed, response.SimulationResult)
+
+	observedJSON, err := json.Marshal(req.Observed)
+	if err != nil {
+		return models.CalibrationReport{}, fmt.Errorf("encode observed data: %w", err)
+	}
+	metricsJSON, err := json.Marshal(metrics)
+	if err != nil {
+		return models.CalibrationReport{}, fmt.Errorf("encode metrics: %w", err)
+	}
+
+	record := model_database.CalibrationReport{
+		ID:               uuid.New().String(),
+		ScenarioDetailID: scenarioDetailID,
+		SimulationRunID:  run.ID,
+		TimePeriod:       timePeriod,
+		Observed:         string(observedJSON),
+		Metrics:          string(metricsJSON),
+		CreatedAt:        time.Now(),
+	}
+
+	if err := config.DB.Omit("ScenarioDetail", "SimulationRun").Create(&record).Error; err != nil {
+		return models.CalibrationReport{}, fmt.Errorf("save calibration report: %w", err)
+	}
+
+	return models.CalibrationReport{
+		CalibrationReportID: record.ID,
+		ScenarioDetailID:    record.ScenarioDetailID,
+		SimulationRunID:     record.SimulationRunID,
+		TimePeriod:          record.TimePeriod,
+		CreatedAt:           record.CreatedAt.Format(time.RFC3339),
+		Observed:            req.Observed,
+		Metrics:             metrics,
+	}, nil
+}
+
+// GetCalibrationReportsByScenarioDetailID ดึงรายงานทั้งหมดของ scenario (ใหม่สุดก่อน)
+func GetCalibrationReportsByScenarioDetailID(scenarioDetailID string) ([]models.CalibrationReport, error) {
+	var records []model_database.CalibrationReport
+	err := config.DB.
+		Where("scenario_detail_id = ?", scenarioDetailID).
+		Order("created_at DESC").
+		Find(&records).Error
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]models.CalibrationReport, 0, len(records))
+	for _, r := range records {
+		report := models.CalibrationReport{
+			CalibrationReportID: r.ID,
+			ScenarioDetailID:    r.ScenarioDetailID,
+			SimulationRunID:     r.SimulationRunID,
+			TimePeriod:          r.TimePeriod,
+			CreatedAt:           r.CreatedAt.Format(time.RFC3339),
+		}
+		if err := json.Unmarshal([]byte(r.Observed), &report.Observed); err != nil {
+			return nil, fmt.Errorf("decode observed data of report %s: %w", r.ID, err)
+		}
+		if err := json.Unmarshal([]byte(r.Metrics), &report.Metrics); err != nil {
+			return nil, fmt.Errorf("decode metrics of report %s: %w", r.ID, err)
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+// ComputeCalibrationMetrics คำนวณ MAE / RMSE / MAPE / bias แยกตาม waiting time, queue length, travel time
+func ComputeCalibrationMetrics(
+	observed models.ObservedData,
+	sim models.SimulationResult,
+) models.CalibrationMetrics {
+
+	waiting := models.CalibrationMetric{Residuals: []models.CalibrationResidual{}}
+	queue := models.CalibrationMetric{Residuals: []models.CalibrationResidual{}}
+	travel := models.CalibrationMetric{Residuals: []models.CalibrationResidual{}}
+
+	for _, obs := range observed.Stations {
+		key := strings.TrimSpace(obs.StationName)
+
+		if obs.AverageWaitingTime != nil {
+			simVal, ok := simulatedStationValue(sim, key, obs.SlotName, func(s models.ResultStation) float64 {
+				return s.AverageWaitingTime
+			})
+			appendResidual(&waiting, key, obs.SlotName, *obs.AverageWaitingTime, simVal, ok)
+		}
+
+		if obs.AverageQueueLength != nil {
+			simVal, ok := simulatedStationValue(sim, key, obs.SlotName, func(s models.ResultStation) float64 {
+				return s.AverageQueueLength
+			})
+			appendResidual(&queue, key, obs.SlotName, *obs.AverageQueueLength, simVal, ok)
+		}
+	}
+
+	for _, obs := range observed.Routes {
+		key := strings.TrimSpace(obs.RouteID)
+		if obs.AverageTravelTime == nil {
+			continue
+		}
+		simVal, ok := simulatedRouteValue(sim, key, obs.SlotName)
+		appendResidual(&travel, key, obs.SlotName, *obs.AverageTravelTime, simVal, ok)
+	}
+
+	waiting.Metrics = errorMetrics(waiting.Residuals)
+	queue.Metrics = errorMetrics(queue.Residuals)
+	travel.Metrics = errorMetrics(travel.Residuals)
+
+	return models.CalibrationMetrics{
+		WaitingTime: waiting,
+		QueueLength: queue,
+		TravelTime:  travel,
+	}
+}
+
+func appendResidual(m *models.CalibrationMetric, key, slot string, obs, simVal float64, matched bool) {
+	if !matched {
+		label := key
+		if slot != "" {
+			label = fmt.Sprintf("%s@%s", key, slot)
+		}
+		m.Unmatched = append(m.Unmatched, label)
+		return
+	}
+
+	m.Residuals = append(m.Residuals, models.CalibrationResidual{
+		Key:       key,
+		SlotName:  slot,
+		Observed:  obs,
+		Simulated: simVal,
+		Error:     simVal - obs,
+	})
+}
+
+// simulatedStationValue หา station ตามชื่อ (ไม่สนตัวพิมพ์) ถ้าไม่ระบุ slot จะเฉลี่ยทุก slot
+func simulatedStationValue(
+	sim models.SimulationResult,
+	stationName string,
+	slotName string,
+	pick func(models.ResultStation) float64,
+) (float64, bool) {
+
+	sum, count := 0.0, 0
+	for _, slot := range sim.SlotResults {
+		if slotName != "" && !strings.EqualFold(slot.SlotName, slotName) {
+			continue
+		}
+		for _, st := range slot.ResultStation {
+			if strings.EqualFold(strings.TrimSpace(st.StationName), stationName) {
+				sum += pick(st)
+				count++
+			}
+		}
+	}
+
+	if count == 0 {
+		return 0, false
+	}
+	return sum / float64(count), true
+}
+
+func simulatedRouteValue(
+	sim models.SimulationResult,
+	routeID string,
+	slotName string,
+) (float64, bool) {
+
+	sum, count := 0.0, 0
+	for _, slot := range sim.SlotResults {
+		if slotName != "" && !strings.EqualFold(slot.SlotName, slotName) {
+			continue
+		}
+		for _, rt := range slot.ResultRoute {
+			if strings.TrimSpace(rt.RouteID) == routeID {
+				sum += rt.AverageTravelTime
+				count++
+			}
+		}
+	}
+
+	if count == 0 {
+		return 0, false
+	}
+	return sum / float64(count), true
+}
+
+func errorMetrics(residuals []models.CalibrationResidual) models.ErrorMetrics {
+	n := len(residuals)
+	if n == 0 {
+		return models.ErrorMetrics{}
+	}
+
+	var absSum, sqSum, biasSum, apeSum float64
+	apeCount := 0
+
+	for _, r := range residuals {
+		absSum += math.Abs(r.Error)
+		sqSum += r.Error * r.Error
+		biasSum += r.Error
+		if r.Observed != 0 {
+			apeSum += math.Abs(r.Error / r.Observed)
+			apeCount++
+		}
+	}
+
+	metrics := models.ErrorMetrics{
+		Count: n,
+		MAE:   absSum / float64(n),
+		RMSE:  math.Sqrt(sqSum / float64(n)),
+		Bias:  biasSum / float64(n),
+	}
+	if apeCount > 0 {
+		metrics.MAPE = apeSum / float64(apeCount) * 100
+	}
+
+	return metrics
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"DeSS_T_Backend-go/model_database"
+	"DeSS_T_Backend-go/models"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ResolveDayType ตรวจ day_type ของ simulation request กับ day type ที่ configuration / scenario มีอยู่
+//   - ไม่มีข้อมูลที่ระบุ day type เลย → ใช้ได้ทุกค่า (ข้อมูลทั้งหมดใช้ได้ทุกวัน)
+//   - มีข้อมูลที่ระบุ day type → ต้องระบุ day_type และต้องเป็นหนึ่งในนั้น
+func ResolveDayType(
+	dayType string,
+	cfg models.ConfigurationDetail,
+	scenario models.ScenarioDetail,
+) (string, error) {
+
+	dayType, err := models.NormalizeDayType(dayType)
+	if err != nil {
+		return "", err
+	}
+
+	available := make(map[string]struct{})
+	for _, d := range cfg.AlightingData {
+		available[d.DayType] = struct{}{}
+	}
+	for _, d := range cfg.InterArrivalData {
+		available[d.DayType] = struct{}{}
+	}
+	for _, s := range scenario.BusScenario.ScheduleData {
+		available[s.DayType] = struct{}{}
+	}
+	delete(available, models.DayTypeAll)
+
+	if len(available) == 0 {
+		return dayType, nil
+	}
+
+	names := make([]string, 0, len(available))
+	for name := range available {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if dayType == models.DayTypeAll {
+		return "", fmt.Errorf("day_type is required: data is defined for %s", strings.Join(names, ", "))
+	}
+	if _, ok := available[dayType]; !ok {
+		return "", fmt.Errorf("day_type %q has no data (available: %s)", dayType, strings.Join(names, ", "))
+	}
+	return dayType, nil
+}
+
+// selectDayType คืน index ของแถวที่ใช้กับ dayType
+// แถวที่ key เดียวกัน (เช่น station+time range) มีทั้งแบบระบุ day type และไม่ระบุ → ใช้แบบที่ระบุ
+func selectDayType(n int, dayTypeOf, keyOf func(i int) string, dayType string) []int {
+	specific := make(map[string]bool)
+	for i := 0; i < n; i++ {
+		if dayType != models.DayTypeAll && dayTypeOf(i) == dayType {
+			specific[keyOf(i)] = true
+		}
+	}
+
+	keep := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		dt := dayTypeOf(i)
+		if !models.MatchesDayType(dt, dayType) {
+			continue
+		}
+		if dt == models.DayTypeAll && specific[keyOf(i)] {
+			continue
+		}
+		keep = append(keep, i)
+	}
+	return keep
+}
+
+// configurationForDayType คืนสำเนา configuration ที่มีเฉพาะ demand ของ dayType
+func configurationForDayType(cfg models.ConfigurationDetail, dayType string) models.ConfigurationDetail {
+	ad, ia := cfg.AlightingData, cfg.InterArrivalData
+
+	alighting := make([]models.AlightingData, 0, len(ad))
+	for _, i := range selectDayType(
+		len(ad),
+		func(i int) string { return ad[i].DayType },
+		func(i int) string { return ad[i].StationID + "|" + ad[i].TimePeriod },
+		dayType,
+	) {
+		alighting = append(alighting, ad[i])
+	}
+
+	interarrival := make([]models.InterArrivalData, 0, len(ia))
+	for _, i := range selectDayType(
+		len(ia),
+		func(i int) string { return ia[i].DayType },
+		func(i int) string { return ia[i].StationID + "|" + ia[i].TimePeriod },
+		dayType,
+	) {
+		interarrival = append(interarrival, ia[i])
+	}
+
+	cfg.AlightingData = alighting
+	cfg.InterArrivalData = interarrival
+	return cfg
+}
+
+// NormalizeConfigurationDayTypes ตรวจและจัดรูป day_type ของ demand ก่อนบันทึก configuration
+func NormalizeConfigurationDayTypes(cd *model_database.ConfigurationDetail) error {
+	for i := range cd.AlightingData {
+		dt, err := models.NormalizeDayType(cd.AlightingData[i].DayType)
+		if err != nil {
+			return fmt.Errorf("alighting station %s (%s): %w", cd.AlightingData[i].StationDetailID, cd.AlightingData[i].TimePeriod, err)
+		}
+		cd.AlightingData[i].DayType = dt
+	}
+	for i := range cd.InterArrivalData {
+		dt, err := models.NormalizeDayType(cd.InterArrivalData[i].DayType)
+		if err != nil {
+			return fmt.Errorf("interarrival station %s (%s): %w", cd.InterArrivalData[i].StationDetailID, cd.InterArrivalData[i].TimePeriod, err)
+		}
+		cd.InterArrivalData[i].DayType = dt
+	}
+	return nil
+}
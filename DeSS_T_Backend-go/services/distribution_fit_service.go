@@ -0,0 +1,101 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"DeSS_T_Backend-go/fitting"
+	"DeSS_T_Backend-go/models"
+)
+
+const (
+	FitEngineAuto   = "auto"   // ลอง Python ก่อน ถ้าล่มใช้ Go
+	FitEnginePython = "python" // Python เท่านั้น
+	FitEngineGo     = "go"     // Go เท่านั้น
+)
+
+// ParseFitEngine ตรวจค่า engine จาก request ค่าว่างคือ auto
+func ParseFitEngine(s string) (string, error) {
+	switch e := strings.ToLower(strings.TrimSpace(s)); e {
+	case "":
+		return FitEngineAuto, nil
+	case FitEngineAuto, FitEnginePython, FitEngineGo:
+		return e, nil
+	}
+	return "", fmt.Errorf("unknown engine %q (use auto, python or go)", s)
+}
+
+// FitDistributions fit distribution ของข้อมูลที่ parse จาก workbook
+// ทุก item มี candidate และ diagnostics ที่คำนวณด้วย Go เสมอ แม้ผู้ชนะจะมาจาก Python
+func FitDistributions(
+	kind fitting.Kind,
+	data models.Data,
+	engine string,
+	criterion fitting.Criterion,
+) (models.DataFitResponse, error) {
+
+	native := fitting.FitData(data, kind, criterion)
+	native.Engine = FitEngineGo
+	if engine == FitEngineGo {
+		return native, nil
+	}
+
+	var raw map[string]interface{}
+	var err error
+	if kind == fitting.KindAlighting {
+		raw, err = CallPythonAlightingDistributionFit(data)
+	} else {
+		raw, err = CallPythonInterarrivalDistributionFit(data)
+	}
+
+	var result models.DataFitResponse
+	if err == nil {
+		err = decodePythonFit(raw, &result)
+	}
+	if err != nil {
+		if engine == FitEnginePython {
+			return models.DataFitResponse{}, err
+		}
+		log.Printf("⚠️  python %s fit failed, falling back to go: %v", kind, err)
+		return native, nil
+	}
+
+	// แนบ diagnostics ของ Go ให้ item ของ Python (จับคู่ด้วย Station + Time_Range)
+	diagnostics := make(map[string]models.FitItem, len(native.DataFitResponse))
+	for _, item := range native.DataFitResponse {
+		diagnostics[item.Station+"|"+item.TimeRange] = item
+	}
+	for i := range result.DataFitResponse {
+		item := &result.DataFitResponse[i]
+		// ผู้ชนะของ Python ที่ simulation engine สร้างไม่ได้ (เช่น Normal) ใช้ผลของ Go แทน เพื่อให้บันทึกได้ทันที
+		if !fitting.IsSimulated(item.Distribution) {
+			if d, ok := diagnostics[item.Station+"|"+item.TimeRange]; ok {
+				log.Printf("⚠️  python %s fit chose %s for station %s (%s), using go result %s", kind, item.Distribution, item.Station, item.TimeRange, d.Distribution)
+				*item = d
+				continue
+			}
+		}
+		item.Parameters = fitting.ParameterMap(item.Distribution, item.ArgumentList)
+		if d, ok := diagnostics[item.Station+"|"+item.TimeRange]; ok {
+			item.SampleSize = d.SampleSize
+			item.Candidates = d.Candidates
+			item.Histogram = d.Histogram
+		}
+	}
+	result.Engine = FitEnginePython
+
+	return result, nil
+}
+
+func decodePythonFit(raw map[string]interface{}, out *models.DataFitResponse) error {
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return fmt.Errorf("encode python fit response: %w", err)
+	}
+	if err := json.Unmarshal(b, out); err != nil {
+		return fmt.Errorf("decode python fit response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"DeSS_T_Backend-go/fitting"
+	"DeSS_T_Backend-go/model_database"
+	"DeSS_T_Backend-go/models"
+)
+
+type distributionRow struct {
+	kind, station, period string
+	name, argumentList    string
+}
+
+// validateDistributionRows ตรวจทุกแถวกับ registry ด้วย parse แล้วรวม error ทั้งหมดไว้ในครั้งเดียว
+// (ผู้ใช้แก้ได้ครบในรอบเดียว ไม่ต้องส่งซ้ำทีละแถว)
+func validateDistributionRows(rows []distributionRow, parse func(name, argumentList string) (fitting.Parsed, error)) error {
+	var errs []error
+	for _, r := range rows {
+		if _, err := parse(r.name, r.argumentList); err != nil {
+			errs = append(errs, fmt.Errorf("%s station %s (%s): %w", r.kind, r.station, r.period, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ValidateConfigurationDistributions ตรวจ distribution ของ configuration ก่อนบันทึก
+// แถวที่ส่งมาใหม่ต้องเป็นตัวที่ simulation engine สร้างได้
+func ValidateConfigurationDistributions(
+	alighting []model_database.AlightingData,
+	interarrival []model_database.InterArrivalData,
+) error {
+
+	rows := make([]distributionRow, 0, len(alighting)+len(interarrival))
+	for _, d := range alighting {
+		rows = append(rows, distributionRow{string(fitting.KindAlighting), d.StationDetailID, d.TimePeriod, d.Distribution, d.ArgumentList})
+	}
+	for _, d := range interarrival {
+		rows = append(rows, distributionRow{string(fitting.KindInterarrival), d.StationDetailID, d.TimePeriod, d.Distribution, d.ArgumentList})
+	}
+	return validateDistributionRows(rows, fitting.ParseSimulated)
+}
+
+// ValidateSimulationDistributions ตรวจเฉพาะ distribution ที่จะถูกส่งให้ simulation (อยู่ในช่วงเวลาและ day type ที่เลือก)
+// เป็นข้อมูลที่บันทึกไว้แล้ว จึงตรวจแค่ว่าอ่านได้ (ParseDistribution) ไม่ปฏิเสธ family ที่บันทึกก่อนมีการตรวจ simulated
+func ValidateSimulationDistributions(cfg models.ConfigurationDetail, periods []string, dayType string) error {
+	cfg = configurationForDayType(cfg, dayType)
+	rows := make([]distributionRow, 0, len(cfg.AlightingData)+len(cfg.InterArrivalData))
+	for _, d := range cfg.AlightingData {
+		if isTimeRangeInAnyPeriod(d.TimePeriod, periods) {
+			rows = append(rows, distributionRow{string(fitting.KindAlighting), d.StationID, d.TimePeriod, d.Distribution, d.ArgumentList})
+		}
+	}
+	for _, d := range cfg.InterArrivalData {
+		if isTimeRangeInAnyPeriod(d.TimePeriod, periods) {
+			rows = append(rows, distributionRow{string(fitting.KindInterarrival), d.StationID, d.TimePeriod, d.Distribution, d.ArgumentList})
+		}
+	}
+	return validateDistributionRows(rows, fitting.ParseDistribution)
+}
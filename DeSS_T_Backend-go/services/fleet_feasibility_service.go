@@ -0,0 +1,412 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"DeSS_T_Backend-go/config"
+	"DeSS_T_Backend-go/model_database"
+	"DeSS_T_Backend-go/models"
+)
+
+// DefaultLayoverMinutes คือเวลาพักที่ปลายทางแต่ละฝั่งก่อนรถคันเดิมออกเที่ยวถัดไปได้
+const DefaultLayoverMinutes = 5
+
+// FleetViolation คือช่วงเวลาที่ตารางเวลาต้องใช้รถมากกว่า MaxBus ของเส้นทาง
+// CycleMinutes คือเวลาที่รถหนึ่งคันถูกใช้ต่อเที่ยว (รอบไป-กลับ + พัก หรือเที่ยวเดียวแบบ engine ขึ้นกับการตรวจ)
+type FleetViolation struct {
+	RoutePathID   string  `json:"route_path_id"`
+	RouteName     string  `json:"route_name"`
+	DayType       string  `json:"day_type,omitempty"`
+	TimeRange     string  `json:"time_range"`
+	RequiredBuses int     `json:"required_buses"`
+	MaxBus        int     `json:"max_bus"`
+	CycleMinutes  float64 `json:"cycle_minutes"`
+}
+
+// FleetFeasibilityError รวมทุกช่วงเวลาที่จำนวนรถไม่พอ
+type FleetFeasibilityError struct {
+	Violations []FleetViolation `json:"violations"`
+}
+
+func (v FleetViolation) String() string {
+	name := v.RouteName
+	if name == "" {
+		name = v.RoutePathID
+	}
+	return fmt.Sprintf(
+		"route %q %s needs %d buses (cycle %.0f min) but max_bus is %d",
+		name, v.TimeRange, v.RequiredBuses, v.CycleMinutes, v.MaxBus,
+	)
+}
+
+func (e *FleetFeasibilityError) Error() string {
+	msg := e.Violations[0].String()
+	if n := len(e.Violations) - 1; n > 0 {
+		msg += fmt.Sprintf(" (and %d more time windows)", n)
+	}
+	return msg
+}
+
+type fleetSegment struct {
+	travelSec, distanceM float64
+}
+
+// fleetBand คือค่าของรถในช่วงเวลาหนึ่ง (วินาทีของวัน) ค่าหลักของเส้นทางใช้ช่วง [0, ∞)
+type fleetBand struct {
+	start, end  int
+	speedKmh    float64
+	avgTravelMn float64
+	maxBus      int
+}
+
+type fleetRoute struct {
+	id, name string
+	segments []fleetSegment
+	closed   bool // ปลายทางคือต้นทาง (วงกลม) → รอบเดียวก็กลับถึงต้นทาง
+	bands    []fleetBand
+	base     fleetBand
+}
+
+func (r *fleetRoute) bandAt(sec int) fleetBand {
+	for _, b := range r.bands {
+		if sec >= b.start && sec < b.end {
+			return b
+		}
+	}
+	return r.base
+}
+
+// cycleSeconds คือเวลาตั้งแต่ออกรถจนรถคันเดิมพร้อมออกเที่ยวถัดไป
+// เวลาวิ่งแต่ละช่วงคิดแบบเดียวกับ engine: ค่ามากสุดของ travel time, ระยะ/ความเร็ว และ avg_travel_time ตามสัดส่วนระยะ
+//   - roundTrip: รถจริงต้องวิ่งกลับ (เส้นทางที่ไม่ใช่วงกลม คิดเวลาเท่าขาไป) และพักปลายทางทั้งสองฝั่ง
+//   - ไม่ใช่ roundTrip: แบบ engine ที่คืนรถทันทีที่จบเที่ยวเดียว (ไม่รวมเวลาจอดป้าย จึงเป็นค่าต่ำสุด)
+func (r *fleetRoute) cycleSeconds(b fleetBand, roundTrip bool) float64 {
+	total := 0.0
+	for _, s := range r.segments {
+		total += s.distanceM
+	}
+
+	running := 0.0
+	for _, s := range r.segments {
+		t := s.travelSec
+		if b.speedKmh > 0 {
+			t = math.Max(t, s.distanceM/(b.speedKmh/3.6))
+		}
+		if b.avgTravelMn > 0 && total > 0 {
+			t = math.Max(t, s.distanceM/total*b.avgTravelMn*60)
+		}
+		running += t
+	}
+
+	if !roundTrip {
+		return running
+	}
+	legs := 2.0
+	if r.closed {
+		legs = 1
+	}
+	return legs * (running + DefaultLayoverMinutes*60)
+}
+
+// fleetViolations หาช่วงเวลาที่จำนวนเที่ยวที่ยังไม่จบรอบ (ออกแล้วแต่ยังไม่พร้อมออกใหม่) เกิน MaxBus
+func fleetViolations(r *fleetRoute, dayType string, departures []int, roundTrip bool) []FleetViolation {
+	if len(departures) == 0 {
+		return nil
+	}
+
+	type event struct {
+		at, delta int
+		cycle     float64
+	}
+	events := make([]event, 0, 2*len(departures)+2*len(r.bands))
+	for _, d := range departures {
+		cycle := r.cycleSeconds(r.bandAt(d), roundTrip)
+		events = append(events, event{at: d, delta: 1, cycle: cycle}, event{at: d + int(math.Ceil(cycle)), delta: -1})
+	}
+	// ขอบของ band ทำให้ MaxBus เปลี่ยน ต้องตรวจใหม่ที่จุดนั้นด้วย
+	for _, b := range r.bands {
+		events = append(events, event{at: b.start}, event{at: b.end})
+	}
+	// รถที่จบรอบพร้อมกับเที่ยวใหม่ออกถือว่าใช้ต่อได้
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].at != events[j].at {
+			return events[i].at < events[j].at
+		}
+		return events[i].delta < events[j].delta
+	})
+
+	var out []FleetViolation
+	var open *FleetViolation
+	openStart := 0
+	active := 0
+	for i, e := range events {
+		active += e.delta
+		if e.delta > 0 {
+			if cycle := e.cycle / 60; open != nil && cycle > open.CycleMinutes {
+				open.CycleMinutes = math.Round(cycle*10) / 10
+			}
+		}
+		// ประเมินหลังจบทุก event ที่เวลาเดียวกัน
+		if i+1 < len(events) && events[i+1].at == e.at {
+			continue
+		}
+
+		maxBus := r.bandAt(e.at).maxBus
+		switch {
+		case active > maxBus && open == nil:
+			openStart = e.at
+			open = &FleetViolation{
+				RoutePathID:   r.id,
+				RouteName:     r.name,
+				DayType:       dayType,
+				RequiredBuses: active,
+				MaxBus:        maxBus,
+				CycleMinutes:  math.Round(r.cycleSeconds(r.bandAt(e.at), roundTrip)/60*10) / 10,
+			}
+		case active > maxBus:
+			if active-maxBus > open.RequiredBuses-open.MaxBus {
+				open.RequiredBuses, open.MaxBus = active, maxBus
+			}
+		case open != nil:
+			open.TimeRange = minuteToClock(openStart/60) + "-" + minuteToClock((e.at+59)/60)
+			out = append(out, *open)
+			open = nil
+		}
+	}
+	return out
+}
+
+// departureSeconds แปลงเวลาออกรถ (HH:MM[:SS]) เป็นวินาทีของวัน เรียงจากเช้าไปค่ำ
+func departureSeconds(times []string) []int {
+	out := make([]int, 0, len(times))
+	for _, t := range times {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		out = append(out, clockToSecond(t))
+	}
+	sort.Ints(out)
+	return out
+}
+
+func fleetBandsOf(bands []model_database.BusInformationBand) []fleetBand {
+	out := make([]fleetBand, 0, len(bands))
+	for _, b := range bands {
+		start, end, err := parsePeriod(b.TimeRange)
+		if err != nil {
+			continue
+		}
+		out = append(out, fleetBand{
+			start:       start * 60,
+			end:         end * 60,
+			speedKmh:    float64(b.Speed),
+			avgTravelMn: float64(b.AvgTravelTime),
+			maxBus:      b.MaxBus,
+		})
+	}
+	return out
+}
+
+// fleetBandsOfWindows แปลง band ที่เลือกต่อ time window ของ request (engine ใช้ window แรกที่มีเวลาออกรถอยู่ในช่วง)
+func fleetBandsOfWindows(bands []models.RouteBusBand) []fleetBand {
+	out := make([]fleetBand, 0, len(bands))
+	for _, b := range bands {
+		start, end, err := parsePeriod(b.Period)
+		if err != nil {
+			continue
+		}
+		info := b.RouteBusInformation
+		out = append(out, fleetBand{
+			start:       start * 60,
+			end:         end * 60,
+			speedKmh:    info.BusSpeed,
+			avgTravelMn: info.AvgTravelTime,
+			maxBus:      info.MaxBus,
+		})
+	}
+	return out
+}
+
+// CheckScenarioFleetFeasibility คืนช่วงเวลาที่ตารางเวลาของ scenario ต้องใช้รถเกิน MaxBus เมื่อคิดรอบไป-กลับ + พักปลายทาง
+// เป็นคำเตือนสำหรับวางแผนเท่านั้น (engine ใช้รถแค่เที่ยวเดียว จึงยังจำลองตารางเหล่านี้ได้ครบ) ไม่ปฏิเสธการบันทึก
+// เวลาเดินทางของแต่ละช่วงดึงจาก RouteBetween ของ station pair ใน database
+// ตารางที่สร้างจากกฎ headway คำนวณจากกฎ (กฎที่ผิดรายงานโดย ApplyScheduleRules)
+func CheckScenarioFleetFeasibility(sd *model_database.ScenarioDetail) ([]FleetViolation, error) {
+	if sd == nil || sd.RouteScenario == nil || sd.BusScenario == nil {
+		return nil, nil
+	}
+
+	pairIDs := make([]string, 0)
+	for _, rp := range sd.RouteScenario.RoutePaths {
+		for _, o := range rp.Orders {
+			pairIDs = append(pairIDs, strings.TrimSpace(o.StationPairID))
+		}
+	}
+	pairs := make(map[string]model_database.StationPair)
+	if len(pairIDs) > 0 {
+		var dbPairs []model_database.StationPair
+		if err := config.DB.Preload("RouteBetween").Where("id IN ?", pairIDs).Find(&dbPairs).Error; err != nil {
+			return nil, fmt.Errorf("load station pairs: %w", err)
+		}
+		for _, sp := range dbPairs {
+			pairs[sp.ID] = sp
+		}
+	}
+
+	infos := make(map[string]model_database.BusInformation)
+	for _, bi := range sd.BusScenario.BusInformations {
+		infos[strings.TrimSpace(bi.RoutePathID)] = bi
+	}
+
+	routes := make(map[string]*fleetRoute)
+	for _, rp := range sd.RouteScenario.RoutePaths {
+		id := strings.TrimSpace(rp.ID)
+		bi, ok := infos[id]
+		if !ok || len(rp.Orders) == 0 {
+			continue // ไม่มีข้อมูลรถหรือเส้นทางว่าง ตรวจที่อื่น
+		}
+
+		orders := append([]model_database.Order(nil), rp.Orders...)
+		sort.Slice(orders, func(i, j int) bool { return orders[i].Order < orders[j].Order })
+
+		r := &fleetRoute{
+			id:    id,
+			name:  rp.Name,
+			bands: fleetBandsOf(bi.Bands),
+			base: fleetBand{
+				end:         math.MaxInt32,
+				speedKmh:    float64(bi.Speed),
+				avgTravelMn: float64(bi.AvgTravelTime),
+				maxBus:      bi.MaxBus,
+			},
+		}
+		complete := true
+		for _, o := range orders {
+			sp, ok := pairs[strings.TrimSpace(o.StationPairID)]
+			if !ok || sp.RouteBetween == nil {
+				complete = false
+				break
+			}
+			r.segments = append(r.segments, fleetSegment{travelSec: sp.RouteBetween.TravelTime, distanceM: sp.RouteBetween.Distance})
+		}
+		if !complete {
+			continue // pair ที่ไม่รู้จัก รายงานโดย ValidateScenarioRouteTopology
+		}
+		first, last := pairs[strings.TrimSpace(orders[0].StationPairID)], pairs[strings.TrimSpace(orders[len(orders)-1].StationPairID)]
+		r.closed = first.FstStationID == last.SndStationID
+		routes[id] = r
+	}
+
+	var violations []FleetViolation
+	for _, sch := range sd.BusScenario.ScheduleDatas {
+		r := routes[strings.TrimSpace(sch.RoutePathID)]
+		if r == nil {
+			continue
+		}
+		list := sch.ScheduleList
+		if hasHeadwayRule(sch) {
+			generated := sch
+			generated.HeadwayBands = append([]model_database.ScheduleHeadwayBand(nil), sch.HeadwayBands...)
+			if err := applyHeadwayRule(&generated); err != nil {
+				continue
+			}
+			list = generated.ScheduleList
+		}
+		dayType, _ := models.NormalizeDayType(sch.DayType)
+		violations = append(violations, fleetViolations(r, dayType, departureSeconds(strings.Split(list, ",")), true)...)
+	}
+	return violations, nil
+}
+
+// CheckSimulationFleetFeasibility ตรวจ request ที่แปลงแล้วก่อนส่งเข้า engine ใช้ค่ารถของ band ที่ถูกเลือกให้แต่ละ time window
+//   - error (*FleetFeasibilityError): เที่ยวที่ engine จะไม่ปล่อยรถแน่นอน คิดแบบ engine ที่รถถูกใช้แค่เที่ยวเดียว
+//     (engine ข้ามเที่ยวเมื่อรถที่วิ่งอยู่ครบ MaxBus และบันทึกไว้ใน log เท่านั้น)
+//   - warnings: ช่วงที่รถไม่พอเมื่อคิดรอบไป-กลับ + พักปลายทาง (engine ยังจำลองได้ครบ)
+//
+// request ที่มี RunPeriods ถูกรันแยกทีละช่วง (รถไม่ค้างข้ามช่วง) จึงตรวจทีละช่วงด้วย band ที่เลือกให้ช่วงนั้น
+func CheckSimulationFleetFeasibility(data models.SimulationRequest) ([]FleetViolation, error) {
+	if len(data.RunPeriods) == 0 {
+		return checkSimulationFleet(data)
+	}
+
+	var violations, warnings []FleetViolation
+	for _, period := range data.RunPeriods {
+		w, err := checkSimulationFleet(segmentRequest(data, period))
+		warnings = append(warnings, w...)
+		var fe *FleetFeasibilityError
+		if errors.As(err, &fe) {
+			violations = append(violations, fe.Violations...)
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if len(violations) > 0 {
+		return warnings, &FleetFeasibilityError{Violations: violations}
+	}
+	return warnings, nil
+}
+
+// CheckRunFleetFeasibility คือกฎที่ทุกทางที่สั่งจำลองใช้ร่วมกัน (run ปกติ, calibration)
+// รถไม่พอจนเที่ยวไม่ออก (*FleetFeasibilityError) ปฏิเสธ เว้นแต่ allowInfeasible; คำเตือนรอบไป-กลับคืนไปแนบกับผลลัพธ์
+func CheckRunFleetFeasibility(data models.SimulationRequest, allowInfeasible bool) ([]FleetViolation, error) {
+	warnings, err := CheckSimulationFleetFeasibility(data)
+	var fe *FleetFeasibilityError
+	if errors.As(err, &fe) && allowInfeasible {
+		return warnings, nil
+	}
+	return warnings, err
+}
+
+func checkSimulationFleet(data models.SimulationRequest) ([]FleetViolation, error) {
+	pairs := make(map[string]models.RoutePair, len(data.ConfigurationData.RoutePair))
+	for _, p := range data.ConfigurationData.RoutePair {
+		pairs[p.RoutePairID] = p
+	}
+
+	var violations, warnings []FleetViolation
+	for _, sd := range data.ScenarioData {
+		pairIDs := strings.Split(sd.RouteOrder, "$")
+		r := &fleetRoute{
+			id:    sd.RouteID,
+			name:  sd.RouteName,
+			bands: fleetBandsOfWindows(sd.BusBands),
+			base: fleetBand{
+				end:         math.MaxInt32,
+				speedKmh:    sd.RouteBusInformation.BusSpeed,
+				avgTravelMn: sd.RouteBusInformation.AvgTravelTime,
+				maxBus:      sd.RouteBusInformation.MaxBus,
+			},
+		}
+		complete := len(pairIDs) > 0
+		for _, id := range pairIDs {
+			p, ok := pairs[id]
+			if !ok {
+				complete = false
+				break
+			}
+			r.segments = append(r.segments, fleetSegment{travelSec: p.TravelTime, distanceM: p.Distance})
+		}
+		if !complete {
+			continue
+		}
+		r.closed = pairs[pairIDs[0]].FstStation == pairs[pairIDs[len(pairIDs)-1]].SndStation
+
+		times := make([]string, 0, len(sd.RouteSchedule))
+		for _, s := range sd.RouteSchedule {
+			times = append(times, s.DepartureTime)
+		}
+		departures := departureSeconds(times)
+		violations = append(violations, fleetViolations(r, data.DayType, departures, false)...)
+		warnings = append(warnings, fleetViolations(r, data.DayType, departures, true)...)
+	}
+
+	if len(violations) > 0 {
+		return warnings, &FleetFeasibilityError{Violations: violations}
+	}
+	return warnings, nil
+}
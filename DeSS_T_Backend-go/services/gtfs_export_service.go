@@ -0,0 +1,124 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"DeSS_T_Backend-go/config"
+	"DeSS_T_Backend-go/model_database"
+	"DeSS_T_Backend-go/models"
+)
+
+// ErrInvalidGTFSExport คือ scenario ที่แปลงเป็น GTFS ไม่ได้ (controller ตอบ 400)
+var ErrInvalidGTFSExport = errors.New("scenario cannot be exported as GTFS")
+
+// ExportUserScenarioGTFS สร้าง GTFS zip จาก user scenario และ configuration ที่ scenario อ้างอิง
+// feed ใช้กำหนด agency / timezone / ช่วงวันที่ของ calendar (ค่าว่างใช้ค่าเริ่มต้น)
+// agency_url ไม่มีค่าเริ่มต้น: ว่างจะใช้ GTFS_AGENCY_URL ถ้าไม่ได้ตั้งไว้จะ export ไม่ได้
+// คืน zip และคำเตือนของข้อมูลที่ไม่ได้ export
+func ExportUserScenarioGTFS(userScenarioID string, feed models.GTFSExportFeed) (*bytes.Buffer, []string, error) {
+	var us model_database.UserScenario
+	if err := config.DB.Select("id", "name", "scenario_detail_id").First(&us, "id = ?", userScenarioID).Error; err != nil {
+		return nil, nil, err
+	}
+
+	sd, _, err := GetScenarioDetailByID(us.ScenarioDetailID)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg, err := GetConfigurationDetailByID(sd.ConfigurationDetailID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if feed.AgencyName == "" {
+		feed.AgencyName = us.Name
+	}
+	if feed.AgencyURL == "" {
+		feed.AgencyURL = strings.TrimSpace(os.Getenv("GTFS_AGENCY_URL"))
+	}
+	if feed.Timezone == "" {
+		feed.Timezone = models.DefaultGTFSTimezone
+	}
+	if feed.StartDate == "" {
+		feed.StartDate = time.Now().Format("20060102")
+	}
+	if feed.EndDate == "" {
+		start, _ := time.Parse("20060102", feed.StartDate)
+		feed.EndDate = start.AddDate(1, 0, 0).Format("20060102")
+	}
+
+	// stop_id ใช้ station_id_osm (เช่น stop_id เดิมของ GTFS ที่ import มา) เมื่อทุก station มีและไม่ซ้ำ
+	stations := cfg.NetworkModel.StationDetails
+	useOSM := len(stations) > 0
+	seenOSM := make(map[string]bool, len(stations))
+	for _, st := range stations {
+		code := strings.TrimSpace(st.StationIDOSM)
+		if code == "" || seenOSM[code] {
+			useOSM = false
+			break
+		}
+		seenOSM[code] = true
+	}
+	stopID := make(map[string]string, len(stations))
+	for _, st := range stations {
+		id := st.ID
+		if useOSM {
+			id = strings.TrimSpace(st.StationIDOSM)
+		}
+		stopID[st.ID] = id
+		name := strings.TrimSpace(st.Name)
+		if name == "" {
+			name = id
+		}
+		feed.Stops = append(feed.Stops, models.GTFSExportStop{ID: id, Name: name, Lat: st.Lat, Lon: st.Lon})
+	}
+
+	schedules := make(map[string][]models.GTFSSchedule)
+	for _, sch := range sd.BusScenario.ScheduleData {
+		schedules[sch.RoutePathID] = append(schedules[sch.RoutePathID], models.GTFSSchedule{
+			DayType:    sch.DayType,
+			Departures: strings.Split(sch.ScheduleList, ","),
+		})
+	}
+
+	for _, rp := range sd.RouteScenario.RoutePaths {
+		orders := append([]models.Order(nil), rp.Orders...)
+		sort.Slice(orders, func(i, j int) bool { return orders[i].Order < orders[j].Order })
+		if len(orders) == 0 {
+			continue
+		}
+
+		name := strings.TrimSpace(rp.Name)
+		if name == "" {
+			name = rp.RoutePathID
+		}
+		route := models.GTFSExportRoute{
+			ID:        rp.RoutePathID,
+			Name:      name,
+			Color:     rp.Color,
+			Shape:     rp.Route.Coordinates,
+			Schedules: schedules[rp.RoutePathID],
+		}
+		for i, o := range orders {
+			if i == 0 {
+				route.StopIDs = append(route.StopIDs, stopID[o.StationPair.FstStationID])
+			}
+			route.StopIDs = append(route.StopIDs, stopID[o.StationPair.SndStationID])
+			route.TravelSeconds = append(route.TravelSeconds, o.StationPair.RouteBetween.TravelTime)
+		}
+		feed.Routes = append(feed.Routes, route)
+	}
+
+	var buf bytes.Buffer
+	warnings, err := models.WriteGTFS(&buf, feed)
+	if err != nil {
+		return nil, warnings, fmt.Errorf("%w: %v", ErrInvalidGTFSExport, err)
+	}
+	return &buf, warnings, nil
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"DeSS_T_Backend-go/model_database"
+	"DeSS_T_Backend-go/models"
+)
+
+// ImportGTFS บันทึก plan ที่แปลงจาก GTFS feed เป็น user configuration (ป้าย + station pair)
+// และ user scenario (route path, schedule, bus information) ผ่าน service เดิมของทั้งสองส่วน
+// ถ้าบันทึก scenario ไม่สำเร็จ configuration ที่เพิ่งสร้างจะถูกลบทิ้ง
+func ImportGTFS(plan models.GTFSImportPlan, name, createBy string) (models.GTFSImportResult, error) {
+	now := time.Now()
+
+	// --- 1. Configuration: id ชั่วคราวของ station คือ stop_id (SaveUserConfiguration gen ใหม่ให้) ---
+	netModel := &model_database.NetworkModel{NetworkModelName: name}
+	for _, st := range plan.Stops {
+		netModel.StationDetails = append(netModel.StationDetails, model_database.StationDetail{
+			ID:           st.StopID,
+			Name:         st.Name,
+			Lat:          st.Lat,
+			Lon:          st.Lon,
+			StationIDOSM: st.StopID, // ใช้จับคู่รหัสป้ายของข้อมูลแตะบัตรได้
+			LocationJSON: model_database.LocationData{
+				Type:        "Point",
+				Coordinates: []float64{st.Lon, st.Lat},
+			},
+		})
+	}
+	for _, seg := range plan.Segments {
+		netModel.StationPairs = append(netModel.StationPairs, model_database.StationPair{
+			FstStationID: seg.FromStopID,
+			SndStationID: seg.ToStopID,
+			RouteBetween: &model_database.RouteBetween{
+				TravelTime: seg.TravelSeconds,
+				Distance:   seg.DistanceMeters,
+			},
+		})
+	}
+
+	savedConfig, err := SaveUserConfiguration(model_database.UserConfiguration{
+		Name:       name,
+		ModifyDate: now,
+		CreateBy:   createBy,
+		ConfigurationDetail: &model_database.ConfigurationDetail{
+			NetworkModel: netModel,
+		},
+	})
+	if err != nil {
+		return models.GTFSImportResult{}, fmt.Errorf("save configuration: %w", err)
+	}
+
+	// SaveUserConfiguration แก้ id ใน slice เดิม index จึงตรงกับ plan.Segments
+	pairIDs := make([]string, len(netModel.StationPairs))
+	for i, sp := range netModel.StationPairs {
+		pairIDs[i] = sp.ID
+	}
+
+	// --- 2. Scenario: RoutePath id ชั่วคราว "gtfs-<index>" (CreateUserScenario gen ใหม่ให้) ---
+	routeScenario := &model_database.RouteScenario{}
+	busScenario := &model_database.BusScenario{}
+	for i, p := range plan.Patterns {
+		rpID := fmt.Sprintf("gtfs-%d", i)
+
+		rp := model_database.RoutePath{
+			ID:    rpID,
+			Name:  p.Name,
+			Color: p.Color,
+			RouteJSON: model_database.LineStringData{
+				Type:        "LineString",
+				Coordinates: p.Shape,
+			},
+		}
+		links := make([]routeLink, 0, len(p.Segments))
+		endpoints := make(map[string]pairEndpoints, len(p.Segments))
+		for j, seg := range p.Segments {
+			rp.Orders = append(rp.Orders, model_database.Order{
+				Order:         j + 1,
+				StationPairID: pairIDs[seg],
+			})
+			links = append(links, routeLink{Order: j + 1, StationPairID: pairIDs[seg]})
+			endpoints[pairIDs[seg]] = pairEndpoints{Fst: plan.Segments[seg].FromStopID, Snd: plan.Segments[seg].ToStopID}
+		}
+		// ลำดับป้ายของ feed เป็นของจริง เส้นทางเลขแปดจึงเปิด allow_revisit ให้ และแจ้งไว้ใน warnings
+		var topoErr *RouteTopologyError
+		if err := validateRouteLinks(rpID, p.Name, false, links, endpoints); errors.As(err, &topoErr) && topoErr.Kind == TopologyRevisit {
+			rp.AllowRevisit = true
+			plan.Summary.Warnings = append(plan.Summary.Warnings, fmt.Sprintf("route %q revisits a stop outside an out-and-back; imported with allow_revisit", p.Name))
+		}
+		routeScenario.RoutePaths = append(routeScenario.RoutePaths, rp)
+
+		for _, sch := range p.Schedules {
+			busScenario.ScheduleDatas = append(busScenario.ScheduleDatas, model_database.ScheduleData{
+				RoutePathID:  rpID,
+				DayType:      sch.DayType,
+				ScheduleList: strings.Join(sch.Departures, ","),
+			})
+		}
+
+		busScenario.BusInformations = append(busScenario.BusInformations, model_database.BusInformation{
+			RoutePathID:   rpID,
+			Speed:         float32(p.SpeedKmh),
+			MaxDis:        float32(plan.Options.MaxDistanceKm),
+			MaxBus:        p.MaxBus,
+			Capacity:      plan.Options.Capacity,
+			AvgTravelTime: float32(p.AvgTravelMinutes),
+		})
+	}
+
+	scenario := model_database.UserScenario{
+		Name:       name,
+		ModifyDate: now,
+		CreateBy:   createBy,
+		ScenarioDetail: &model_database.ScenarioDetail{
+			ConfigurationDetailID: savedConfig.ConfigurationDetailID,
+			RouteScenario:         routeScenario,
+			BusScenario:           busScenario,
+		},
+	}
+
+	rollback := func(cause error) (models.GTFSImportResult, error) {
+		if err := DeleteUserConfigurationByID(savedConfig.ID); err != nil {
+			log.Printf("⚠️ GTFS import: cannot remove configuration %s after failure: %v", savedConfig.ID, err)
+		}
+		return models.GTFSImportResult{}, cause
+	}
+
+	if err := ValidateScenarioRouteTopology(scenario.ScenarioDetail); err != nil {
+		return rollback(fmt.Errorf("route topology: %w", err))
+	}
+
+	// MaxBus จาก feed คือจำนวนเที่ยวที่วิ่งพร้อมกัน ตรงกับที่ engine ใช้ รอบไป-กลับที่รถไม่พอเป็นแค่คำเตือน
+	fleetWarnings, err := CheckScenarioFleetFeasibility(scenario.ScenarioDetail)
+	if err != nil {
+		return rollback(err)
+	}
+	for _, v := range fleetWarnings {
+		plan.Summary.Warnings = append(plan.Summary.Warnings, "round trip: "+v.String())
+	}
+
+	savedScenario, err := CreateUserScenario(scenario)
+	if err != nil {
+		return rollback(fmt.Errorf("save scenario: %w", err))
+	}
+
+	return models.GTFSImportResult{
+		UserConfigurationID:   savedConfig.ID,
+		ConfigurationDetailID: savedConfig.ConfigurationDetailID,
+		UserScenarioID:        savedScenario.ID,
+		ScenarioDetailID:      savedScenario.ScenarioDetailID,
+		Summary:               plan.Summary,
+	}, nil
+}
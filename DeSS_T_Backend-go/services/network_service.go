@@ -30,68 +30,63 @@ type ORSMatrixResponse struct {
 }
 
 const (
-	orsMatrixURL       = "https://api.openrouteservice.org/v2/matrix/driving-car"
+	defaultORSBaseURL  = "https://api.openrouteservice.org"
+	defaultORSProfile  = "driving-car"
 	orsMatrixChunkSize = 30
 )
 
+// orsBaseURL อ่านจาก ORS_BASE_URL (เช่น ORS ที่ host เอง) ค่าเริ่มต้นคือ api.openrouteservice.org
+func orsBaseURL() string {
+	if v := strings.TrimRight(strings.TrimSpace(os.Getenv("ORS_BASE_URL")), "/"); v != "" {
+		return v
+	}
+	return defaultORSBaseURL
+}
+
+// orsProfile อ่านจาก ORS_PROFILE ค่าเริ่มต้น driving-car
+func orsProfile() string {
+	if v := strings.TrimSpace(os.Getenv("ORS_PROFILE")); v != "" {
+		return v
+	}
+	return defaultORSProfile
+}
+
+// OrsMatrix ขอ ORS เฉพาะคู่ที่ยังไม่มีใน travel cache
 func OrsMatrix(stations []models.StationDetail, key string) (*ORSMatrixResponse, error) {
-	n := len(stations)
-	if n == 0 {
-		return &ORSMatrixResponse{}, nil
+	m, err := withTravelCache(orsProvider{key: key}, orsBaseURL(), orsProfile()).Matrix(stations)
+	if err != nil {
+		return nil, err
 	}
+	res := ORSMatrixResponse(*m)
+	return &res, nil
+}
 
+// OrsTable คือ matrix จาก sources ไป destinations (ORS ส่งทั้งสองชุดใน locations แล้วระบุ index)
+func OrsTable(sources, destinations []models.StationDetail, key string) (*RoutingMatrix, error) {
 	client := &http.Client{Timeout: orsHTTPTimeout()}
 
-	if n <= orsMatrixChunkSize {
-		locations := buildLocations(stations, nil)
-		reqBody := ORSMatrixRequest{Locations: locations, Metrics: []string{"distance", "duration"}, Units: "m"}
-		return doOrsMatrix(reqBody, key, client)
-	}
-
-	distances := make([][]float64, n)
-	durations := make([][]float64, n)
-	for i := range distances {
-		distances[i] = make([]float64, n)
-		durations[i] = make([]float64, n)
-	}
-
-	for si := 0; si < n; si += orsMatrixChunkSize {
-		srcEnd := minInt(si+orsMatrixChunkSize, n)
-		srcBlock := stations[si:srcEnd]
-
-		for di := 0; di < n; di += orsMatrixChunkSize {
-			dstEnd := minInt(di+orsMatrixChunkSize, n)
-			dstBlock := stations[di:dstEnd]
-
-			locations := buildLocations(srcBlock, dstBlock)
-			body := ORSMatrixRequest{
-				Locations:    locations,
-				Metrics:      []string{"distance", "duration"},
-				Units:        "m",
-				Sources:      makeIndices(0, len(srcBlock)),
-				Destinations: makeIndices(len(srcBlock), len(srcBlock)+len(dstBlock)),
-			}
-
-			chunk, err := doOrsMatrix(body, key, client)
-			if err != nil {
-				return nil, fmt.Errorf("ORS Matrix chunk (%d-%d,%d-%d) failed: %w", si, srcEnd, di, dstEnd, err)
-			}
-
-			for i := range chunk.Distances {
-				for j := range chunk.Distances[i] {
-					distances[si+i][di+j] = chunk.Distances[i][j]
-					durations[si+i][di+j] = chunk.Durations[i][j]
-				}
-			}
+	m, err := chunkedTable(sources, destinations, orsMatrixChunkSize, func(src, dst []models.StationDetail) (*RoutingMatrix, error) {
+		body := ORSMatrixRequest{Locations: buildLocations(src, dst), Metrics: []string{"distance", "duration"}, Units: "m"}
+		if dst != nil {
+			body.Sources = makeIndices(0, len(src))
+			body.Destinations = makeIndices(len(src), len(src)+len(dst))
+		}
+		res, err := doOrsMatrix(body, key, client)
+		if err != nil {
+			return nil, err
 		}
+		chunk := RoutingMatrix(*res)
+		return &chunk, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ORS Matrix: %w", err)
 	}
-
-	return &ORSMatrixResponse{Distances: distances, Durations: durations}, nil
+	return m, nil
 }
 
 func doOrsMatrix(body ORSMatrixRequest, key string, client *http.Client) (*ORSMatrixResponse, error) {
 	b, _ := json.Marshal(body)
-	req, _ := http.NewRequest("POST", orsMatrixURL, bytes.NewBuffer(b))
+	req, _ := http.NewRequest("POST", orsBaseURL()+"/v2/matrix/"+orsProfile(), bytes.NewBuffer(b))
 	req.Header.Set("Authorization", key)
 	req.Header.Set("Content-Type", "application/json")
 
@@ -139,6 +134,7 @@ func minInt(a, b int) int {
 	return b
 }
 
+// orsHTTPTimeout คือ timeout ของ request ไปยัง routing server ทุกตัว (ORS_HTTP_TIMEOUT_SECONDS)
 func orsHTTPTimeout() time.Duration {
 	const defaultSeconds = 60
 	env := strings.TrimSpace(os.Getenv("ORS_HTTP_TIMEOUT_SECONDS"))
@@ -156,7 +152,7 @@ func orsHTTPTimeout() time.Duration {
 
 func OrsRoute(start [2]float64, end [2]float64, key string) ([][2]float64, error) {
 	// Use /geojson suffix in URL path to get GeoJSON format
-	url := "https://api.openrouteservice.org/v2/directions/driving-car/geojson"
+	url := orsBaseURL() + "/v2/directions/" + orsProfile() + "/geojson"
 	body := map[string]interface{}{
 		"coordinates": [][]float64{
 			{start[0], start[1]},
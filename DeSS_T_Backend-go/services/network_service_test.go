@@ -0,0 +1,128 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+const testORSKey = "test-key"
+
+func fakeORS(t *testing.T, requests *int32) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		if got := r.Header.Get("Authorization"); got != testORSKey {
+			t.Errorf("Authorization = %q", got)
+		}
+
+		switch r.URL.Path {
+		case "/v2/matrix/" + defaultORSProfile:
+			var body ORSMatrixRequest
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("decode request: %v", err)
+			}
+			src, dst := body.Sources, body.Destinations
+			if src == nil {
+				src = makeIndices(0, len(body.Locations))
+			}
+			if dst == nil {
+				dst = makeIndices(0, len(body.Locations))
+			}
+			res := ORSMatrixResponse{Distances: make([][]float64, len(src)), Durations: make([][]float64, len(src))}
+			for i, si := range src {
+				res.Distances[i] = make([]float64, len(dst))
+				res.Durations[i] = make([]float64, len(dst))
+				for j, dj := range dst {
+					v := testDuration(testIndex(body.Locations[si][1]), testIndex(body.Locations[dj][1]))
+					res.Durations[i][j], res.Distances[i][j] = v, v*10
+				}
+			}
+			json.NewEncoder(w).Encode(res)
+		case "/v2/directions/" + defaultORSProfile + "/geojson":
+			w.Write([]byte(`{"type":"FeatureCollection","features":[{"geometry":{"type":"LineString","coordinates":[[100,0,12.5],[100.2,0.004],[100,0.01]]}}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestORSTableChunks(t *testing.T) {
+	var requests int32
+	srv := fakeORS(t, &requests)
+	defer srv.Close()
+	t.Setenv("ORS_BASE_URL", srv.URL)
+	t.Setenv("ORS_PROFILE", "")
+
+	src := testStations("S", orsMatrixChunkSize+4, 100)
+	dst := testStations("D", orsMatrixChunkSize+1, 101)
+	m, err := orsProvider{key: testORSKey}.Table(src, dst)
+	if err != nil {
+		t.Fatalf("Table: %v", err)
+	}
+	checkTestMatrix(t, m, len(src), len(dst), 10)
+	if requests != 4 {
+		t.Errorf("requests = %d, want 4 chunks", requests)
+	}
+}
+
+func TestORSMatrix(t *testing.T) {
+	var requests int32
+	srv := fakeORS(t, &requests)
+	defer srv.Close()
+	t.Setenv("ORS_BASE_URL", srv.URL)
+	t.Setenv("ORS_PROFILE", "")
+
+	// ไม่ได้ต่อ database → OrsMatrix ไม่ผ่าน travel cache
+	res, err := OrsMatrix(testStations("S", 6, 100), testORSKey)
+	if err != nil {
+		t.Fatalf("OrsMatrix: %v", err)
+	}
+	m := RoutingMatrix(*res)
+	checkTestMatrix(t, &m, 6, 6, 10)
+	if requests != 1 {
+		t.Errorf("requests = %d, want 1", requests)
+	}
+}
+
+func TestORSRoute(t *testing.T) {
+	var requests int32
+	srv := fakeORS(t, &requests)
+	defer srv.Close()
+	t.Setenv("ORS_BASE_URL", srv.URL)
+	t.Setenv("ORS_PROFILE", "")
+
+	coords, err := orsProvider{key: testORSKey}.Route([2]float64{100, 0}, [2]float64{100, 0.01})
+	if err != nil {
+		t.Fatalf("Route: %v", err)
+	}
+	want := [][2]float64{{100, 0}, {100.2, 0.004}, {100, 0.01}}
+	if len(coords) != len(want) {
+		t.Fatalf("coords = %v, want %v", coords, want)
+	}
+	for i := range want {
+		if coords[i] != want[i] {
+			t.Errorf("coords[%d] = %v, want %v", i, coords[i], want[i])
+		}
+	}
+}
+
+func TestORSErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"error":"Access to this API has been disallowed"}`))
+	}))
+	defer srv.Close()
+	t.Setenv("ORS_BASE_URL", srv.URL)
+
+	p := orsProvider{key: testORSKey}
+	if _, err := p.Matrix(testStations("S", 2, 100)); err == nil || !strings.Contains(err.Error(), "(403)") {
+		t.Errorf("Matrix err = %v, want HTTP 403", err)
+	}
+	if _, err := p.Route([2]float64{100, 0}, [2]float64{100, 0.01}); err == nil || !strings.Contains(err.Error(), "(403)") {
+		t.Errorf("Route err = %v, want HTTP 403", err)
+	}
+}
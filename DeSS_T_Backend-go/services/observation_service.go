@@ -0,0 +1,599 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"DeSS_T_Backend-go/config"
+	"DeSS_T_Backend-go/fitting"
+	"DeSS_T_Backend-go/model_database"
+	"DeSS_T_Backend-go/models"
+)
+
+// ErrUnknownStation คือ station ที่ไม่อยู่ใน network model ของ configuration
+var ErrUnknownStation = errors.New("station does not belong to the configuration")
+
+// ErrForeignDataset คือ Empirical ที่อ้าง dataset ของ configuration อื่นหรือคนละชนิดข้อมูล
+var ErrForeignDataset = errors.New("dataset does not belong to the configuration")
+
+// ErrInvalidRefit คือ request refit ที่ค่าไม่ถูกต้อง (controller ตอบ 400)
+var ErrInvalidRefit = errors.New("invalid refit request")
+
+// ParseObservationKind ตรวจชนิดข้อมูล (alighting | interarrival)
+func ParseObservationKind(s string) (fitting.Kind, error) {
+	switch k := fitting.Kind(strings.ToLower(strings.TrimSpace(s))); k {
+	case fitting.KindAlighting, fitting.KindInterarrival:
+		return k, nil
+	}
+	return "", fmt.Errorf("unknown kind %q (use alighting or interarrival)", s)
+}
+
+// ConfigurationStationMap คืน map ชื่อ station → station_detail_id ของ configuration
+// ใช้แทน station_map เมื่อ upload ข้อมูลให้ configuration ที่บันทึกแล้ว
+// (รวมชื่อ sheet ที่ template ตั้งให้ด้วย กรณีชื่อ station ยาวเกินหรือมีอักขระที่ Excel ไม่รับ)
+func ConfigurationStationMap(configDetailID string) (map[string]string, error) {
+	stations, err := configurationStations(configDetailID)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(stations))
+	for _, st := range stations {
+		names = append(names, st.Name)
+	}
+
+	stationMap := make(map[string]string, 2*len(stations))
+	for i, sheet := range models.StationSheetNames(names) {
+		stationMap[sheet] = stations[i].ID
+	}
+	for _, st := range stations {
+		stationMap[strings.TrimSpace(st.Name)] = st.ID
+	}
+	return stationMap, nil
+}
+
+// configurationStations ดึง station ของ network model ที่ configuration ใช้ เรียงตามชื่อ
+func configurationStations(configDetailID string) ([]model_database.StationDetail, error) {
+	var cfg model_database.ConfigurationDetail
+	if err := config.DB.Select("id", "network_model_id").First(&cfg, "id = ?", configDetailID).Error; err != nil {
+		return nil, err
+	}
+
+	var stations []model_database.StationDetail
+	if err := config.DB.Select("id", "station_name", "station_id_osm").
+		Where("network_model_id = ?", cfg.NetworkModelID).
+		Order("station_name, id").
+		Find(&stations).Error; err != nil {
+		return nil, err
+	}
+	return stations, nil
+}
+
+// SaveObservationDatasets เก็บค่าดิบของแต่ละ station / time period ของ day type หนึ่ง
+// dataset เดิมที่ key ซ้ำ (configuration, kind, station, time period, day type) จะถูกแทนที่
+func SaveObservationDatasets(
+	configDetailID string,
+	kind fitting.Kind,
+	dayType string,
+	data models.Data,
+) ([]models.ObservationDataset, error) {
+
+	var result []models.ObservationDataset
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		result, err = saveObservationDatasets(tx, configDetailID, kind, dayType, data)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// saveObservationDatasets คือ SaveObservationDatasets ภายใน transaction ที่ผู้เรียกเปิดไว้
+// (ใช้ร่วมกันเมื่อต้องบันทึกหลายชนิดให้สำเร็จหรือยกเลิกพร้อมกัน)
+func saveObservationDatasets(
+	tx *gorm.DB,
+	configDetailID string,
+	kind fitting.Kind,
+	dayType string,
+	data models.Data,
+) ([]models.ObservationDataset, error) {
+
+	dayType, err := models.NormalizeDayType(dayType)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg model_database.ConfigurationDetail
+	if err := tx.Select("id", "network_model_id").First(&cfg, "id = ?", configDetailID).Error; err != nil {
+		return nil, err
+	}
+
+	stationIDs := make([]string, 0, len(data.Data))
+	for _, item := range data.Data {
+		stationIDs = append(stationIDs, item.Station)
+	}
+
+	var known []string
+	if err := tx.Model(&model_database.StationDetail{}).
+		Where("network_model_id = ? AND id IN ?", cfg.NetworkModelID, stationIDs).
+		Pluck("id", &known).Error; err != nil {
+		return nil, fmt.Errorf("load stations: %w", err)
+	}
+	knownSet := make(map[string]struct{}, len(known))
+	for _, id := range known {
+		knownSet[id] = struct{}{}
+	}
+	for _, id := range stationIDs {
+		if _, ok := knownSet[id]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownStation, id)
+		}
+	}
+
+	now := time.Now()
+	records := make([]model_database.ObservationDataset, 0, len(data.Data))
+
+	for _, item := range data.Data {
+		// ช่วงที่ไม่มีข้อมูลเก็บเป็น dataset ว่าง (sample_size 0) ไม่ใช่ค่า 0 ที่ parser ใส่แทน
+		values := make([]float64, 0, len(item.Records))
+		times := make([]int, 0, len(item.Records))
+		timed := true
+		for _, rec := range item.Records {
+			if rec.Placeholder {
+				continue
+			}
+			values = append(values, rec.NumericValue)
+			times = append(times, rec.TimeOfDay)
+			timed = timed && rec.Timed
+		}
+		valuesJSON, err := json.Marshal(values)
+		if err != nil {
+			return nil, fmt.Errorf("encode values: %w", err)
+		}
+		// เก็บเวลาเฉพาะเมื่อทุกตัวอย่างมีเวลา
+		var timestamps *string
+		if timed {
+			timesJSON, err := json.Marshal(times)
+			if err != nil {
+				return nil, fmt.Errorf("encode timestamps: %w", err)
+			}
+			s := string(timesJSON)
+			timestamps = &s
+		}
+
+		timePeriod := strings.TrimSpace(item.TimeRange)
+		if err := tx.Where(
+			"configuration_detail_id = ? AND kind = ? AND station_detail_id = ? AND time_period = ? AND day_type = ?",
+			configDetailID, string(kind), item.Station, timePeriod, dayType,
+		).Delete(&model_database.ObservationDataset{}).Error; err != nil {
+			return nil, err
+		}
+
+		record := model_database.ObservationDataset{
+			ID:                    uuid.New().String(),
+			ConfigurationDetailID: configDetailID,
+			StationDetailID:       item.Station,
+			Kind:                  string(kind),
+			TimePeriod:            timePeriod,
+			DayType:               dayType,
+			SampleSize:            len(values),
+			Values:                string(valuesJSON),
+			Timestamps:            timestamps,
+			CreatedAt:             now,
+		}
+		if err := tx.Omit("StationDetail", "ConfigurationDetail").Create(&record).Error; err != nil {
+			return nil, fmt.Errorf("save observation dataset: %w", err)
+		}
+		records = append(records, record)
+	}
+
+	result := make([]models.ObservationDataset, 0, len(records))
+	for _, r := range records {
+		ds, err := observationDatasetToDTO(r, false)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, ds)
+	}
+	return result, nil
+}
+
+// GetObservationDatasets ดึง dataset ของ configuration (kind ว่าง = ทุกชนิด, dayType nil = ทุก day type)
+func GetObservationDatasets(configDetailID string, kind string, dayType *string, withValues bool) ([]models.ObservationDataset, error) {
+	q := config.DB.Where("configuration_detail_id = ?", configDetailID)
+	if kind != "" {
+		q = q.Where("kind = ?", kind)
+	}
+	if dayType != nil {
+		q = q.Where("day_type = ?", *dayType)
+	}
+
+	var records []model_database.ObservationDataset
+	if err := q.Order("station_detail_id, day_type, time_period").Find(&records).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]models.ObservationDataset, 0, len(records))
+	for _, r := range records {
+		ds, err := observationDatasetToDTO(r, withValues)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, ds)
+	}
+	return result, nil
+}
+
+// RefitObservationDatasets fit ใหม่จาก dataset ที่เก็บไว้ โดยเลือก family หรือแบ่ง time range ใหม่ได้
+//   - dataset ที่มีเวลาของแต่ละตัวอย่าง (timestamp, แตะบัตร) แบ่งตัวอย่างตามเวลาเข้า time range ใหม่
+//   - dataset ที่ไม่มีเวลา (workbook) ต้องอยู่ใน time range ใหม่ทั้งช่วง ถ้าคร่อมหลายช่วงจะ error เพราะแบ่งค่าไม่ได้
+//   - dataset ที่ไม่ทับ time range ใหม่เลยอยู่ใน Unassigned
+func RefitObservationDatasets(configDetailID string, req models.RefitRequest) (models.RefitResponse, error) {
+	kind, err := ParseObservationKind(req.Kind)
+	if err != nil {
+		return models.RefitResponse{}, fmt.Errorf("%w: %v", ErrInvalidRefit, err)
+	}
+	criterion, err := fitting.ParseCriterion(req.Criterion)
+	if err != nil {
+		return models.RefitResponse{}, fmt.Errorf("%w: %v", ErrInvalidRefit, err)
+	}
+
+	var only []string
+	if strings.TrimSpace(req.Distribution) != "" {
+		name, ok := fitting.CanonicalName(req.Distribution)
+		if !ok {
+			return models.RefitResponse{}, fmt.Errorf("%w: unknown distribution %q", ErrInvalidRefit, req.Distribution)
+		}
+		only = []string{name}
+	}
+
+	for _, tr := range req.TimeRanges {
+		if _, _, err := parsePeriod(tr); err != nil {
+			return models.RefitResponse{}, fmt.Errorf("%w: %v", ErrInvalidRefit, err)
+		}
+	}
+
+	dayType, err := models.NormalizeDayType(req.DayType)
+	if err != nil {
+		return models.RefitResponse{}, fmt.Errorf("%w: %v", ErrInvalidRefit, err)
+	}
+
+	var cfg model_database.ConfigurationDetail
+	if err := config.DB.Select("id").First(&cfg, "id = ?", configDetailID).Error; err != nil {
+		return models.RefitResponse{}, err
+	}
+
+	// ใช้เฉพาะ dataset ของ day type ที่ refit ไม่ปนค่าดิบของวันอื่น
+	q := config.DB.Where("configuration_detail_id = ? AND kind = ? AND day_type = ?", configDetailID, string(kind), dayType)
+	if len(req.StationIDs) > 0 {
+		q = q.Where("station_detail_id IN ?", req.StationIDs)
+	}
+	var records []model_database.ObservationDataset
+	if err := q.Order("station_detail_id, time_period").Find(&records).Error; err != nil {
+		return models.RefitResponse{}, err
+	}
+	if len(records) == 0 {
+		return models.RefitResponse{}, fmt.Errorf("no %s datasets stored for configuration %s (day_type %q): %w", kind, configDetailID, dayType, gorm.ErrRecordNotFound)
+	}
+
+	groups, unassigned, err := groupObservationValues(records, req.TimeRanges)
+	if err != nil {
+		return models.RefitResponse{}, err
+	}
+	response := models.RefitResponse{Kind: string(kind), Unassigned: unassigned}
+
+	items := make([]models.FitItem, 0, len(groups))
+	for _, g := range groups {
+		item := fitting.FitItemWith(g.values, kind, criterion, only)
+		item.Station = g.station
+		item.TimeRange = g.period
+		items = append(items, item)
+	}
+	response.DataFitResponse = items
+
+	if req.Apply {
+		for _, item := range items {
+			if _, err := fitting.ParseSimulated(item.Distribution, item.ArgumentList); err != nil {
+				return models.RefitResponse{}, fmt.Errorf("%w: station %s (%s): %v", ErrInvalidRefit, item.Station, item.TimeRange, err)
+			}
+		}
+		if err := applyRefit(configDetailID, kind, dayType, items); err != nil {
+			return models.RefitResponse{}, err
+		}
+		response.Applied = true
+	}
+
+	return response, nil
+}
+
+// observationGroup คือค่าดิบของ station หนึ่งในช่วงเวลาหนึ่งที่จะ fit รวมกัน
+type observationGroup struct {
+	station, period string
+	values          []float64
+}
+
+// groupObservationValues จัดค่าดิบของ dataset เข้า time range (ไม่ส่ง timeRanges = ใช้ time period เดิม)
+// ผลเรียงตาม station แล้วเวลาเริ่ม, unassigned คือ dataset ที่ไม่ทับ time range ใหม่เลย
+//   - dataset ที่อยู่ใน time range ใหม่ทั้งช่วงใช้ทั้งก้อน
+//   - dataset ที่คร่อมหลายช่วงแบ่งตามเวลาของแต่ละตัวอย่าง ตัวอย่างนอกทุกช่วงไม่ถูกใช้
+//   - dataset ที่คร่อมแต่ไม่มีเวลา (workbook) แบ่งไม่ได้ คืน ErrInvalidRefit
+func groupObservationValues(records []model_database.ObservationDataset, timeRanges []string) ([]observationGroup, []string, error) {
+	type groupKey struct{ station, period string }
+	index := make(map[groupKey]int)
+	var groups []observationGroup
+	var unassigned []string
+
+	add := func(station, period string, values ...float64) {
+		key := groupKey{station: station, period: period}
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, observationGroup{station: station, period: period})
+		}
+		groups[i].values = append(groups[i].values, values...)
+	}
+
+	for _, r := range records {
+		var values []float64
+		if err := json.Unmarshal([]byte(r.Values), &values); err != nil {
+			return nil, nil, fmt.Errorf("decode dataset %s: %w", r.ID, err)
+		}
+
+		if len(timeRanges) == 0 {
+			add(r.StationDetailID, r.TimePeriod, values...)
+			continue
+		}
+		if period := enclosingTimeRange(r.TimePeriod, timeRanges); period != "" {
+			add(r.StationDetailID, period, values...)
+			continue
+		}
+		if !overlapsAnyPeriod(r.TimePeriod, timeRanges) {
+			unassigned = append(unassigned, r.StationDetailID+"@"+r.TimePeriod)
+			continue
+		}
+		if len(values) == 0 {
+			continue
+		}
+		if r.Timestamps == nil {
+			return nil, nil, fmt.Errorf(
+				"%w: dataset %s (station %s, %s) has no per-sample times and cannot be split across time ranges %s; use ranges that contain the whole period",
+				ErrInvalidRefit, r.ID, r.StationDetailID, r.TimePeriod, strings.Join(timeRanges, ", "),
+			)
+		}
+
+		var times []int
+		if err := json.Unmarshal([]byte(*r.Timestamps), &times); err != nil {
+			return nil, nil, fmt.Errorf("decode dataset %s timestamps: %w", r.ID, err)
+		}
+		if len(times) != len(values) {
+			return nil, nil, fmt.Errorf("dataset %s: %d timestamps for %d values", r.ID, len(times), len(values))
+		}
+		for i, v := range values {
+			if period := timeRangeOfSecond(times[i], timeRanges); period != "" {
+				add(r.StationDetailID, period, v)
+			}
+		}
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].station != groups[j].station {
+			return groups[i].station < groups[j].station
+		}
+		si, _, _ := parsePeriod(groups[i].period)
+		sj, _, _ := parsePeriod(groups[j].period)
+		return si < sj
+	})
+	return groups, unassigned, nil
+}
+
+// applyRefit แทนที่ AlightingData / InterArrivalData ของ station ที่ refit
+// แถวเดิมของ day type เดียวกันที่ช่วงเวลาทับกับช่วงใหม่จะถูกลบก่อน เพื่อไม่ให้มี distribution ซ้อนกัน
+func applyRefit(configDetailID string, kind fitting.Kind, dayType string, items []models.FitItem) error {
+	byStation := make(map[string][]models.FitItem)
+	for _, item := range items {
+		byStation[item.Station] = append(byStation[item.Station], item)
+	}
+
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		for stationID, stationItems := range byStation {
+			periods := make([]string, 0, len(stationItems))
+			for _, item := range stationItems {
+				periods = append(periods, item.TimeRange)
+			}
+
+			if kind == fitting.KindAlighting {
+				var existing []model_database.AlightingData
+				if err := tx.Where("configuration_detail_id = ? AND station_detail_id = ? AND day_type = ?", configDetailID, stationID, dayType).Find(&existing).Error; err != nil {
+					return err
+				}
+				for _, row := range existing {
+					if overlapsAnyPeriod(row.TimePeriod, periods) {
+						if err := tx.Delete(&model_database.AlightingData{}, "id = ?", row.ID).Error; err != nil {
+							return err
+						}
+					}
+				}
+				for _, item := range stationItems {
+					row := model_database.AlightingData{
+						ID:                    uuid.New().String(),
+						ConfigurationDetailID: configDetailID,
+						TimePeriod:            item.TimeRange,
+						Distribution:          item.Distribution,
+						ArgumentList:          item.ArgumentList,
+						StationDetailID:       stationID,
+						DayType:               dayType,
+					}
+					if err := tx.Omit("StationDetail", "ConfigurationDetail").Create(&row).Error; err != nil {
+						return fmt.Errorf("save alighting data: %w", err)
+					}
+				}
+				continue
+			}
+
+			var existing []model_database.InterArrivalData
+			if err := tx.Where("configuration_detail_id = ? AND station_detail_id = ? AND day_type = ?", configDetailID, stationID, dayType).Find(&existing).Error; err != nil {
+				return err
+			}
+			for _, row := range existing {
+				if overlapsAnyPeriod(row.TimePeriod, periods) {
+					if err := tx.Delete(&model_database.InterArrivalData{}, "id = ?", row.ID).Error; err != nil {
+						return err
+					}
+				}
+			}
+			for _, item := range stationItems {
+				row := model_database.InterArrivalData{
+					ID:                    uuid.New().String(),
+					ConfigurationDetailID: configDetailID,
+					TimePeriod:            item.TimeRange,
+					Distribution:          item.Distribution,
+					ArgumentList:          item.ArgumentList,
+					StationDetailID:       stationID,
+					DayType:               dayType,
+				}
+				if err := tx.Omit("StationDetail", "ConfigurationDetail").Create(&row).Error; err != nil {
+					return fmt.Errorf("save interarrival data: %w", err)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// enclosingTimeRange คืน time range ใหม่ที่ครอบ period เดิมไว้ทั้งช่วง ("" ถ้าไม่มี)
+func enclosingTimeRange(period string, ranges []string) string {
+	ps, pe, err := parsePeriod(period)
+	if err != nil {
+		return ""
+	}
+	for _, r := range ranges {
+		rs, re, err := parsePeriod(r)
+		if err != nil {
+			continue
+		}
+		if ps >= rs && pe <= re {
+			return strings.TrimSpace(r)
+		}
+	}
+	return ""
+}
+
+// timeRangeOfSecond คืน time range ที่มีเวลา sec (วินาทีของวัน) อยู่ ("" ถ้าไม่มี)
+func timeRangeOfSecond(sec int, ranges []string) string {
+	for _, r := range ranges {
+		rs, re, err := parsePeriod(r)
+		if err == nil && sec >= rs*60 && sec < re*60 {
+			return strings.TrimSpace(r)
+		}
+	}
+	return ""
+}
+
+func overlapsAnyPeriod(period string, periods []string) bool {
+	ps, pe, err := parsePeriod(period)
+	for _, p := range periods {
+		if err != nil {
+			if strings.TrimSpace(period) == strings.TrimSpace(p) {
+				return true
+			}
+			continue
+		}
+		s, e, perr := parsePeriod(p)
+		if perr == nil && ps < e && s < pe {
+			return true
+		}
+	}
+	return false
+}
+
+func observationDatasetToDTO(r model_database.ObservationDataset, withValues bool) (models.ObservationDataset, error) {
+	ds := models.ObservationDataset{
+		ObservationDatasetID:  r.ID,
+		ConfigurationDetailID: r.ConfigurationDetailID,
+		StationID:             r.StationDetailID,
+		Kind:                  r.Kind,
+		TimePeriod:            r.TimePeriod,
+		DayType:               r.DayType,
+		SampleSize:            r.SampleSize,
+		Timed:                 r.Timestamps != nil,
+		CreatedAt:             r.CreatedAt.Format(time.RFC3339),
+	}
+	if withValues {
+		if err := json.Unmarshal([]byte(r.Values), &ds.Values); err != nil {
+			return ds, fmt.Errorf("decode dataset %s: %w", r.ID, err)
+		}
+	}
+	return ds, nil
+}
+
+// ResolveEmpiricalReferences แปลง Empirical ที่อ้างอิง dataset ("dataset=<id>") เป็นตาราง quantile ละเอียด
+// เพื่อให้ simulation engine สุ่มจากตัวอย่างที่เก็บไว้ได้โดยตรง (ไม่แก้ slice ของผู้เรียก)
+func ResolveEmpiricalReferences(cfg models.ConfigurationDetail) (models.ConfigurationDetail, error) {
+	cache := make(map[string]string)
+	resolve := func(kind fitting.Kind, distribution, argumentList string) (string, error) {
+		parsed, err := fitting.ParseDistribution(distribution, argumentList)
+		if err != nil || parsed.Dataset == "" {
+			return argumentList, nil
+		}
+		key := string(kind) + "|" + parsed.Dataset
+		if table, ok := cache[key]; ok {
+			return table, nil
+		}
+
+		// อ่านได้เฉพาะ dataset ของ configuration นี้และชนิดเดียวกับแถวที่อ้าง
+		var record model_database.ObservationDataset
+		err = config.DB.
+			Where("id = ? AND configuration_detail_id = ? AND kind = ?", parsed.Dataset, cfg.ConfigurationDetailID, string(kind)).
+			Limit(1).
+			Find(&record).Error
+		if err != nil {
+			return "", fmt.Errorf("empirical dataset %s: %w", parsed.Dataset, err)
+		}
+		if record.ID == "" {
+			return "", fmt.Errorf("%w: empirical %s dataset %s", ErrForeignDataset, kind, parsed.Dataset)
+		}
+		var values []float64
+		if err := json.Unmarshal([]byte(record.Values), &values); err != nil {
+			return "", fmt.Errorf("decode dataset %s: %w", record.ID, err)
+		}
+		table, err := fitting.EmpiricalArgumentList(values, fitting.DenseQuantiles)
+		if err != nil {
+			return "", fmt.Errorf("empirical dataset %s: %w", record.ID, err)
+		}
+		cache[key] = table
+		return table, nil
+	}
+
+	alighting := make([]models.AlightingData, len(cfg.AlightingData))
+	copy(alighting, cfg.AlightingData)
+	for i := range alighting {
+		args, err := resolve(fitting.KindAlighting, alighting[i].Distribution, alighting[i].ArgumentList)
+		if err != nil {
+			return cfg, err
+		}
+		alighting[i].ArgumentList = args
+	}
+
+	interarrival := make([]models.InterArrivalData, len(cfg.InterArrivalData))
+	copy(interarrival, cfg.InterArrivalData)
+	for i := range interarrival {
+		args, err := resolve(fitting.KindInterarrival, interarrival[i].Distribution, interarrival[i].ArgumentList)
+		if err != nil {
+			return cfg, err
+		}
+		interarrival[i].ArgumentList = args
+	}
+
+	cfg.AlightingData = alighting
+	cfg.InterArrivalData = interarrival
+	return cfg, nil
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"DeSS_T_Backend-go/model_database"
+)
+
+func testObservationDataset(station, period, values string, timestamps string) model_database.ObservationDataset {
+	ds := model_database.ObservationDataset{ID: station + "@" + period, StationDetailID: station, TimePeriod: period, Values: values}
+	if timestamps != "" {
+		ds.Timestamps = &timestamps
+	}
+	return ds
+}
+
+func TestGroupObservationValues(t *testing.T) {
+	// s1 มาจาก timestamp (07:10, 07:50, 08:20, 08:40), s2 มาจาก workbook
+	records := []model_database.ObservationDataset{
+		testObservationDataset("s1", "07:00-09:00", "[1,2,3,4]", "[25800,28200,30000,31200]"),
+		testObservationDataset("s2", "07:00-08:00", "[5,6]", ""),
+		testObservationDataset("s2", "08:00-09:00", "[7]", ""),
+		testObservationDataset("s2", "12:00-13:00", "[8]", ""),
+	}
+
+	t.Run("original periods", func(t *testing.T) {
+		groups, unassigned, err := groupObservationValues(records, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(groups) != 4 || len(unassigned) != 0 {
+			t.Errorf("groups = %+v, unassigned = %v", groups, unassigned)
+		}
+	})
+
+	t.Run("merge and split", func(t *testing.T) {
+		groups, unassigned, err := groupObservationValues(records, []string{"07:00-08:00", "08:00-09:00"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []observationGroup{
+			{station: "s1", period: "07:00-08:00", values: []float64{1, 2}},
+			{station: "s1", period: "08:00-09:00", values: []float64{3, 4}},
+			{station: "s2", period: "07:00-08:00", values: []float64{5, 6}},
+			{station: "s2", period: "08:00-09:00", values: []float64{7}},
+		}
+		if !reflect.DeepEqual(groups, want) {
+			t.Errorf("groups = %+v, want %+v", groups, want)
+		}
+		if !reflect.DeepEqual(unassigned, []string{"s2@12:00-13:00"}) {
+			t.Errorf("unassigned = %v", unassigned)
+		}
+	})
+
+	t.Run("samples outside every range are dropped", func(t *testing.T) {
+		groups, _, err := groupObservationValues(records[:1], []string{"06:00-07:30"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []observationGroup{{station: "s1", period: "06:00-07:30", values: []float64{1}}}
+		if !reflect.DeepEqual(groups, want) {
+			t.Errorf("groups = %+v, want %+v", groups, want)
+		}
+	})
+
+	t.Run("untimed dataset cannot be split", func(t *testing.T) {
+		_, _, err := groupObservationValues(records[1:2], []string{"07:00-07:30", "07:30-08:00"})
+		if !errors.Is(err, ErrInvalidRefit) {
+			t.Errorf("err = %v, want ErrInvalidRefit", err)
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"DeSS_T_Backend-go/models"
+)
+
+/* ─────────────────────────── OFFLINE OSM GRAPH ─────────────────────────── */
+
+// osmGraphEntry โหลดกราฟของไฟล์หนึ่งครั้งแล้วใช้ร่วมกันทุก request
+type osmGraphEntry struct {
+	once  sync.Once
+	graph *models.RoadGraph
+	err   error
+}
+
+var osmGraphs = struct {
+	sync.Mutex
+	entries map[string]*osmGraphEntry
+}{entries: make(map[string]*osmGraphEntry)}
+
+func loadOSMGraph(path string) (*models.RoadGraph, error) {
+	osmGraphs.Lock()
+	entry, ok := osmGraphs.entries[path]
+	if !ok {
+		entry = &osmGraphEntry{}
+		osmGraphs.entries[path] = entry
+	}
+	osmGraphs.Unlock()
+
+	entry.once.Do(func() {
+		started := time.Now()
+		entry.graph, entry.err = models.LoadRoadGraph(path)
+		if entry.err != nil {
+			log.Printf("❌ OSM road graph %s: %v", path, entry.err)
+			return
+		}
+		log.Printf("✅ OSM road graph %s loaded: %d nodes, %d edges in %s",
+			path, entry.graph.Nodes(), entry.graph.Edges(), time.Since(started).Round(time.Millisecond))
+	})
+	return entry.graph, entry.err
+}
+
+// osmProvider คำนวณเส้นทางจาก OSM extract ในเครื่อง (OSM_EXTRACT_PATH) ไม่ต้องต่อ network
+type osmProvider struct {
+	path string
+}
+
+// newOSMProvider เริ่มโหลดกราฟไว้เบื้องหลังทันที request แรกจึงไม่ต้องรอโหลดทั้งหมด
+func newOSMProvider() (RoutingProvider, error) {
+	path := strings.TrimSpace(os.Getenv("OSM_EXTRACT_PATH"))
+	if path == "" {
+		return nil, fmt.Errorf("%w: OSM_EXTRACT_PATH is missing", ErrRoutingProviderUnavailable)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRoutingProviderUnavailable, err)
+	}
+	go loadOSMGraph(path)
+	return osmProvider{path: path}, nil
+}
+
+func (p osmProvider) Name() string { return RoutingProviderOSM }
+
+func (p osmProvider) Matrix(stations []models.StationDetail) (*RoutingMatrix, error) {
+	return p.Table(stations, stations)
+}
+
+// Table คู่ที่ไม่มีเส้นทางบนกราฟ (oneway / ถนนขาด) ใช้ค่าประมาณของ haversine แทน
+func (p osmProvider) Table(sources, destinations []models.StationDetail) (*RoutingMatrix, error) {
+	g, err := loadOSMGraph(p.path)
+	if err != nil {
+		return nil, err
+	}
+
+	points := func(stations []models.StationDetail) [][2]float64 {
+		out := make([][2]float64, len(stations))
+		for i, s := range stations {
+			out[i] = s.Location.Coordinates
+		}
+		return out
+	}
+	dist, dur, err := g.Table(points(sources), points(destinations))
+	if err != nil {
+		return nil, fmt.Errorf("OSM matrix: %w", err)
+	}
+
+	m := &RoutingMatrix{Distances: dist, Durations: dur}
+	var estimate *RoutingMatrix
+	unreachable := 0
+	for i := range dist {
+		for j := range dist[i] {
+			if dist[i][j] >= 0 {
+				continue
+			}
+			if estimate == nil {
+				estimate, _ = newHaversineProvider().Table(sources, destinations)
+			}
+			m.Distances[i][j], m.Durations[i][j] = estimate.Distances[i][j], estimate.Durations[i][j]
+			unreachable++
+		}
+	}
+	if unreachable > 0 {
+		log.Printf("⚠️ OSM matrix: %d station pairs have no road path - using haversine estimate", unreachable)
+	}
+	return m, nil
+}
+
+func (p osmProvider) Route(start, end [2]float64) ([][2]float64, error) {
+	g, err := loadOSMGraph(p.path)
+	if err != nil {
+		return nil, err
+	}
+	path, err := g.Route(start, end)
+	if err != nil {
+		return nil, fmt.Errorf("OSM route: %w", err)
+	}
+	return path.Coordinates, nil
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"DeSS_T_Backend-go/models"
+)
+
+/* ───────────────────────────── OSRM ───────────────────────────── */
+
+const (
+	defaultOSRMProfile = "driving"
+	// ค่า --max-table-size เริ่มต้นของ osrm-routed คือ 100 location
+	osrmTableChunkSize = 50
+)
+
+// osrmProvider ใช้ OSRM server ที่ host เอง (/table สำหรับ matrix, /route สำหรับเส้นทาง)
+type osrmProvider struct {
+	baseURL string
+	profile string
+	client  *http.Client
+}
+
+func newOSRMProvider() (RoutingProvider, error) {
+	base := strings.TrimRight(strings.TrimSpace(os.Getenv("OSRM_URL")), "/")
+	if base == "" {
+		return nil, fmt.Errorf("%w: OSRM_URL is missing", ErrRoutingProviderUnavailable)
+	}
+	profile := strings.TrimSpace(os.Getenv("OSRM_PROFILE"))
+	if profile == "" {
+		profile = defaultOSRMProfile
+	}
+	return withTravelCache(osrmProvider{baseURL: base, profile: profile, client: &http.Client{Timeout: orsHTTPTimeout()}}, base, profile), nil
+}
+
+func (p osrmProvider) Name() string { return RoutingProviderOSRM }
+
+// osrmCoordinates คือ "lon,lat;lon,lat;..." ตามรูปแบบ path ของ OSRM
+func osrmCoordinates(locations [][]float64) string {
+	parts := make([]string, len(locations))
+	for i, loc := range locations {
+		parts[i] = strconv.FormatFloat(loc[0], 'f', 6, 64) + "," + strconv.FormatFloat(loc[1], 'f', 6, 64)
+	}
+	return strings.Join(parts, ";")
+}
+
+func osrmIndices(start, end int) string {
+	parts := make([]string, 0, end-start)
+	for _, i := range makeIndices(start, end) {
+		parts = append(parts, strconv.Itoa(i))
+	}
+	return strings.Join(parts, ";")
+}
+
+func (p osrmProvider) Matrix(stations []models.StationDetail) (*RoutingMatrix, error) {
+	return p.Table(stations, stations)
+}
+
+func (p osrmProvider) Table(sources, destinations []models.StationDetail) (*RoutingMatrix, error) {
+	m, err := chunkedTable(sources, destinations, osrmTableChunkSize, func(src, dst []models.StationDetail) (*RoutingMatrix, error) {
+		query := "annotations=duration,distance"
+		if dst != nil {
+			query += "&sources=" + osrmIndices(0, len(src)) + "&destinations=" + osrmIndices(len(src), len(src)+len(dst))
+		}
+		url := fmt.Sprintf("%s/table/v1/%s/%s?%s", p.baseURL, p.profile, osrmCoordinates(buildLocations(src, dst)), query)
+		req, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			return nil, err
+		}
+		data, err := doRoutingRequest(p.client, req, "OSRM Table")
+		if err != nil {
+			return nil, err
+		}
+
+		var parsed struct {
+			Code      string      `json:"code"`
+			Message   string      `json:"message"`
+			Durations [][]float64 `json:"durations"`
+			Distances [][]float64 `json:"distances"`
+		}
+		if err := json.Unmarshal(data, &parsed); err != nil {
+			return nil, fmt.Errorf("Invalid OSRM Table response: %v", err)
+		}
+		if parsed.Code != "Ok" {
+			return nil, fmt.Errorf("OSRM Table error (%s): %s", parsed.Code, parsed.Message)
+		}
+		return &RoutingMatrix{Distances: parsed.Distances, Durations: parsed.Durations}, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("OSRM Table: %w", err)
+	}
+	return m, nil
+}
+
+func (p osrmProvider) Route(start, end [2]float64) ([][2]float64, error) {
+	coords := osrmCoordinates([][]float64{{start[0], start[1]}, {end[0], end[1]}})
+	url := fmt.Sprintf("%s/route/v1/%s/%s?overview=full&geometries=geojson", p.baseURL, p.profile, coords)
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	data, err := doRoutingRequest(p.client, req, "OSRM Route")
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+		Routes  []struct {
+			Geometry struct {
+				Coordinates [][]float64 `json:"coordinates"`
+			} `json:"geometry"`
+		} `json:"routes"`
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("OSRM parse error: %v", err)
+	}
+	if parsed.Code != "Ok" {
+		return nil, fmt.Errorf("OSRM Route error (%s): %s", parsed.Code, parsed.Message)
+	}
+	if len(parsed.Routes) == 0 {
+		return nil, fmt.Errorf("OSRM returned no routes")
+	}
+
+	result := make([][2]float64, 0, len(parsed.Routes[0].Geometry.Coordinates))
+	for _, c := range parsed.Routes[0].Geometry.Coordinates {
+		if len(c) >= 2 {
+			result = append(result, [2]float64{c[0], c[1]})
+		}
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("OSRM returned empty coordinates")
+	}
+	return result, nil
+}
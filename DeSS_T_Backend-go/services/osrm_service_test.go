@@ -0,0 +1,180 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeOSRM ตอบ /table/v1 ตามพิกัดใน path (index จาก latitude) และ /route/v1 เป็น GeoJSON
+func fakeOSRM(t *testing.T, requests *int32) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+		if len(parts) != 4 || parts[1] != "v1" || parts[2] != "driving" {
+			http.Error(w, `{"code":"InvalidUrl","message":"bad path"}`, http.StatusBadRequest)
+			return
+		}
+
+		var lats []float64
+		for _, pair := range strings.Split(parts[3], ";") {
+			lonLat := strings.Split(pair, ",")
+			lat, _ := strconv.ParseFloat(lonLat[1], 64)
+			lats = append(lats, lat)
+		}
+
+		switch parts[0] {
+		case "table":
+			// sources / destinations คั่นด้วย ";" ซึ่ง net/url ไม่รับใน query จึงแยกเอง
+			query := make(map[string]string)
+			for _, kv := range strings.Split(r.URL.RawQuery, "&") {
+				if k, v, ok := strings.Cut(kv, "="); ok {
+					query[k] = v
+				}
+			}
+			if got := query["annotations"]; got != "duration,distance" {
+				t.Errorf("annotations = %q", got)
+			}
+			indices := func(key string) []int {
+				raw := query[key]
+				if raw == "" {
+					return makeIndices(0, len(lats))
+				}
+				var out []int
+				for _, s := range strings.Split(raw, ";") {
+					n, _ := strconv.Atoi(s)
+					out = append(out, n)
+				}
+				return out
+			}
+			src, dst := indices("sources"), indices("destinations")
+			durations := make([][]float64, len(src))
+			distances := make([][]float64, len(src))
+			for i, si := range src {
+				durations[i] = make([]float64, len(dst))
+				distances[i] = make([]float64, len(dst))
+				for j, dj := range dst {
+					v := testDuration(testIndex(lats[si]), testIndex(lats[dj]))
+					durations[i][j], distances[i][j] = v, v*10
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"code": "Ok", "durations": durations, "distances": distances})
+		case "route":
+			if got := r.URL.Query().Get("geometries"); got != "geojson" {
+				t.Errorf("geometries = %q", got)
+			}
+			w.Write([]byte(`{"code":"Ok","routes":[{"geometry":{"type":"LineString","coordinates":[[100,0],[100.5,0.005],[100,0.01]]}}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func testOSRMProvider(srv *httptest.Server) osrmProvider {
+	return osrmProvider{baseURL: srv.URL, profile: defaultOSRMProfile, client: srv.Client()}
+}
+
+func TestOSRMTableChunks(t *testing.T) {
+	var requests int32
+	srv := fakeOSRM(t, &requests)
+	defer srv.Close()
+
+	src := testStations("S", osrmTableChunkSize+10, 100)
+	dst := testStations("D", osrmTableChunkSize+5, 101)
+	m, err := testOSRMProvider(srv).Table(src, dst)
+	if err != nil {
+		t.Fatalf("Table: %v", err)
+	}
+	checkTestMatrix(t, m, len(src), len(dst), 10)
+	if requests != 4 {
+		t.Errorf("requests = %d, want 4 chunks", requests)
+	}
+}
+
+func TestOSRMMatrix(t *testing.T) {
+	var requests int32
+	srv := fakeOSRM(t, &requests)
+	defer srv.Close()
+
+	stations := testStations("S", 5, 100)
+	m, err := testOSRMProvider(srv).Matrix(stations)
+	if err != nil {
+		t.Fatalf("Matrix: %v", err)
+	}
+	checkTestMatrix(t, m, 5, 5, 10)
+	if requests != 1 {
+		t.Errorf("requests = %d, want 1", requests)
+	}
+}
+
+func TestOSRMRoute(t *testing.T) {
+	var requests int32
+	srv := fakeOSRM(t, &requests)
+	defer srv.Close()
+
+	coords, err := testOSRMProvider(srv).Route([2]float64{100, 0}, [2]float64{100, 0.01})
+	if err != nil {
+		t.Fatalf("Route: %v", err)
+	}
+	want := [][2]float64{{100, 0}, {100.5, 0.005}, {100, 0.01}}
+	if len(coords) != len(want) {
+		t.Fatalf("coords = %v, want %v", coords, want)
+	}
+	for i := range want {
+		if coords[i] != want[i] {
+			t.Errorf("coords[%d] = %v, want %v", i, coords[i], want[i])
+		}
+	}
+}
+
+func TestOSRMErrorCodes(t *testing.T) {
+	cases := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		{"no segment", http.StatusOK, `{"code":"NoSegment","message":"Could not find a matching segment for any coordinate."}`, "NoSegment"},
+		{"no route", http.StatusOK, `{"code":"NoRoute","message":"Impossible route between points"}`, "NoRoute"},
+		{"too big", http.StatusBadRequest, `{"code":"TooBig","message":"Too many table coordinates"}`, "(400)"},
+		{"not json", http.StatusOK, `<html>proxy error</html>`, "Invalid OSRM Table response"},
+	}
+	stations := testStations("S", 3, 100)
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				w.Write([]byte(tc.body))
+			}))
+			defer srv.Close()
+
+			_, err := testOSRMProvider(srv).Matrix(stations)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Matrix err = %v, want it to mention %q", err, tc.want)
+			}
+			if tc.name == "not json" {
+				return
+			}
+			_, err = testOSRMProvider(srv).Route([2]float64{100, 0}, [2]float64{100, 0.01})
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Route err = %v, want it to mention %q", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestOSRMTableRejectsWrongShape(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":"Ok","durations":[[0,1],[1,0]],"distances":[[0,1],[1,0]]}`))
+	}))
+	defer srv.Close()
+
+	if _, err := testOSRMProvider(srv).Matrix(testStations("S", 3, 100)); err == nil {
+		t.Error("expected an error for a 2x2 answer to a 3x3 request")
+	}
+}
@@ -0,0 +1,241 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"DeSS_T_Backend-go/config"
+	"DeSS_T_Backend-go/model_database"
+	"DeSS_T_Backend-go/models"
+)
+
+const (
+	TopologyEmptyRoute     = "empty_route"
+	TopologyDuplicateOrder = "duplicate_order"
+	TopologyUnknownPair    = "unknown_pair"
+	TopologyGap            = "gap"
+	TopologyLoop           = "loop"          // pair ที่ต้นทางกับปลายทางเป็น station เดียวกัน
+	TopologyRepeatedPair   = "repeated_pair" // วิ่งทิศเดียวกันระหว่าง station คู่เดิมซ้ำ (A→B สองครั้งในเส้นทางเดียว)
+	TopologyRevisit        = "revisit"       // กลับมา station เดิมโดยไม่ใช่ขากลับ (ต้องเปิด allow_revisit)
+)
+
+// RouteTopologyError บอกจุดที่ลำดับ station pair ของเส้นทางขาดหรือผิด
+type RouteTopologyError struct {
+	RoutePathID   string `json:"route_path_id"`
+	RouteName     string `json:"route_name"`
+	Kind          string `json:"kind"`
+	Order         int    `json:"order"`
+	StationPairID string `json:"station_pair_id,omitempty"`
+	Detail        string `json:"detail"`
+}
+
+func (e *RouteTopologyError) Error() string {
+	name := e.RouteName
+	if name == "" {
+		name = e.RoutePathID
+	}
+	return fmt.Sprintf("route %q order %d: %s", name, e.Order, e.Detail)
+}
+
+// routeLink คือ order หนึ่งแถวของเส้นทาง (ไม่ผูกกับ model ใด model หนึ่ง)
+type routeLink struct {
+	Order         int
+	StationPairID string
+}
+
+type pairEndpoints struct {
+	Fst string
+	Snd string
+}
+
+// validateRouteLinks ตรวจว่า
+//   - order ไม่ซ้ำ
+//   - ทุก pair อยู่ใน network model
+//   - ไม่มี pair ที่วนอยู่ที่ station เดียว และไม่วิ่ง A→B ทิศเดิมซ้ำในเส้นทางเดียว
+//   - SndStation ของ pair i ตรงกับ FstStation ของ pair i+1
+//   - กลับมา station เดิมได้เฉพาะขากลับของไป-กลับ (A→B→C→B→A) หรือปิดวงที่ station แรก
+//     เส้นทางเลขแปด (A→B→C→A→D→E→A) ต้องเปิด allowRevisit ของเส้นทางนั้น
+func validateRouteLinks(
+	routePathID string,
+	routeName string,
+	allowRevisit bool,
+	links []routeLink,
+	pairs map[string]pairEndpoints,
+) error {
+
+	fail := func(kind string, l routeLink, detail string) error {
+		return &RouteTopologyError{
+			RoutePathID:   routePathID,
+			RouteName:     routeName,
+			Kind:          kind,
+			Order:         l.Order,
+			StationPairID: l.StationPairID,
+			Detail:        detail,
+		}
+	}
+
+	if len(links) == 0 {
+		return &RouteTopologyError{
+			RoutePathID: routePathID,
+			RouteName:   routeName,
+			Kind:        TopologyEmptyRoute,
+			Detail:      "route has no orders",
+		}
+	}
+
+	sorted := make([]routeLink, len(links))
+	copy(sorted, links)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Order < sorted[j].Order
+	})
+
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].Order == sorted[i-1].Order {
+			return fail(TopologyDuplicateOrder, sorted[i], fmt.Sprintf(
+				"order %d is used by both %s and %s",
+				sorted[i].Order, sorted[i-1].StationPairID, sorted[i].StationPairID,
+			))
+		}
+	}
+
+	for _, l := range sorted {
+		if _, ok := pairs[l.StationPairID]; !ok {
+			return fail(TopologyUnknownPair, l, fmt.Sprintf(
+				"station pair %s does not belong to the network model", l.StationPairID,
+			))
+		}
+	}
+
+	// stations[k] คือ station ที่ถึงหลัง order k (stations[0] คือต้นทาง)
+	stations := []string{pairs[sorted[0].StationPairID].Fst}
+	visited := map[string]int{stations[0]: sorted[0].Order}
+	usedPair := make(map[pairEndpoints]int, len(sorted))
+	// mirror คือ index ใน stations ที่ขากลับกำลังย้อนไป (-1 = ไม่ได้ย้อนทาง)
+	mirror := -1
+
+	for i, l := range sorted {
+		p := pairs[l.StationPairID]
+
+		if p.Fst == p.Snd {
+			return fail(TopologyLoop, l, fmt.Sprintf(
+				"station pair %s starts and ends at station %s", l.StationPairID, p.Fst,
+			))
+		}
+
+		if i > 0 {
+			prev := sorted[i-1]
+			prevPair := pairs[prev.StationPairID]
+			if prev.StationPairID == l.StationPairID {
+				return fail(TopologyRepeatedPair, l, fmt.Sprintf(
+					"station pair %s (%s → %s) is repeated at orders %d and %d",
+					l.StationPairID, p.Fst, p.Snd, prev.Order, l.Order,
+				))
+			}
+			if prevPair.Snd != p.Fst {
+				return fail(TopologyGap, l, fmt.Sprintf(
+					"gap between order %d (%s → %s) and order %d (%s → %s)",
+					prev.Order, prevPair.Fst, prevPair.Snd, l.Order, p.Fst, p.Snd,
+				))
+			}
+		}
+
+		if at, used := usedPair[p]; used {
+			return fail(TopologyRepeatedPair, l, fmt.Sprintf(
+				"%s → %s is travelled twice (orders %d and %d)", p.Fst, p.Snd, at, l.Order,
+			))
+		}
+		usedPair[p] = l.Order
+
+		n := len(stations) // index ของ station ที่กำลังจะถึง
+		switch at, seen := visited[p.Snd]; {
+		case !seen:
+			mirror = -1
+		case mirror > 0 && stations[mirror-1] == p.Snd:
+			mirror-- // ย้อนทางต่อ
+		case n >= 2 && stations[n-2] == p.Snd:
+			mirror = n - 2 // กลับรถที่ station ก่อนหน้า
+		case i == len(sorted)-1 && p.Snd == stations[0]:
+			// ปิดวงที่ต้นทาง
+		case allowRevisit:
+			mirror = -1
+		default:
+			return fail(TopologyRevisit, l, fmt.Sprintf(
+				"station %s is visited again (first reached at order %d) without retracing the route; enable allow_revisit for a figure-eight route",
+				p.Snd, at,
+			))
+		}
+		stations = append(stations, p.Snd)
+		if _, seen := visited[p.Snd]; !seen {
+			visited[p.Snd] = l.Order
+		}
+	}
+
+	return nil
+}
+
+// ValidateRouteOrders ตรวจ topology ของ RoutePath (DTO) กับ station pair ของ configuration
+// ถ้า cfg ไม่ได้ส่ง StationPair มาเลย จะใช้ StationPair ที่แนบมากับ order แทน
+func ValidateRouteOrders(rp models.RoutePath, cfgPairs []models.StationPair) error {
+	pairs := make(map[string]pairEndpoints, len(cfgPairs))
+	for _, sp := range cfgPairs {
+		pairs[sp.StationPairID] = pairEndpoints{Fst: sp.FstStationID, Snd: sp.SndStationID}
+	}
+
+	links := make([]routeLink, 0, len(rp.Orders))
+	for _, o := range rp.Orders {
+		links = append(links, routeLink{Order: o.Order, StationPairID: o.StationPairID})
+		if _, ok := pairs[o.StationPairID]; !ok && len(cfgPairs) == 0 && o.StationPair.StationPairID == o.StationPairID {
+			pairs[o.StationPairID] = pairEndpoints{Fst: o.StationPair.FstStationID, Snd: o.StationPair.SndStationID}
+		}
+	}
+
+	return validateRouteLinks(rp.RoutePathID, rp.Name, rp.AllowRevisit, links, pairs)
+}
+
+// ValidateScenarioRouteTopology ตรวจทุก RoutePath ของ scenario ที่จะบันทึก
+// โดยดึง station pair จาก network model ของ configuration ที่ scenario อ้างอิง
+func ValidateScenarioRouteTopology(sd *model_database.ScenarioDetail) error {
+	if sd == nil || sd.RouteScenario == nil {
+		return nil
+	}
+
+	var cfg model_database.ConfigurationDetail
+	if err := config.DB.Select("id", "network_model_id").First(&cfg, "id = ?", sd.ConfigurationDetailID).Error; err != nil {
+		return fmt.Errorf("configuration_detail_id %s: %w", sd.ConfigurationDetailID, err)
+	}
+
+	pairIDs := make([]string, 0)
+	for _, rp := range sd.RouteScenario.RoutePaths {
+		for _, o := range rp.Orders {
+			pairIDs = append(pairIDs, strings.TrimSpace(o.StationPairID))
+		}
+	}
+
+	pairs := make(map[string]pairEndpoints)
+	if len(pairIDs) > 0 {
+		var dbPairs []model_database.StationPair
+		err := config.DB.
+			Select("id", "fst_station_id", "snd_station_id").
+			Where("network_model_id = ? AND id IN ?", cfg.NetworkModelID, pairIDs).
+			Find(&dbPairs).Error
+		if err != nil {
+			return fmt.Errorf("load station pairs: %w", err)
+		}
+		for _, sp := range dbPairs {
+			pairs[sp.ID] = pairEndpoints{Fst: sp.FstStationID, Snd: sp.SndStationID}
+		}
+	}
+
+	for _, rp := range sd.RouteScenario.RoutePaths {
+		links := make([]routeLink, 0, len(rp.Orders))
+		for _, o := range rp.Orders {
+			links = append(links, routeLink{Order: o.Order, StationPairID: strings.TrimSpace(o.StationPairID)})
+		}
+		if err := validateRouteLinks(rp.ID, rp.Name, rp.AllowRevisit, links, pairs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testRouteLinks สร้าง order และ pair จากลำดับ station เช่น "A B C B A" (pair id คือ "A-B")
+func testRouteLinks(route string) ([]routeLink, map[string]pairEndpoints) {
+	stations := strings.Fields(route)
+	links := make([]routeLink, 0, len(stations)-1)
+	pairs := make(map[string]pairEndpoints)
+	for i := 1; i < len(stations); i++ {
+		id := stations[i-1] + "-" + stations[i]
+		links = append(links, routeLink{Order: i, StationPairID: id})
+		pairs[id] = pairEndpoints{Fst: stations[i-1], Snd: stations[i]}
+	}
+	return links, pairs
+}
+
+func TestValidateRouteLinks(t *testing.T) {
+	cases := []struct {
+		name         string
+		route        string
+		allowRevisit bool
+		kind         string // "" = ผ่าน
+	}{
+		{"straight", "A B C D", false, ""},
+		{"closed loop", "A B C A", false, ""},
+		{"out and back", "A B C B A", false, ""},
+		{"short out and back", "A B A", false, ""},
+		{"spur", "A B C B D", false, ""},
+		{"out and back opted in", "A B C B A", true, ""},
+		{"figure eight", "A B C A D E A", false, TopologyRevisit},
+		{"figure eight opted in", "A B C A D E A", true, ""},
+		{"lollipop", "A B C D B A", false, TopologyRevisit},
+		{"loop continuing past the origin", "A B C A B", false, TopologyRevisit},
+		{"loop continuing past the origin opted in", "A B C A B", true, TopologyRepeatedPair},
+		{"same direction twice even when opted in", "A B C A B D", true, TopologyRepeatedPair},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			links, pairs := testRouteLinks(tc.route)
+			err := validateRouteLinks("r1", tc.name, tc.allowRevisit, links, pairs)
+
+			if tc.kind == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var topoErr *RouteTopologyError
+			if !errors.As(err, &topoErr) || topoErr.Kind != tc.kind {
+				t.Fatalf("err = %v, want kind %s", err, tc.kind)
+			}
+		})
+	}
+}
+
+func TestValidateRouteLinksReportsOrder(t *testing.T) {
+	links, pairs := testRouteLinks("A B C A D E A")
+	err := validateRouteLinks("r1", "eight", false, links, pairs)
+
+	var topoErr *RouteTopologyError
+	if !errors.As(err, &topoErr) {
+		t.Fatalf("err = %v, want a RouteTopologyError", err)
+	}
+	// กลับมา A ครั้งแรกกลางทางที่ order 3 (C → A)
+	if topoErr.Order != 3 || topoErr.StationPairID != "C-A" {
+		t.Errorf("error at order %d (%s), want order 3 (C-A)", topoErr.Order, topoErr.StationPairID)
+	}
+}
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"DeSS_T_Backend-go/models"
+)
+
+/* ─────────────────────────── ROUTING PROVIDER ─────────────────────────── */
+
+const (
+	RoutingProviderORS       = "ors"
+	RoutingProviderOSRM      = "osrm"
+	RoutingProviderValhalla  = "valhalla"
+	RoutingProviderHaversine = "haversine"
+	RoutingProviderOSM       = "osm"
+)
+
+var (
+	// ErrUnknownRoutingProvider คือชื่อ provider ที่ระบบไม่รู้จัก
+	ErrUnknownRoutingProvider = errors.New("unknown routing provider")
+	// ErrRoutingProviderUnavailable คือ provider ที่ยังไม่ได้ตั้งค่า (เช่น ไม่มี API key / URL ของ server)
+	ErrRoutingProviderUnavailable = errors.New("routing provider is not configured")
+)
+
+// RoutingMatrix คือระยะทาง (เมตร) และเวลาเดินทาง (วินาที) ระหว่างทุกคู่ station ตามลำดับที่ส่งเข้าไป
+type RoutingMatrix struct {
+	Distances [][]float64 `json:"distances"`
+	Durations [][]float64 `json:"durations"`
+}
+
+// RoutingProvider คือแหล่งคำนวณ matrix และเส้นทางบนถนน (พิกัดเป็น [lon, lat] ทั้งหมด)
+// Matrix คือทุกคู่ของ stations, Table คือจาก sources แต่ละตัวไป destinations แต่ละตัว
+type RoutingProvider interface {
+	Name() string
+	Matrix(stations []models.StationDetail) (*RoutingMatrix, error)
+	Table(sources, destinations []models.StationDetail) (*RoutingMatrix, error)
+	Route(start, end [2]float64) ([][2]float64, error)
+}
+
+// NewRoutingProvider สร้าง provider ตามชื่อ ค่าตั้งของแต่ละตัวอ่านจาก environment
+//   - ors:       ORS_API_KEY, ORS_BASE_URL (ค่าเริ่มต้น api.openrouteservice.org), ORS_PROFILE (ค่าเริ่มต้น driving-car)
+//   - osrm:      OSRM_URL, OSRM_PROFILE (ค่าเริ่มต้น driving)
+//   - valhalla:  VALHALLA_URL, VALHALLA_COSTING (ค่าเริ่มต้น auto)
+//   - osm:       OSM_EXTRACT_PATH (.osm.pbf / .osm ในเครื่อง ไม่ต้องต่อ network)
+//   - haversine: ROUTING_DETOUR_FACTOR, ROUTING_OFFLINE_SPEED_KMH (ไม่ต้องต่อ network)
+//
+// ors / osrm / valhalla ผ่าน travel cache ใน Postgres (TRAVEL_CACHE_TTL) ส่วน osm / haversine คำนวณในเครื่องไม่ต้อง cache
+func NewRoutingProvider(name string) (RoutingProvider, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case RoutingProviderORS:
+		key := strings.TrimSpace(os.Getenv("ORS_API_KEY"))
+		if key == "" {
+			return nil, fmt.Errorf("%w: ORS_API_KEY is missing", ErrRoutingProviderUnavailable)
+		}
+		return withTravelCache(orsProvider{key: key}, orsBaseURL(), orsProfile()), nil
+	case RoutingProviderOSRM:
+		return newOSRMProvider()
+	case RoutingProviderValhalla:
+		return newValhallaProvider()
+	case RoutingProviderOSM:
+		return newOSMProvider()
+	case RoutingProviderHaversine, "offline":
+		return newHaversineProvider(), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownRoutingProvider, name)
+	}
+}
+
+// DefaultRoutingProviderName คือ provider จาก ROUTING_PROVIDER (หรือ MATRIX_MODE แบบเดิม) ค่าเริ่มต้น ors
+func DefaultRoutingProviderName() string {
+	for _, env := range []string{"ROUTING_PROVIDER", "MATRIX_MODE"} {
+		if v := strings.ToLower(strings.TrimSpace(os.Getenv(env))); v != "" {
+			return v
+		}
+	}
+	return RoutingProviderORS
+}
+
+// routingFallbackEnabled คือ ROUTING_FALLBACK=haversine (หรือ true / 1) อนุญาตให้ใช้ระยะเส้นตรงแทน provider ของระบบที่ยังไม่ได้ตั้งค่า
+func routingFallbackEnabled() bool {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("ROUTING_FALLBACK"))) {
+	case RoutingProviderHaversine, "true", "1":
+		return true
+	}
+	return false
+}
+
+// ResolveRoutingProvider เลือก provider ของ request
+// requested ว่าง = ใช้ค่าตั้งของระบบ ถ้าค่าตั้งนั้นยังใช้ไม่ได้จะคืน error
+// เว้นแต่เปิด ROUTING_FALLBACK ไว้ จึงถอยไปใช้ haversine และคืน fallback = true ให้ผู้เรียกแจ้งใน response
+// ถ้าผู้ใช้ระบุ provider เองแล้วใช้ไม่ได้จะคืน error เสมอ
+func ResolveRoutingProvider(requested string) (provider RoutingProvider, fallback bool, err error) {
+	if strings.TrimSpace(requested) != "" {
+		provider, err = NewRoutingProvider(requested)
+		return provider, false, err
+	}
+
+	name := DefaultRoutingProviderName()
+	provider, err = NewRoutingProvider(name)
+	if errors.Is(err, ErrRoutingProviderUnavailable) && routingFallbackEnabled() {
+		log.Printf("⚠️ routing provider %s unavailable (%v) - using offline haversine estimate", name, err)
+		return newHaversineProvider(), true, nil
+	}
+	return provider, false, err
+}
+
+/* ───────────────────────────── SHARED HELPERS ───────────────────────────── */
+
+func newRoutingMatrix(rows, cols int) *RoutingMatrix {
+	m := &RoutingMatrix{Distances: make([][]float64, rows), Durations: make([][]float64, rows)}
+	for i := 0; i < rows; i++ {
+		m.Distances[i] = make([]float64, cols)
+		m.Durations[i] = make([]float64, cols)
+	}
+	return m
+}
+
+func (m *RoutingMatrix) checkShape(rows, cols int) error {
+	if len(m.Distances) != rows || len(m.Durations) != rows {
+		return fmt.Errorf("matrix has %d/%d rows, expected %d", len(m.Distances), len(m.Durations), rows)
+	}
+	for i := 0; i < rows; i++ {
+		if len(m.Distances[i]) != cols || len(m.Durations[i]) != cols {
+			return fmt.Errorf("matrix row %d has %d/%d columns, expected %d", i, len(m.Distances[i]), len(m.Durations[i]), cols)
+		}
+	}
+	return nil
+}
+
+// chunkedTable แบ่ง sources / destinations เป็น block ละ size ตัวตามข้อจำกัดจำนวน location ต่อ request ของ routing server
+// ถ้า sources กับ destinations เป็น slice เดียวกันและไม่เกิน size, fetch ได้ dst == nil (ขอทุกคู่ใน request เดียว)
+func chunkedTable(sources, destinations []models.StationDetail, size int, fetch func(src, dst []models.StationDetail) (*RoutingMatrix, error)) (*RoutingMatrix, error) {
+	rows, cols := len(sources), len(destinations)
+	if rows == 0 || cols == 0 {
+		return newRoutingMatrix(rows, cols), nil
+	}
+
+	if rows <= size && rows == cols && &sources[0] == &destinations[0] {
+		m, err := fetch(sources, nil)
+		if err != nil {
+			return nil, err
+		}
+		if err := m.checkShape(rows, cols); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+
+	out := newRoutingMatrix(rows, cols)
+	for si := 0; si < rows; si += size {
+		srcEnd := minInt(si+size, rows)
+		for di := 0; di < cols; di += size {
+			dstEnd := minInt(di+size, cols)
+
+			chunk, err := fetch(sources[si:srcEnd], destinations[di:dstEnd])
+			if err == nil {
+				err = chunk.checkShape(srcEnd-si, dstEnd-di)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("matrix chunk (%d-%d,%d-%d) failed: %w", si, srcEnd, di, dstEnd, err)
+			}
+
+			for i := range chunk.Distances {
+				copy(out.Distances[si+i][di:dstEnd], chunk.Distances[i])
+				copy(out.Durations[si+i][di:dstEnd], chunk.Durations[i])
+			}
+		}
+	}
+	return out, nil
+}
+
+// doRoutingRequest ส่ง request แล้วคืน body เมื่อได้ 200 (label ใช้นำหน้าข้อความ error)
+func doRoutingRequest(client *http.Client, req *http.Request, label string) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s request failed: %v", label, err)
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s error (%d): %s", label, resp.StatusCode, string(data))
+	}
+	return data, nil
+}
+
+func postRoutingJSON(client *http.Client, url string, body interface{}, label string) ([]byte, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return doRoutingRequest(client, req, label)
+}
+
+func envFloat(name string, def float64) float64 {
+	v, err := strconv.ParseFloat(strings.TrimSpace(os.Getenv(name)), 64)
+	if err != nil || v <= 0 {
+		return def
+	}
+	return v
+}
+
+/* ─────────────────────────────── ORS ─────────────────────────────── */
+
+type orsProvider struct {
+	key string
+}
+
+func (p orsProvider) Name() string { return RoutingProviderORS }
+
+func (p orsProvider) Matrix(stations []models.StationDetail) (*RoutingMatrix, error) {
+	return p.Table(stations, stations)
+}
+
+func (p orsProvider) Table(sources, destinations []models.StationDetail) (*RoutingMatrix, error) {
+	return OrsTable(sources, destinations, p.key)
+}
+
+func (p orsProvider) Route(start, end [2]float64) ([][2]float64, error) {
+	return OrsRoute(start, end, p.key)
+}
+
+/* ───────────────────────────── HAVERSINE ───────────────────────────── */
+
+const (
+	defaultRoutingDetourFactor = 1.3
+	defaultOfflineSpeedKmh     = 30.0
+)
+
+// haversineProvider ประมาณระยะถนนจากระยะเส้นตรง × detour factor และเวลาจากความเร็วเฉลี่ย
+// ใช้ได้โดยไม่ต้องต่อ network เส้นทางเป็นเส้นตรงระหว่างจุด
+type haversineProvider struct {
+	detour   float64
+	speedKmh float64
+}
+
+func newHaversineProvider() haversineProvider {
+	return haversineProvider{
+		detour:   envFloat("ROUTING_DETOUR_FACTOR", defaultRoutingDetourFactor),
+		speedKmh: envFloat("ROUTING_OFFLINE_SPEED_KMH", defaultOfflineSpeedKmh),
+	}
+}
+
+func (p haversineProvider) Name() string { return RoutingProviderHaversine }
+
+func (p haversineProvider) Matrix(stations []models.StationDetail) (*RoutingMatrix, error) {
+	return p.Table(stations, stations)
+}
+
+func (p haversineProvider) Table(sources, destinations []models.StationDetail) (*RoutingMatrix, error) {
+	m := newRoutingMatrix(len(sources), len(destinations))
+	for i, a := range sources {
+		for j, b := range destinations {
+			ac, bc := a.Location.Coordinates, b.Location.Coordinates
+			d := models.HaversineMeters(ac[1], ac[0], bc[1], bc[0]) * p.detour
+			m.Distances[i][j] = d
+			m.Durations[i][j] = d / (p.speedKmh / 3.6)
+		}
+	}
+	return m, nil
+}
+
+func (p haversineProvider) Route(start, end [2]float64) ([][2]float64, error) {
+	return [][2]float64{start, end}, nil
+}
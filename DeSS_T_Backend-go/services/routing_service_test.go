@@ -0,0 +1,185 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+
+	"DeSS_T_Backend-go/models"
+)
+
+// testStations สร้าง station n ตัวเรียงตาม latitude (lat = index × 0.01) ที่ longitude เดียวกัน
+// fake server ใช้ latitude ย้อนกลับเป็น index ได้
+func testStations(prefix string, n int, lon float64) []models.StationDetail {
+	out := make([]models.StationDetail, n)
+	for i := range out {
+		lat := float64(i) * 0.01
+		out[i] = models.StationDetail{
+			StationDetailID: fmt.Sprintf("%s%d", prefix, i),
+			Location:        models.GeoPoint{Type: "Point", Coordinates: [2]float64{lon, lat}},
+			Lat:             lat,
+			Lon:             lon,
+		}
+	}
+	return out
+}
+
+// testIndex คืน index ของ station จาก latitude ที่ fake server ได้รับ
+func testIndex(lat float64) int {
+	return int(math.Round(lat / 0.01))
+}
+
+// testDuration คือค่าที่ fake server ตอบสำหรับคู่ (i, j) ตรวจได้ว่า cell ถูกวางถูกตำแหน่งหลังต่อ chunk
+func testDuration(i, j int) float64 {
+	return float64(i*1000 + j)
+}
+
+func checkTestMatrix(t *testing.T, m *RoutingMatrix, rows, cols int, distanceScale float64) {
+	t.Helper()
+	if err := m.checkShape(rows, cols); err != nil {
+		t.Fatalf("shape: %v", err)
+	}
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			if got, want := m.Durations[i][j], testDuration(i, j); got != want {
+				t.Fatalf("duration[%d][%d] = %v, want %v", i, j, got, want)
+			}
+			if got, want := m.Distances[i][j], testDuration(i, j)*distanceScale; math.Abs(got-want) > 1e-6 {
+				t.Fatalf("distance[%d][%d] = %v, want %v", i, j, got, want)
+			}
+		}
+	}
+}
+
+func TestChunkedTableStitchesBlocks(t *testing.T) {
+	src := testStations("S", 7, 100)
+	dst := testStations("D", 5, 101)
+
+	calls := 0
+	m, err := chunkedTable(src, dst, 3, func(s, d []models.StationDetail) (*RoutingMatrix, error) {
+		calls++
+		if d == nil {
+			t.Fatal("separate sources and destinations must not be fetched as a square matrix")
+		}
+		if len(s) > 3 || len(d) > 3 {
+			t.Fatalf("chunk %dx%d is larger than 3", len(s), len(d))
+		}
+		chunk := newRoutingMatrix(len(s), len(d))
+		for i := range s {
+			for j := range d {
+				v := testDuration(testIndex(s[i].Lat), testIndex(d[j].Lat))
+				chunk.Durations[i][j], chunk.Distances[i][j] = v, v
+			}
+		}
+		return chunk, nil
+	})
+	if err != nil {
+		t.Fatalf("chunkedTable: %v", err)
+	}
+	if calls != 6 { // ceil(7/3) × ceil(5/3)
+		t.Errorf("calls = %d, want 6", calls)
+	}
+	checkTestMatrix(t, m, 7, 5, 1)
+}
+
+func TestChunkedTableSquareMatrixInOneRequest(t *testing.T) {
+	stations := testStations("S", 4, 100)
+	calls := 0
+	m, err := chunkedTable(stations, stations, 10, func(s, d []models.StationDetail) (*RoutingMatrix, error) {
+		calls++
+		if d != nil {
+			t.Fatal("a matrix within the chunk size should be fetched without destinations")
+		}
+		return newRoutingMatrix(len(s), len(s)), nil
+	})
+	if err != nil {
+		t.Fatalf("chunkedTable: %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+	if err := m.checkShape(4, 4); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestChunkedTableRejectsWrongShape(t *testing.T) {
+	src := testStations("S", 4, 100)
+	dst := testStations("D", 4, 101)
+
+	for name, fetch := range map[string]func(s, d []models.StationDetail) (*RoutingMatrix, error){
+		"missing row": func(s, d []models.StationDetail) (*RoutingMatrix, error) {
+			return newRoutingMatrix(len(s)-1, len(d)), nil
+		},
+		"missing column": func(s, d []models.StationDetail) (*RoutingMatrix, error) {
+			m := newRoutingMatrix(len(s), len(d))
+			m.Durations[0] = m.Durations[0][:len(d)-1]
+			return m, nil
+		},
+	} {
+		if _, err := chunkedTable(src, dst, 2, fetch); err == nil {
+			t.Errorf("%s: expected a shape error", name)
+		}
+	}
+
+	wantErr := errors.New("server down")
+	_, err := chunkedTable(src, dst, 2, func(s, d []models.StationDetail) (*RoutingMatrix, error) {
+		return nil, wantErr
+	})
+	if !errors.Is(err, wantErr) {
+		t.Errorf("err = %v, want wrapped %v", err, wantErr)
+	}
+}
+
+func TestHaversineProvider(t *testing.T) {
+	t.Setenv("ROUTING_DETOUR_FACTOR", "")
+	t.Setenv("ROUTING_OFFLINE_SPEED_KMH", "36")
+	p := newHaversineProvider()
+
+	stations := testStations("S", 2, 100)
+	m, err := p.Matrix(stations)
+	if err != nil {
+		t.Fatalf("Matrix: %v", err)
+	}
+	want := models.HaversineMeters(0, 100, 0.01, 100) * defaultRoutingDetourFactor
+	if math.Abs(m.Distances[0][1]-want) > 1e-6 {
+		t.Errorf("distance = %v, want %v", m.Distances[0][1], want)
+	}
+	if math.Abs(m.Durations[0][1]-want/10) > 1e-6 { // 36 km/h = 10 m/s
+		t.Errorf("duration = %v, want %v", m.Durations[0][1], want/10)
+	}
+	if m.Distances[0][0] != 0 {
+		t.Errorf("distance to self = %v", m.Distances[0][0])
+	}
+}
+
+func TestResolveRoutingProviderFallbackIsOptIn(t *testing.T) {
+	t.Setenv("ROUTING_PROVIDER", RoutingProviderOSRM)
+	t.Setenv("OSRM_URL", "")
+
+	t.Setenv("ROUTING_FALLBACK", "")
+	if _, _, err := ResolveRoutingProvider(""); !errors.Is(err, ErrRoutingProviderUnavailable) {
+		t.Fatalf("without ROUTING_FALLBACK: err = %v, want ErrRoutingProviderUnavailable", err)
+	}
+
+	t.Setenv("ROUTING_FALLBACK", "haversine")
+	p, fallback, err := ResolveRoutingProvider("")
+	if err != nil {
+		t.Fatalf("with ROUTING_FALLBACK: %v", err)
+	}
+	if !fallback || p.Name() != RoutingProviderHaversine {
+		t.Errorf("provider = %s, fallback = %v, want haversine fallback", p.Name(), fallback)
+	}
+
+	// provider ที่ผู้ใช้ระบุเองไม่ถอยไป haversine
+	if _, _, err := ResolveRoutingProvider(RoutingProviderOSRM); !errors.Is(err, ErrRoutingProviderUnavailable) {
+		t.Errorf("explicit provider: err = %v, want ErrRoutingProviderUnavailable", err)
+	}
+}
+
+func TestResolveRoutingProviderUnknown(t *testing.T) {
+	if _, _, err := ResolveRoutingProvider("teleport"); !errors.Is(err, ErrUnknownRoutingProvider) {
+		t.Errorf("err = %v, want ErrUnknownRoutingProvider", err)
+	}
+}
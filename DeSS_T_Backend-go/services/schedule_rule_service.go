@@ -0,0 +1,164 @@
+package services
+
+import (
+	"DeSS_T_Backend-go/config"
+	"DeSS_T_Backend-go/model_database"
+	"DeSS_T_Backend-go/models"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidScheduleRule คือกฎ headway ที่สร้างตารางเวลาไม่ได้ (controller ตอบ 400)
+var ErrInvalidScheduleRule = errors.New("invalid schedule rule")
+
+// hasHeadwayRule บอกว่า schedule นี้สร้างจากกฎ headway (ไม่ใช่ป้อนเวลาออกรถเอง)
+func hasHeadwayRule(sch model_database.ScheduleData) bool {
+	return len(sch.HeadwayBands) > 0 || sch.FirstDeparture != "" || sch.LastDeparture != ""
+}
+
+// applyHeadwayRule สร้าง ScheduleList ใหม่จากกฎ headway ของ schedule
+func applyHeadwayRule(sch *model_database.ScheduleData) error {
+	sch.FirstDeparture = strings.TrimSpace(sch.FirstDeparture)
+	sch.LastDeparture = strings.TrimSpace(sch.LastDeparture)
+
+	bands := make([]models.ScheduleHeadwayBand, 0, len(sch.HeadwayBands))
+	for i := range sch.HeadwayBands {
+		sch.HeadwayBands[i].TimeRange = strings.ReplaceAll(sch.HeadwayBands[i].TimeRange, " ", "")
+		bands = append(bands, models.ScheduleHeadwayBand{
+			TimeRange:      sch.HeadwayBands[i].TimeRange,
+			HeadwayMinutes: sch.HeadwayBands[i].HeadwayMinutes,
+		})
+	}
+
+	departures, err := models.GenerateHeadwaySchedule(sch.FirstDeparture, sch.LastDeparture, bands)
+	if err != nil {
+		return fmt.Errorf("%w: route_path_id %s: %v", ErrInvalidScheduleRule, sch.RoutePathID, err)
+	}
+	sch.ScheduleList = strings.Join(departures, ",")
+	return nil
+}
+
+// ApplyScheduleRules สร้าง ScheduleList ของทุก schedule ที่มีกฎ headway (เรียกก่อนบันทึก scenario)
+// schedule ที่ไม่มีกฎใช้ ScheduleList ตามที่ส่งมา
+func ApplyScheduleRules(schedules []model_database.ScheduleData) error {
+	var errs []error
+	for i := range schedules {
+		if !hasHeadwayRule(schedules[i]) {
+			continue
+		}
+		if err := applyHeadwayRule(&schedules[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SaveScheduleRule บันทึกกฎ headway ของเส้นทางใน scenario แล้วสร้าง ScheduleList ใหม่
+// schedule เดิมของ route + day type เดียวกันจะถูกแทนที่ (กฎเดิมถูกลบ)
+func SaveScheduleRule(scenarioDetailID string, rule models.HeadwayRule) (models.ScheduleData, error) {
+	var sd model_database.ScenarioDetail
+	if err := config.DB.Select("id", "bus_scenario_id", "route_scenario_id").
+		First(&sd, "id = ?", scenarioDetailID).Error; err != nil {
+		return models.ScheduleData{}, err
+	}
+
+	routePathID := strings.TrimSpace(rule.RoutePathID)
+	var count int64
+	if err := config.DB.Model(&model_database.RoutePath{}).
+		Where("id = ? AND route_scenario_id = ?", routePathID, sd.RouteScenarioID).
+		Count(&count).Error; err != nil {
+		return models.ScheduleData{}, err
+	}
+	if count == 0 {
+		return models.ScheduleData{}, fmt.Errorf("%w: route_path_id %q is not part of this scenario", ErrInvalidScheduleRule, routePathID)
+	}
+
+	dayType, err := models.NormalizeDayType(rule.DayType)
+	if err != nil {
+		return models.ScheduleData{}, fmt.Errorf("%w: %v", ErrInvalidScheduleRule, err)
+	}
+
+	sch := model_database.ScheduleData{
+		RoutePathID:    routePathID,
+		BusScenarioID:  sd.BusScenarioID,
+		DayType:        dayType,
+		FirstDeparture: rule.FirstDeparture,
+		LastDeparture:  rule.LastDeparture,
+	}
+	for _, b := range rule.HeadwayBands {
+		sch.HeadwayBands = append(sch.HeadwayBands, model_database.ScheduleHeadwayBand{
+			TimeRange:      b.TimeRange,
+			HeadwayMinutes: b.HeadwayMinutes,
+		})
+	}
+	if err := applyHeadwayRule(&sch); err != nil {
+		return models.ScheduleData{}, err
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var existing model_database.ScheduleData
+		err := tx.Where("bus_scenario_id = ? AND route_path_id = ? AND day_type = ?", sd.BusScenarioID, routePathID, dayType).
+			First(&existing).Error
+		switch {
+		case err == nil:
+			sch.ID = existing.ID
+			if err := tx.Where("schedule_data_id = ?", existing.ID).Delete(&model_database.ScheduleHeadwayBand{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&existing).Omit("RoutePath", "BusScenario", "HeadwayBands").Updates(map[string]interface{}{
+				"schedule_list":   sch.ScheduleList,
+				"first_departure": sch.FirstDeparture,
+				"last_departure":  sch.LastDeparture,
+			}).Error; err != nil {
+				return fmt.Errorf("update schedule data: %w", err)
+			}
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			sch.ID = uuid.New().String()
+			if err := tx.Omit("RoutePath", "BusScenario", "HeadwayBands").Create(&sch).Error; err != nil {
+				return fmt.Errorf("create schedule data: %w", err)
+			}
+		default:
+			return err
+		}
+
+		for i := range sch.HeadwayBands {
+			band := &sch.HeadwayBands[i]
+			band.ID = uuid.New().String()
+			band.ScheduleDataID = sch.ID
+			if err := tx.Omit("ScheduleData").Create(band).Error; err != nil {
+				return fmt.Errorf("create schedule headway band: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return models.ScheduleData{}, err
+	}
+
+	return scheduleDataToDTO(sch), nil
+}
+
+// scheduleDataToDTO แปลง ScheduleData ของ DB (พร้อมกฎ headway ถ้ามี) เป็น DTO
+func scheduleDataToDTO(sch model_database.ScheduleData) models.ScheduleData {
+	dto := models.ScheduleData{
+		ScheduleDataID: sch.ID,
+		ScheduleList:   sch.ScheduleList,
+		RoutePathID:    sch.RoutePathID,
+		BusScenarioID:  sch.BusScenarioID,
+		DayType:        sch.DayType,
+		FirstDeparture: sch.FirstDeparture,
+		LastDeparture:  sch.LastDeparture,
+	}
+	for _, b := range sch.HeadwayBands {
+		dto.HeadwayBands = append(dto.HeadwayBands, models.ScheduleHeadwayBand{
+			ScheduleHeadwayBandID: b.ID,
+			TimeRange:             b.TimeRange,
+			HeadwayMinutes:        b.HeadwayMinutes,
+		})
+	}
+	return dto
+}
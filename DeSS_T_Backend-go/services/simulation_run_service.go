@@ -0,0 +1,149 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"DeSS_T_Backend-go/config"
+	"DeSS_T_Backend-go/model_database"
+	"DeSS_T_Backend-go/models"
+)
+
+// SaveSimulationRun เก็บผลลัพธ์จาก Python ไว้ใน DB เพื่อใช้อ้างอิงภายหลัง (calibration, animation)
+func SaveSimulationRun(
+	scenarioDetailID string,
+	timePeriods string,
+	timeSlot string,
+	dayType string,
+	result map[string]interface{},
+) (model_database.SimulationRun, error) {
+
+	payload, err := json.Marshal(result)
+	if err != nil {
+		return model_database.SimulationRun{}, fmt.Errorf("encode simulation result: %w", err)
+	}
+
+	run := model_database.SimulationRun{
+		ID:          uuid.New().String(),
+		TimePeriods: timePeriods,
+		TimeSlot:    timeSlot,
+		DayType:     dayType,
+		Result:      string(payload),
+		CreatedAt:   time.Now(),
+	}
+	if id := strings.TrimSpace(scenarioDetailID); id != "" {
+		run.ScenarioDetailID = &id
+	}
+
+	if err := config.DB.Create(&run).Error; err != nil {
+		return model_database.SimulationRun{}, fmt.Errorf("save simulation run: %w", err)
+	}
+
+	return run, nil
+}
+
+// GetSimulationRunByID ดึง run ที่เคยเก็บไว้ พร้อม decode ผลลัพธ์เป็น SimulationResponse
+func GetSimulationRunByID(runID string) (model_database.SimulationRun, models.SimulationResponse, error) {
+	var run model_database.SimulationRun
+	if err := config.DB.First(&run, "id = ?", runID).Error; err != nil {
+		return run, models.SimulationResponse{}, err
+	}
+
+	response, err := decodeSimulationResponse([]byte(run.Result))
+	if err != nil {
+		return run, models.SimulationResponse{}, err
+	}
+
+	return run, response, nil
+}
+
+// ExecuteSimulation ส่ง request ที่แปลงแล้วไป Python และสรุปผลตาม time window (ถ้ามี)
+func ExecuteSimulation(data models.SimulationRequest) (map[string]interface{}, error) {
+	var result map[string]interface{}
+	var err error
+	if len(data.RunPeriods) > 1 {
+		result, err = executeSimulationSegments(data)
+	} else {
+		result, err = CallPythonSimulation(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data.TimeWindows) > 0 {
+		response, err := simulationResponseFromMap(result)
+		if err != nil {
+			return nil, err
+		}
+		result["window_results"] = BuildWindowResults(response.SimulationResult, data.TimeWindows)
+	}
+	result["applied_bus_bands"] = AppliedBusBands(data)
+
+	return result, nil
+}
+
+// RunAndStoreSimulation แปลง request → ตรวจจำนวนรถ (CheckRunFleetFeasibility) → เรียก Python → เก็บ run ลง DB
+func RunAndStoreSimulation(req models.ProjectSimulationRequest) (model_database.SimulationRun, models.SimulationResponse, error) {
+	transformedData, err := TransformSimulationRequest(
+		req.ScenarioDetail,
+		req.ConfigurationDetail,
+		req.TimePeriods,
+		req.TimeSlot,
+		req.TimeWindows,
+		req.DayType,
+	)
+	if err != nil {
+		return model_database.SimulationRun{}, models.SimulationResponse{}, err
+	}
+
+	fleetWarnings, err := CheckRunFleetFeasibility(transformedData, req.AllowInfeasibleSchedule)
+	if err != nil {
+		return model_database.SimulationRun{}, models.SimulationResponse{}, err
+	}
+
+	result, err := ExecuteSimulation(transformedData)
+	if err != nil {
+		return model_database.SimulationRun{}, models.SimulationResponse{}, err
+	}
+	if len(fleetWarnings) > 0 {
+		result["fleet_warnings"] = fleetWarnings
+	}
+
+	run, err := SaveSimulationRun(
+		req.ScenarioDetail.ScenarioDetailID,
+		transformedData.TimePeriod,
+		req.TimeSlot,
+		transformedData.DayType,
+		result,
+	)
+	if err != nil {
+		return model_database.SimulationRun{}, models.SimulationResponse{}, err
+	}
+
+	response, err := decodeSimulationResponse([]byte(run.Result))
+	if err != nil {
+		return run, models.SimulationResponse{}, err
+	}
+
+	return run, response, nil
+}
+
+func decodeSimulationResponse(raw []byte) (models.SimulationResponse, error) {
+	var response models.SimulationResponse
+	if err := json.Unmarshal(raw, &response); err != nil {
+		return response, fmt.Errorf("decode simulation result: %w", err)
+	}
+	return response, nil
+}
+
+func simulationResponseFromMap(result map[string]interface{}) (models.SimulationResponse, error) {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return models.SimulationResponse{}, fmt.Errorf("encode simulation result: %w", err)
+	}
+	return decodeSimulationResponse(raw)
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"DeSS_T_Backend-go/models"
+)
+
+// BuildWindowResults รวม slot_results ของ engine เป็นผลลัพธ์ต่อ time window ที่ตั้งชื่อไว้
+// slot จะถูกนับเข้า window เมื่อเวลาเริ่มของ slot (HH:MM ตัวแรกใน slot_name) อยู่ใน window
+func BuildWindowResults(
+	sim models.SimulationResult,
+	windows []models.TimeWindow,
+) []models.SimulationWindowResult {
+
+	results := make([]models.SimulationWindowResult, 0, len(windows))
+
+	for _, w := range windows {
+		start, end, err := parsePeriod(w.Period)
+		if err != nil {
+			continue
+		}
+
+		wr := models.SimulationWindowResult{
+			Name:          w.Name,
+			Period:        w.Period,
+			SlotNames:     []string{},
+			ResultStation: []models.ResultStation{},
+			ResultRoute:   []models.ResultRoute{},
+		}
+
+		stationSum := make(map[string]*models.ResultStation)
+		stationCount := make(map[string]int)
+		routeSum := make(map[string]*models.ResultRoute)
+		routeCount := make(map[string]int)
+
+		for _, slot := range sim.SlotResults {
+			slotStart, ok := slotStartMinute(slot.SlotName)
+			if !ok || slotStart < start || slotStart >= end {
+				continue
+			}
+			wr.SlotNames = append(wr.SlotNames, slot.SlotName)
+
+			for _, st := range slot.ResultStation {
+				acc, exists := stationSum[st.StationName]
+				if !exists {
+					acc = &models.ResultStation{StationName: st.StationName}
+					stationSum[st.StationName] = acc
+				}
+				acc.AverageWaitingTime += st.AverageWaitingTime
+				acc.AverageQueueLength += st.AverageQueueLength
+				stationCount[st.StationName]++
+			}
+
+			for _, rt := range slot.ResultRoute {
+				acc, exists := routeSum[rt.RouteID]
+				if !exists {
+					acc = &models.ResultRoute{RouteID: rt.RouteID}
+					routeSum[rt.RouteID] = acc
+				}
+				acc.AverageUtilization += rt.AverageUtilization
+				acc.AverageTravelTime += rt.AverageTravelTime
+				acc.AverageTravelDistance += rt.AverageTravelDistance
+				acc.AverageWaitingTime += rt.AverageWaitingTime
+				acc.AverageQueueLength += rt.AverageQueueLength
+				acc.CustomersCount += rt.CustomersCount
+				routeCount[rt.RouteID]++
+			}
+		}
+
+		for name, acc := range stationSum {
+			n := float64(stationCount[name])
+			acc.AverageWaitingTime /= n
+			acc.AverageQueueLength /= n
+			wr.ResultStation = append(wr.ResultStation, *acc)
+		}
+		sort.Slice(wr.ResultStation, func(i, j int) bool {
+			return wr.ResultStation[i].StationName < wr.ResultStation[j].StationName
+		})
+
+		for id, acc := range routeSum {
+			n := float64(routeCount[id])
+			acc.AverageUtilization /= n
+			acc.AverageTravelTime /= n
+			acc.AverageTravelDistance /= n
+			acc.AverageWaitingTime /= n
+			acc.AverageQueueLength /= n
+			wr.ResultRoute = append(wr.ResultRoute, *acc)
+		}
+		sort.Slice(wr.ResultRoute, func(i, j int) bool {
+			return wr.ResultRoute[i].RouteID < wr.ResultRoute[j].RouteID
+		})
+
+		results = append(results, wr)
+	}
+
+	return results
+}
+
+// slotStartMinute หา HH:MM ตัวแรกใน slot_name เช่น "08:00-08:30" หรือ "Slot 08:00"
+func slotStartMinute(slotName string) (int, bool) {
+	for _, token := range strings.FieldsFunc(slotName, func(r rune) bool {
+		return r == '-' || r == ' ' || r == '_'
+	}) {
+		if m, ok := parseClockMinute(token); ok {
+			return m, true
+		}
+	}
+	return 0, false
+}
+
+// engineNoValue คือค่าที่ engine ใช้แทน "ไม่มีข้อมูล" ใน average ต่าง ๆ (safe_mean ฝั่ง Python)
+const engineNoValue = -99999.9
+
+// executeSimulationSegments รัน engine แยกทีละช่วงใน RunPeriods แล้วรวมเป็น result เดียว
+// engine รันต่อเนื่องตาม time_period เสมอ ถ้ารวดเดียวตั้งแต่ window แรกถึงสุดท้ายจะมีผู้โดยสาร/รถในช่วงว่างปนมาด้วย
+//   - slot_results / logs / bus_events ต่อกันตามลำดับเวลา (bus_id เติมช่วงเวลาต่อท้ายกันชนกันระหว่างช่วง)
+//   - result_summary เฉลี่ยถ่วงน้ำหนักตามความยาวของแต่ละช่วง (ไม่นับค่า "ไม่มีข้อมูล")
+func executeSimulationSegments(data models.SimulationRequest) (map[string]interface{}, error) {
+	var slots, logs, events []interface{}
+	summarySum := make(map[string]float64)
+	summaryWeight := make(map[string]float64)
+
+	for _, period := range data.RunPeriods {
+		start, end, err := parsePeriod(period)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := CallPythonSimulation(segmentRequest(data, period))
+		if err != nil {
+			return nil, fmt.Errorf("simulation %s: %w", period, err)
+		}
+
+		simResult, _ := res["simulation_result"].(map[string]interface{})
+		if s, ok := simResult["slot_results"].([]interface{}); ok {
+			slots = append(slots, s...)
+		}
+		if summary, ok := simResult["result_summary"].(map[string]interface{}); ok {
+			for k, v := range summary {
+				if f, ok := v.(float64); ok && f > engineNoValue/2 {
+					summarySum[k] += f * float64(end-start)
+					summaryWeight[k] += float64(end - start)
+				}
+			}
+		}
+		if l, ok := res["logs"].([]interface{}); ok {
+			logs = append(logs, l...)
+		}
+		if evs, ok := res["bus_events"].([]interface{}); ok {
+			for _, ev := range evs {
+				if m, ok := ev.(map[string]interface{}); ok {
+					m["bus_id"] = fmt.Sprintf("%v@%s", m["bus_id"], period)
+				}
+				events = append(events, ev)
+			}
+		}
+	}
+
+	summary := make(map[string]interface{})
+	for _, k := range []string{"average_waiting_time", "average_queue_length", "average_utilization", "average_travel_time", "average_travel_distance"} {
+		if w := summaryWeight[k]; w > 0 {
+			summary[k] = summarySum[k] / w
+		} else {
+			summary[k] = engineNoValue
+		}
+	}
+
+	if slots == nil {
+		slots = []interface{}{}
+	}
+	if logs == nil {
+		logs = []interface{}{}
+	}
+	return map[string]interface{}{
+		"result": "success",
+		"simulation_result": map[string]interface{}{
+			"result_summary": summary,
+			"slot_results":   slots,
+		},
+		"logs":        logs,
+		"bus_events":  events,
+		"run_periods": data.RunPeriods,
+	}, nil
+}
+
+// segmentRequest ตัด request ให้เหลือเฉพาะช่วง period (ตารางเวลา, distribution และ band ของรถของช่วงนั้น)
+func segmentRequest(data models.SimulationRequest, period string) models.SimulationRequest {
+	seg := data
+	seg.TimePeriod = period
+	seg.TimeWindows = nil
+	seg.RunPeriods = nil
+
+	seg.ScenarioData = make([]models.ScenarioData, len(data.ScenarioData))
+	for i, sc := range data.ScenarioData {
+		sc.RouteSchedule = filterSchedules(sc.RouteSchedule, period)
+		// band ของ request แม่เลือกจากทุก window รวมกัน ช่วงที่รันแยกต้องเลือกใหม่จากช่วงของตัวเอง
+		if sc.BusSource != nil {
+			sc.RouteBusInformation = selectBusBand(*sc.BusSource, []string{period})
+		}
+		bands := make([]models.RouteBusBand, 0, len(sc.BusBands))
+		for _, b := range sc.BusBands {
+			if start, _, err := parsePeriod(b.Period); err == nil && isTimeInPeriod(minuteToClock(start), period) {
+				bands = append(bands, b)
+			}
+		}
+		sc.BusBands = bands
+		seg.ScenarioData[i] = sc
+	}
+
+	seg.ConfigurationData.AlightingSimData = filterSimData(data.ConfigurationData.AlightingSimData, period)
+	seg.ConfigurationData.InterarrivalSimData = filterSimData(data.ConfigurationData.InterarrivalSimData, period)
+	return seg
+}
+
+func filterSchedules(schedules []models.RouteSchedule, period string) []models.RouteSchedule {
+	out := make([]models.RouteSchedule, 0, len(schedules))
+	for _, s := range schedules {
+		if isTimeInPeriod(s.DepartureTime, period) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func filterSimData(data []models.SimData, period string) []models.SimData {
+	out := make([]models.SimData, 0, len(data))
+	for _, d := range data {
+		if isTimeRangeInPeriod(d.TimeRange, period) {
+			out = append(out, d)
+		}
+	}
+	return out
+}
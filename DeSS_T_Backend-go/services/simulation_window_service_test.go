@@ -0,0 +1,88 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"DeSS_T_Backend-go/models"
+)
+
+// request ที่มี window เช้าและเย็นไม่ต่อเนื่องกัน รันแยกสองช่วง, เที่ยวละ 30 นาที ออกทุก 10 นาที (ต้องใช้รถ 3 คัน)
+func testSegmentedRequest() models.SimulationRequest {
+	bi := models.BusInformation{
+		MaxBus: 1,
+		Bands: []models.BusInformationBand{
+			{TimeRange: "07:00-09:00", MaxBus: 2},
+			{TimeRange: "16:00-19:00", MaxBus: 3},
+		},
+	}
+	windows := []models.TimeWindow{
+		{Name: "morning", Period: "07:00-08:00"},
+		{Name: "evening", Period: "17:00-18:00"},
+	}
+
+	var schedule []models.RouteSchedule
+	for _, t := range []string{"07:00", "07:10", "07:20", "17:00", "17:10", "17:20"} {
+		schedule = append(schedule, models.RouteSchedule{DepartureTime: t})
+	}
+
+	return models.SimulationRequest{
+		TimePeriod: "07:00-18:00",
+		RunPeriods: []string{"07:00-08:00", "17:00-18:00"},
+		ConfigurationData: models.ConfigurationData{
+			RoutePair: []models.RoutePair{
+				{RoutePairID: "p1", FstStation: "a", SndStation: "b", TravelTime: 1800},
+			},
+		},
+		ScenarioData: []models.ScenarioData{{
+			RouteID:             "r1",
+			RouteOrder:          "p1",
+			RouteSchedule:       schedule,
+			RouteBusInformation: selectBusBand(bi, []string{"07:00-08:00", "17:00-18:00"}),
+			BusBands:            selectWindowBusBands(bi, windows),
+			BusSource:           &bi,
+		}},
+	}
+}
+
+func TestSegmentRequestReselectsBand(t *testing.T) {
+	data := testSegmentedRequest()
+
+	for period, want := range map[string]string{"07:00-08:00": "07:00-09:00", "17:00-18:00": "16:00-19:00"} {
+		sc := segmentRequest(data, period).ScenarioData[0]
+		if got := sc.RouteBusInformation.AppliedBand; got != want {
+			t.Errorf("%s: applied band = %q, want %q", period, got, want)
+		}
+		if len(sc.RouteSchedule) != 3 {
+			t.Errorf("%s: schedule = %v, want 3 departures", period, sc.RouteSchedule)
+		}
+		if len(sc.BusBands) != 1 || sc.BusBands[0].Period != period {
+			t.Errorf("%s: bus bands = %+v, want only the band of the segment", period, sc.BusBands)
+		}
+	}
+}
+
+func TestCheckSimulationFleetFeasibilityPerSegment(t *testing.T) {
+	_, err := CheckSimulationFleetFeasibility(testSegmentedRequest())
+
+	// เช้า band ให้ 2 คันไม่พอ, เย็น band ให้ 3 คันพอ
+	var fe *FleetFeasibilityError
+	if !errors.As(err, &fe) {
+		t.Fatalf("err = %v, want a FleetFeasibilityError", err)
+	}
+	if len(fe.Violations) != 1 {
+		t.Fatalf("violations = %+v, want only the morning", fe.Violations)
+	}
+	if v := fe.Violations[0]; v.MaxBus != 2 || v.RequiredBuses != 3 || v.TimeRange != "07:20-07:30" {
+		t.Errorf("violation = %+v, want 3 buses needed against max_bus 2 at 07:20-07:30", v)
+	}
+}
+
+func TestCheckRunFleetFeasibilityAllowInfeasible(t *testing.T) {
+	if _, err := CheckRunFleetFeasibility(testSegmentedRequest(), false); err == nil {
+		t.Fatal("expected the morning shortage to reject the run")
+	}
+	if _, err := CheckRunFleetFeasibility(testSegmentedRequest(), true); err != nil {
+		t.Fatalf("allow_infeasible_schedule should let the run through: %v", err)
+	}
+}
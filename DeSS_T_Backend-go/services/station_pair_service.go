@@ -0,0 +1,415 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"DeSS_T_Backend-go/config"
+	"DeSS_T_Backend-go/model_database"
+	"DeSS_T_Backend-go/models"
+)
+
+// ErrInvalidPairRequest คือ request ขอ station pair ที่ไม่มีคู่ให้คำนวณ (controller ตอบ 400)
+var ErrInvalidPairRequest = errors.New("invalid station pair request")
+
+const (
+	// จำนวน source ต่อการเรียก Table หนึ่งครั้งเมื่อคำนวณเฉพาะบางคู่
+	pairTableBlockSize = 50
+	// จำนวนแถวต่อ INSERT ของ station pair / route between
+	pairInsertBatchSize = 500
+)
+
+// ComputePairTravel คำนวณระยะทาง (เมตร) และเวลาเดินทาง (วินาที) เฉพาะคู่ที่ระบุ (index ของ stations)
+// ทุกคู่ = ขอ Matrix ทีเดียว, ไม่เช่นนั้นจัด source ที่อยู่ใกล้กันเป็น block แล้วขอ Table ไปเฉพาะปลายทางที่ block นั้นใช้
+func ComputePairTravel(provider RoutingProvider, stations []models.StationDetail, pairs [][2]int) ([]float64, []float64, error) {
+	n := len(stations)
+	distances := make([]float64, len(pairs))
+	durations := make([]float64, len(pairs))
+	if len(pairs) == 0 {
+		return distances, durations, nil
+	}
+
+	if len(pairs) == n*(n-1) {
+		m, err := provider.Matrix(stations)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := m.checkShape(n, n); err != nil {
+			return nil, nil, err
+		}
+		for k, p := range pairs {
+			distances[k] = m.Distances[p[0]][p[1]]
+			durations[k] = m.Durations[p[0]][p[1]]
+		}
+		return distances, durations, nil
+	}
+
+	bySource := make(map[int][]int) // source index → index ใน pairs
+	for k, p := range pairs {
+		bySource[p[0]] = append(bySource[p[0]], k)
+	}
+	sources := make([]int, 0, len(bySource))
+	for s := range bySource {
+		sources = append(sources, s)
+	}
+	sort.Slice(sources, func(a, b int) bool {
+		ca, cb := stations[sources[a]].Location.Coordinates, stations[sources[b]].Location.Coordinates
+		if ca[0] != cb[0] {
+			return ca[0] < cb[0]
+		}
+		return ca[1] < cb[1]
+	})
+
+	for start := 0; start < len(sources); start += pairTableBlockSize {
+		block := sources[start:minInt(start+pairTableBlockSize, len(sources))]
+
+		col := make(map[int]int)
+		var src, dst []models.StationDetail
+		for _, s := range block {
+			src = append(src, stations[s])
+			for _, k := range bySource[s] {
+				if _, ok := col[pairs[k][1]]; !ok {
+					col[pairs[k][1]] = len(dst)
+					dst = append(dst, stations[pairs[k][1]])
+				}
+			}
+		}
+
+		m, err := provider.Table(src, dst)
+		if err == nil {
+			err = m.checkShape(len(src), len(dst))
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		for row, s := range block {
+			for _, k := range bySource[s] {
+				distances[k] = m.Distances[row][col[pairs[k][1]]]
+				durations[k] = m.Durations[row][col[pairs[k][1]]]
+			}
+		}
+	}
+	return distances, durations, nil
+}
+
+// BuildStationPairs สร้าง station pair (พร้อม RouteBetween) ตามโหมดที่เลือก ใช้ตอนสร้าง network model ใหม่
+func BuildStationPairs(provider RoutingProvider, stations []models.StationDetail, sel models.PairSelection) ([]models.StationPair, error) {
+	selected, err := models.SelectStationPairs(stations, sel)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPairRequest, err)
+	}
+
+	distances, durations, err := ComputePairTravel(provider, stations, selected)
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := make([]models.StationPair, len(selected))
+	for k, p := range selected {
+		fst, snd := stations[p[0]].StationDetailID, stations[p[1]].StationDetailID
+		routeBetweenID := fmt.Sprintf("%s-%s-route", fst, snd)
+		pairs[k] = models.StationPair{
+			StationPairID:  fmt.Sprintf("%s-%s", fst, snd),
+			FstStationID:   fst,
+			SndStationID:   snd,
+			RouteBetweenID: routeBetweenID,
+			RouteBetween: models.RouteBetween{
+				RouteBetweenID: routeBetweenID,
+				TravelTime:     durations[k],
+				Distance:       distances[k],
+			},
+		}
+	}
+	return pairs, nil
+}
+
+// EnsureStationPairs คืน station pair ของคู่ที่อยู่ติดกันใน routes ตามลำดับที่พบ
+// คู่ที่ network model ของ configuration ยังไม่มีจะคำนวณด้วย provider แล้วบันทึกเพิ่ม
+// คืนจำนวนคู่ที่สร้างใหม่ด้วย
+func EnsureStationPairs(configDetailID string, routes [][]string, provider RoutingProvider) ([]models.StationPair, int, error) {
+	wanted := models.RoutePairs(routes)
+	if len(wanted) == 0 {
+		return nil, 0, fmt.Errorf("%w: routes need at least two different stations in a row", ErrInvalidPairRequest)
+	}
+
+	var cfg model_database.ConfigurationDetail
+	if err := config.DB.Select("id", "network_model_id").First(&cfg, "id = ?", configDetailID).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var dbStations []model_database.StationDetail
+	if err := config.DB.Select("id", "station_name", "lat", "lon").
+		Where("network_model_id = ?", cfg.NetworkModelID).
+		Find(&dbStations).Error; err != nil {
+		return nil, 0, err
+	}
+	stationIndex := make(map[string]int, len(dbStations))
+	stations := make([]models.StationDetail, len(dbStations))
+	for i, st := range dbStations {
+		stationIndex[st.ID] = i
+		stations[i] = models.StationDetail{
+			StationDetailID: st.ID,
+			Name:            st.Name,
+			Location:        models.GeoPoint{Type: "Point", Coordinates: [2]float64{st.Lon, st.Lat}},
+			Lat:             st.Lat,
+			Lon:             st.Lon,
+		}
+	}
+
+	fstIDs := make([]string, 0, len(wanted))
+	for _, p := range wanted {
+		for _, id := range p {
+			if _, ok := stationIndex[id]; !ok {
+				return nil, 0, fmt.Errorf("%w: %s", ErrUnknownStation, id)
+			}
+		}
+		fstIDs = append(fstIDs, p[0])
+	}
+
+	var existing []model_database.StationPair
+	if err := config.DB.Preload("RouteBetween").
+		Where("network_model_id = ? AND fst_station_id IN ?", cfg.NetworkModelID, fstIDs).
+		Find(&existing).Error; err != nil {
+		return nil, 0, err
+	}
+	byKey := make(map[[2]string]model_database.StationPair, len(existing))
+	for _, sp := range existing {
+		byKey[[2]string{sp.FstStationID, sp.SndStationID}] = sp
+	}
+
+	var missing [][2]int
+	created := 0
+	for _, p := range wanted {
+		if _, ok := byKey[p]; !ok {
+			missing = append(missing, [2]int{stationIndex[p[0]], stationIndex[p[1]]})
+		}
+	}
+
+	if len(missing) > 0 {
+		distances, durations, err := ComputePairTravel(provider, stations, missing)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s travel time failed: %w", provider.Name(), err)
+		}
+
+		routeBetweens := make([]model_database.RouteBetween, len(missing))
+		pairs := make([]model_database.StationPair, len(missing))
+		for k, p := range missing {
+			routeBetweens[k] = model_database.RouteBetween{
+				ID:         uuid.New().String(),
+				TravelTime: durations[k],
+				Distance:   distances[k],
+			}
+			pairs[k] = model_database.StationPair{
+				ID:             uuid.New().String(),
+				FstStationID:   stations[p[0]].StationDetailID,
+				SndStationID:   stations[p[1]].StationDetailID,
+				RouteBetweenID: routeBetweens[k].ID,
+				NetworkModelID: cfg.NetworkModelID,
+			}
+		}
+
+		// request อื่นอาจสร้างคู่เดียวกันไปพร้อมกัน: คู่ที่ชน unique index ข้ามไป แล้วอ่านคู่ที่อยู่ใน DB จริงกลับมา
+		missingFst := make([]string, 0, len(pairs))
+		for _, sp := range pairs {
+			missingFst = append(missingFst, sp.FstStationID)
+		}
+		err = config.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.CreateInBatches(&routeBetweens, pairInsertBatchSize).Error; err != nil {
+				return fmt.Errorf("failed to save route between: %w", err)
+			}
+			res := tx.Omit(clause.Associations).
+				Clauses(clause.OnConflict{DoNothing: true}).
+				CreateInBatches(&pairs, pairInsertBatchSize)
+			if res.Error != nil {
+				return fmt.Errorf("failed to save station pair: %w", res.Error)
+			}
+			created = int(res.RowsAffected)
+
+			var saved []model_database.StationPair
+			if err := tx.Preload("RouteBetween").
+				Where("network_model_id = ? AND fst_station_id IN ?", cfg.NetworkModelID, missingFst).
+				Find(&saved).Error; err != nil {
+				return fmt.Errorf("failed to reload station pair: %w", err)
+			}
+			used := make(map[string]bool, len(saved))
+			for _, sp := range saved {
+				used[sp.RouteBetweenID] = true
+				byKey[[2]string{sp.FstStationID, sp.SndStationID}] = sp
+			}
+
+			// route between ของคู่ที่ชนไม่มี station pair ใช้ ลบทิ้ง
+			var orphans []string
+			for _, rb := range routeBetweens {
+				if !used[rb.ID] {
+					orphans = append(orphans, rb.ID)
+				}
+			}
+			if len(orphans) > 0 {
+				if err := tx.Delete(&model_database.RouteBetween{}, "id IN ?", orphans).Error; err != nil {
+					return fmt.Errorf("failed to remove unused route between: %w", err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	out := make([]models.StationPair, 0, len(wanted))
+	for _, p := range wanted {
+		sp := byKey[p]
+		pair := models.StationPair{
+			StationPairID:  sp.ID,
+			FstStationID:   sp.FstStationID,
+			SndStationID:   sp.SndStationID,
+			RouteBetweenID: sp.RouteBetweenID,
+			NetworkModelID: sp.NetworkModelID,
+		}
+		if sp.RouteBetween != nil {
+			pair.RouteBetween = models.RouteBetween{
+				RouteBetweenID: sp.RouteBetween.ID,
+				TravelTime:     sp.RouteBetween.TravelTime,
+				Distance:       sp.RouteBetween.Distance,
+			}
+		}
+		out = append(out, pair)
+	}
+	return out, created, nil
+}
+
+// orderPairRequest คือ order ที่อ้าง station pair ที่ network model ยังไม่มี แต่ระบุ station ต้นทาง/ปลายทางมา
+type orderPairRequest struct {
+	fst, snd string
+	set      func(pair models.StationPair)
+}
+
+// ensureOrderPairs คำนวณ station pair ที่ order ต้องใช้แต่ยังไม่มีด้วย routing provider เริ่มต้น
+// แล้วผูก id ของคู่ที่ได้กลับเข้า order (order ที่ต้นทาง = ปลายทางข้ามไป ให้ตรวจ topology รายงานเอง)
+func ensureOrderPairs(configDetailID string, reqs []orderPairRequest) error {
+	routes := make([][]string, 0, len(reqs))
+	for _, r := range reqs {
+		if r.fst != "" && r.snd != "" && r.fst != r.snd {
+			routes = append(routes, []string{r.fst, r.snd})
+		}
+	}
+	if len(routes) == 0 {
+		return nil
+	}
+
+	provider, _, err := ResolveRoutingProvider("")
+	if err != nil {
+		return err
+	}
+	pairs, _, err := EnsureStationPairs(configDetailID, routes, provider)
+	if err != nil {
+		return err
+	}
+
+	byKey := make(map[[2]string]models.StationPair, len(pairs))
+	for _, sp := range pairs {
+		byKey[[2]string{sp.FstStationID, sp.SndStationID}] = sp
+	}
+	for _, r := range reqs {
+		if sp, ok := byKey[[2]string{r.fst, r.snd}]; ok {
+			r.set(sp)
+		}
+	}
+	return nil
+}
+
+// ResolveScenarioStationPairs สร้าง station pair ที่เส้นทางของ scenario ใช้แต่ network model ยังไม่มี
+// (network แบบ knn / radius / routes มีเฉพาะบางคู่) จาก FstStation/SndStation ที่แนบมากับ order
+// ต้องเรียกก่อน ValidateScenarioRouteTopology ตอนบันทึก scenario
+func ResolveScenarioStationPairs(sd *model_database.ScenarioDetail) error {
+	if sd == nil || sd.RouteScenario == nil {
+		return nil
+	}
+
+	var cfg model_database.ConfigurationDetail
+	if err := config.DB.Select("id", "network_model_id").First(&cfg, "id = ?", sd.ConfigurationDetailID).Error; err != nil {
+		return fmt.Errorf("configuration_detail_id %s: %w", sd.ConfigurationDetailID, err)
+	}
+
+	var pairIDs []string
+	for _, rp := range sd.RouteScenario.RoutePaths {
+		for _, o := range rp.Orders {
+			if id := strings.TrimSpace(o.StationPairID); id != "" {
+				pairIDs = append(pairIDs, id)
+			}
+		}
+	}
+	known := make(map[string]bool)
+	if len(pairIDs) > 0 {
+		var ids []string
+		if err := config.DB.Model(&model_database.StationPair{}).
+			Where("network_model_id = ? AND id IN ?", cfg.NetworkModelID, pairIDs).
+			Pluck("id", &ids).Error; err != nil {
+			return fmt.Errorf("load station pairs: %w", err)
+		}
+		for _, id := range ids {
+			known[id] = true
+		}
+	}
+
+	var reqs []orderPairRequest
+	for i := range sd.RouteScenario.RoutePaths {
+		rp := &sd.RouteScenario.RoutePaths[i]
+		for j := range rp.Orders {
+			o := &rp.Orders[j]
+			if known[strings.TrimSpace(o.StationPairID)] || o.StationPair == nil {
+				continue
+			}
+			reqs = append(reqs, orderPairRequest{
+				fst: strings.TrimSpace(o.StationPair.FstStationID),
+				snd: strings.TrimSpace(o.StationPair.SndStationID),
+				set: func(sp models.StationPair) { o.StationPairID = sp.StationPairID },
+			})
+		}
+	}
+
+	return ensureOrderPairs(sd.ConfigurationDetailID, reqs)
+}
+
+// resolveRouteStationPairs ทำแบบเดียวกับ ResolveScenarioStationPairs กับ request จำลอง
+// คู่ที่สร้างเพิ่มจะถูกเติมเข้า network model ของ cfg เพื่อให้ TransformConfiguration ใช้เวลาเดินทางได้
+func resolveRouteStationPairs(cfg *models.ConfigurationDetail, scenario *models.ScenarioDetail) error {
+	if len(cfg.NetworkModel.StationPairs) == 0 {
+		return nil // ไม่ได้ส่ง network มา ValidateRouteOrders ใช้ pair ที่แนบกับ order แทน
+	}
+	known := make(map[string]bool, len(cfg.NetworkModel.StationPairs))
+	for _, sp := range cfg.NetworkModel.StationPairs {
+		known[sp.StationPairID] = true
+	}
+
+	var reqs []orderPairRequest
+	for i := range scenario.RouteScenario.RoutePaths {
+		rp := &scenario.RouteScenario.RoutePaths[i]
+		for j := range rp.Orders {
+			o := &rp.Orders[j]
+			if known[strings.TrimSpace(o.StationPairID)] {
+				continue
+			}
+			reqs = append(reqs, orderPairRequest{
+				fst: strings.TrimSpace(o.StationPair.FstStationID),
+				snd: strings.TrimSpace(o.StationPair.SndStationID),
+				set: func(sp models.StationPair) {
+					o.StationPairID = sp.StationPairID
+					o.StationPair = sp
+					if !known[sp.StationPairID] {
+						known[sp.StationPairID] = true
+						cfg.NetworkModel.StationPairs = append(cfg.NetworkModel.StationPairs, sp)
+					}
+				},
+			})
+		}
+	}
+
+	return ensureOrderPairs(cfg.ConfigurationDetailID, reqs)
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"DeSS_T_Backend-go/config"
+	"DeSS_T_Backend-go/fitting"
+	"DeSS_T_Backend-go/models"
+)
+
+// ConfigurationStopMap คืน map รหัสป้าย → station_detail_id สำหรับข้อมูลแตะบัตร
+// รับทั้ง station_id_osm และชื่อ station ของ configuration
+func ConfigurationStopMap(configDetailID string) (map[string]string, error) {
+	stations, err := configurationStations(configDetailID)
+	if err != nil {
+		return nil, err
+	}
+
+	stopMap := make(map[string]string, 2*len(stations))
+	for _, st := range stations {
+		if name := strings.TrimSpace(st.Name); name != "" {
+			stopMap[name] = st.ID
+		}
+	}
+	// รหัส OSM มาทีหลังเพื่อให้ชนะกรณีชื่อ station บังเอิญตรงกับรหัสของ station อื่น
+	for _, st := range stations {
+		if code := strings.TrimSpace(st.StationIDOSM); code != "" {
+			stopMap[code] = st.ID
+		}
+	}
+	return stopMap, nil
+}
+
+// SaveTapObservations เก็บข้อมูลที่ได้จากการแตะบัตรเป็น observation dataset ทั้งสองชนิด เพื่อ refit ภายหลังได้
+// ทั้งสองชนิดบันทึกใน transaction เดียว ชนิดใดล้มเหลวจะไม่เหลือข้อมูลครึ่งเดียว
+func SaveTapObservations(configDetailID string, dayType string, result models.TapImportResult) (map[string][]models.ObservationDataset, error) {
+	saved := make(map[string][]models.ObservationDataset, 2)
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		for _, item := range []struct {
+			kind fitting.Kind
+			data models.Data
+		}{
+			{fitting.KindInterarrival, result.Interarrival},
+			{fitting.KindAlighting, result.Alighting},
+		} {
+			if len(item.data.Data) == 0 {
+				continue
+			}
+			datasets, err := saveObservationDatasets(tx, configDetailID, item.kind, dayType, item.data)
+			if err != nil {
+				return fmt.Errorf("save %s datasets: %w", item.kind, err)
+			}
+			saved[string(item.kind)] = datasets
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
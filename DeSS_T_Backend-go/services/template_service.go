@@ -0,0 +1,128 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"DeSS_T_Backend-go/config"
+	"DeSS_T_Backend-go/fitting"
+	"DeSS_T_Backend-go/model_database"
+	"DeSS_T_Backend-go/models"
+)
+
+// ErrInvalidTemplate คือ request สร้าง template ที่ค่าไม่ถูกต้อง (controller ตอบ 400)
+var ErrInvalidTemplate = errors.New("invalid template request")
+
+// DistributionTemplateFor สร้าง workbook สำหรับ upload ข้อมูล alighting / interarrival ของ configuration
+// timeRanges ว่าง = ใช้ time period ที่ configuration มีอยู่แล้วสำหรับข้อมูลชนิดนั้น
+func DistributionTemplateFor(
+	configDetailID string,
+	kind fitting.Kind,
+	timeRanges []string,
+) (*bytes.Buffer, error) {
+
+	stations, err := configurationStations(configDetailID)
+	if err != nil {
+		return nil, err
+	}
+	if len(stations) == 0 {
+		return nil, fmt.Errorf("%w: configuration has no stations", ErrInvalidTemplate)
+	}
+
+	if len(timeRanges) == 0 {
+		timeRanges, err = configurationTimePeriods(configDetailID, kind)
+		if err != nil {
+			return nil, err
+		}
+		if len(timeRanges) == 0 {
+			return nil, fmt.Errorf("%w: configuration has no %s data yet, time_ranges is required", ErrInvalidTemplate, kind)
+		}
+	}
+	for _, tr := range timeRanges {
+		if _, _, err := parsePeriod(tr); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+		}
+	}
+
+	names := make([]string, 0, len(stations))
+	for _, st := range stations {
+		names = append(names, st.Name)
+	}
+
+	if kind == fitting.KindAlighting {
+		return models.DistributionTemplate(names, timeRanges, true, "จำนวนคนลงที่ station นี้ ใต้ช่วงเวลาที่สังเกต")
+	}
+	return models.DistributionTemplate(names, timeRanges, false, "ช่วงห่างระหว่างผู้โดยสารที่มาถึงติดกัน (นาที)")
+}
+
+// ScheduleTemplateFor สร้าง workbook ตารางเวลาออกรถ หนึ่งคอลัมน์ต่อ route path ของ route scenario
+func ScheduleTemplateFor(routeScenarioID string) (*bytes.Buffer, error) {
+	var scenario model_database.RouteScenario
+	if err := config.DB.Select("id").First(&scenario, "id = ?", routeScenarioID).Error; err != nil {
+		return nil, err
+	}
+
+	var routeNames []string
+	if err := config.DB.Model(&model_database.RoutePath{}).
+		Where("route_scenario_id = ?", routeScenarioID).
+		Order("name").
+		Pluck("name", &routeNames).Error; err != nil {
+		return nil, err
+	}
+	if len(routeNames) == 0 {
+		return nil, fmt.Errorf("%w: route scenario has no route paths", ErrInvalidTemplate)
+	}
+
+	return models.ScheduleTemplate(routeNames)
+}
+
+// RouteScenarioPathMap คืนชื่อ route path → route_path_id ของ route scenario (ใช้จับคู่หัวคอลัมน์ตารางเวลา)
+func RouteScenarioPathMap(routeScenarioID string) (map[string]string, error) {
+	var scenario model_database.RouteScenario
+	if err := config.DB.Select("id").First(&scenario, "id = ?", routeScenarioID).Error; err != nil {
+		return nil, err
+	}
+
+	var paths []model_database.RoutePath
+	if err := config.DB.Select("id", "name").
+		Where("route_scenario_id = ?", routeScenarioID).
+		Find(&paths).Error; err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]string, len(paths))
+	for _, p := range paths {
+		out[strings.TrimSpace(p.Name)] = p.ID
+	}
+	return out, nil
+}
+
+// configurationTimePeriods คืน time period ที่ไม่ซ้ำของข้อมูลชนิดนั้น เรียงตามเวลาเริ่ม
+func configurationTimePeriods(configDetailID string, kind fitting.Kind) ([]string, error) {
+	var periods []string
+	q := config.DB.Model(&model_database.InterArrivalData{})
+	if kind == fitting.KindAlighting {
+		q = config.DB.Model(&model_database.AlightingData{})
+	}
+	if err := q.Where("configuration_detail_id = ?", configDetailID).
+		Distinct("time_period").
+		Pluck("time_period", &periods).Error; err != nil {
+		return nil, err
+	}
+
+	out := make([]string, 0, len(periods))
+	for _, p := range periods {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		si, _, _ := parsePeriod(out[i])
+		sj, _, _ := parsePeriod(out[j])
+		return si < sj
+	})
+	return out, nil
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"DeSS_T_Backend-go/models"
+	"fmt"
 	"sort"
 	"strconv"
 	"strings"
@@ -13,16 +14,198 @@ func TransformSimulationRequest(
 	cfg models.ConfigurationDetail,
 	timePeriods string,
 	timeSlot string,
-) models.SimulationRequest {
+	timeWindows []models.TimeWindow,
+	dayType string,
+) (models.SimulationRequest, error) {
 
-	scenarioData := TransformScenario(scenario, timePeriods)
-	configurationData := TransformConfiguration(cfg, scenario, timePeriods)
+	span, periods, windows, err := ResolveTimeWindows(timePeriods, timeWindows)
+	if err != nil {
+		return models.SimulationRequest{}, err
+	}
+
+	dayType, err = ResolveDayType(dayType, cfg, scenario)
+	if err != nil {
+		return models.SimulationRequest{}, err
+	}
+
+	// คู่ที่เส้นทางใช้แต่ network แบบ knn / radius ยังไม่มี ต้องคำนวณก่อนตรวจ topology
+	if err := resolveRouteStationPairs(&cfg, &scenario); err != nil {
+		return models.SimulationRequest{}, fmt.Errorf("compute missing station pairs: %w", err)
+	}
+
+	for _, rp := range scenario.RouteScenario.RoutePaths {
+		if err := ValidateRouteOrders(rp, cfg.NetworkModel.StationPairs); err != nil {
+			return models.SimulationRequest{}, err
+		}
+	}
+
+	// distribution ที่แก้เองหรือพิมพ์ผิดต้องไม่หลุดไปถึง engine (Python จะแทนด้วยค่าคงที่แบบเงียบๆ)
+	if err := ValidateSimulationDistributions(cfg, periods, dayType); err != nil {
+		return models.SimulationRequest{}, err
+	}
+	cfg, err = ResolveEmpiricalReferences(cfg)
+	if err != nil {
+		return models.SimulationRequest{}, err
+	}
+
+	var runPeriods []string
+	if strings.TrimSpace(timePeriods) == "" {
+		if segments := mergeWindowPeriods(windows); len(segments) > 1 {
+			runPeriods = segments
+		}
+	}
+
+	scenarioData := TransformScenario(scenario, periods, windows, dayType)
+	configurationData := TransformConfiguration(cfg, scenario, periods, dayType)
 	return models.SimulationRequest{
-		TimePeriod:        timePeriods,
+		TimePeriod:        span,
 		TimeSlot:          timeSlot,
+		TimeWindows:       windows,
+		DayType:           dayType,
+		RunPeriods:        runPeriods,
 		ConfigurationData: configurationData,
 		ScenarioData:      scenarioData,
+	}, nil
+}
+
+// ResolveTimeWindows ตรวจสอบช่วงเวลาแล้วคืนค่า
+//   - span: ช่วงรวมที่ส่งให้ engine (ต้นสุด-ท้ายสุด)
+//   - periods: ช่วงที่ใช้กรอง schedule / distribution
+//   - windows: window ที่ตั้งชื่อแล้ว (ชื่อว่างจะใช้ period แทน)
+//
+// ถ้ามี timePeriods → windows เป็น sub-period ที่ต้องอยู่ภายใน timePeriods
+// ถ้าไม่มี → windows คือช่วงเวลาที่ไม่ต่อเนื่องกัน และกรองข้อมูลตามแต่ละ window
+// span ครอบทุก window (ใช้เป็น time_period ของ run) แต่ engine ไม่รู้จักช่วงว่างระหว่าง window
+// จึงต้องรันแยกตาม mergeWindowPeriods (ดู ExecuteSimulation)
+func ResolveTimeWindows(
+	timePeriods string,
+	timeWindows []models.TimeWindow,
+) (string, []string, []models.TimeWindow, error) {
+
+	timePeriods = strings.TrimSpace(timePeriods)
+	if timePeriods == "" && len(timeWindows) == 0 {
+		return "", nil, nil, fmt.Errorf("time_periods or time_windows is required")
 	}
+
+	windows := make([]models.TimeWindow, 0, len(timeWindows))
+	names := make(map[string]struct{})
+	for i, w := range timeWindows {
+		period := strings.ReplaceAll(w.Period, " ", "")
+		if _, _, err := parsePeriod(period); err != nil {
+			return "", nil, nil, fmt.Errorf("time_windows[%d]: %w", i, err)
+		}
+		name := strings.TrimSpace(w.Name)
+		if name == "" {
+			name = period
+		}
+		if _, dup := names[name]; dup {
+			return "", nil, nil, fmt.Errorf("time_windows[%d]: duplicate name %q", i, name)
+		}
+		names[name] = struct{}{}
+		windows = append(windows, models.TimeWindow{Name: name, Period: period})
+	}
+
+	if timePeriods != "" {
+		timePeriods = strings.ReplaceAll(timePeriods, " ", "")
+		start, end, err := parsePeriod(timePeriods)
+		if err != nil {
+			return "", nil, nil, fmt.Errorf("time_periods: %w", err)
+		}
+		for _, w := range windows {
+			ws, we, _ := parsePeriod(w.Period)
+			if ws < start || we > end {
+				return "", nil, nil, fmt.Errorf("time window %q (%s) is outside time_periods %s", w.Name, w.Period, timePeriods)
+			}
+		}
+		return timePeriods, []string{timePeriods}, windows, nil
+	}
+
+	sorted := make([]models.TimeWindow, len(windows))
+	copy(sorted, windows)
+	sort.Slice(sorted, func(i, j int) bool {
+		a, _, _ := parsePeriod(sorted[i].Period)
+		b, _, _ := parsePeriod(sorted[j].Period)
+		return a < b
+	})
+
+	periods := make([]string, 0, len(sorted))
+	for _, w := range sorted {
+		periods = append(periods, w.Period)
+	}
+
+	first, _, _ := parsePeriod(sorted[0].Period)
+	last := 0
+	for _, w := range sorted {
+		if _, e, _ := parsePeriod(w.Period); e > last {
+			last = e
+		}
+	}
+	span := fmt.Sprintf("%s-%s", minuteToClock(first), minuteToClock(last))
+
+	return span, periods, windows, nil
+}
+
+// mergeWindowPeriods รวม window ที่ซ้อนหรือต่อกันเป็นช่วงเดียว เรียงตามเวลา
+func mergeWindowPeriods(windows []models.TimeWindow) []string {
+	type span struct{ start, end int }
+	spans := make([]span, 0, len(windows))
+	for _, w := range windows {
+		if s, e, err := parsePeriod(w.Period); err == nil {
+			spans = append(spans, span{s, e})
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	var merged []span
+	for _, sp := range spans {
+		if n := len(merged); n > 0 && sp.start <= merged[n-1].end {
+			if sp.end > merged[n-1].end {
+				merged[n-1].end = sp.end
+			}
+			continue
+		}
+		merged = append(merged, sp)
+	}
+
+	out := make([]string, 0, len(merged))
+	for _, sp := range merged {
+		out = append(out, fmt.Sprintf("%s-%s", minuteToClock(sp.start), minuteToClock(sp.end)))
+	}
+	return out
+}
+
+// parsePeriod แปลง "HH:MM-HH:MM" เป็นนาที (start < end)
+func parsePeriod(period string) (int, int, error) {
+	pr := strings.Split(period, "-")
+	if len(pr) != 2 {
+		return 0, 0, fmt.Errorf("invalid period %q, expected HH:MM-HH:MM", period)
+	}
+	start, ok1 := parseClockMinute(pr[0])
+	end, ok2 := parseClockMinute(pr[1])
+	if !ok1 || !ok2 {
+		return 0, 0, fmt.Errorf("invalid period %q, expected HH:MM-HH:MM", period)
+	}
+	if start >= end {
+		return 0, 0, fmt.Errorf("invalid period %q, start must be before end", period)
+	}
+	return start, end, nil
+}
+
+func parseClockMinute(t string) (int, bool) {
+	parts := strings.Split(strings.TrimSpace(t), ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, false
+	}
+	h, err1 := strconv.Atoi(parts[0])
+	m, err2 := strconv.Atoi(parts[1])
+	if err1 != nil || err2 != nil || h < 0 || h > 24 || m < 0 || m > 59 || (h == 24 && m > 0) {
+		return 0, false
+	}
+	return h*60 + m, true
+}
+
+func minuteToClock(m int) string {
+	return fmt.Sprintf("%02d:%02d", m/60, m%60)
 }
 
 func buildRouteOrder(orders []models.Order) string {
@@ -40,13 +223,24 @@ func buildRouteOrder(orders []models.Order) string {
 
 func indexBusScenario(
 	busScenarios models.BusScenario,
-	timePeriods string,
+	periods []string,
+	dayType string,
 ) (map[string][]string, map[string]models.BusInformation) {
 
 	scheduleMap := make(map[string][]string)
 	busInfoMap := make(map[string]models.BusInformation)
 
-	for _, sch := range busScenarios.ScheduleData {
+	// ตารางเวลาของ day type ที่เลือกแทนตารางที่ไม่ระบุ day type ของ route เดียวกัน
+	schedules := busScenarios.ScheduleData
+	selected := selectDayType(
+		len(schedules),
+		func(i int) string { return schedules[i].DayType },
+		func(i int) string { return schedules[i].RoutePathID },
+		dayType,
+	)
+
+	for _, i := range selected {
+		sch := schedules[i]
 
 		rawTimes := strings.Split(sch.ScheduleList, ",")
 		filtered := make([]string, 0)
@@ -54,7 +248,7 @@ func indexBusScenario(
 		for _, t := range rawTimes {
 			t = strings.TrimSpace(t)
 
-			if isTimeInPeriod(t, timePeriods) {
+			if isTimeInAnyPeriod(t, periods) {
 				filtered = append(filtered, t)
 			}
 		}
@@ -73,14 +267,18 @@ func indexBusScenario(
 }
 
 
+// TransformScenario แปลงเส้นทาง ตารางเวลา และค่ารถ
+// bus_information คือ band ของทั้งช่วงที่จำลอง, ถ้ามี time window จะเลือก band แยกต่อ window ไว้ใน bus_bands
 func TransformScenario(
 	scenario models.ScenarioDetail,
-	timePeriods string,
+	periods []string,
+	windows []models.TimeWindow,
+	dayType string,
 ) []models.ScenarioData {
 
 	var result []models.ScenarioData
 
-	scheduleMap, busInfoMap := indexBusScenario(scenario.BusScenario, timePeriods)
+	scheduleMap, busInfoMap := indexBusScenario(scenario.BusScenario, periods, dayType)
 
 	rs := scenario.RouteScenario
 	for _, rp := range rs.RoutePaths {
@@ -96,19 +294,18 @@ func TransformScenario(
 
 		bi := busInfoMap[rp.RoutePathID]
 
-		result = append(result, models.ScenarioData{
+		sd := models.ScenarioData{
 			RouteID:       rp.RoutePathID,
 			RouteName:     rp.Name,
 			RouteOrder:    routeOrder,
 			RouteSchedule: schedules,
-			RouteBusInformation: models.RouteBusInformation{
-				BusSpeed:    float64(bi.Speed),
-				MaxDistance: float64(bi.MaxDis),
-				MaxBus:      bi.MaxBus,
-				BusCapacity: bi.Capacity,
-				AvgTravelTime: float64(bi.AvgTravelTime),
-			},
-		})
+			RouteBusInformation: selectBusBand(bi, periods),
+			BusSource:     &bi,
+		}
+		if len(windows) > 0 {
+			sd.BusBands = selectWindowBusBands(bi, windows)
+		}
+		result = append(result, sd)
 	}
 	
 
@@ -117,9 +314,11 @@ func TransformScenario(
 func TransformConfiguration(
 	cfg models.ConfigurationDetail,
 	scenario models.ScenarioDetail,
-	timePeriods string,
+	periods []string,
+	dayType string,
 ) models.ConfigurationData {
 
+	cfg = configurationForDayType(cfg, dayType)
 	usedPairIDs := collectUsedPairIDs(scenario.RouteScenario.RoutePaths)
 
 	routePairs := make([]models.RoutePair, 0)
@@ -162,7 +361,7 @@ func TransformConfiguration(
 
 	alightingData := groupFitItemsToSimData(
 		alightingFitItems,
-		timePeriods,
+		periods,
 		usedStations,
 	)
 
@@ -170,7 +369,7 @@ func TransformConfiguration(
 
 	interarrivalData := groupFitItemsToSimData(
 		interArrivalFitItems,
-		timePeriods,
+		periods,
 		usedStations,
 	)
 	
@@ -220,7 +419,7 @@ func InterArrivalDataToFitItems(
 
 func groupFitItemsToSimData(
 	items []models.FitItem,
-	timePeriods string,
+	periods []string,
 	usedStations map[string]struct{},
 ) []models.SimData {
 	
@@ -229,7 +428,7 @@ func groupFitItemsToSimData(
 	for _, item := range items {
 
 		// filter ตาม time
-		if !isTimeRangeInPeriod(item.TimeRange, timePeriods) {
+		if !isTimeRangeInAnyPeriod(item.TimeRange, periods) {
 			continue
 		}
 
@@ -294,6 +493,24 @@ func isTimeInPeriod(timeStr, period string) bool {
 	return t >= start && t < end
 }
 
+func isTimeInAnyPeriod(timeStr string, periods []string) bool {
+	for _, p := range periods {
+		if isTimeInPeriod(timeStr, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func isTimeRangeInAnyPeriod(timeRange string, periods []string) bool {
+	for _, p := range periods {
+		if isTimeRangeInPeriod(timeRange, p) {
+			return true
+		}
+	}
+	return false
+}
+
 func collectUsedPairIDs(routes []models.RoutePath) map[string]struct{} {
 	used := make(map[string]struct{})
 
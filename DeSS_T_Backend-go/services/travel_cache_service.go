@@ -0,0 +1,290 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"DeSS_T_Backend-go/config"
+	"DeSS_T_Backend-go/model_database"
+	"DeSS_T_Backend-go/models"
+)
+
+/* ───────────────────────────── TRAVEL CACHE ───────────────────────────── */
+
+const (
+	defaultTravelCacheTTL = 30 * 24 * time.Hour
+	// จำนวน source key ต่อ query ตอนอ่าน cache (จำกัดขนาด IN (...))
+	travelCacheLookupChunk = 200
+)
+
+// travelCacheTTL อ่านจาก TRAVEL_CACHE_TTL (Go duration เช่น 720h) ค่าเริ่มต้น 30 วัน, 0 / off = ปิด cache
+func travelCacheTTL() time.Duration {
+	v := strings.ToLower(strings.TrimSpace(os.Getenv("TRAVEL_CACHE_TTL")))
+	switch v {
+	case "":
+		return defaultTravelCacheTTL
+	case "0", "off", "false":
+		return 0
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("⚠️ invalid TRAVEL_CACHE_TTL %q (%v) - using %s", v, err, defaultTravelCacheTTL)
+		return defaultTravelCacheTTL
+	}
+	return d
+}
+
+// travelKey ปัดพิกัด [lon, lat] เหลือทศนิยม 5 ตำแหน่ง (~1 เมตร) จุดที่ห่างกันน้อยกว่านั้นใช้ค่าเดียวกัน
+func travelKey(c [2]float64) string {
+	return fmt.Sprintf("%.5f,%.5f", c[0], c[1])
+}
+
+// travelServerKey คือ hash ของ base URL ของ routing server ค่าจาก server คนละตัว (เช่น OSRM คนละ extract) จึงไม่ปนกัน
+func travelServerKey(baseURL string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimRight(strings.TrimSpace(baseURL), "/"))))
+	return hex.EncodeToString(sum[:8])
+}
+
+// cachedProvider ครอบ provider ที่ต้องเรียก server (ors / osrm / valhalla) ด้วย cache ใน Postgres
+// Matrix / Table ขอ provider เฉพาะ cell ที่ยังไม่มีใน cache หรือหมดอายุแล้ว, Route เก็บ geometry ไว้ด้วย
+// อ่าน / เขียน cache ไม่ได้จะ log แล้วทำงานต่อเหมือนไม่มี cache
+type cachedProvider struct {
+	RoutingProvider
+	server  string
+	profile string
+	ttl     time.Duration
+}
+
+// withTravelCache คืน provider เดิมถ้ายังไม่ได้ต่อ database หรือปิด cache ไว้
+func withTravelCache(p RoutingProvider, baseURL, profile string) RoutingProvider {
+	ttl := travelCacheTTL()
+	if config.DB == nil || ttl <= 0 {
+		return p
+	}
+	return cachedProvider{RoutingProvider: p, server: travelServerKey(baseURL), profile: profile, ttl: ttl}
+}
+
+func (c cachedProvider) scope(db *gorm.DB) *gorm.DB {
+	return db.Model(&model_database.TravelCache{}).Where("provider = ? AND server = ? AND profile = ?", c.Name(), c.server, c.profile)
+}
+
+func (c cachedProvider) Matrix(stations []models.StationDetail) (*RoutingMatrix, error) {
+	return c.Table(stations, stations)
+}
+
+func (c cachedProvider) Table(sources, destinations []models.StationDetail) (*RoutingMatrix, error) {
+	out := newRoutingMatrix(len(sources), len(destinations))
+	srcKeys := make([]string, len(sources))
+	dstKeys := make([]string, len(destinations))
+	for i, s := range sources {
+		srcKeys[i] = travelKey(s.Location.Coordinates)
+	}
+	for j, d := range destinations {
+		dstKeys[j] = travelKey(d.Location.Coordinates)
+	}
+
+	cached := c.lookupCells(srcKeys, dstKeys)
+
+	// จุดที่ต้องส่งให้ provider (ไม่ซ้ำ key) และคู่ที่ขาด
+	var points []models.StationDetail
+	pointIndex := make(map[string]int)
+	point := func(key string, s models.StationDetail) int {
+		if k, ok := pointIndex[key]; ok {
+			return k
+		}
+		pointIndex[key] = len(points)
+		points = append(points, s)
+		return len(points) - 1
+	}
+	var missing [][2]int
+	missingIndex := make(map[[2]string]int)
+	for i := range sources {
+		for j := range destinations {
+			key := [2]string{srcKeys[i], dstKeys[j]}
+			if key[0] == key[1] {
+				continue // จุดเดียวกัน = 0
+			}
+			if cell, ok := cached[key]; ok {
+				out.Distances[i][j], out.Durations[i][j] = cell[0], cell[1]
+				continue
+			}
+			if _, ok := missingIndex[key]; !ok {
+				missingIndex[key] = len(missing)
+				missing = append(missing, [2]int{point(key[0], sources[i]), point(key[1], destinations[j])})
+			}
+		}
+	}
+	if len(missing) == 0 {
+		return out, nil
+	}
+
+	distances, durations, err := ComputePairTravel(c.RoutingProvider, points, missing)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range sources {
+		for j := range destinations {
+			if k, ok := missingIndex[[2]string{srcKeys[i], dstKeys[j]}]; ok {
+				out.Distances[i][j], out.Durations[i][j] = distances[k], durations[k]
+			}
+		}
+	}
+	c.storeCells(points, missing, distances, durations)
+	return out, nil
+}
+
+// lookupCells คืน [distance, duration] ที่ยังไม่หมดอายุของคู่ key ที่ขอ
+func (c cachedProvider) lookupCells(srcKeys, dstKeys []string) map[[2]string][2]float64 {
+	src, dst := uniqueStrings(srcKeys), uniqueStrings(dstKeys)
+	cached := make(map[[2]string][2]float64)
+	since := time.Now().Add(-c.ttl)
+
+	for start := 0; start < len(src); start += travelCacheLookupChunk {
+		var rows []model_database.TravelCache
+		err := c.scope(config.DB).
+			Select("src_key", "dst_key", "distance", "duration").
+			Where("src_key IN ? AND dst_key IN ?", src[start:minInt(start+travelCacheLookupChunk, len(src))], dst).
+			Where("matrix_cached_at > ? AND distance IS NOT NULL AND duration IS NOT NULL", since).
+			Find(&rows).Error
+		if err != nil {
+			log.Printf("⚠️ travel cache read failed: %v", err)
+			return map[[2]string][2]float64{}
+		}
+		for _, r := range rows {
+			cached[[2]string{r.SrcKey, r.DstKey}] = [2]float64{*r.Distance, *r.Duration}
+		}
+	}
+	return cached
+}
+
+// storeCells บันทึกค่าที่ provider เพิ่งคำนวณ (ไม่เก็บ cell ที่ได้ 0 ทั้งคู่ = ไปไม่ถึง เพื่อให้ขอใหม่ครั้งหน้า)
+func (c cachedProvider) storeCells(points []models.StationDetail, pairs [][2]int, distances, durations []float64) {
+	now := time.Now()
+	rows := make([]model_database.TravelCache, 0, len(pairs))
+	for k, p := range pairs {
+		if distances[k] == 0 && durations[k] == 0 {
+			continue
+		}
+		d, t := distances[k], durations[k]
+		rows = append(rows, model_database.TravelCache{
+			Provider:       c.Name(),
+			Server:         c.server,
+			Profile:        c.profile,
+			SrcKey:         travelKey(points[p[0]].Location.Coordinates),
+			DstKey:         travelKey(points[p[1]].Location.Coordinates),
+			Distance:       &d,
+			Duration:       &t,
+			MatrixCachedAt: &now,
+		})
+	}
+	if len(rows) == 0 {
+		return
+	}
+
+	err := config.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "provider"}, {Name: "server"}, {Name: "profile"}, {Name: "src_key"}, {Name: "dst_key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"distance", "duration", "matrix_cached_at"}),
+	}).CreateInBatches(&rows, pairInsertBatchSize).Error
+	if err != nil {
+		log.Printf("⚠️ travel cache write failed: %v", err)
+	}
+}
+
+func (c cachedProvider) Route(start, end [2]float64) ([][2]float64, error) {
+	src, dst := travelKey(start), travelKey(end)
+
+	var row model_database.TravelCache
+	err := c.scope(config.DB).
+		Select("geometry").
+		Where("src_key = ? AND dst_key = ? AND geometry IS NOT NULL AND geometry_cached_at > ?", src, dst, time.Now().Add(-c.ttl)).
+		Limit(1).
+		Find(&row).Error
+	if err != nil {
+		log.Printf("⚠️ travel cache read failed: %v", err)
+	} else if row.Geometry != nil {
+		var coords [][2]float64
+		if json.Unmarshal([]byte(*row.Geometry), &coords) == nil && len(coords) > 0 {
+			return coords, nil
+		}
+	}
+
+	coords, err := c.RoutingProvider.Route(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	if b, mErr := json.Marshal(coords); mErr == nil {
+		geometry, now := string(b), time.Now()
+		err := config.DB.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "provider"}, {Name: "server"}, {Name: "profile"}, {Name: "src_key"}, {Name: "dst_key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"geometry", "geometry_cached_at"}),
+		}).Create(&model_database.TravelCache{
+			Provider:         c.Name(),
+			Server:           c.server,
+			Profile:          c.profile,
+			SrcKey:           src,
+			DstKey:           dst,
+			Geometry:         &geometry,
+			GeometryCachedAt: &now,
+		}).Error
+		if err != nil {
+			log.Printf("⚠️ travel cache write failed: %v", err)
+		}
+	}
+	return coords, nil
+}
+
+// ErrTravelCacheFilterRequired คือคำขอลบ cache ที่ไม่มี filter และไม่ได้ยืนยันว่าจะลบทั้งหมด (controller ตอบ 400)
+var ErrTravelCacheFilterRequired = errors.New("travel cache filter required")
+
+// InvalidateTravelCache ลบ cache ตาม provider / profile (ว่าง = ทุกตัว)
+// expiredOnly = ลบเฉพาะแถวที่ทั้ง matrix และ geometry หมดอายุตาม TRAVEL_CACHE_TTL แล้ว
+// ไม่มี filter เลยต้องส่ง all = true ถึงจะลบทั้งตาราง
+func InvalidateTravelCache(provider, profile string, expiredOnly, all bool) (int64, error) {
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	profile = strings.TrimSpace(profile)
+	if provider == "" && profile == "" && !expiredOnly && !all {
+		return 0, ErrTravelCacheFilterRequired
+	}
+
+	q := config.DB.Session(&gorm.Session{AllowGlobalUpdate: true})
+	if provider != "" {
+		q = q.Where("provider = ?", provider)
+	}
+	if profile != "" {
+		q = q.Where("profile = ?", profile)
+	}
+	if expiredOnly {
+		since := time.Now().Add(-travelCacheTTL())
+		q = q.Where("(matrix_cached_at IS NULL OR matrix_cached_at <= ?) AND (geometry_cached_at IS NULL OR geometry_cached_at <= ?)", since, since)
+	}
+
+	res := q.Delete(&model_database.TravelCache{})
+	if res.Error != nil {
+		return 0, fmt.Errorf("failed to invalidate travel cache: %w", res.Error)
+	}
+	return res.RowsAffected, nil
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
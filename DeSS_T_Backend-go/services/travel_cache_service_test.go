@@ -0,0 +1,25 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTravelServerKey(t *testing.T) {
+	a := travelServerKey("http://osrm-bangkok:5000")
+	if a != travelServerKey(" HTTP://osrm-bangkok:5000/ ") {
+		t.Error("the same server with different spelling should share a key")
+	}
+	if a == travelServerKey("http://osrm-chiangmai:5000") {
+		t.Error("different servers must not share a key")
+	}
+	if len(a) != 16 {
+		t.Errorf("key %q, want 16 hex characters", a)
+	}
+}
+
+func TestInvalidateTravelCacheNeedsFilter(t *testing.T) {
+	if _, err := InvalidateTravelCache(" ", "", false, false); !errors.Is(err, ErrTravelCacheFilterRequired) {
+		t.Errorf("err = %v, want ErrTravelCacheFilterRequired", err)
+	}
+}
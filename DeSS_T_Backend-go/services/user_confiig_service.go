@@ -11,6 +11,7 @@ import (
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func SaveUserConfiguration(input model_database.UserConfiguration) (model_database.UserConfiguration, error) {
@@ -68,7 +69,9 @@ func SaveUserConfiguration(input model_database.UserConfiguration) (model_databa
 			}
 		}
 
-		// --- 4. บันทึก StationPair & RouteBetween ---
+		// --- 4. บันทึก StationPair & RouteBetween (insert ทีละ batch ไม่ใช่ทีละคู่) ---
+		routeBetweens := make([]*model_database.RouteBetween, 0, len(netModel.StationPairs))
+		seenPairs := make(map[[2]string]int, len(netModel.StationPairs))
 		for i := range netModel.StationPairs {
 			pair := &netModel.StationPairs[i]
 			pair.ID = uuid.New().String()
@@ -80,17 +83,28 @@ func SaveUserConfiguration(input model_database.UserConfiguration) (model_databa
 			if !ok1 || !ok2 {
 				return fmt.Errorf("station_pair [%d]: fst(%s) or snd(%s) not found in map", i, pair.FstStationID, pair.SndStationID)
 			}
+			if pair.RouteBetween == nil {
+				return fmt.Errorf("station_pair [%d]: RouteBetween is missing", i)
+			}
+
+			// station_pairs มี unique index (network_model_id, fst, snd)
+			if j, dup := seenPairs[[2]string{newFst, newSnd}]; dup {
+				return fmt.Errorf("station_pair [%d]: same stations as station_pair [%d]", i, j)
+			}
+			seenPairs[[2]string{newFst, newSnd}] = i
 
 			pair.FstStationID = newFst
 			pair.SndStationID = newSnd
 			pair.RouteBetween.ID = uuid.New().String()
-
-			if err := tx.Create(&pair.RouteBetween).Error; err != nil {
-				return err
-			}
 			pair.RouteBetweenID = pair.RouteBetween.ID
+			routeBetweens = append(routeBetweens, pair.RouteBetween)
+		}
 
-			if err := tx.Omit("FstStation", "SndStation", "NetworkModel").Create(pair).Error; err != nil {
+		if len(routeBetweens) > 0 {
+			if err := tx.CreateInBatches(routeBetweens, pairInsertBatchSize).Error; err != nil {
+				return fmt.Errorf("failed to save route between: %w", err)
+			}
+			if err := tx.Omit(clause.Associations).CreateInBatches(&netModel.StationPairs, pairInsertBatchSize).Error; err != nil {
 				return fmt.Errorf("failed to save station pair: %w", err)
 			}
 		}
@@ -175,8 +189,8 @@ func SaveUserConfiguration(input model_database.UserConfiguration) (model_databa
 					}
 					lineWKT := fmt.Sprintf("LINESTRING(%s)", strings.Join(points, ","))
 
-					query := `INSERT INTO route_paths (id, name, color, route_scenario_id, route) VALUES (?, ?, ?, ?, ST_GeomFromText(?, 4326))`
-					if err := tx.Exec(query, rp.ID, rp.Name, rp.Color, rp.RouteScenarioID, lineWKT).Error; err != nil {
+					query := `INSERT INTO route_paths (id, name, color, route_scenario_id, allow_revisit, route) VALUES (?, ?, ?, ?, ?, ST_GeomFromText(?, 4326))`
+					if err := tx.Exec(query, rp.ID, rp.Name, rp.Color, rp.RouteScenarioID, rp.AllowRevisit, lineWKT).Error; err != nil {
 						return fmt.Errorf("failed to save route path %s: %w", rp.Name, err)
 					}
 				}
@@ -294,7 +308,7 @@ func DeleteUserConfigurationByID(configID string) error {
         }
 
         // ค. ลบ ConfigurationDetail (ถ้ามี)
-        // ตรงนี้จะลบ AlightingData, InterArrivalData ตาม Cascade
+        // ตรงนี้จะลบ AlightingData, InterArrivalData, ObservationDataset ตาม Cascade
         if userConfig.ConfigurationDetailID != "" {
             if err := tx.Delete(&model_database.ConfigurationDetail{}, "id = ?", userConfig.ConfigurationDetailID).Error; err != nil {
                 return err
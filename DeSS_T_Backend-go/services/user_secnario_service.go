@@ -69,8 +69,8 @@ func CreateUserScenario(input model_database.UserScenario) (model_database.UserS
 					
 					if len(points) > 0 {
 						lineWKT := fmt.Sprintf("LINESTRING(%s)", strings.Join(points, ","))
-						query := `INSERT INTO route_paths (id, name, color, route_scenario_id, route) VALUES (?, ?, ?, ?, ST_GeomFromText(?, 4326))`
-						if err := tx.Exec(query, rp.ID, rp.Name, rp.Color, rp.RouteScenarioID, lineWKT).Error; err != nil {
+						query := `INSERT INTO route_paths (id, name, color, route_scenario_id, allow_revisit, route) VALUES (?, ?, ?, ?, ?, ST_GeomFromText(?, 4326))`
+						if err := tx.Exec(query, rp.ID, rp.Name, rp.Color, rp.RouteScenarioID, rp.AllowRevisit, lineWKT).Error; err != nil {
 							return fmt.Errorf("failed to save route geometry: %w", err)
 						}
 					}
@@ -116,10 +116,27 @@ func CreateUserScenario(input model_database.UserScenario) (model_database.UserS
 						return fmt.Errorf("ไม่พบอ้างอิง route_path_id: %s ใน schedule_data", schedule.RoutePathID)
 					}
 
+					dayType, err := models.NormalizeDayType(schedule.DayType)
+					if err != nil {
+						return fmt.Errorf("schedule_data ของ route_path_id %s: %w", schedule.RoutePathID, err)
+					}
+					schedule.DayType = dayType
+
 					// บันทึกโดยตัด Pointer ทิ้ง
-					if err := tx.Omit("RoutePath", "BusScenario").Create(schedule).Error; err != nil {
+					if err := tx.Omit("RoutePath", "BusScenario", "HeadwayBands").Create(schedule).Error; err != nil {
 						return fmt.Errorf("failed to create schedule data: %w", err)
 					}
+
+					// กฎ headway (ถ้ามี) — ScheduleList ถูกสร้างจากกฎไว้แล้วใน ApplyScheduleRules
+					for j := range schedule.HeadwayBands {
+						band := &schedule.HeadwayBands[j]
+						band.ID = uuid.New().String()
+						band.ScheduleDataID = schedule.ID
+
+						if err := tx.Omit("ScheduleData").Create(band).Error; err != nil {
+							return fmt.Errorf("failed to create schedule headway band: %w", err)
+						}
+					}
 				}
 				// ----------------------------------------
 
@@ -138,9 +155,21 @@ func CreateUserScenario(input model_database.UserScenario) (model_database.UserS
 						return fmt.Errorf("ไม่พบอ้างอิง route_path_id: %s ใน route_scenario", info.RoutePathID)
 					}
 
-					if err := tx.Omit("RoutePath", "BusScenario").Create(info).Error; err != nil {
+					if err := tx.Omit("RoutePath", "BusScenario", "Bands").Create(info).Error; err != nil {
 						return err
 					}
+
+					// ค่าตามช่วงเวลา (ถ้ามี)
+					for j := range info.Bands {
+						band := &info.Bands[j]
+						band.ID = uuid.New().String()
+						band.BusInformationID = info.ID
+						band.TimeRange = strings.TrimSpace(band.TimeRange)
+
+						if err := tx.Omit("BusInformation").Create(band).Error; err != nil {
+							return fmt.Errorf("failed to create bus information band: %w", err)
+						}
+					}
 				}
 			}
 
@@ -212,7 +241,9 @@ func GetScenarioDetailByID(scenarioDetailID string) (models.ScenarioDetail, stri
 	err := config.DB.
 		Preload("BusScenario").
 		Preload("BusScenario.BusInformations").
+		Preload("BusScenario.BusInformations.Bands").
 		Preload("BusScenario.ScheduleDatas").
+		Preload("BusScenario.ScheduleDatas.HeadwayBands").
 		Preload("RouteScenario").
 		Preload("RouteScenario.RoutePaths").
 		Preload("RouteScenario.RoutePaths.Orders").
@@ -254,6 +285,20 @@ func GetScenarioDetailByID(scenarioDetailID string) (models.ScenarioDetail, stri
 	if dbSD.BusScenario != nil {
 		var mappedBusInfos []models.BusInformation
 		for _, info := range dbSD.BusScenario.BusInformations {
+			var mappedBands []models.BusInformationBand
+			for _, band := range info.Bands {
+				mappedBands = append(mappedBands, models.BusInformationBand{
+					BusInformationBandID: band.ID,
+					BusInformationID:     band.BusInformationID,
+					TimeRange:            band.TimeRange,
+					Speed:                band.Speed,
+					MaxDis:               band.MaxDis,
+					MaxBus:               band.MaxBus,
+					Capacity:             band.Capacity,
+					AvgTravelTime:        band.AvgTravelTime,
+				})
+			}
+
 			mappedBusInfos = append(mappedBusInfos, models.BusInformation{
 				BusInformationID: info.ID,
 				Speed:            info.Speed,
@@ -263,17 +308,13 @@ func GetScenarioDetailByID(scenarioDetailID string) (models.ScenarioDetail, stri
 				AvgTravelTime:	  info.AvgTravelTime,
 				BusScenarioID:    info.BusScenarioID,
 				RoutePathID:      info.RoutePathID,
+				Bands:            mappedBands,
 			})
 		}
 
 		var mappedSchedules []models.ScheduleData
 		for _, sch := range dbSD.BusScenario.ScheduleDatas {
-			mappedSchedules = append(mappedSchedules, models.ScheduleData{
-				ScheduleDataID: sch.ID,
-				ScheduleList:   sch.ScheduleList,
-				RoutePathID:    sch.RoutePathID,
-				BusScenarioID:  sch.BusScenarioID,
-			})
+			mappedSchedules = append(mappedSchedules, scheduleDataToDTO(sch))
 		}
 
 		response.BusScenario = models.BusScenario{
@@ -327,6 +368,7 @@ func GetScenarioDetailByID(scenarioDetailID string) (models.ScenarioDetail, stri
 				Name:        rp.Name,
 				Color:       rp.Color,
 				Route:       parseWKTToGeoLineString(wktString), 
+				AllowRevisit: rp.AllowRevisit,
 				Orders:      mappedOrders,
 			})
 		}
@@ -386,7 +428,13 @@ func DeleteUserScenarioByID(scenarioID string) error {
 		// ค. กวาดลบฝั่ง Bus Scenario
 		if busScenarioID != "" {
 			// ลบข้อมูลลูกๆ ของ Bus ก่อน
+			tx.Where("bus_information_id IN (?)",
+				tx.Model(&model_database.BusInformation{}).Select("id").Where("bus_scenario_id = ?", busScenarioID),
+			).Delete(&model_database.BusInformationBand{})
 			tx.Where("bus_scenario_id = ?", busScenarioID).Delete(&model_database.BusInformation{})
+			tx.Where("schedule_data_id IN (?)",
+				tx.Model(&model_database.ScheduleData{}).Select("id").Where("bus_scenario_id = ?", busScenarioID),
+			).Delete(&model_database.ScheduleHeadwayBand{})
 			tx.Where("bus_scenario_id = ?", busScenarioID).Delete(&model_database.ScheduleData{})
 			
 			// ลบ BusScenario ตัวแม่
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"DeSS_T_Backend-go/models"
+)
+
+/* ───────────────────────────── VALHALLA ───────────────────────────── */
+
+const (
+	defaultValhallaCosting  = "auto"
+	valhallaMatrixChunkSize = 50
+)
+
+// valhallaProvider ใช้ Valhalla server ที่ host เอง (/sources_to_targets และ /route)
+type valhallaProvider struct {
+	baseURL string
+	costing string
+	client  *http.Client
+}
+
+type valhallaLocation struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+func newValhallaProvider() (RoutingProvider, error) {
+	base := strings.TrimRight(strings.TrimSpace(os.Getenv("VALHALLA_URL")), "/")
+	if base == "" {
+		return nil, fmt.Errorf("%w: VALHALLA_URL is missing", ErrRoutingProviderUnavailable)
+	}
+	costing := strings.TrimSpace(os.Getenv("VALHALLA_COSTING"))
+	if costing == "" {
+		costing = defaultValhallaCosting
+	}
+	return withTravelCache(valhallaProvider{baseURL: base, costing: costing, client: &http.Client{Timeout: orsHTTPTimeout()}}, base, costing), nil
+}
+
+func (p valhallaProvider) Name() string { return RoutingProviderValhalla }
+
+func valhallaLocations(stations []models.StationDetail) []valhallaLocation {
+	out := make([]valhallaLocation, len(stations))
+	for i, s := range stations {
+		out[i] = valhallaLocation{Lat: s.Location.Coordinates[1], Lon: s.Location.Coordinates[0]}
+	}
+	return out
+}
+
+func (p valhallaProvider) Matrix(stations []models.StationDetail) (*RoutingMatrix, error) {
+	return p.Table(stations, stations)
+}
+
+func (p valhallaProvider) Table(sources, destinations []models.StationDetail) (*RoutingMatrix, error) {
+	m, err := chunkedTable(sources, destinations, valhallaMatrixChunkSize, func(src, dst []models.StationDetail) (*RoutingMatrix, error) {
+		if dst == nil {
+			dst = src
+		}
+		body := map[string]interface{}{
+			"sources": valhallaLocations(src),
+			"targets": valhallaLocations(dst),
+			"costing": p.costing,
+			"units":   "kilometers",
+		}
+		data, err := postRoutingJSON(p.client, p.baseURL+"/sources_to_targets", body, "Valhalla Matrix")
+		if err != nil {
+			return nil, err
+		}
+
+		// ค่า null (ไปไม่ถึง) ได้ 0 เหมือน matrix ของ ORS
+		var parsed struct {
+			SourcesToTargets [][]struct {
+				Distance float64 `json:"distance"`
+				Time     float64 `json:"time"`
+			} `json:"sources_to_targets"`
+		}
+		if err := json.Unmarshal(data, &parsed); err != nil {
+			return nil, fmt.Errorf("Invalid Valhalla Matrix response: %v", err)
+		}
+
+		m := &RoutingMatrix{
+			Distances: make([][]float64, len(parsed.SourcesToTargets)),
+			Durations: make([][]float64, len(parsed.SourcesToTargets)),
+		}
+		for i, row := range parsed.SourcesToTargets {
+			m.Distances[i] = make([]float64, len(row))
+			m.Durations[i] = make([]float64, len(row))
+			for j, cell := range row {
+				m.Distances[i][j] = cell.Distance * 1000
+				m.Durations[i][j] = cell.Time
+			}
+		}
+		return m, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Valhalla Matrix: %w", err)
+	}
+	return m, nil
+}
+
+func (p valhallaProvider) Route(start, end [2]float64) ([][2]float64, error) {
+	body := map[string]interface{}{
+		"locations": []valhallaLocation{
+			{Lat: start[1], Lon: start[0]},
+			{Lat: end[1], Lon: end[0]},
+		},
+		"costing":         p.costing,
+		"directions_type": "none",
+	}
+	data, err := postRoutingJSON(p.client, p.baseURL+"/route", body, "Valhalla Route")
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed struct {
+		Trip struct {
+			Legs []struct {
+				Shape string `json:"shape"`
+			} `json:"legs"`
+		} `json:"trip"`
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("Valhalla parse error: %v", err)
+	}
+
+	var result [][2]float64
+	for _, leg := range parsed.Trip.Legs {
+		pts, err := decodePolyline(leg.Shape, 1e6)
+		if err != nil {
+			return nil, fmt.Errorf("Valhalla shape: %w", err)
+		}
+		result = append(result, pts...)
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("Valhalla returned empty coordinates")
+	}
+	return result, nil
+}
+
+// decodePolyline ถอด encoded polyline (Valhalla ใช้ความละเอียด 1e6) เป็น [lon, lat]
+func decodePolyline(encoded string, precision float64) ([][2]float64, error) {
+	var out [][2]float64
+	var lat, lon int
+	for i := 0; i < len(encoded); {
+		var deltas [2]int
+		for k := range deltas {
+			result, shift := 0, uint(0)
+			for {
+				if i >= len(encoded) {
+					return nil, fmt.Errorf("truncated polyline")
+				}
+				b := int(encoded[i]) - 63
+				i++
+				result |= (b & 0x1f) << shift
+				shift += 5
+				if b < 0x20 {
+					break
+				}
+			}
+			if result&1 != 0 {
+				deltas[k] = ^(result >> 1)
+			} else {
+				deltas[k] = result >> 1
+			}
+		}
+		lat += deltas[0]
+		lon += deltas[1]
+		out = append(out, [2]float64{float64(lon) / precision, float64(lat) / precision})
+	}
+	return out, nil
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// encodeTestPolyline เข้ารหัส [lon, lat] เป็น encoded polyline (ตัวกลับของ decodePolyline)
+func encodeTestPolyline(points [][2]float64, precision float64) string {
+	var sb strings.Builder
+	var prevLat, prevLon int
+	for _, p := range points {
+		lat, lon := int(math.Round(p[1]*precision)), int(math.Round(p[0]*precision))
+		for _, delta := range []int{lat - prevLat, lon - prevLon} {
+			v := delta << 1
+			if delta < 0 {
+				v = ^v
+			}
+			for v >= 0x20 {
+				sb.WriteByte(byte((0x20 | (v & 0x1f)) + 63))
+				v >>= 5
+			}
+			sb.WriteByte(byte(v + 63))
+		}
+		prevLat, prevLon = lat, lon
+	}
+	return sb.String()
+}
+
+func fakeValhalla(t *testing.T, requests *int32) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		if r.Method != http.MethodPost {
+			t.Errorf("method = %s, want POST", r.Method)
+		}
+
+		switch r.URL.Path {
+		case "/sources_to_targets":
+			var body struct {
+				Sources []valhallaLocation `json:"sources"`
+				Targets []valhallaLocation `json:"targets"`
+				Costing string             `json:"costing"`
+				Units   string             `json:"units"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Errorf("decode request: %v", err)
+			}
+			if body.Costing != defaultValhallaCosting || body.Units != "kilometers" {
+				t.Errorf("costing = %q, units = %q", body.Costing, body.Units)
+			}
+
+			type cell struct {
+				Distance *float64 `json:"distance"`
+				Time     *float64 `json:"time"`
+			}
+			rows := make([][]cell, len(body.Sources))
+			for i, s := range body.Sources {
+				rows[i] = make([]cell, len(body.Targets))
+				for j, d := range body.Targets {
+					v := testDuration(testIndex(s.Lat), testIndex(d.Lat))
+					km := v * 10 / 1000
+					rows[i][j] = cell{Distance: &km, Time: &v}
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"sources_to_targets": rows})
+		case "/route":
+			shape := encodeTestPolyline([][2]float64{{100, 0}, {100.123456, 0.005}, {100, 0.01}}, 1e6)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"trip": map[string]interface{}{"legs": []map[string]string{{"shape": shape}}},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func testValhallaProvider(srv *httptest.Server) valhallaProvider {
+	return valhallaProvider{baseURL: srv.URL, costing: defaultValhallaCosting, client: srv.Client()}
+}
+
+func TestValhallaTableChunks(t *testing.T) {
+	var requests int32
+	srv := fakeValhalla(t, &requests)
+	defer srv.Close()
+
+	src := testStations("S", valhallaMatrixChunkSize+3, 100)
+	dst := testStations("D", valhallaMatrixChunkSize+7, 101)
+	m, err := testValhallaProvider(srv).Table(src, dst)
+	if err != nil {
+		t.Fatalf("Table: %v", err)
+	}
+	// ระยะทางเป็นกิโลเมตรใน response → เมตร
+	checkTestMatrix(t, m, len(src), len(dst), 10)
+	if requests != 4 {
+		t.Errorf("requests = %d, want 4 chunks", requests)
+	}
+}
+
+func TestValhallaMatrixUnreachableIsZero(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"sources_to_targets":[[{"distance":0,"time":0},{"distance":null,"time":null}],[{"distance":1.5,"time":90},{"distance":0,"time":0}]]}`))
+	}))
+	defer srv.Close()
+
+	m, err := testValhallaProvider(srv).Matrix(testStations("S", 2, 100))
+	if err != nil {
+		t.Fatalf("Matrix: %v", err)
+	}
+	if m.Distances[0][1] != 0 || m.Durations[0][1] != 0 {
+		t.Errorf("unreachable cell = %v / %v, want 0", m.Distances[0][1], m.Durations[0][1])
+	}
+	if m.Distances[1][0] != 1500 || m.Durations[1][0] != 90 {
+		t.Errorf("cell [1][0] = %v m / %v s, want 1500 / 90", m.Distances[1][0], m.Durations[1][0])
+	}
+}
+
+func TestValhallaMatrixRejectsWrongShape(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"sources_to_targets":[[{"distance":0,"time":0}]]}`))
+	}))
+	defer srv.Close()
+
+	if _, err := testValhallaProvider(srv).Matrix(testStations("S", 2, 100)); err == nil {
+		t.Error("expected an error for a 1x1 answer to a 2x2 request")
+	}
+}
+
+func TestValhallaRoute(t *testing.T) {
+	var requests int32
+	srv := fakeValhalla(t, &requests)
+	defer srv.Close()
+
+	coords, err := testValhallaProvider(srv).Route([2]float64{100, 0}, [2]float64{100, 0.01})
+	if err != nil {
+		t.Fatalf("Route: %v", err)
+	}
+	want := [][2]float64{{100, 0}, {100.123456, 0.005}, {100, 0.01}}
+	if len(coords) != len(want) {
+		t.Fatalf("coords = %v, want %v", coords, want)
+	}
+	for i := range want {
+		if math.Abs(coords[i][0]-want[i][0]) > 1e-9 || math.Abs(coords[i][1]-want[i][1]) > 1e-9 {
+			t.Errorf("coords[%d] = %v, want %v", i, coords[i], want[i])
+		}
+	}
+}
+
+func TestValhallaErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error_code":171,"error":"No suitable edges near location"}`))
+	}))
+	defer srv.Close()
+
+	p := testValhallaProvider(srv)
+	if _, err := p.Matrix(testStations("S", 2, 100)); err == nil || !strings.Contains(err.Error(), "(400)") {
+		t.Errorf("Matrix err = %v, want HTTP 400", err)
+	}
+	if _, err := p.Route([2]float64{100, 0}, [2]float64{100, 0.01}); err == nil || !strings.Contains(err.Error(), "No suitable edges") {
+		t.Errorf("Route err = %v, want the server message", err)
+	}
+}
+
+func TestDecodePolylineTruncated(t *testing.T) {
+	encoded := encodeTestPolyline([][2]float64{{100.5, 13.7}}, 1e6)
+	if _, err := decodePolyline(encoded[:len(encoded)-1], 1e6); err == nil {
+		t.Error("expected an error for a truncated polyline")
+	}
+}
@@ -8,6 +8,6 @@ router = APIRouter()
 def simulate(req: SimulationRequest):
     try:
         result = run_simulation(req)
-        return {"result": "success", "simulation_result": result.simulation_result, "logs": result.logs}
+        return {"result": "success", "simulation_result": result.simulation_result, "logs": result.logs, "bus_events": result.bus_events}
     except Exception as e:
         raise HTTPException(status_code=500, detail=str(e))
\ No newline at end of file
@@ -14,12 +14,19 @@ class RouteBusInformation(BaseModel):
     bus_capacity: int = Field(..., alias="bus_capacity")
     avg_travel_time: float = Field(..., alias="avg_travel_time")
 
+class RouteBusBand(BaseModel):
+    window: str = Field("", alias="window")
+    period: str = Field(..., alias="period")
+    bus_information: RouteBusInformation = Field(..., alias="bus_information")
+
 class ScenarioData(BaseModel):
     route_id: str = Field(..., alias="route_id")
     route_name: str = Field(..., alias="route_name")
     route_order: str = Field(..., alias="route_order")
     route_schedule: List[RouteSchedule] = Field(..., alias="route_schedule")
     bus_information: RouteBusInformation = Field(..., alias="bus_information")
+    # ค่ารถต่อ time window เลือกตามเวลาออกรถ (เวลานอกทุก window ใช้ bus_information)
+    bus_bands: List[RouteBusBand] = Field(default_factory=list, alias="bus_bands")
 
 class station(BaseModel):
     station_id: str = Field(..., alias="station_id")
@@ -65,10 +72,21 @@ class SimulationLog(BaseModel):
     message: str
 
 
+class BusEvent(BaseModel):
+    # arrive/depart ของรถแต่ละคันที่แต่ละสถานี (station_id = id ที่ส่งมาใน route_pair)
+    bus_id: str
+    route_id: str
+    station_id: str
+    type: str  # "arrive" | "depart"
+    time: str  # HH:MM:SS
+    load: int  # ผู้โดยสารบนรถหลัง event
+
+
 class SimulationResponse(BaseModel):
     result: str
     simulation_result: SimulationResult
     logs: List[SimulationLog]
+    bus_events: List[BusEvent] = []
 
 class SimulationResult(BaseModel):
     result_summary: ResultSummary
@@ -11,7 +11,8 @@ def fit_best_distribution_alighting(values: List[float]) -> Dict:
     Fit distributions specifically optimized for alighting (discrete/count data).
     """
     if not values:
-        return {"name": "No Alighting", "params": (0,)}
+        # ไม่มีข้อมูล = ไม่มีคนลง (ใช้ Constant ที่ simulation engine รู้จัก)
+        return {"name": "Constant", "params": (0.0,)}
 
     data = np.array(values)
     n = len(data)
@@ -38,11 +39,11 @@ def fit_best_distribution_alighting(values: List[float]) -> Dict:
 
     # --- Group B: Continuous Distributions (For high volume/spread) ---
     # เราจะลอง Continuous เฉพาะเมื่อข้อมูลมีค่าหลากหลายพอ
+    # ลองเฉพาะ family ที่ simulation engine สร้างได้ (build_distribution)
     if np.unique(data).size > 3:
         cont_distributions = {
             "Exponential": stats.expon,
             "Gamma": stats.gamma,
-            "Normal": stats.norm,
             "Uniform": stats.uniform
         }
 
@@ -5,6 +5,7 @@ import salabim as sim
 from app.services.simulation_logger import add_log, SimulationLogger
 
 from app.schemas.Simulation import (
+    BusEvent,
     ResultRoute,
     ResultStation,
     SimulationResponse,
@@ -46,6 +47,7 @@ class SimulationEngine:
         self.env.route_active_bus = {}   # route_id -> int
         self.env.route_max_bus = {}      # route_id -> int
         self.env.route_bus_seq = {}   # route_id -> running bus number
+        self.bus_events = []          # arrive/depart ของรถทุกคัน (ใช้ทำ timeline ฝั่ง Go)
 
 
     def build(self):
@@ -96,16 +98,26 @@ class SimulationEngine:
             self.env.route_bus_seq.setdefault(rid, 0)
             self.env.route_max_bus[rid] = max_bus
 
+            bands = self.config.get("BUS_BANDS", {}).get(route_id, [])
+
             for depart_time in self.config["BUS_SCHEDULES"][route_id]:
+                # ค่ารถของ time window ที่เวลาออกรถอยู่ (ไม่อยู่ใน window ไหนใช้ค่าของทั้งช่วง)
+                band_info, band_times = info, self.config["TRAVEL_TIMES"][route_id]
+                for t0, t1, b_info, b_times in bands:
+                    if t0 <= depart_time < t1:
+                        band_info, band_times = b_info, b_times
+                        break
+
                 Bus(
                     route_id=route_id,
                     route=route_objs,
-                    capacity=info["capacity"],
-                    max_distance=info["max_distance"],
+                    capacity=band_info["capacity"],
+                    max_distance=band_info["max_distance"],
+                    max_bus=band_info["max_bus"],
                     depart_time=depart_time,
                     alighting_rules=processed_alighting, # ใช้ตัวที่ปรุงเสร็จแล้ว
                     time_ctx=self.config["TIME_CTX"],
-                    travel_times={k: v / 60 for k, v in self.config["TRAVEL_TIMES"][route_id].items()},
+                    travel_times={k: v / 60 for k, v in band_times.items()},
                     travel_distances=self.config["TRAVEL_DISTANCES"][route_id],
                     env=self.env
                 )
@@ -228,8 +240,19 @@ class SimulationEngine:
                 result_summary=summary,
                 slot_results=slot_results
             ),
-            logs=self.env.logger.logs
+            logs=self.env.logger.logs,
+            bus_events=self.bus_events
         )
+
+    def record_bus_event(self, bus, station, event_type):
+        self.bus_events.append(BusEvent(
+            bus_id=bus.bus_id,
+            route_id=bus.route_id,
+            station_id=station.name,
+            type=event_type,
+            time=self.config["TIME_CTX"].sim_to_clock(self.env.now()),
+            load=len(bus.passengers),
+        ))
     
 class SlotTicker(sim.Component):
     def __init__(self, time_ctx, env):
@@ -330,6 +353,7 @@ class Bus(sim.Component):
         route,
         capacity,
         max_distance,    # 👈 เพิ่ม
+        max_bus,
         depart_time,
         alighting_rules,
         time_ctx,
@@ -348,6 +372,7 @@ class Bus(sim.Component):
 
         self.route = route
         self.capacity = capacity
+        self.max_bus = max_bus
         self.depart_time = depart_time
         self.alighting_rules = alighting_rules
         self.time_ctx = time_ctx
@@ -382,7 +407,7 @@ class Bus(sim.Component):
             
         # 2. ตรวจสอบจำนวนรถที่วิ่งอยู่ในเส้นทาง (Max Bus Check)
         active = self.env.route_active_bus[self.route_id]
-        max_bus = self.env.route_max_bus[self.route_id]
+        max_bus = self.max_bus
 
         if active >= max_bus:
             add_log(
@@ -405,6 +430,7 @@ class Bus(sim.Component):
             is_last_station = (i == len(self.route) - 1)
             
             add_log(self.env, "Bus", f"Bus {self.bus_id} arrives at {station.name}")
+            self.env.sim_engine.record_bus_event(self, station, "arrive")
 
             # ---------- ALIGHTING (ผู้โดยสารลงรถ) ----------
             if is_first_station:
@@ -531,10 +557,12 @@ class Bus(sim.Component):
 
                 # เริ่มวิ่งจริง
                 add_log(self.env, "Bus", f"Bus {self.bus_id} traveling to {to_st} (Time: {travel_time:.2f})")
+                self.env.sim_engine.record_bus_event(self, station, "depart")
                 yield self.hold(max(0.0001, travel_time))
 
-        # 4. จบเส้นทาง (End of Route)
+        # 4. จบเส้นทาง (End of Route) depart ที่สถานีสุดท้าย = รถออกจากเส้นทาง (ผู้โดยสารลงหมดแล้ว)
         add_log(self.env, "Bus", f"Bus {self.bus_id} finished route at {self.route[-1].name}")
+        self.env.sim_engine.record_bus_event(self, self.route[-1], "depart")
         
         self.env.route_travel_time_mon[self.route_id].tally(self.total_travel_time)
         self.env.route_travel_dist_mon[self.route_id].tally(self.total_travel_dist)
@@ -24,6 +24,14 @@ def build_simulation_config(req):
         bus_info
     )
 
+    bus_bands = map_bus_bands(
+        req.scenario_data,
+        bus_routes,
+        travel_times_ideal,
+        distances,
+        time_ctx
+    )
+
     route_distances = build_route_distances(
         bus_routes,
         distances
@@ -56,6 +64,7 @@ def build_simulation_config(req):
         "TRAVEL_DISTANCES": route_distances,
         "BUS_ROUTES": bus_routes,
         "BUS_INFO": bus_info,
+        "BUS_BANDS": bus_bands,
         "BUS_SCHEDULES": bus_schedules,
         "INTERARRIVAL_RULES": interarrival_rules,
         "ALIGHTING_RULES": alighting_rules,
@@ -153,6 +162,10 @@ def build_distribution(name: str, args: str):
         # Constant ไม่ต้องการ env ในการระบุตัวเลขคงที่
         return lambda env: sim.Constant(params["value"])
 
+    if name == "no alighting":
+        # ชื่อเก่าของ alighting fitter เมื่อไม่มีข้อมูล = ไม่มีคนลง
+        return lambda env: sim.Constant(params.get("value", 0.0))
+
     if name == "poisson":
         # Poisson ใน Salabim ไม่รับ env ใน __init__
         return lambda env: sim.Poisson(params["lambda"])
@@ -171,6 +184,14 @@ def build_distribution(name: str, args: str):
         high = params.get("high", params.get("max"))
         return lambda env: sim.Uniform(low, high, env=env) + params.get("loc", 0.0)
 
+    if name == "empirical":
+        # ตาราง quantile q0..q100 → Cdf ของ salabim (สุ่มแบบ inverse CDF เชิงเส้นระหว่างจุด)
+        table = sorted((float(k[1:]), v) for k, v in params.items() if k.startswith("q"))
+        spec = []
+        for p, v in table:
+            spec.extend([v, p / 100.0])
+        return lambda env: sim.Cdf(spec, env=env)
+
     return lambda env: sim.Constant(999999, env=env)
 
 
@@ -200,22 +221,45 @@ def map_bus_information(scenarios):
     bus_info = {}
 
     for sc in scenarios:
-        info = sc.bus_information
+        bus_info[sc.route_id] = to_bus_info(sc.bus_information)
 
-        bus_info[sc.route_id] = {
-            # km/h → m/s
-            "speed": info.bus_speed * 1000 / 3600,
+    return bus_info
 
-            # km → m
-            "max_distance": info.max_distance * 1000,
 
-            "max_bus": info.max_bus,
-            "capacity": info.bus_capacity,
-            #minutes to seconds
-            "avg_travel_time": info.avg_travel_time *60
-        }
+def map_bus_bands(scenarios, bus_routes, travel_times_ideal, travel_distances, time_ctx):
+    # route_id -> [(t0, t1, info, travel_times)] เวลาเป็น sim minute, window แรกที่ครอบเวลาออกรถถูกใช้
+    bands = {}
 
-    return bus_info
+    for sc in scenarios:
+        route_bands = []
+        for band in sc.bus_bands:
+            t0, t1 = time_ctx.range_to_sim(band.period)
+            info = to_bus_info(band.bus_information)
+            travel_times = build_travel_times(
+                {sc.route_id: bus_routes[sc.route_id]},
+                travel_times_ideal,
+                travel_distances,
+                {sc.route_id: info}
+            )[sc.route_id]
+            route_bands.append((t0, t1, info, travel_times))
+        bands[sc.route_id] = route_bands
+
+    return bands
+
+
+def to_bus_info(info):
+    return {
+        # km/h → m/s
+        "speed": info.bus_speed * 1000 / 3600,
+
+        # km → m
+        "max_distance": info.max_distance * 1000,
+
+        "max_bus": info.max_bus,
+        "capacity": info.bus_capacity,
+        #minutes to seconds
+        "avg_travel_time": info.avg_travel_time *60
+    }
 
 
 def map_bus_schedules(scenarios, time_ctx):
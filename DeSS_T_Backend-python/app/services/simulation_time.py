@@ -1,5 +1,6 @@
 def parse_hour_min(t: str) -> int:
-    h, m = map(int, t.replace(":", ".").split("."))
+    # "HH:MM" หรือ "HH:MM:SS" (วินาทีถูกตัดทิ้ง simulation ทำงานเป็นนาที)
+    h, m = map(int, t.replace(":", ".").split(".")[:2])
     return h * 60 + m
 
 class TimeContext:
@@ -19,6 +20,11 @@ class TimeContext:
         total = int(sim_time) + self.real_start
         return f"{total//60:02d}:{total%60:02d}"
 
+    def sim_to_clock(self, sim_time: float) -> str:
+        # HH:MM:SS ใช้กับ bus_events ที่ต้องละเอียดกว่านาที
+        total = int(round((sim_time + self.real_start) * 60))
+        return f"{total//3600:02d}:{(total%3600)//60:02d}:{total%60:02d}"
+
     def slot_index(self, sim_time: float) -> int:
         return min(
             int(sim_time // self.slot_length),
@@ -584,6 +584,7 @@ export default function Scenario({
           capacity: info?.capacity ?? 16,
           maxBuses: info?.max_bus ?? 4,
           routeTravelingTime: info?.avg_travel_time ?? 0,
+          allowRevisit: path.allow_revisit ?? false,
         };
       },
     );
@@ -647,6 +648,15 @@ export default function Scenario({
     );
   };
 
+  const toggleAllowRevisit = (routeId: string) => {
+    markAsChanged();
+    setRoutes((prev) =>
+      prev.map((r) =>
+        r.id === routeId ? { ...r, allowRevisit: !r.allowRevisit } : r,
+      ),
+    );
+  };
+
   const handleColorPick = (routeId: string, color: ColorResult) => {
     markAsChanged(); // 🔹 Mark as changed when route color updated
     setRoutes((prev) =>
@@ -822,6 +832,20 @@ export default function Scenario({
             },
           };
           orders.push(order);
+        } else {
+          // network แบบ knn / radius ไม่มีทุกคู่: ส่ง station ต้นทาง/ปลายทางไป ให้ backend คำนวณคู่นี้ตอนบันทึก
+          orders.push({
+            order_id: `${routeId}-order-${i + 1}`,
+            order: i + 1,
+            station_pair_id: "",
+            route_path_id: routeId,
+            station_pair: {
+              StationPairID: "",
+              FstStation: currentStationId,
+              SndStation: nextStationId,
+              route_between_id: "",
+            },
+          });
         }
       }
 
@@ -1034,6 +1058,7 @@ export default function Scenario({
         : "route-scenario-" + currentScenarioId,
       route: buildGeoJsonLineString(r.segments),
       orders: r.orders,
+      allow_revisit: r.allowRevisit ?? false,
     }));
 
     const routeScenario: RouteScenario = {
@@ -1646,6 +1671,7 @@ export default function Scenario({
                       onUpdateName={updateName}
                       onToggleColorPicker={setOpenColorPickerId}
                       onToggleExpand={toggleStationExpand}
+                      onToggleAllowRevisit={toggleAllowRevisit}
                       getStationName={getStationName}
                     />
                   )}
@@ -18,6 +18,7 @@ export interface SimpleRoute {
   capacity: number;
   maxBuses: number;
   routeTravelingTime: number;
+  allowRevisit?: boolean; // เส้นทางเลขแปดที่ผ่าน station เดิมซ้ำโดยไม่ใช่ขากลับ
 }
 
 interface RouteCardProps {
@@ -40,6 +41,7 @@ interface RouteCardProps {
   onToggleColorPicker: () => void;
   onCloseColorPicker: () => void;
   onToggleExpand: () => void;
+  onToggleAllowRevisit: () => void;
   getStationName: (stationId: string) => string;
 }
 
@@ -63,6 +65,7 @@ export default function RouteCard({
   onToggleColorPicker,
   onCloseColorPicker,
   onToggleExpand,
+  onToggleAllowRevisit,
   getStationName,
 }: RouteCardProps) {
   const isDisabled = isLocked || isOtherRouteBeingEdited;
@@ -349,6 +352,15 @@ export default function RouteCard({
                     )}
                   </React.Fragment>
                 ))}
+                <label className="mt-4 flex items-center gap-2 text-sm text-gray-600">
+                  <input
+                    type="checkbox"
+                    checked={route.allowRevisit ?? false}
+                    onChange={onToggleAllowRevisit}
+                    disabled={isDisabled}
+                  />
+                  Allow revisiting stations (figure-eight route)
+                </label>
                 <span
                   role="button"
                   tabIndex={0}
@@ -19,6 +19,7 @@ interface RoutesListProps {
   onUpdateName: (routeId: string, name: string) => void;
   onToggleColorPicker: (routeId: string | null) => void;
   onToggleExpand: (routeId: string) => void;
+  onToggleAllowRevisit: (routeId: string) => void;
   getStationName: (stationId: string) => string;
 }
 
@@ -39,6 +40,7 @@ export default function RoutesList({
   onUpdateName,
   onToggleColorPicker,
   onToggleExpand,
+  onToggleAllowRevisit,
   getStationName,
 }: RoutesListProps) {
   return (
@@ -78,6 +80,7 @@ export default function RoutesList({
               }
               onCloseColorPicker={() => onToggleColorPicker(null)}
               onToggleExpand={() => onToggleExpand(r.id)}
+              onToggleAllowRevisit={() => onToggleAllowRevisit(r.id)}
               getStationName={getStationName}
             />
           );
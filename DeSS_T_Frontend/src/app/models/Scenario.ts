@@ -54,6 +54,7 @@ export interface RoutePath {
   route_scenario_id: string;
   route: GeoLineString;
   orders?: Order[];
+  allow_revisit?: boolean; // เลขแปด: ผ่าน station เดิมซ้ำโดยไม่ใช่ขากลับ
 }
 
 // ------------------- ORDER --------------------